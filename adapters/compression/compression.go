@@ -0,0 +1,322 @@
+// Package compression provides transparent decompression and configurable
+// compression for file based adapters.
+//
+// Sources open files through Open or ReadFile, which detect the codec from
+// the file extension or, failing that, from the leading magic bytes. Stores
+// write through Create or WriteFile with an explicit Compression setting.
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Compression selects how a store compresses its output.
+type Compression string
+
+const (
+	// None writes plain bytes.
+	None Compression = ""
+	// Auto picks the codec from the destination file extension, and writes
+	// plain bytes when that codec cannot write.
+	Auto Compression = "auto"
+	// Gzip writes gzip compressed output.
+	Gzip Compression = "gzip"
+	// Bzip2 identifies bzip2 data. It can only be read.
+	Bzip2 Compression = "bzip2"
+	// Zstd identifies zstd data. It requires a registered codec.
+	Zstd Compression = "zstd"
+)
+
+// Codec describes a compression format.
+type Codec struct {
+	Name       Compression                               // Codec identifier
+	Extensions []string                                  // File extensions, including the leading dot
+	Magic      []byte                                    // Leading bytes identifying the format
+	NewReader  func(r io.Reader) (io.ReadCloser, error)  // Decompressor, nil if reading is unsupported
+	NewWriter  func(w io.Writer) (io.WriteCloser, error) // Compressor, nil if writing is unsupported
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = map[Compression]Codec{
+		Gzip: {
+			Name:       Gzip,
+			Extensions: []string{".gz", ".gzip"},
+			Magic:      []byte{0x1f, 0x8b},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			},
+		},
+		Bzip2: {
+			Name:       Bzip2,
+			Extensions: []string{".bz2"},
+			Magic:      []byte("BZh"),
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(bzip2.NewReader(r)), nil
+			},
+		},
+		// The standard library has no public zstd package, so the codec is
+		// only detected. Register a Codec named Zstd to enable it.
+		Zstd: {
+			Name:       Zstd,
+			Extensions: []string{".zst", ".zstd"},
+			Magic:      []byte{0x28, 0xb5, 0x2f, 0xfd},
+		},
+	}
+)
+
+// Register adds or replaces a codec. It is typically called from an init
+// function to plug in formats that are not part of the standard library.
+func Register(codec Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[codec.Name] = codec
+}
+
+// Lookup returns the codec registered under the given name.
+func Lookup(name Compression) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// sorted returns the registered codecs ordered by name, so that lookups
+// matching several codecs always pick the same one.
+func sorted() []Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := make([]Codec, 0, len(codecs))
+	for _, c := range codecs {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// ByExtension returns the codec matching the extension of path. When
+// several codecs claim the extension, the first by name wins.
+func ByExtension(path string) (Codec, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return Codec{}, false
+	}
+
+	for _, c := range sorted() {
+		for _, e := range c.Extensions {
+			if e == ext {
+				return c, true
+			}
+		}
+	}
+	return Codec{}, false
+}
+
+// ByMagic returns the codec whose magic bytes prefix header. When several
+// codecs match, the first by name wins.
+func ByMagic(header []byte) (Codec, bool) {
+	for _, c := range sorted() {
+		if len(c.Magic) > 0 && bytes.HasPrefix(header, c.Magic) {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// TrimExtension removes a compression extension from path, so that
+// "logs.json.gz" becomes "logs.json".
+func TrimExtension(path string) string {
+	if _, ok := ByExtension(path); ok {
+		return strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return path
+}
+
+// NewReader wraps r with a decompressor. The codec is detected from the
+// extension of name when possible, otherwise from the magic bytes of the
+// stream. Uncompressed input is returned as is. A codec that cannot read
+// is only trusted on its magic bytes, so that the plain files Auto writes
+// with its extension can be read back.
+func NewReader(r io.Reader, name string) (io.ReadCloser, error) {
+	if c, ok := ByExtension(name); ok && c.NewReader != nil {
+		return decompress(c, r)
+	}
+
+	br := bufio.NewReader(r)
+	header, err := br.Peek(maxMagicLen())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	if c, ok := ByMagic(header); ok {
+		return decompress(c, br)
+	}
+	return io.NopCloser(br), nil
+}
+
+// Resolve returns the compression c writes a file named name with. Auto
+// resolves to the codec of the extension, or to None when there is none or
+// it cannot write, such as zstd without a registered codec; skipped is then
+// the name of that codec, for callers to warn about.
+func Resolve(name string, c Compression) (resolved, skipped Compression) {
+	if c != Auto {
+		return c, None
+	}
+	codec, ok := ByExtension(name)
+	switch {
+	case !ok:
+		return None, None
+	case codec.NewWriter == nil:
+		return None, codec.Name
+	}
+	return codec.Name, None
+}
+
+// NewWriter wraps w with the compressor selected by c. Auto resolves the
+// codec from the extension of name, see Resolve.
+func NewWriter(w io.Writer, name string, c Compression) (io.WriteCloser, error) {
+	c, _ = Resolve(name, c)
+	if c == None {
+		return nopWriteCloser{w}, nil
+	}
+
+	codec, ok := Lookup(c)
+	if !ok {
+		return nil, fmt.Errorf("unknown compression: %s", c)
+	}
+	if codec.NewWriter == nil {
+		return nil, fmt.Errorf("compression %s does not support writing", c)
+	}
+	return codec.NewWriter(w)
+}
+
+// Open opens the file at path for reading and transparently decompresses it.
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return wrapFile(f, path)
+}
+
+// OpenFS opens the file at path in fsys and transparently decompresses it.
+func OpenFS(fsys fs.FS, path string) (io.ReadCloser, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return wrapFile(f, path)
+}
+
+// ReadFile reads and decompresses the whole file at path.
+func ReadFile(path string) ([]byte, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Create creates the file at path and returns a writer compressing with c.
+// Closing the writer flushes the compressor and closes the file.
+func Create(path string, c Compression) (io.WriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWriter(f, path, c)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return writeCloser{Writer: w, closers: closers{w, f}}, nil
+}
+
+// WriteFile compresses data with c and writes it to path.
+func WriteFile(path string, data []byte, c Compression) error {
+	w, err := Create(path, c)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func wrapFile(f io.ReadCloser, path string) (io.ReadCloser, error) {
+	r, err := NewReader(f, path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return readCloser{Reader: r, closers: closers{r, f}}, nil
+}
+
+func decompress(c Codec, r io.Reader) (io.ReadCloser, error) {
+	if c.NewReader == nil {
+		return nil, fmt.Errorf("compression %s is not registered for reading", c.Name)
+	}
+	rc, err := c.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s stream: %w", c.Name, err)
+	}
+	return rc, nil
+}
+
+func maxMagicLen() int {
+	n := 0
+	for _, c := range sorted() {
+		if len(c.Magic) > n {
+			n = len(c.Magic)
+		}
+	}
+	return n
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// closers closes every layer of a wrapped file, outermost first, so that
+// compressors are flushed before the underlying file is closed.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var first error
+	for _, c := range cs {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type readCloser struct {
+	io.Reader
+	closers
+}
+
+type writeCloser struct {
+	io.Writer
+	closers
+}
@@ -0,0 +1,260 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByExtension(t *testing.T) {
+	t.Run("should detect gzip from extension", func(t *testing.T) {
+		codec, ok := ByExtension("logs.json.gz")
+
+		assert.True(t, ok)
+		assert.Equal(t, Gzip, codec.Name)
+	})
+
+	t.Run("should detect zstd from extension", func(t *testing.T) {
+		codec, ok := ByExtension("logs.json.ZST")
+
+		assert.True(t, ok)
+		assert.Equal(t, Zstd, codec.Name)
+	})
+
+	t.Run("should not detect plain files", func(t *testing.T) {
+		_, ok := ByExtension("logs.json")
+
+		assert.False(t, ok)
+	})
+
+	t.Run("should pick the first codec by name when several claim an extension", func(t *testing.T) {
+		Register(Codec{Name: "zzz", Extensions: []string{".gz"}})
+		t.Cleanup(func() { unregister("zzz") })
+
+		for range 20 {
+			codec, ok := ByExtension("logs.json.gz")
+
+			assert.True(t, ok)
+			assert.Equal(t, Gzip, codec.Name)
+		}
+	})
+}
+
+// unregister removes a codec registered by a test.
+func unregister(name Compression) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(codecs, name)
+}
+
+func TestTrimExtension(t *testing.T) {
+	t.Run("should remove compression extension", func(t *testing.T) {
+		assert.Equal(t, "logs.json", TrimExtension("logs.json.gz"))
+	})
+
+	t.Run("should keep other extensions", func(t *testing.T) {
+		assert.Equal(t, "logs.json", TrimExtension("logs.json"))
+	})
+}
+
+func TestNewReader(t *testing.T) {
+	t.Run("should decompress gzip by magic bytes", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(gzipBytes(t, "hello")), "stdin")
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(r)
+
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(content))
+	})
+
+	t.Run("should pass plain input through", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader([]byte("[1, 2]")), "stdin")
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(r)
+
+		require.NoError(t, err)
+		assert.Equal(t, "[1, 2]", string(content))
+	})
+
+	t.Run("should pass empty input through", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(nil), "stdin")
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(r)
+
+		require.NoError(t, err)
+		assert.Empty(t, content)
+	})
+
+	t.Run("should return error for unregistered zstd", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}), "stdin")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not registered")
+	})
+
+	t.Run("should read plain input named after a codec that cannot read", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader([]byte("plain")), "file.zst")
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(r)
+
+		require.NoError(t, err)
+		assert.Equal(t, "plain", string(content))
+	})
+
+	t.Run("should return error for corrupt gzip", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte("plain")), "file.gz")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to open gzip stream")
+	})
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Run("should return error for unknown compression", func(t *testing.T) {
+		_, err := NewWriter(&bytes.Buffer{}, "out", Compression("lz4"))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown compression")
+	})
+
+	t.Run("should write plain bytes when auto finds a codec that cannot write", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, "out.json.zst", Auto)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte("payload"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, "payload", buf.String())
+	})
+
+	t.Run("should return error for read-only codec", func(t *testing.T) {
+		_, err := NewWriter(&bytes.Buffer{}, "out", Bzip2)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "does not support writing")
+	})
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		c        Compression
+		resolved Compression
+		skipped  Compression
+	}{
+		{"an explicit codec", "out.json", Gzip, Gzip, None},
+		{"the codec of the extension", "out.json.gz", Auto, Gzip, None},
+		{"no codec without extension", "out.json", Auto, None, None},
+		{"no codec for a codec that cannot write", "out.json.zst", Auto, None, Zstd},
+	}
+
+	for _, tt := range tests {
+		t.Run("should resolve "+tt.name, func(t *testing.T) {
+			resolved, skipped := Resolve(tt.path, tt.c)
+
+			assert.Equal(t, tt.resolved, resolved)
+			assert.Equal(t, tt.skipped, skipped)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("should use registered codec", func(t *testing.T) {
+		original, _ := Lookup(Zstd)
+		t.Cleanup(func() { Register(original) })
+
+		codec := original
+		codec.NewReader = func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader([]byte("decoded"))), nil
+		}
+		Register(codec)
+
+		r, err := NewReader(bytes.NewReader([]byte("anything")), "file.zst")
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(r)
+
+		require.NoError(t, err)
+		assert.Equal(t, "decoded", string(content))
+	})
+}
+
+func TestWriteFileReadFile(t *testing.T) {
+	t.Run("should round-trip gzip file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json.gz")
+
+		require.NoError(t, WriteFile(path, []byte("payload"), Auto))
+
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x1f, 0x8b}, raw[:2])
+
+		content, err := ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(content))
+	})
+
+	t.Run("should write plain file when auto finds no codec", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+
+		require.NoError(t, WriteFile(path, []byte("payload"), Auto))
+
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(raw))
+	})
+
+	t.Run("should detect compression of file without extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data")
+
+		require.NoError(t, WriteFile(path, []byte("payload"), Gzip))
+
+		content, err := ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(content))
+	})
+
+	t.Run("should return error for non-existent file", func(t *testing.T) {
+		_, err := ReadFile("/non/existent/file.json.gz")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestOpenFS(t *testing.T) {
+	t.Run("should decompress file from file system", func(t *testing.T) {
+		fsys := fstest.MapFS{"data.json.gz": {Data: gzipBytes(t, "payload")}}
+
+		r, err := OpenFS(fsys, "data.json.gz")
+		require.NoError(t, err)
+		defer r.Close()
+
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(content))
+	})
+}
+
+func gzipBytes(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Source loads a RecordSet from a snapshot file. Compressed files (e.g.
+// ".snapshot.gz") are decompressed transparently.
+type Source struct {
+	FilePath string
+}
+
+// NewSource creates a Source reading the snapshot at filePath.
+func NewSource(filePath string) *Source {
+	return &Source{FilePath: filePath}
+}
+
+// Load reads the snapshot. Schemas and record sources are the ones stored
+// in the snapshot.
+func (s *Source) Load() (*domain.RecordSet, error) {
+	r, err := compression.Open(s.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer r.Close()
+
+	rs, err := Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", s.FilePath, err)
+	}
+	return rs, nil
+}
+
+// Store writes a RecordSet to a snapshot file.
+type Store struct {
+	FilePath    string
+	Compression compression.Compression // Output compression, Auto picks it from the extension
+}
+
+// NewStore creates a Store writing the snapshot to filePath, compressed when
+// the file extension names a codec.
+func NewStore(filePath string) *Store {
+	return &Store{FilePath: filePath, Compression: compression.Auto}
+}
+
+// Store writes the snapshot, replacing the file.
+func (s *Store) Store(data *domain.RecordSet) error {
+	w, err := compression.Create(s.FilePath, s.Compression)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := Encode(w, data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
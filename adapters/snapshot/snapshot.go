@@ -0,0 +1,545 @@
+// Package snapshot reads and writes RecordSets in a lossless format, used to
+// persist intermediate results such as pipeline checkpoints.
+//
+// A snapshot is newline-delimited JSON: a header holding the schema and the
+// custom types it refers to, followed by one line per record. Every value
+// keeps its exact type: ints are not widened to floats, floats keep NaN,
+// infinities and negative zero, dates keep their nanoseconds and location,
+// and nulls keep their column type. Encoding the same RecordSet always
+// produces the same bytes.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Version is the version of the format written by Encode. Decode also
+// reads version 1 snapshots, which name types by schema ID or custom type
+// name only.
+const Version = 2
+
+// header is the first line of a snapshot.
+type header struct {
+	Version int                    `json:"version"`
+	Schema  *schemaJSON            `json:"schema"`
+	Types   map[string]*schemaJSON `json:"types,omitempty"` // Schemas of custom types and nested records by unique type name
+}
+
+type schemaJSON struct {
+	ID      string       `json:"id"`
+	Columns []columnJSON `json:"columns"`
+}
+
+type columnJSON struct {
+	ID    string   `json:"id"`
+	Type  typeJSON `json:"type"`
+	Array bool     `json:"array,omitempty"`
+}
+
+// typeJSON references a native or custom type. Both empty means no type.
+type typeJSON struct {
+	Native string `json:"native,omitempty"`
+	Custom string `json:"custom,omitempty"`
+	Ref    string `json:"ref,omitempty"` // Type name of the schema of a custom type, empty for none
+}
+
+type recordJSON struct {
+	Values   map[string]valueJSON `json:"values"`
+	Source   string               `json:"source,omitempty"`
+	Schema   string               `json:"schema,omitempty"`    // Type name of the record schema when it is not the schema of its RecordSet
+	NoSchema bool                 `json:"no_schema,omitempty"` // Record without schema in a RecordSet with one
+}
+
+// valueJSON holds exactly one of its fields.
+type valueJSON struct {
+	String    *string     `json:"s,omitempty"`
+	Int       *int64      `json:"i,omitempty"`
+	Float     *string     `json:"f,omitempty"`
+	Bool      *bool       `json:"b,omitempty"`
+	Date      *dateJSON   `json:"d,omitempty"`
+	Null      *typeJSON   `json:"null,omitempty"`
+	Array     *arrayJSON  `json:"a,omitempty"`
+	Record    *recordJSON `json:"r,omitempty"`
+	NilRecord bool        `json:"nil_record,omitempty"` // RecordValue without record
+}
+
+type dateJSON struct {
+	Sec    int64  `json:"sec"`
+	Nsec   int    `json:"nsec"`
+	Zone   string `json:"zone"`   // Location name
+	Offset int    `json:"offset"` // Offset from UTC in seconds at that instant
+}
+
+type arrayJSON struct {
+	Type     typeJSON    `json:"type"`
+	Elements []valueJSON `json:"elements"`
+}
+
+// Encode writes rs to w as a snapshot.
+func Encode(w io.Writer, rs *domain.RecordSet) error {
+	if rs == nil {
+		return errors.New("cannot encode nil RecordSet")
+	}
+
+	e := &encoder{
+		names:  map[*domain.DataSchema]string{},
+		types:  map[string]*domain.DataSchema{},
+		custom: map[string]*domain.DataSchema{},
+	}
+	for _, record := range rs.Records {
+		if record.Schema != rs.Schema && record.Schema != nil {
+			if err := e.addSchema(record.Schema.ID, record.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	if err := e.collectSchema(rs.Schema); err != nil {
+		return err
+	}
+	for _, record := range rs.Records {
+		if err := e.collectRecord(record); err != nil {
+			return err
+		}
+	}
+
+	h := header{Version: Version, Schema: e.schema(rs.Schema)}
+	if len(e.types) > 0 {
+		h.Types = make(map[string]*schemaJSON, len(e.types))
+		for name, schema := range e.types {
+			h.Types[name] = e.schema(schema)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+	for i, record := range rs.Records {
+		r, err := e.record(record, rs.Schema)
+		if err != nil {
+			return domain.NewRecordError(i, record, err)
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encoder collects the schemas referenced by a RecordSet. Each schema is
+// a type named after its ID, or the name of the custom type referring to
+// it, with a "#n" suffix when another schema already took that name. Names
+// are given in the order schemas are first met, which does not depend on
+// map iteration, so that the same RecordSet always gets the same names.
+type encoder struct {
+	names  map[*domain.DataSchema]string // Type name of each schema
+	types  map[string]*domain.DataSchema // Schema of each type name
+	custom map[string]*domain.DataSchema // First schema of each custom type name
+}
+
+// addSchema registers a schema under a unique type name derived from name
+// and collects the types it refers to.
+func (e *encoder) addSchema(name string, schema *domain.DataSchema) error {
+	if schema == nil {
+		return nil
+	}
+	if _, ok := e.names[schema]; ok {
+		return nil
+	}
+	unique := name
+	for n := 2; e.types[unique] != nil; n++ {
+		unique = name + "#" + strconv.Itoa(n)
+	}
+	e.names[schema] = unique
+	e.types[unique] = schema
+	return e.collectSchema(schema)
+}
+
+func (e *encoder) collectSchema(schema *domain.DataSchema) error {
+	if schema == nil {
+		return nil
+	}
+	for _, col := range schema.Columns {
+		if err := e.collectType(col.GetType()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectType registers the schema of a custom type. Custom types sharing a
+// name must have equal schemas: a name cannot stand for two types.
+func (e *encoder) collectType(t domain.SchemaType) error {
+	custom, ok := t.(domain.CustomType)
+	if !ok || custom.Schema == nil {
+		return nil
+	}
+	if first, ok := e.custom[custom.Name]; !ok {
+		e.custom[custom.Name] = custom.Schema
+	} else if !equalSchemas(first, custom.Schema, map[[2]*domain.DataSchema]bool{}) {
+		return fmt.Errorf("conflicting definitions of type %s", custom.Name)
+	}
+	return e.addSchema(custom.Name, custom.Schema)
+}
+
+func (e *encoder) collectRecord(record *domain.Record) error {
+	ids := make([]string, 0, len(record.Values))
+	for id := range record.Values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := e.collectValue(record.Values[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) collectValue(value domain.Value) error {
+	switch v := value.(type) {
+	case domain.NullValue:
+		return e.collectType(v.Type)
+	case domain.ArrayValue:
+		if err := e.collectType(v.ElementType); err != nil {
+			return err
+		}
+		for _, element := range v.Elements {
+			if err := e.collectValue(element); err != nil {
+				return err
+			}
+		}
+	case domain.RecordValue:
+		if v.Record != nil {
+			if schema := v.Record.Schema; schema != nil {
+				if err := e.addSchema(schema.ID, schema); err != nil {
+					return err
+				}
+			}
+			return e.collectRecord(v.Record)
+		}
+	}
+	return nil
+}
+
+// equalSchemas reports whether two schemas have the same ID and columns.
+// seen holds the pairs being compared, which are assumed equal so that
+// recursive schemas terminate.
+func equalSchemas(a, b *domain.DataSchema, seen map[[2]*domain.DataSchema]bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.ID != b.ID || len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	if seen[[2]*domain.DataSchema{a, b}] {
+		return true
+	}
+	seen[[2]*domain.DataSchema{a, b}] = true
+	for i, col := range a.Columns {
+		other := b.Columns[i]
+		if col.GetID() != other.GetID() || col.IsArray() != other.IsArray() {
+			return false
+		}
+		ca, okA := col.GetType().(domain.CustomType)
+		cb, okB := other.GetType().(domain.CustomType)
+		switch {
+		case okA && okB:
+			if ca.Name != cb.Name || !equalSchemas(ca.Schema, cb.Schema, seen) {
+				return false
+			}
+		case okA || okB || col.GetType() != other.GetType():
+			return false
+		}
+	}
+	return true
+}
+
+func (e *encoder) schema(schema *domain.DataSchema) *schemaJSON {
+	if schema == nil {
+		return nil
+	}
+	result := &schemaJSON{ID: schema.ID, Columns: make([]columnJSON, 0, len(schema.Columns))}
+	for _, col := range schema.Columns {
+		result.Columns = append(result.Columns, columnJSON{ID: col.GetID(), Type: e.typeOf(col.GetType()), Array: col.IsArray()})
+	}
+	return result
+}
+
+func (e *encoder) typeOf(t domain.SchemaType) typeJSON {
+	switch t := t.(type) {
+	case domain.NativeType:
+		return typeJSON{Native: string(t)}
+	case domain.CustomType:
+		return typeJSON{Custom: t.Name, Ref: e.names[t.Schema]}
+	}
+	return typeJSON{}
+}
+
+func (e *encoder) record(record *domain.Record, schema *domain.DataSchema) (*recordJSON, error) {
+	result := &recordJSON{Source: record.Source}
+	switch {
+	case record.Schema == schema:
+	case record.Schema == nil:
+		result.NoSchema = true
+	default:
+		result.Schema = e.names[record.Schema]
+	}
+
+	if record.Values != nil {
+		result.Values = make(map[string]valueJSON, len(record.Values))
+	}
+	for id, value := range record.Values {
+		v, err := e.value(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", id, err)
+		}
+		result.Values[id] = v
+	}
+	return result, nil
+}
+
+func (e *encoder) value(value domain.Value) (valueJSON, error) {
+	switch v := value.(type) {
+	case domain.StringValue:
+		s := string(v)
+		return valueJSON{String: &s}, nil
+	case domain.IntValue:
+		i := int64(v)
+		return valueJSON{Int: &i}, nil
+	case domain.FloatValue:
+		f := strconv.FormatFloat(float64(v), 'g', -1, 64)
+		return valueJSON{Float: &f}, nil
+	case domain.BoolValue:
+		b := bool(v)
+		return valueJSON{Bool: &b}, nil
+	case domain.DateValue:
+		t := time.Time(v)
+		_, offset := t.Zone()
+		return valueJSON{Date: &dateJSON{Sec: t.Unix(), Nsec: t.Nanosecond(), Zone: t.Location().String(), Offset: offset}}, nil
+	case domain.NullValue:
+		t := e.typeOf(v.Type)
+		return valueJSON{Null: &t}, nil
+	case domain.ArrayValue:
+		a := &arrayJSON{Type: e.typeOf(v.ElementType)}
+		if v.Elements != nil {
+			a.Elements = make([]valueJSON, 0, len(v.Elements))
+		}
+		for i, element := range v.Elements {
+			encoded, err := e.value(element)
+			if err != nil {
+				return valueJSON{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			a.Elements = append(a.Elements, encoded)
+		}
+		return valueJSON{Array: a}, nil
+	case domain.RecordValue:
+		if v.Record == nil {
+			return valueJSON{NilRecord: true}, nil
+		}
+		r, err := e.record(v.Record, nil)
+		if err != nil {
+			return valueJSON{}, err
+		}
+		return valueJSON{Record: r}, nil
+	}
+	return valueJSON{}, fmt.Errorf("unsupported value type %T", value)
+}
+
+// Decode reads a snapshot written by Encode.
+func Decode(r io.Reader) (*domain.RecordSet, error) {
+	dec := json.NewDecoder(r)
+
+	var h header
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if h.Version != Version && h.Version != 1 {
+		return nil, fmt.Errorf("unsupported snapshot version %d", h.Version)
+	}
+
+	d := &decoder{types: make(map[string]*domain.DataSchema, len(h.Types)), byName: h.Version == 1}
+	// Create every type first so that schemas can refer to each other.
+	for name, s := range h.Types {
+		d.types[name] = &domain.DataSchema{ID: s.ID}
+	}
+	for name, s := range h.Types {
+		if err := d.fillSchema(d.types[name], s); err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+	}
+	var schema *domain.DataSchema
+	if h.Schema != nil {
+		schema = &domain.DataSchema{ID: h.Schema.ID}
+		if err := d.fillSchema(schema, h.Schema); err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+	}
+
+	rs := domain.NewRecordSet(schema)
+	for i := 0; ; i++ {
+		var r recordJSON
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, &domain.RecordError{Index: i, Err: err}
+		}
+		record, err := d.record(&r, schema)
+		if err != nil {
+			return nil, &domain.RecordError{Index: i, Err: err}
+		}
+		rs.Add(record)
+	}
+	return rs, nil
+}
+
+type decoder struct {
+	types  map[string]*domain.DataSchema
+	byName bool // Custom types refer to the type of their name, as in version 1
+}
+
+func (d *decoder) fillSchema(schema *domain.DataSchema, s *schemaJSON) error {
+	schema.Columns = make([]domain.SchemaColumn, 0, len(s.Columns))
+	for _, col := range s.Columns {
+		t, err := d.typ(col.Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.ID, err)
+		}
+		if col.Array {
+			schema.Columns = append(schema.Columns, domain.SchemaColumnArray{ID: col.ID, RefSchema: t})
+		} else {
+			schema.Columns = append(schema.Columns, domain.SchemaColumnSingle{ID: col.ID, SchemaType: t})
+		}
+	}
+	return nil
+}
+
+func (d *decoder) typ(t typeJSON) (domain.SchemaType, error) {
+	switch {
+	case t.Native != "" && t.Custom != "":
+		return nil, errors.New("type is both native and custom")
+	case t.Native != "":
+		return domain.NativeType(t.Native), nil
+	case t.Custom != "" && d.byName:
+		return domain.CustomType{Name: t.Custom, Schema: d.types[t.Custom]}, nil
+	case t.Custom != "":
+		custom := domain.CustomType{Name: t.Custom}
+		if t.Ref != "" {
+			s, ok := d.types[t.Ref]
+			if !ok {
+				return nil, fmt.Errorf("unknown type %q", t.Ref)
+			}
+			custom.Schema = s
+		}
+		return custom, nil
+	}
+	return nil, nil
+}
+
+func (d *decoder) record(r *recordJSON, schema *domain.DataSchema) (*domain.Record, error) {
+	record := &domain.Record{Schema: schema, Source: r.Source}
+	switch {
+	case r.NoSchema:
+		record.Schema = nil
+	case r.Schema != "":
+		s, ok := d.types[r.Schema]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema %q", r.Schema)
+		}
+		record.Schema = s
+	}
+
+	if r.Values != nil {
+		record.Values = make(map[string]domain.Value, len(r.Values))
+	}
+	for id, v := range r.Values {
+		value, err := d.value(v)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", id, err)
+		}
+		record.Values[id] = value
+	}
+	return record, nil
+}
+
+func (d *decoder) value(v valueJSON) (domain.Value, error) {
+	switch {
+	case v.String != nil:
+		return domain.StringValue(*v.String), nil
+	case v.Int != nil:
+		return domain.IntValue(*v.Int), nil
+	case v.Float != nil:
+		f, err := strconv.ParseFloat(*v.Float, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", *v.Float)
+		}
+		return domain.FloatValue(f), nil
+	case v.Bool != nil:
+		return domain.BoolValue(*v.Bool), nil
+	case v.Date != nil:
+		t := time.Unix(v.Date.Sec, int64(v.Date.Nsec))
+		return domain.DateValue(t.In(location(t, v.Date.Zone, v.Date.Offset))), nil
+	case v.Null != nil:
+		t, err := d.typ(*v.Null)
+		if err != nil {
+			return nil, err
+		}
+		return domain.NullValue{Type: t}, nil
+	case v.Array != nil:
+		t, err := d.typ(v.Array.Type)
+		if err != nil {
+			return nil, err
+		}
+		array := domain.ArrayValue{ElementType: t}
+		if v.Array.Elements != nil {
+			array.Elements = make([]domain.Value, 0, len(v.Array.Elements))
+		}
+		for i, element := range v.Array.Elements {
+			decoded, err := d.value(element)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			array.Elements = append(array.Elements, decoded)
+		}
+		return array, nil
+	case v.Record != nil:
+		record, err := d.record(v.Record, nil)
+		if err != nil {
+			return nil, err
+		}
+		return domain.RecordValue{Record: record}, nil
+	case v.NilRecord:
+		return domain.RecordValue{}, nil
+	}
+	return nil, errors.New("empty value")
+}
+
+// location returns the named location, or a fixed zone with that name and
+// offset when the time zone database has no such location or gives it
+// another offset at t.
+func location(t time.Time, name string, offset int) *time.Location {
+	var loc *time.Location
+	switch name {
+	case "UTC":
+		loc = time.UTC
+	case "Local":
+		loc = time.Local
+	case "":
+	default:
+		loc, _ = time.LoadLocation(name)
+	}
+	if loc != nil {
+		if _, o := t.In(loc).Zone(); o == offset {
+			return loc
+		}
+	}
+	return time.FixedZone(name, offset)
+}
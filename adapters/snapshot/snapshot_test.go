@@ -0,0 +1,293 @@
+package snapshot
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// everyTypeFixture returns records holding every value type, including
+// extreme ints, nested records, arrays and nulls.
+func everyTypeFixture() *domain.RecordSet {
+	address := &domain.DataSchema{
+		ID: "Address",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+		},
+	}
+	addressType := domain.CustomType{Name: "Address", Schema: address}
+	schema := &domain.DataSchema{
+		ID: "Customer",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "orders", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "balance", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			domain.SchemaColumnSingle{ID: "since", SchemaType: domain.NativeTypeDate},
+			domain.SchemaColumnSingle{ID: "address", SchemaType: addressType},
+			domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+			domain.SchemaColumnArray{ID: "previous", RefSchema: addressType},
+		},
+	}
+
+	city := domain.NewRecord(address)
+	city.Set("city", domain.StringValue("Lyon"))
+
+	full := domain.NewRecord(schema)
+	full.Source = "customers.json"
+	full.Set("name", domain.StringValue("Ada \"Countess\"\n"))
+	full.Set("orders", domain.IntValue(math.MaxInt64))
+	full.Set("balance", domain.FloatValue(0.1))
+	full.Set("active", domain.BoolValue(true))
+	full.Set("since", domain.DateValue(time.Date(2024, 3, 10, 8, 30, 0, 123456789, time.UTC)))
+	full.Set("address", domain.RecordValue{Record: city})
+	full.Set("tags", domain.ArrayValue{ElementType: domain.NativeTypeString, Elements: []domain.Value{domain.StringValue("vip")}})
+	full.Set("previous", domain.ArrayValue{ElementType: addressType, Elements: []domain.Value{}})
+
+	sparse := domain.NewRecord(schema)
+	sparse.Set("name", domain.NullValue{Type: domain.NativeTypeString})
+	sparse.Set("orders", domain.IntValue(math.MinInt64))
+	sparse.Set("address", domain.NullValue{Type: addressType})
+	sparse.Set("tags", domain.ArrayValue{ElementType: domain.NativeTypeString})
+	sparse.Set("previous", domain.RecordValue{})
+
+	rs := domain.NewRecordSet(schema)
+	rs.Add(full)
+	rs.Add(sparse)
+	return rs
+}
+
+func roundTrip(t *testing.T, rs *domain.RecordSet) *domain.RecordSet {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, rs))
+	decoded, err := Decode(&buf)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestEncodeDecode(t *testing.T) {
+	t.Run("should round-trip every value type, schema and source", func(t *testing.T) {
+		rs := everyTypeFixture()
+
+		decoded := roundTrip(t, rs)
+
+		assert.Equal(t, rs, decoded)
+	})
+
+	t.Run("should keep special floats exactly", func(t *testing.T) {
+		rs := domain.NewRecordSet(nil)
+		for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1), math.SmallestNonzeroFloat64, math.MaxFloat64, 1.0 / 3} {
+			record := domain.NewRecord(nil)
+			record.Set("f", domain.FloatValue(f))
+			rs.Add(record)
+		}
+
+		decoded := roundTrip(t, rs)
+
+		require.Equal(t, rs.Count(), decoded.Count())
+		for i, record := range rs.Records {
+			want, got := math.Float64bits(record.GetFloat("f")), math.Float64bits(decoded.Get(i).GetFloat("f"))
+			if math.IsNaN(record.GetFloat("f")) {
+				assert.True(t, math.IsNaN(decoded.Get(i).GetFloat("f")))
+				continue
+			}
+			assert.Equal(t, want, got, i)
+		}
+	})
+
+	t.Run("should keep date locations and nanoseconds", func(t *testing.T) {
+		paris, err := time.LoadLocation("Europe/Paris")
+		require.NoError(t, err)
+		dates := []time.Time{
+			time.Date(2024, 7, 1, 12, 0, 0, 1, paris),
+			time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("", 5*3600+1800)),
+			time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("EST", 3600)),
+			{},
+		}
+		rs := domain.NewRecordSet(nil)
+		for _, d := range dates {
+			record := domain.NewRecord(nil)
+			record.Set("d", domain.DateValue(d))
+			rs.Add(record)
+		}
+
+		decoded := roundTrip(t, rs)
+
+		for i, want := range dates {
+			got := decoded.Get(i).GetDate("d")
+			assert.True(t, want.Equal(got), i)
+			assert.Equal(t, want.Location().String(), got.Location().String(), i)
+			assert.Equal(t, want.Format(time.RFC3339Nano), got.Format(time.RFC3339Nano), i)
+		}
+		assert.Equal(t, time.Time{}, decoded.Get(3).GetDate("d"))
+	})
+
+	t.Run("should keep records with another schema or none", func(t *testing.T) {
+		rs := everyTypeFixture()
+		other := &domain.DataSchema{ID: "Legacy", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+		}}
+		rs.Records[0].Schema = other
+		rs.Records[1].Schema = nil
+
+		decoded := roundTrip(t, rs)
+
+		assert.Equal(t, other, decoded.Get(0).Schema)
+		assert.Nil(t, decoded.Get(1).Schema)
+	})
+
+	t.Run("should encode deterministically", func(t *testing.T) {
+		var a, b bytes.Buffer
+
+		require.NoError(t, Encode(&a, everyTypeFixture()))
+		require.NoError(t, Encode(&b, everyTypeFixture()))
+
+		assert.Equal(t, a.String(), b.String())
+		assert.Equal(t, 3, strings.Count(a.String(), "\n"))
+	})
+
+	t.Run("should keep distinct schemas sharing a type name apart", func(t *testing.T) {
+		// Arrange
+		city := &domain.DataSchema{ID: "Address", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+		}}
+		street := &domain.DataSchema{ID: "Address", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "street", SchemaType: domain.NativeTypeString},
+		}}
+		schema := &domain.DataSchema{ID: "Customer", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "home", SchemaType: domain.CustomType{Name: "Address", Schema: city}},
+		}}
+		rs := domain.NewRecordSet(schema)
+		for range 2 {
+			record := domain.NewRecord(schema)
+			work := domain.NewRecord(street)
+			work.Set("street", domain.StringValue("Rue de la Paix"))
+			record.Set("work", domain.RecordValue{Record: work})
+			rs.Add(record)
+		}
+		rs.Records[1].Schema = street
+
+		// Act
+		decoded := roundTrip(t, rs)
+
+		// Assert
+		assert.Equal(t, rs, decoded)
+		home := decoded.Schema.Columns[0].GetType().(domain.CustomType)
+		assert.Equal(t, "Address", home.Name)
+		assert.Equal(t, city, home.Schema)
+		assert.Equal(t, street, decoded.Get(1).Schema)
+		work := decoded.Get(0).Values["work"].(domain.RecordValue).Record
+		assert.Same(t, decoded.Get(1).Schema, work.Schema)
+
+		var a, b bytes.Buffer
+		require.NoError(t, Encode(&a, rs))
+		require.NoError(t, Encode(&b, decoded))
+		assert.Equal(t, a.String(), b.String())
+	})
+
+	t.Run("should round-trip an empty set without schema", func(t *testing.T) {
+		decoded := roundTrip(t, domain.NewRecordSet(nil))
+
+		assert.Equal(t, domain.NewRecordSet(nil), decoded)
+	})
+}
+
+// unknownValue is a Value implementation the format does not know.
+type unknownValue struct{}
+
+func (unknownValue) GetType() domain.SchemaType { return nil }
+func (unknownValue) IsNull() bool               { return false }
+
+func TestEncodeDecode_Errors(t *testing.T) {
+	t.Run("should reject nil RecordSet", func(t *testing.T) {
+		assert.EqualError(t, Encode(&bytes.Buffer{}, nil), "cannot encode nil RecordSet")
+	})
+
+	t.Run("should reject unsupported values", func(t *testing.T) {
+		rs := domain.NewRecordSet(nil)
+		record := domain.NewRecord(nil)
+		record.Set("x", domain.ArrayValue{Elements: []domain.Value{unknownValue{}}})
+		rs.Add(record)
+
+		err := Encode(&bytes.Buffer{}, rs)
+
+		assert.EqualError(t, err, "record 0: column x: element 0: unsupported value type snapshot.unknownValue")
+	})
+
+	t.Run("should reject custom types with conflicting definitions", func(t *testing.T) {
+		country := &domain.DataSchema{ID: "Country", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "code", SchemaType: domain.NativeTypeString},
+		}}
+		city := &domain.DataSchema{ID: "Country", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+		}}
+		copied := &domain.DataSchema{ID: "Country", Columns: country.Columns}
+		schema := &domain.DataSchema{ID: "Customer", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "from", SchemaType: domain.CustomType{Name: "Country", Schema: country}},
+			domain.SchemaColumnSingle{ID: "to", SchemaType: domain.CustomType{Name: "Country", Schema: copied}},
+		}}
+		require.NoError(t, Encode(&bytes.Buffer{}, domain.NewRecordSet(schema)))
+
+		schema.Columns[1] = domain.SchemaColumnSingle{ID: "to", SchemaType: domain.CustomType{Name: "Country", Schema: city}}
+		err := Encode(&bytes.Buffer{}, domain.NewRecordSet(schema))
+
+		assert.EqualError(t, err, "conflicting definitions of type Country")
+	})
+
+	t.Run("should report unknown type references", func(t *testing.T) {
+		_, err := Decode(strings.NewReader(`{"version": 2, "schema": {"id": "S", "columns": [{"id": "a", "type": {"custom": "A", "ref": "A"}}]}}`))
+
+		assert.EqualError(t, err, `schema: column a: unknown type "A"`)
+	})
+
+	t.Run("should reject unknown versions", func(t *testing.T) {
+		_, err := Decode(strings.NewReader(`{"version": 99, "schema": null}`))
+
+		assert.EqualError(t, err, "unsupported snapshot version 99")
+	})
+
+	t.Run("should report invalid records", func(t *testing.T) {
+		_, err := Decode(strings.NewReader(`{"version": 1, "schema": null}
+{"values": {"a": {"s": "ok"}}}
+{"values": {"a": {}}}`))
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 1, recordErr.Index)
+		assert.EqualError(t, err, "record 1: column a: empty value")
+	})
+
+	t.Run("should report a missing header", func(t *testing.T) {
+		_, err := Decode(strings.NewReader(``))
+
+		assert.ErrorContains(t, err, "failed to read header")
+	})
+}
+
+func TestSourceStore(t *testing.T) {
+	t.Run("should round-trip through a compressed file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "customers.snapshot.gz")
+		rs := everyTypeFixture()
+
+		require.NoError(t, NewStore(path).Store(rs))
+		loaded, err := NewSource(path).Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, rs, loaded)
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		_, err := NewSource(filepath.Join(t.TempDir(), "missing.snapshot")).Load()
+
+		assert.ErrorContains(t, err, "failed to read file")
+	})
+}
@@ -0,0 +1,155 @@
+package source
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+)
+
+// CSVSource reads CSV whose first row names the columns, from a file, a
+// file system or a stream. Cells are cast from strings to the type of their
+// column with domain.Cast; empty cells are null. Cells of array and custom
+// type columns hold JSON, mapped like JSONSource does.
+type CSVSource struct {
+	FilePath string
+	Schema   *domain.DataSchema
+	FS       fs.FS     // File system FilePath is resolved in, nil for the OS file system
+	Reader   io.Reader // Input stream, takes precedence over FilePath when set
+
+	Comma                  rune               // Field delimiter, ',' if zero
+	Cast                   domain.CastOptions // Options casting cells to column types, strict by default
+	DisallowUnknownColumns bool               // Reject header columns missing from the schema, instead of ignoring them
+	Uncompressed           bool               // Read the input as is, without detecting compression, see JSONSource
+	Logger                 *slog.Logger       // Receives a debug event with the records loaded, nil disables logging
+}
+
+// NewCSVSource creates a CSVSource reading the given file.
+func NewCSVSource(filePath string, schema *domain.DataSchema) *CSVSource {
+	return &CSVSource{FilePath: filePath, Schema: schema}
+}
+
+// NewCSVSourceFromReader creates a CSVSource reading from r. The reader is
+// consumed by the first Load.
+func NewCSVSourceFromReader(r io.Reader, schema *domain.DataSchema) *CSVSource {
+	return &CSVSource{Schema: schema, Reader: r}
+}
+
+// Fingerprint returns the path, size and modification time of the file, or
+// "" when reading from Reader.
+func (s *CSVSource) Fingerprint() (string, error) {
+	if s.Reader != nil {
+		return "", nil
+	}
+	return fileFingerprint(s.FS, s.FilePath)
+}
+
+// Load reads the CSV input and returns a RecordSet. Failures are reported
+// as a *domain.RecordError whose Index is the row after the header, counted
+// from 0, wrapping a *domain.SchemaError naming the column.
+func (s *CSVSource) Load() (*domain.RecordSet, error) {
+	parser := &JSONSource{FilePath: s.FilePath, Schema: s.Schema, FS: s.FS, Reader: s.Reader, Uncompressed: s.Uncompressed}
+	data, err := parser.read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	if s.Comma != 0 {
+		r.Comma = s.Comma
+	}
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return domain.NewRecordSet(s.Schema), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if err := s.checkHeader(header); err != nil {
+		return nil, err
+	}
+
+	recordSet := domain.NewRecordSet(s.Schema)
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", &domain.RecordError{Index: i, Source: s.FilePath, Err: err})
+		}
+		record, err := s.mapRow(parser, header, row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map record: %w", &domain.RecordError{Index: i, Source: s.FilePath, Err: err})
+		}
+		record.Source = s.FilePath
+		recordSet.Add(record)
+	}
+
+	logging.Or(s.Logger).Debug("loaded records", "path", s.FilePath, "records", recordSet.Count())
+	return recordSet, nil
+}
+
+func (s *CSVSource) checkHeader(header []string) error {
+	if !s.DisallowUnknownColumns {
+		return nil
+	}
+	var unknown []string
+	for _, name := range header {
+		if !s.Schema.HasColumn(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &domain.SchemaError{Schema: s.Schema.ID, Err: fmt.Errorf("unknown columns: %s", strings.Join(unknown, ", "))}
+	}
+	return nil
+}
+
+// mapRow maps the cells of a row to a record, parser mapping JSON cells.
+func (s *CSVSource) mapRow(parser *JSONSource, header, row []string) (*domain.Record, error) {
+	record := domain.NewRecord(s.Schema)
+	for i, name := range header {
+		col := s.Schema.Column(name)
+		if col == nil || i >= len(row) {
+			continue
+		}
+		value, err := s.mapCell(parser, row[i], col)
+		if err != nil {
+			return nil, err
+		}
+		record.Set(name, value)
+	}
+	return record, nil
+}
+
+func (s *CSVSource) mapCell(parser *JSONSource, cell string, col domain.SchemaColumn) (domain.Value, error) {
+	typ := col.GetType()
+	if cell == "" {
+		return domain.NullValue{Type: typ}, nil
+	}
+
+	if typ.IsNative() && !col.IsArray() {
+		value, err := domain.Cast(domain.StringValue(cell), typ.(domain.NativeType), s.Cast)
+		if err != nil {
+			return nil, parser.schemaError(col.GetID(), err)
+		}
+		return value, nil
+	}
+
+	var raw any
+	if err := json.Unmarshal([]byte(cell), &raw); err != nil {
+		return nil, parser.schemaError(col.GetID(), fmt.Errorf("invalid JSON: %w", err))
+	}
+	return parser.mapValue(raw, typ, col.IsArray(), col.GetID())
+}
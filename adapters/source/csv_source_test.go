@@ -0,0 +1,90 @@
+package source
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVSource_Load(t *testing.T) {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			domain.SchemaColumnSingle{ID: "added", SchemaType: domain.NativeTypeDate},
+			domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+		},
+	}
+
+	t.Run("should cast cells to the column types", func(t *testing.T) {
+		filePath := createTempFile(t, "name,price,quantity,active,added,tags\n"+
+			"Laptop,999.99,5,true,2024-03-01T10:00:00Z,\"[\"\"tech\"\",\"\"sale\"\"]\"\n")
+
+		result, err := NewCSVSource(filePath, schema).Load()
+
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Count())
+		record := result.Records[0]
+		assert.Equal(t, domain.StringValue("Laptop"), record.Get("name"))
+		assert.Equal(t, domain.FloatValue(999.99), record.Get("price"))
+		assert.Equal(t, domain.IntValue(5), record.Get("quantity"))
+		assert.Equal(t, domain.BoolValue(true), record.Get("active"))
+		assert.Equal(t, domain.DateValue(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)), record.Get("added"))
+		tags, ok := record.Get("tags").(domain.ArrayValue)
+		require.True(t, ok)
+		assert.Equal(t, []domain.Value{domain.StringValue("tech"), domain.StringValue("sale")}, tags.Elements)
+		assert.Equal(t, filePath, record.Source)
+	})
+
+	t.Run("should load empty cells as null and ignore unknown columns", func(t *testing.T) {
+		source := NewCSVSourceFromReader(strings.NewReader("color;name;quantity\ngrey;Phone;\n"), schema)
+		source.Comma = ';'
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Count())
+		assert.Equal(t, "Phone", result.Records[0].GetString("name"))
+		assert.True(t, result.Records[0].Get("quantity").IsNull())
+		assert.Nil(t, result.Records[0].Get("color"))
+	})
+
+	t.Run("should return an empty set for empty input", func(t *testing.T) {
+		result, err := NewCSVSourceFromReader(strings.NewReader(""), schema).Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Count())
+	})
+
+	t.Run("should report the row and column of an invalid cell", func(t *testing.T) {
+		source := NewCSVSourceFromReader(strings.NewReader("name,quantity\nLaptop,5\nPhone,many\n"), schema)
+
+		_, err := source.Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 1, recordErr.Index)
+		var schemaErr *domain.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, "quantity", schemaErr.Column)
+	})
+
+	t.Run("should reject unknown columns when disallowed", func(t *testing.T) {
+		source := NewCSVSourceFromReader(strings.NewReader("name,color\nLaptop,grey\n"), schema)
+		source.DisallowUnknownColumns = true
+
+		_, err := source.Load()
+
+		var schemaErr *domain.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.ErrorContains(t, err, "unknown columns: color")
+	})
+}
@@ -0,0 +1,315 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+)
+
+// HTTPSource loads the records of a JSON REST API, following its pages.
+//
+// Each response is decoded as JSON and its records are the array found at
+// RecordsPath, mapped like JSONSource maps the items of its array. The
+// Pagination then tells the URL of the next page, until it returns none or
+// a page already fetched, so that pagination cycles end.
+type HTTPSource struct {
+	URL         string
+	Schema      *domain.DataSchema
+	RecordsPath string     // Dot-separated path of the record array in the response, such as "data.items"; the response itself if empty
+	Pagination  Pagination // Next page to fetch, nil to fetch URL only
+	MaxPages    int        // Pages fetched at most, unlimited if zero
+
+	Header    http.Header     // Headers sent with the requests and redirects to the scheme, host and port of URL, such as Authorization
+	Client    *http.Client    // Client sending the requests, nil for http.DefaultClient
+	RateLimit time.Duration   // Minimum time between the start of two requests, zero for no limit
+	Context   context.Context // Context of the requests and waits, nil for context.Background()
+
+	DisallowUnknownColumns bool                // Reject keys missing from the schema, see JSONSource
+	Coerce                 *domain.CastOptions // Cast values whose JSON type does not match their column, see JSONSource
+	Logger                 *slog.Logger        // Receives a debug event per page fetched, nil disables logging
+
+	last time.Time // Start of the last request, for RateLimit
+}
+
+// NewHTTPSource creates an HTTPSource fetching the single page at url.
+func NewHTTPSource(url string, schema *domain.DataSchema) *HTTPSource {
+	return &HTTPSource{URL: url, Schema: schema}
+}
+
+// Page is a response fetched by an HTTPSource, given to its Pagination.
+type Page struct {
+	URL     *url.URL    // URL the page was fetched from
+	Header  http.Header // Response headers
+	Body    any         // Decoded JSON response, numbers being json.Number
+	Records int         // Number of records of the page
+}
+
+// StatusError is returned for responses whose status is not 2xx. Responses
+// 429 Too Many Requests and 5xx are retryable, see retry.RetryableError.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.URL, e.Status)
+}
+
+// Retryable reports whether the request may succeed if sent again.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Load fetches every page and returns their records. Failures to map a
+// record are reported as a *domain.RecordError whose Index counts the
+// records of all pages from 0, and whose Source is the page URL.
+func (s *HTTPSource) Load() (*domain.RecordSet, error) {
+	next, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	origin := next
+	if first, ok := s.Pagination.(firstPager); ok {
+		next = first.first(next)
+	}
+
+	client := s.client(origin)
+	parser := &JSONSource{Schema: s.Schema, DisallowUnknownColumns: s.DisallowUnknownColumns, Coerce: s.Coerce}
+	recordSet := domain.NewRecordSet(s.Schema)
+	visited := map[string]bool{}
+	for pages := 0; next != nil; pages++ {
+		if s.MaxPages > 0 && pages == s.MaxPages {
+			logging.Or(s.Logger).Debug("stopped at page limit", "url", s.URL, "pages", pages)
+			break
+		}
+		if visited[next.String()] {
+			logging.Or(s.Logger).Warn("stopped at page already fetched", "url", next.String(), "pages", pages)
+			break
+		}
+		visited[next.String()] = true
+
+		page, items, err := s.fetch(client, next, sameOrigin(origin, next))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			object, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("failed to map record: %w", &domain.RecordError{Index: recordSet.Count(), Source: page.URL.String(), Err: fmt.Errorf("expected object, got %T", item)})
+			}
+			record, err := parser.mapToRecord(object, "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to map record: %w", &domain.RecordError{Index: recordSet.Count(), Source: page.URL.String(), Err: err})
+			}
+			record.Source = page.URL.String()
+			recordSet.Add(record)
+		}
+		logging.Or(s.Logger).Debug("fetched page", "url", page.URL.String(), "records", page.Records)
+
+		if s.Pagination == nil {
+			break
+		}
+		if next, err = s.Pagination.Next(page); err != nil {
+			return nil, fmt.Errorf("failed to paginate %s: %w", page.URL, err)
+		}
+	}
+
+	logging.Or(s.Logger).Debug("loaded records", "url", s.URL, "records", recordSet.Count())
+	return recordSet, nil
+}
+
+// client returns a copy of Client whose redirects to another origin than
+// origin drop Header, so that credentials do not leak to other hosts the
+// API redirects to. net/http only drops Authorization and Cookie itself.
+func (s *HTTPSource) client(origin *url.URL) *http.Client {
+	client := http.DefaultClient
+	if s.Client != nil {
+		client = s.Client
+	}
+	clone := *client
+	checkRedirect := client.CheckRedirect
+	clone.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !sameOrigin(origin, req.URL) {
+			for key := range s.Header {
+				req.Header.Del(key)
+			}
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &clone
+}
+
+// fetch gets a page with client and returns it with its record array.
+// Header is only sent when trusted, so that credentials do not leak to
+// other hosts linked by the pagination.
+func (s *HTTPSource) fetch(client *http.Client, u *url.URL, trusted bool) (*Page, []any, error) {
+	ctx := s.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := s.wait(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if trusted {
+		for key, values := range s.Header {
+			req.Header[key] = values
+		}
+	} else if len(s.Header) > 0 {
+		logging.Or(s.Logger).Debug("headers not sent to other origin", "url", u.String())
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, nil, &StatusError{URL: u.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	// Numbers are kept as json.Number, so that paginations read cursors and
+	// IDs exactly; records get float64 numbers, as with JSONSource.
+	var body any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JSON from %s: %w", u, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("failed to parse JSON from %s: unexpected data after the response", u)
+	}
+
+	value, ok := lookupPath(body, s.RecordsPath)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: records path %s not found", u, s.RecordsPath)
+	}
+	var items []any
+	switch v := value.(type) {
+	case nil:
+	case []any:
+		items = floatNumbers(v).([]any)
+	default:
+		return nil, nil, fmt.Errorf("%s: records path %s: expected array, got %T", u, s.RecordsPath, value)
+	}
+	return &Page{URL: u, Header: resp.Header, Body: body, Records: len(items)}, items, nil
+}
+
+// floatNumbers returns a copy of a decoded JSON value whose json.Number
+// values are converted to float64.
+func floatNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := strconv.ParseFloat(v.String(), 64)
+		return f
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = floatNumbers(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = floatNumbers(item)
+		}
+		return out
+	}
+	return value
+}
+
+// sameOrigin reports whether a and b have the same scheme, host and port,
+// like net/http compares hosts before forwarding Authorization on redirects.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		port(a) == port(b)
+}
+
+// port returns the port of u, the default one of its scheme if it has none.
+func port(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// wait sleeps until RateLimit elapsed since the last request.
+func (s *HTTPSource) wait(ctx context.Context) error {
+	if s.RateLimit > 0 && !s.last.IsZero() {
+		if delay := s.RateLimit - time.Since(s.last); delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	s.last = time.Now()
+	return nil
+}
+
+// lookupPath returns the value at a dot-separated path of a decoded JSON
+// value. Path elements index objects by key and arrays by position.
+func lookupPath(value any, path string) (any, bool) {
+	if path == "" {
+		return value, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
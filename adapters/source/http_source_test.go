@@ -0,0 +1,352 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var userSchema = &domain.DataSchema{
+	ID: "User",
+	Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+		domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+	},
+}
+
+// usersJSON returns the JSON array of the users with IDs from..to-1.
+func usersJSON(from, to int) string {
+	items := "["
+	for id := from; id < to; id++ {
+		if id > from {
+			items += ","
+		}
+		items += fmt.Sprintf(`{"id": %d, "name": "user %d"}`, id, id)
+	}
+	return items + "]"
+}
+
+func userIDs(rs *domain.RecordSet) []int64 {
+	ids := []int64{}
+	for _, record := range rs.Records {
+		ids = append(ids, int64(record.Get("id").(domain.IntValue)))
+	}
+	return ids
+}
+
+func TestHTTPSource_Load(t *testing.T) {
+	t.Run("should load the records at the records path with the configured headers", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"data": {"items": %s}}`, usersJSON(1, 3))
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL+"/users", userSchema)
+		source.RecordsPath = "data.items"
+		source.Header = http.Header{"Authorization": {"Bearer secret"}}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, userIDs(result))
+		assert.Equal(t, "user 1", result.Records[0].GetString("name"))
+		assert.Equal(t, server.URL+"/users", result.Records[0].Source)
+	})
+
+	t.Run("should not send headers to other origins", func(t *testing.T) {
+		var received []string
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, r.Header.Get("Authorization"))
+			fmt.Fprintf(w, `{"items": %s}`, usersJSON(2, 3))
+		}))
+		defer other.Close()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, r.Header.Get("Authorization"))
+			fmt.Fprintf(w, `{"items": %s, "next": %q}`, usersJSON(1, 2), other.URL+"/users")
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL+"/users", userSchema)
+		source.RecordsPath = "items"
+		source.Pagination = NextLink{Path: "next"}
+		source.Header = http.Header{"Authorization": {"Bearer secret"}}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, userIDs(result))
+		assert.Equal(t, []string{"Bearer secret", ""}, received)
+	})
+
+	t.Run("should not send headers on redirects to other origins", func(t *testing.T) {
+		var received []string
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, r.Header.Get("X-Api-Key"))
+			fmt.Fprintf(w, `{"items": %s}`, usersJSON(1, 3))
+		}))
+		defer other.Close()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, r.Header.Get("X-Api-Key"))
+			if r.URL.Path == "/users" {
+				http.Redirect(w, r, "/v2/users", http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, other.URL+"/users", http.StatusFound)
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL+"/users", userSchema)
+		source.RecordsPath = "items"
+		source.Header = http.Header{"X-Api-Key": {"secret"}}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, userIDs(result))
+		assert.Equal(t, []string{"secret", "secret", ""}, received)
+	})
+
+	t.Run("should follow next links in the body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			next := `null`
+			if page < 2 {
+				next = fmt.Sprintf(`"/users?page=%d"`, page+1)
+			}
+			fmt.Fprintf(w, `{"items": %s, "links": {"next": %s}}`, usersJSON(page*2, page*2+2), next)
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL+"/users", userSchema)
+		source.RecordsPath = "items"
+		source.Pagination = NextLink{Path: "links.next"}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0, 1, 2, 3, 4, 5}, userIDs(result))
+		assert.Equal(t, server.URL+"/users?page=2", result.Records[5].Source)
+	})
+
+	t.Run("should pass cursors as a query parameter", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("after") {
+			case "":
+				fmt.Fprintf(w, `{"users": %s, "next_cursor": "abc"}`, usersJSON(1, 3))
+			case "abc":
+				fmt.Fprintf(w, `{"users": %s, "next_cursor": null}`, usersJSON(3, 4))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.RecordsPath = "users"
+		source.Pagination = Cursor{Path: "next_cursor", Param: "after"}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, userIDs(result))
+	})
+
+	t.Run("should send numeric cursors exactly", func(t *testing.T) {
+		var cursors []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cursors = append(cursors, r.URL.Query().Get("after"))
+			if r.URL.Query().Get("after") == "" {
+				fmt.Fprintf(w, `{"users": %s, "next": 9007199254740993}`, usersJSON(1, 2))
+				return
+			}
+			fmt.Fprintf(w, `{"users": %s}`, usersJSON(2, 3))
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.RecordsPath = "users"
+		source.Pagination = Cursor{Path: "next", Param: "after"}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, userIDs(result))
+		assert.Equal(t, []string{"", "9007199254740993"}, cursors)
+	})
+
+	t.Run("should stop at a page already fetched", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			next := map[string]string{"": "a", "a": "b", "b": "a"}[r.URL.Query().Get("cursor")]
+			fmt.Fprintf(w, `{"users": %s, "next": %q}`, usersJSON(requests, requests+1), next)
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.RecordsPath = "users"
+		source.Pagination = Cursor{Path: "next", Param: "cursor"}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, []int64{1, 2, 3}, userIDs(result))
+	})
+
+	t.Run("should page with offset and limit", func(t *testing.T) {
+		var requests []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.RawQuery)
+			offset, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			fmt.Fprint(w, usersJSON(offset, min(offset+limit, 5)))
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.Pagination = OffsetLimit{OffsetParam: "skip", Limit: 2}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0, 1, 2, 3, 4}, userIDs(result))
+		assert.Equal(t, []string{"limit=2", "limit=2&skip=2", "limit=2&skip=4"}, requests)
+	})
+
+	t.Run("should follow the next link of the Link header", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page < 1 {
+				w.Header().Set("Link", `</users?page=0>; rel="first", </users?page=1>; rel="next"`)
+			}
+			fmt.Fprint(w, usersJSON(page, page+1))
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL+"/users", userSchema)
+		source.Pagination = LinkHeader{}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0, 1}, userIDs(result))
+	})
+
+	t.Run("should stop at MaxPages", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, usersJSON(0, 1))
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.Pagination = OffsetLimit{Limit: 1}
+		source.MaxPages = 3
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Count())
+	})
+
+	t.Run("should wait RateLimit between requests", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			fmt.Fprint(w, usersJSON(offset, min(offset+1, 3)))
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.Pagination = OffsetLimit{Limit: 1}
+		source.RateLimit = 30 * time.Millisecond
+		start := time.Now()
+
+		_, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 4, requests)
+		assert.GreaterOrEqual(t, time.Since(start), 3*source.RateLimit)
+	})
+}
+
+func TestHTTPSource_Load_Errors(t *testing.T) {
+	t.Run("should return retryable status errors for 5xx and 429", func(t *testing.T) {
+		status := http.StatusServiceUnavailable
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+
+		_, err := source.Load()
+
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		assert.True(t, statusErr.Retryable())
+
+		status = http.StatusNotFound
+		_, err = source.Load()
+
+		require.ErrorAs(t, err, &statusErr)
+		assert.False(t, statusErr.Retryable())
+		assert.EqualError(t, err, "GET "+server.URL+": 404 Not Found")
+	})
+
+	t.Run("should report the index across pages and the page of an invalid record", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("offset") == "" {
+				fmt.Fprint(w, usersJSON(0, 2))
+				return
+			}
+			fmt.Fprint(w, `[{"id": 2}, {"id": "three"}]`)
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.Pagination = OffsetLimit{Limit: 2}
+
+		_, err := source.Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 3, recordErr.Index)
+		assert.Equal(t, server.URL+"?limit=2&offset=2", recordErr.Source)
+		var schemaErr *domain.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, "id", schemaErr.Column)
+	})
+
+	t.Run("should reject responses without the records path", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"users": []}}`)
+		}))
+		defer server.Close()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.RecordsPath = "data.items"
+
+		_, err := source.Load()
+
+		assert.EqualError(t, err, server.URL+": records path data.items not found")
+	})
+
+	t.Run("should stop waiting when the context is cancelled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, usersJSON(0, 1))
+		}))
+		defer server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		source := NewHTTPSource(server.URL, userSchema)
+		source.Pagination = OffsetLimit{Limit: 1}
+		source.RateLimit = time.Hour
+		source.Context = ctx
+
+		_, err := source.Load()
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -1,21 +1,50 @@
 package source
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
 	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
 )
 
-// JSONSource reads data from a JSON file.
+// JSONSource reads data from a JSON file, a file system or a stream.
+// Compressed input (e.g. ".json.gz") is decompressed transparently.
 type JSONSource struct {
 	FilePath string
 	Schema   *domain.DataSchema
+	FS       fs.FS     // File system FilePath is resolved in, nil for the OS file system
+	Reader   io.Reader // Input stream, takes precedence over FilePath when set
+
+	// DisallowUnknownColumns makes Load fail on keys missing from the schema,
+	// instead of ignoring them, to detect producers adding columns.
+	DisallowUnknownColumns bool
+
+	// Coerce casts values whose JSON type does not match their column, such
+	// as "42" in an int column, with domain.Cast. Nil keeps JSON types strict.
+	Coerce *domain.CastOptions
+
+	// Uncompressed reads the input as is, without detecting compression from
+	// the extension or the magic bytes, so that the size of an untrusted
+	// stream cannot grow past the size read.
+	Uncompressed bool
+
+	// Logger receives debug events about the file read, the records loaded
+	// and the unknown columns ignored. Nil disables logging.
+	Logger *slog.Logger
 }
 
-// NewJSONSource creates a new JSONSource.
+// NewJSONSource creates a new JSONSource reading from the given file.
 func NewJSONSource(filePath string, schema *domain.DataSchema) *JSONSource {
 	return &JSONSource{
 		FilePath: filePath,
@@ -23,9 +52,28 @@ func NewJSONSource(filePath string, schema *domain.DataSchema) *JSONSource {
 	}
 }
 
-// Load reads the JSON file and returns a RecordSet.
+// NewJSONSourceFS creates a new JSONSource reading the given file from fsys,
+// such as an embed.FS.
+func NewJSONSourceFS(fsys fs.FS, filePath string, schema *domain.DataSchema) *JSONSource {
+	return &JSONSource{
+		FilePath: filePath,
+		Schema:   schema,
+		FS:       fsys,
+	}
+}
+
+// NewJSONSourceFromReader creates a new JSONSource reading from r, such as
+// os.Stdin or an HTTP request body. The reader is consumed by the first Load.
+func NewJSONSourceFromReader(r io.Reader, schema *domain.DataSchema) *JSONSource {
+	return &JSONSource{
+		Schema: schema,
+		Reader: r,
+	}
+}
+
+// Load reads the JSON input and returns a RecordSet.
 func (s *JSONSource) Load() (*domain.RecordSet, error) {
-	data, err := os.ReadFile(s.FilePath)
+	data, err := s.read()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -36,30 +84,98 @@ func (s *JSONSource) Load() (*domain.RecordSet, error) {
 	}
 
 	recordSet := domain.NewRecordSet(s.Schema)
+	logger := logging.Or(s.Logger)
+	logUnknown := !s.DisallowUnknownColumns && logger.Enabled(context.Background(), slog.LevelDebug)
 
-	for _, item := range rawData {
-		record, err := s.mapToRecord(item)
+	for i, item := range rawData {
+		record, err := s.mapToRecord(item, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to map record: %w", err)
+			return nil, fmt.Errorf("failed to map record: %w", &domain.RecordError{Index: i, Source: s.FilePath, Err: err})
+		}
+		if logUnknown {
+			if unknown := s.unknownColumns(item); len(unknown) > 0 {
+				logger.Debug("ignored unknown columns", "path", s.FilePath, "record", i, "columns", unknown)
+			}
 		}
+		record.Source = s.FilePath
 		recordSet.Add(record)
 	}
 
+	logger.Debug("loaded records", "path", s.FilePath, "records", recordSet.Count())
 	return recordSet, nil
 }
 
-func (s *JSONSource) mapToRecord(data map[string]any) (*domain.Record, error) {
+// Fingerprint returns the path, size and modification time of the file, or
+// "" when reading from Reader.
+func (s *JSONSource) Fingerprint() (string, error) {
+	if s.Reader != nil {
+		return "", nil
+	}
+	return fileFingerprint(s.FS, s.FilePath)
+}
+
+// fileFingerprint returns the path, size and modification time of a file of
+// fsys, or of the OS file system when fsys is nil.
+func fileFingerprint(fsys fs.FS, path string) (string, error) {
+	var info fs.FileInfo
+	var err error
+	if fsys != nil {
+		info, err = fs.Stat(fsys, path)
+	} else {
+		info, err = os.Stat(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%q %d %d", path, info.Size(), info.ModTime().UnixNano()), nil
+}
+
+func (s *JSONSource) read() ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+
+	switch {
+	case s.Uncompressed && s.Reader != nil:
+		r = io.NopCloser(s.Reader)
+	case s.Uncompressed && s.FS != nil:
+		r, err = s.FS.Open(s.FilePath)
+	case s.Uncompressed:
+		r, err = os.Open(s.FilePath)
+	case s.Reader != nil:
+		r, err = compression.NewReader(s.Reader, s.FilePath)
+	case s.FS != nil:
+		r, err = compression.OpenFS(s.FS, s.FilePath)
+	default:
+		r, err = compression.Open(s.FilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// mapToRecord maps a JSON object to a record. Failures are reported as
+// *domain.SchemaError, with column paths starting with prefix.
+func (s *JSONSource) mapToRecord(data map[string]any, prefix string) (*domain.Record, error) {
 	record := domain.NewRecord(s.Schema)
 
+	if s.DisallowUnknownColumns {
+		if err := s.checkUnknownColumns(data); err != nil {
+			return nil, s.schemaError(strings.TrimSuffix(prefix, "."), err)
+		}
+	}
+
 	for _, col := range s.Schema.Columns {
 		value, exists := data[col.GetID()]
 		if !exists {
 			continue
 		}
 
-		mappedValue, err := s.mapValue(value, col.GetType(), col.IsArray())
+		mappedValue, err := s.mapValue(value, col.GetType(), col.IsArray(), prefix+col.GetID())
 		if err != nil {
-			return nil, fmt.Errorf("column %s: %w", col.GetID(), err)
+			return nil, err
 		}
 
 		record.Set(col.GetID(), mappedValue)
@@ -68,36 +184,68 @@ func (s *JSONSource) mapToRecord(data map[string]any) (*domain.Record, error) {
 	return record, nil
 }
 
-func (s *JSONSource) mapValue(value any, schemaType domain.SchemaType, isArray bool) (domain.Value, error) {
+func (s *JSONSource) checkUnknownColumns(data map[string]any) error {
+	if unknown := s.unknownColumns(data); len(unknown) > 0 {
+		return fmt.Errorf("unknown columns: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// unknownColumns returns the sorted keys of data missing from the schema.
+func (s *JSONSource) unknownColumns(data map[string]any) []string {
+	known := make(map[string]bool, len(s.Schema.Columns))
+	for _, col := range s.Schema.Columns {
+		known[col.GetID()] = true
+	}
+
+	var unknown []string
+	for key := range data {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func (s *JSONSource) schemaError(column string, err error) error {
+	return &domain.SchemaError{Schema: s.Schema.ID, Column: column, Err: err}
+}
+
+func (s *JSONSource) mapValue(value any, schemaType domain.SchemaType, isArray bool, path string) (domain.Value, error) {
 	if value == nil {
 		return domain.NullValue{Type: schemaType}, nil
 	}
 
 	if isArray {
-		return s.mapArrayValue(value, schemaType)
+		return s.mapArrayValue(value, schemaType, path)
 	}
 
-	return s.mapSingleValue(value, schemaType)
+	return s.mapSingleValue(value, schemaType, path)
 }
 
-func (s *JSONSource) mapSingleValue(value any, schemaType domain.SchemaType) (domain.Value, error) {
+func (s *JSONSource) mapSingleValue(value any, schemaType domain.SchemaType, path string) (domain.Value, error) {
 	if schemaType.IsNative() {
-		return s.mapNativeValue(value, schemaType.(domain.NativeType))
+		v, err := s.mapNativeValue(value, schemaType.(domain.NativeType))
+		if err != nil {
+			return nil, s.schemaError(path, err)
+		}
+		return v, nil
 	}
 
 	// Custom type - expect a nested object
 	nestedData, ok := value.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("expected object for custom type %s", schemaType.GetTypeName())
+		return nil, s.schemaError(path, fmt.Errorf("expected object for custom type %s", schemaType.GetTypeName()))
 	}
 
 	customType := schemaType.(domain.CustomType)
 	if customType.Schema == nil {
-		return nil, fmt.Errorf("custom type %s has no schema", customType.Name)
+		return nil, s.schemaError(path, fmt.Errorf("custom type %s has no schema", customType.Name))
 	}
 
-	nestedSource := &JSONSource{Schema: customType.Schema}
-	nestedRecord, err := nestedSource.mapToRecord(nestedData)
+	nestedSource := &JSONSource{Schema: customType.Schema, DisallowUnknownColumns: s.DisallowUnknownColumns, Coerce: s.Coerce}
+	nestedRecord, err := nestedSource.mapToRecord(nestedData, path+".")
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +254,10 @@ func (s *JSONSource) mapSingleValue(value any, schemaType domain.SchemaType) (do
 }
 
 func (s *JSONSource) mapNativeValue(value any, nativeType domain.NativeType) (domain.Value, error) {
+	if s.Coerce != nil {
+		return s.coerceNativeValue(value, nativeType)
+	}
+
 	switch nativeType {
 	case domain.NativeTypeString:
 		str, ok := value.(string)
@@ -140,22 +292,50 @@ func (s *JSONSource) mapNativeValue(value any, nativeType domain.NativeType) (do
 		}
 		return domain.DateValue(t), nil
 
+	case domain.NativeTypeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		return domain.BoolValue(b), nil
+
 	default:
 		return nil, fmt.Errorf("unknown native type: %s", nativeType)
 	}
 }
 
-func (s *JSONSource) mapArrayValue(value any, elementType domain.SchemaType) (domain.Value, error) {
+func (s *JSONSource) coerceNativeValue(value any, nativeType domain.NativeType) (domain.Value, error) {
+	var v domain.Value
+	switch x := value.(type) {
+	case string:
+		v = domain.StringValue(x)
+	case float64:
+		// JSON has a single number type: integral numbers are read as ints
+		// so that they cast to dates as epochs.
+		if x == math.Trunc(x) && math.Abs(x) < 1<<63 {
+			v = domain.IntValue(int64(x))
+		} else {
+			v = domain.FloatValue(x)
+		}
+	case bool:
+		v = domain.BoolValue(x)
+	default:
+		return nil, fmt.Errorf("expected %s, got %T", nativeType, value)
+	}
+	return domain.Cast(v, nativeType, *s.Coerce)
+}
+
+func (s *JSONSource) mapArrayValue(value any, elementType domain.SchemaType, path string) (domain.Value, error) {
 	arr, ok := value.([]any)
 	if !ok {
-		return nil, fmt.Errorf("expected array, got %T", value)
+		return nil, s.schemaError(path, fmt.Errorf("expected array, got %T", value))
 	}
 
 	elements := make([]domain.Value, 0, len(arr))
 	for i, item := range arr {
-		elem, err := s.mapSingleValue(item, elementType)
+		elem, err := s.mapSingleValue(item, elementType, fmt.Sprintf("%s[%d]", path, i))
 		if err != nil {
-			return nil, fmt.Errorf("element %d: %w", i, err)
+			return nil, err
 		}
 		elements = append(elements, elem)
 	}
@@ -1,10 +1,16 @@
 package source
 
 import (
+	"bytes"
+	"compress/gzip"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
 	"github.com/spaghettifactory-oss/pipeforge/domain"
 
 	"github.com/stretchr/testify/assert"
@@ -42,6 +48,7 @@ func TestJSONSource_Load(t *testing.T) {
 
 		last := result.Last()
 		assert.Equal(t, "Phone", last.GetString("name"))
+		assert.Equal(t, filePath, last.Source)
 	})
 
 	t.Run("should load JSON with arrays", func(t *testing.T) {
@@ -291,6 +298,169 @@ func TestJSONSource_Load_UnknownType(t *testing.T) {
 	})
 }
 
+func TestJSONSource_Load_UnknownColumns(t *testing.T) {
+	t.Run("should ignore unknown columns by default", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			},
+		}
+
+		filePath := createTempFile(t, `[{"name": "Laptop", "color": "grey"}]`)
+		source := NewJSONSource(filePath, schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Nil(t, result.First().Get("color"))
+	})
+
+	t.Run("should return error for unknown columns when disallowed", func(t *testing.T) {
+		addressSchema := &domain.DataSchema{
+			ID: "Address",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+			},
+		}
+		schema := &domain.DataSchema{
+			ID: "User",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "Address", Schema: addressSchema}},
+			},
+		}
+
+		filePath := createTempFile(t, `[{"address": {"city": "Paris", "zip": "75001", "country": "FR"}}]`)
+		source := NewJSONSource(filePath, schema)
+		source.DisallowUnknownColumns = true
+
+		result, err := source.Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "column address: unknown columns: country, zip")
+	})
+}
+
+func TestJSONSource_Load_Coerce(t *testing.T) {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			domain.SchemaColumnSingle{ID: "updated", SchemaType: domain.NativeTypeDate},
+			domain.SchemaColumnArray{ID: "codes", RefSchema: domain.NativeTypeString},
+		},
+	}
+
+	t.Run("should cast mismatched JSON types", func(t *testing.T) {
+		filePath := createTempFile(t, `[{"stock": "42", "price": "9.5", "active": "true", "updated": 1705314600, "codes": [1, "b"]}]`)
+		source := NewJSONSource(filePath, schema)
+		source.Coerce = &domain.CastOptions{}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		record := result.First()
+		assert.Equal(t, int64(42), record.GetInt("stock"))
+		assert.Equal(t, 9.5, record.GetFloat("price"))
+		assert.True(t, record.GetBool("active"))
+		assert.Equal(t, time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), record.GetDate("updated"))
+		assert.Equal(t, []domain.Value{domain.StringValue("1"), domain.StringValue("b")}, record.GetArray("codes"))
+	})
+
+	t.Run("should apply cast options", func(t *testing.T) {
+		filePath := createTempFile(t, `[{"stock": 2.5, "active": "yes"}]`)
+		source := NewJSONSource(filePath, schema)
+		source.Coerce = &domain.CastOptions{Mode: domain.CastLenient, Rounding: domain.RoundHalfEven}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.First().GetInt("stock"))
+		assert.True(t, result.First().GetBool("active"))
+	})
+
+	t.Run("should return error for values that do not cast", func(t *testing.T) {
+		filePath := createTempFile(t, `[{"stock": 2.5}]`)
+		source := NewJSONSource(filePath, schema)
+		source.Coerce = &domain.CastOptions{}
+
+		_, err := source.Load()
+
+		assert.ErrorContains(t, err, "column stock: cannot convert 2.5 to int without rounding")
+	})
+}
+
+func TestJSONSource_Load_Errors(t *testing.T) {
+	t.Run("should return typed errors with record index, source and column path", func(t *testing.T) {
+		itemSchema := &domain.DataSchema{
+			ID: "Item",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			},
+		}
+		schema := &domain.DataSchema{
+			ID: "Order",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnArray{ID: "items", RefSchema: domain.CustomType{Name: "Item", Schema: itemSchema}},
+			},
+		}
+		filePath := createTempFile(t, `[{"items": []}, {"items": [{"price": 1}, {"price": "free"}]}]`)
+
+		_, err := NewJSONSource(filePath, schema).Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 1, recordErr.Index)
+		assert.Equal(t, filePath, recordErr.Source)
+
+		var schemaErr *domain.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, "Item", schemaErr.Schema)
+		assert.Equal(t, "items[1].price", schemaErr.Column)
+		assert.EqualError(t, schemaErr, "column items[1].price: expected number, got string")
+	})
+}
+
+func TestJSONSource_Load_Bools(t *testing.T) {
+	t.Run("should load bool field", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			},
+		}
+
+		filePath := createTempFile(t, `[{"active": true}]`)
+		source := NewJSONSource(filePath, schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.True(t, result.First().GetBool("active"))
+	})
+
+	t.Run("should return error when bool field receives non-bool", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			},
+		}
+
+		filePath := createTempFile(t, `[{"active": "yes"}]`)
+		source := NewJSONSource(filePath, schema)
+
+		result, err := source.Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "expected bool")
+	})
+}
+
 func TestJSONSource_Load_Strings(t *testing.T) {
 	t.Run("should return error when string field receives non-string", func(t *testing.T) {
 		schema := &domain.DataSchema{
@@ -443,7 +613,7 @@ func TestJSONSource_Load_Arrays(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "element 1")
+		assert.Contains(t, err.Error(), "column prices[1]: expected number")
 	})
 
 	t.Run("should load array of nested objects", func(t *testing.T) {
@@ -478,6 +648,136 @@ func TestJSONSource_Load_Arrays(t *testing.T) {
 	})
 }
 
+func TestJSONSource_Load_Compressed(t *testing.T) {
+	t.Run("should load gzip compressed JSON", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			},
+		}
+
+		filePath := filepath.Join(t.TempDir(), "test.json.gz")
+		require.NoError(t, compression.WriteFile(filePath, []byte(`[{"name": "Laptop"}]`), compression.Gzip))
+		source := NewJSONSource(filePath, schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Count())
+		assert.Equal(t, "Laptop", result.First().GetString("name"))
+	})
+}
+
+func TestJSONSource_Load_Streams(t *testing.T) {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+		},
+	}
+
+	t.Run("should load from reader", func(t *testing.T) {
+		source := NewJSONSourceFromReader(strings.NewReader(`[{"name": "Laptop"}]`), schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Count())
+		assert.Equal(t, "Laptop", result.First().GetString("name"))
+	})
+
+	t.Run("should load compressed data from reader", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(`[{"name": "Phone"}]`))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		source := NewJSONSourceFromReader(&buf, schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, "Phone", result.First().GetString("name"))
+	})
+
+	t.Run("should not decompress uncompressed sources", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(`[{"name": "Phone"}]`))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		source := NewJSONSourceFromReader(&buf, schema)
+		source.Uncompressed = true
+
+		_, err = source.Load()
+
+		assert.ErrorContains(t, err, "failed to parse JSON")
+	})
+
+	t.Run("should load from file system", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"data/products.json": {Data: []byte(`[{"name": "Laptop"}, {"name": "Phone"}]`)},
+		}
+		source := NewJSONSourceFS(fsys, "data/products.json", schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Count())
+	})
+
+	t.Run("should return error for missing file in file system", func(t *testing.T) {
+		source := NewJSONSourceFS(fstest.MapFS{}, "missing.json", schema)
+
+		result, err := source.Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "failed to read file")
+	})
+}
+
+func TestJSONSource_Fingerprint(t *testing.T) {
+	t.Run("should change when the file changes", func(t *testing.T) {
+		filePath := createTempFile(t, `[{"name": "Laptop"}]`)
+		source := NewJSONSource(filePath, nil)
+		before, err := source.Fingerprint()
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filePath, []byte(`[{"name": "Laptop"}, {"name": "Phone"}]`), 0644))
+		after, err := source.Fingerprint()
+		require.NoError(t, err)
+
+		assert.Contains(t, before, filePath)
+		assert.NotEqual(t, before, after)
+	})
+
+	t.Run("should fingerprint files of a file system", func(t *testing.T) {
+		fsys := fstest.MapFS{"products.json": {Data: []byte(`[]`), ModTime: time.Unix(1700000000, 0)}}
+
+		fingerprint, err := NewJSONSourceFS(fsys, "products.json", nil).Fingerprint()
+
+		require.NoError(t, err)
+		assert.Equal(t, `"products.json" 2 1700000000000000000`, fingerprint)
+	})
+
+	t.Run("should return no fingerprint for streams", func(t *testing.T) {
+		fingerprint, err := NewJSONSourceFromReader(strings.NewReader(`[]`), nil).Fingerprint()
+
+		require.NoError(t, err)
+		assert.Empty(t, fingerprint)
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		_, err := NewJSONSource(filepath.Join(t.TempDir(), "missing.json"), nil).Fingerprint()
+
+		assert.Error(t, err)
+	})
+}
+
 func createTempFile(t *testing.T, content string) string {
 	t.Helper()
 	tmpDir := t.TempDir()
@@ -0,0 +1,206 @@
+package source
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// MultiFileSource loads every file matching a glob pattern or found under a
+// directory, and concatenates them into a single RecordSet.
+// Files are loaded in lexical path order so that results are deterministic.
+type MultiFileSource struct {
+	Pattern      string                             // Glob pattern (e.g., "logs/*.json"), used when Root is empty
+	Root         string                             // Directory walked recursively
+	Include      []string                           // Patterns a file must match, empty matches every file
+	Exclude      []string                           // Patterns excluding a file
+	Schema       *domain.DataSchema                 // Schema of the records in every file
+	FS           fs.FS                              // File system paths are resolved in, nil for the OS file system
+	Open         func(path string) ports.SourcePort // Inner format adapter, defaults to a JSONSource
+	SourceColumn string                             // Optional string column receiving the originating file, replacing a column of Schema with that ID
+	Logger       *slog.Logger                       // Receives the files loaded and skipped, passed to the default JSONSource, nil disables logging
+}
+
+// NewGlobSource creates a MultiFileSource loading the JSON files matching pattern.
+func NewGlobSource(pattern string, schema *domain.DataSchema) *MultiFileSource {
+	return &MultiFileSource{
+		Pattern: pattern,
+		Schema:  schema,
+	}
+}
+
+// NewDirSource creates a MultiFileSource loading the JSON files under root.
+func NewDirSource(root string, schema *domain.DataSchema) *MultiFileSource {
+	return &MultiFileSource{
+		Root:   root,
+		Schema: schema,
+	}
+}
+
+// Load reads every matching file and returns the concatenated RecordSet.
+// Each record has its Source set to the file it was read from.
+func (s *MultiFileSource) Load() (*domain.RecordSet, error) {
+	files, err := s.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	schema := s.outputSchema()
+	result := domain.NewRecordSet(schema)
+	logger := logging.Or(s.Logger)
+
+	for _, file := range files {
+		data, err := s.open(file).Load()
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %w", file, err)
+		}
+		logger.Debug("loaded file", "path", file, "records", data.Count())
+
+		for _, record := range data.Records {
+			record.Source = file
+			if s.SourceColumn != "" {
+				record.Schema = schema
+				record.Set(s.SourceColumn, domain.StringValue(file))
+			}
+			result.Add(record)
+		}
+	}
+
+	logger.Info("loaded files", "files", len(files), "records", result.Count())
+	return result, nil
+}
+
+// Fingerprint returns the path, size and modification time of every file
+// the source would load, so that it changes when a file is added, removed
+// or modified.
+func (s *MultiFileSource) Fingerprint() (string, error) {
+	files, err := s.Files()
+	if err != nil {
+		return "", fmt.Errorf("failed to list files: %w", err)
+	}
+	fingerprints := make([]string, 0, len(files))
+	for _, file := range files {
+		fingerprint, err := fileFingerprint(s.FS, file)
+		if err != nil {
+			return "", err
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return strings.Join(fingerprints, "\n"), nil
+}
+
+// Files returns the sorted list of files the source would load.
+func (s *MultiFileSource) Files() ([]string, error) {
+	var candidates []string
+	var err error
+
+	if s.Root != "" {
+		candidates, err = s.walk()
+	} else {
+		candidates, err = s.glob()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(candidates))
+	for _, file := range candidates {
+		if reason := s.skipReason(file); reason != "" {
+			logging.Or(s.Logger).Debug("skipped file", "path", file, "reason", reason)
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (s *MultiFileSource) glob() ([]string, error) {
+	if s.FS != nil {
+		return fs.Glob(s.FS, s.Pattern)
+	}
+	return filepath.Glob(s.Pattern)
+}
+
+func (s *MultiFileSource) walk() ([]string, error) {
+	var files []string
+	collect := func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	}
+
+	if s.FS != nil {
+		return files, fs.WalkDir(s.FS, s.Root, collect)
+	}
+	return files, filepath.WalkDir(s.Root, collect)
+}
+
+// skipReason returns why file is not loaded, or "" if it is selected.
+func (s *MultiFileSource) skipReason(file string) string {
+	if len(s.Include) > 0 && !s.matchAny(s.Include, file) {
+		return "not included"
+	}
+	if s.matchAny(s.Exclude, file) {
+		return "excluded"
+	}
+	return ""
+}
+
+// matchAny reports whether file matches one of the patterns, either by its
+// base name or by its path relative to Root.
+func (s *MultiFileSource) matchAny(patterns []string, file string) bool {
+	rel := file
+	if s.Root != "" {
+		if r, err := filepath.Rel(s.Root, file); err == nil {
+			rel = filepath.ToSlash(r)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, path.Base(filepath.ToSlash(file))); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MultiFileSource) open(file string) ports.SourcePort {
+	if s.Open != nil {
+		return s.Open(file)
+	}
+	var src *JSONSource
+	if s.FS != nil {
+		src = NewJSONSourceFS(s.FS, file, s.Schema)
+	} else {
+		src = NewJSONSource(file, s.Schema)
+	}
+	src.Logger = s.Logger
+	return src
+}
+
+// outputSchema returns the schema of the loaded records, with the
+// SourceColumn appended, or replacing the column of Schema with its ID,
+// when one is configured.
+func (s *MultiFileSource) outputSchema() *domain.DataSchema {
+	if s.SourceColumn == "" || s.Schema == nil {
+		return s.Schema
+	}
+	return s.Schema.WithColumn(domain.SchemaColumnSingle{ID: s.SourceColumn, SchemaType: domain.NativeTypeString})
+}
@@ -0,0 +1,261 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
+	mocksource "github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logSchema() *domain.DataSchema {
+	return &domain.DataSchema{
+		ID: "Log",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "message", SchemaType: domain.NativeTypeString},
+		},
+	}
+}
+
+func TestMultiFileSource_Load(t *testing.T) {
+	t.Run("should load files matching glob in lexical order", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "10.json", `[{"message": "ten"}]`)
+		writeFile(t, dir, "08.json", `[{"message": "eight"}, {"message": "eight bis"}]`)
+		writeFile(t, dir, "notes.txt", `not json`)
+
+		source := NewGlobSource(filepath.Join(dir, "*.json"), logSchema())
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Count())
+		assert.Equal(t, "eight", result.Get(0).GetString("message"))
+		assert.Equal(t, "eight bis", result.Get(1).GetString("message"))
+		assert.Equal(t, "ten", result.Get(2).GetString("message"))
+	})
+
+	t.Run("should record originating file on each record", func(t *testing.T) {
+		dir := t.TempDir()
+		first := writeFile(t, dir, "a.json", `[{"message": "a"}]`)
+		second := writeFile(t, dir, "b.json", `[{"message": "b"}]`)
+
+		source := NewGlobSource(filepath.Join(dir, "*.json"), logSchema())
+		source.SourceColumn = "_file"
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, first, result.Get(0).Source)
+		assert.Equal(t, second, result.Get(1).Source)
+		assert.Equal(t, second, result.Get(1).GetString("_file"))
+		assert.Len(t, result.Schema.Columns, 2)
+		assert.Same(t, result.Schema, result.Get(0).Schema)
+	})
+
+	t.Run("should replace a schema column named like the source column", func(t *testing.T) {
+		dir := t.TempDir()
+		file := writeFile(t, dir, "a.json", `[{"message": "a", "_file": 3}]`)
+		schema := logSchema()
+		schema.Columns = append(schema.Columns, domain.SchemaColumnSingle{ID: "_file", SchemaType: domain.NativeTypeInt})
+
+		source := NewGlobSource(filepath.Join(dir, "*.json"), schema)
+		source.SourceColumn = "_file"
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "message", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "_file", SchemaType: domain.NativeTypeString},
+		}, result.Schema.Columns)
+		assert.Equal(t, file, result.Get(0).GetString("_file"))
+		assert.Len(t, schema.Columns, 2)
+		assert.Equal(t, domain.NativeTypeInt, schema.Columns[1].GetType())
+	})
+
+	t.Run("should walk directory recursively with include and exclude", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "2024/01/a.json", `[{"message": "a"}]`)
+		writeFile(t, dir, "2024/02/b.json", `[{"message": "b"}]`)
+		writeFile(t, dir, "2024/02/b.tmp.json", `[{"message": "tmp"}]`)
+		writeFile(t, dir, "README.md", `# logs`)
+
+		source := NewDirSource(dir, logSchema())
+		source.Include = []string{"*.json"}
+		source.Exclude = []string{"*.tmp.json"}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Count())
+		assert.Equal(t, "a", result.Get(0).GetString("message"))
+		assert.Equal(t, "b", result.Get(1).GetString("message"))
+	})
+
+	t.Run("should match include against relative path", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "2024/01/a.json", `[{"message": "a"}]`)
+		writeFile(t, dir, "2024/02/b.json", `[{"message": "b"}]`)
+
+		source := NewDirSource(dir, logSchema())
+		source.Include = []string{"2024/02/*"}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Count())
+		assert.Equal(t, "b", result.First().GetString("message"))
+	})
+
+	t.Run("should load from file system", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"logs/b.json": {Data: []byte(`[{"message": "b"}]`)},
+			"logs/a.json": {Data: []byte(`[{"message": "a"}]`)},
+		}
+		source := NewDirSource("logs", logSchema())
+		source.FS = fsys
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Count())
+		assert.Equal(t, "logs/a.json", result.First().Source)
+	})
+
+	t.Run("should use inner format adapter", func(t *testing.T) {
+		var opened []string
+		source := NewGlobSource("*.csv", logSchema())
+		source.FS = fstest.MapFS{"x.csv": {}, "y.csv": {}}
+		source.Open = func(path string) ports.SourcePort {
+			opened = append(opened, path)
+			return stubSource{}
+		}
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"x.csv", "y.csv"}, opened)
+		assert.Equal(t, 2, result.Count())
+	})
+
+	t.Run("should return empty set when nothing matches", func(t *testing.T) {
+		source := NewGlobSource(filepath.Join(t.TempDir(), "*.json"), logSchema())
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		assert.True(t, result.IsEmpty())
+	})
+
+	t.Run("should return error with file name when a file fails", func(t *testing.T) {
+		dir := t.TempDir()
+		broken := writeFile(t, dir, "broken.json", `not json`)
+
+		source := NewGlobSource(filepath.Join(dir, "*.json"), logSchema())
+
+		result, err := source.Load()
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), broken)
+	})
+
+	t.Run("should return error for inner source failure", func(t *testing.T) {
+		source := NewGlobSource("*.json", logSchema())
+		source.FS = fstest.MapFS{"a.json": {}}
+		source.Open = func(string) ports.SourcePort { return mocksource.ErrorSource{} }
+
+		_, err := source.Load()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "source load error")
+	})
+
+	t.Run("should return error for missing directory", func(t *testing.T) {
+		source := NewDirSource("/non/existent/dir", logSchema())
+
+		_, err := source.Load()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list files")
+	})
+
+	t.Run("should return error for malformed pattern", func(t *testing.T) {
+		source := NewGlobSource("[", logSchema())
+
+		_, err := source.Load()
+
+		assert.True(t, errors.Is(err, filepath.ErrBadPattern))
+	})
+}
+
+// stubSource returns a single record with no values.
+type stubSource struct{}
+
+func (stubSource) Load() (*domain.RecordSet, error) {
+	rs := domain.NewRecordSet(logSchema())
+	rs.Add(domain.NewRecord(rs.Schema))
+	return rs, nil
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestMultiFileSource_Logger(t *testing.T) {
+	t.Run("should log loaded and skipped files", func(t *testing.T) {
+		var buf bytes.Buffer
+		fsys := fstest.MapFS{
+			"logs/a.json":     {Data: []byte(`[{"message": "a", "level": "info"}]`)},
+			"logs/b.json.bak": {Data: []byte(`[]`)},
+			"logs/notes.txt":  {Data: []byte(`notes`)},
+		}
+		source := &MultiFileSource{
+			Root:    "logs",
+			FS:      fsys,
+			Schema:  logSchema(),
+			Include: []string{"*.json", "*.bak"},
+			Exclude: []string{"*.bak"},
+			Logger:  logger.NewTextLogger(&buf),
+		}
+
+		_, err := source.Load()
+
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), `level=DEBUG msg="skipped file" path=logs/b.json.bak reason=excluded`+"\n")
+		assert.Contains(t, buf.String(), `level=DEBUG msg="skipped file" path=logs/notes.txt reason="not included"`+"\n")
+		assert.Contains(t, buf.String(), `level=DEBUG msg="ignored unknown columns" path=logs/a.json record=0 columns=[level]`+"\n")
+		assert.Contains(t, buf.String(), `level=DEBUG msg="loaded file" path=logs/a.json records=1`+"\n")
+		assert.Contains(t, buf.String(), `level=INFO msg="loaded files" files=1 records=1`+"\n")
+	})
+}
+
+func TestMultiFileSource_Fingerprint(t *testing.T) {
+	t.Run("should change when a file is added", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.json", `[{"message": "a"}]`)
+		source := NewGlobSource(filepath.Join(dir, "*.json"), logSchema())
+		before, err := source.Fingerprint()
+		require.NoError(t, err)
+
+		writeFile(t, dir, "b.json", `[{"message": "b"}]`)
+		after, err := source.Fingerprint()
+		require.NoError(t, err)
+
+		assert.NotEqual(t, before, after)
+	})
+}
@@ -0,0 +1,94 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+)
+
+// NDJSONSource reads newline-delimited JSON, one object per line, from a
+// file, a file system or a stream. Blank lines are skipped. Objects are
+// mapped like JSONSource maps the items of its array.
+type NDJSONSource struct {
+	FilePath string
+	Schema   *domain.DataSchema
+	FS       fs.FS     // File system FilePath is resolved in, nil for the OS file system
+	Reader   io.Reader // Input stream, takes precedence over FilePath when set
+
+	DisallowUnknownColumns bool                // Reject keys missing from the schema, see JSONSource
+	Coerce                 *domain.CastOptions // Cast values whose JSON type does not match their column, see JSONSource
+	Uncompressed           bool                // Read the input as is, without detecting compression, see JSONSource
+	Logger                 *slog.Logger        // Receives a debug event with the records loaded, nil disables logging
+}
+
+// NewNDJSONSource creates an NDJSONSource reading the given file.
+func NewNDJSONSource(filePath string, schema *domain.DataSchema) *NDJSONSource {
+	return &NDJSONSource{FilePath: filePath, Schema: schema}
+}
+
+// NewNDJSONSourceFromReader creates an NDJSONSource reading from r. The
+// reader is consumed by the first Load.
+func NewNDJSONSourceFromReader(r io.Reader, schema *domain.DataSchema) *NDJSONSource {
+	return &NDJSONSource{Schema: schema, Reader: r}
+}
+
+// Load reads every line and returns a RecordSet. Failures are reported as a
+// *domain.RecordError whose Index is the line number, counted from 0.
+func (s *NDJSONSource) Load() (*domain.RecordSet, error) {
+	parser := &JSONSource{
+		FilePath:               s.FilePath,
+		Schema:                 s.Schema,
+		FS:                     s.FS,
+		Reader:                 s.Reader,
+		DisallowUnknownColumns: s.DisallowUnknownColumns,
+		Coerce:                 s.Coerce,
+		Uncompressed:           s.Uncompressed,
+	}
+	data, err := parser.read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	recordSet, err := parser.mapLines(data)
+	if err != nil {
+		return nil, err
+	}
+	logging.Or(s.Logger).Debug("loaded records", "path", s.FilePath, "records", recordSet.Count())
+	return recordSet, nil
+}
+
+// Fingerprint returns the path, size and modification time of the file, or
+// "" when reading from Reader.
+func (s *NDJSONSource) Fingerprint() (string, error) {
+	if s.Reader != nil {
+		return "", nil
+	}
+	return fileFingerprint(s.FS, s.FilePath)
+}
+
+// mapLines maps each line of NDJSON data to a record.
+func (s *JSONSource) mapLines(data []byte) (*domain.RecordSet, error) {
+	recordSet := domain.NewRecordSet(s.Schema)
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var item map[string]any
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", &domain.RecordError{Index: i, Source: s.FilePath, Err: err})
+		}
+		record, err := s.mapToRecord(item, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to map record: %w", &domain.RecordError{Index: i, Source: s.FilePath, Err: err})
+		}
+		record.Source = s.FilePath
+		recordSet.Add(record)
+	}
+	return recordSet, nil
+}
@@ -0,0 +1,77 @@
+package source
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSONSource_Load(t *testing.T) {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeInt},
+		},
+	}
+
+	t.Run("should load one record per line and skip blank lines", func(t *testing.T) {
+		filePath := createTempFile(t, "{\"name\": \"Laptop\", \"quantity\": 5}\n\n{\"name\": \"Phone\", \"quantity\": 10}\n")
+
+		result, err := NewNDJSONSource(filePath, schema).Load()
+
+		require.NoError(t, err)
+		require.Equal(t, 2, result.Count())
+		assert.Equal(t, "Laptop", result.Records[0].GetString("name"))
+		assert.Equal(t, domain.IntValue(10), result.Records[1].Get("quantity"))
+		assert.Equal(t, filePath, result.Records[1].Source)
+	})
+
+	t.Run("should load from a reader", func(t *testing.T) {
+		source := NewNDJSONSourceFromReader(strings.NewReader(`{"name": "Laptop"}`), schema)
+
+		result, err := source.Load()
+
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Count())
+		assert.Equal(t, "Laptop", result.Records[0].GetString("name"))
+	})
+
+	t.Run("should report the line of an invalid record", func(t *testing.T) {
+		source := NewNDJSONSourceFromReader(strings.NewReader("{\"name\": \"Laptop\"}\n{\"quantity\": \"many\"}\n"), schema)
+
+		_, err := source.Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 1, recordErr.Index)
+		var schemaErr *domain.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, "quantity", schemaErr.Column)
+	})
+
+	t.Run("should report the line of malformed JSON", func(t *testing.T) {
+		source := NewNDJSONSourceFromReader(strings.NewReader("{\"name\": \"Laptop\"}\n{\"name\":\n"), schema)
+
+		_, err := source.Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 1, recordErr.Index)
+		assert.ErrorContains(t, err, "failed to parse JSON")
+	})
+
+	t.Run("should reject unknown keys when disallowed", func(t *testing.T) {
+		source := NewNDJSONSourceFromReader(strings.NewReader(`{"name": "Laptop", "color": "grey"}`), schema)
+		source.DisallowUnknownColumns = true
+
+		_, err := source.Load()
+
+		var schemaErr *domain.SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+	})
+}
@@ -0,0 +1,117 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// OffsetSource loads an append-only NDJSON file (one JSON object per line)
+// incrementally: it only returns the lines appended since the byte offset
+// saved by the previous run.
+//
+// Load only reads complete lines, so a line being written is returned by the
+// next run, and Commit saves the offset after the last line read. The file
+// is read from the start again when it is smaller than the saved offset, as
+// after a rotation. Compressed files are not supported.
+type OffsetSource struct {
+	FilePath string
+	Schema   *domain.DataSchema
+	State    ports.StateStorePort // Where the offset is saved between runs
+	Key      string               // State key of the offset, FilePath if empty
+	Logger   *slog.Logger         // Receives the offsets read, nil disables logging
+
+	pending *int64 // Offset to save on Commit
+}
+
+// NewOffsetSource creates an OffsetSource reading the NDJSON file at
+// filePath.
+func NewOffsetSource(filePath string, schema *domain.DataSchema, state ports.StateStorePort) *OffsetSource {
+	return &OffsetSource{FilePath: filePath, Schema: schema, State: state}
+}
+
+// Load returns the records of the lines appended since the saved offset.
+func (s *OffsetSource) Load() (*domain.RecordSet, error) {
+	s.pending = nil
+
+	offset, err := s.offset()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(s.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.Size() < offset {
+		logging.Or(s.Logger).Info("file truncated, reading from start", "path", s.FilePath, "offset", offset, "size", info.Size())
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	// Keep complete lines only.
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+
+	parser := &JSONSource{FilePath: s.FilePath, Schema: s.Schema}
+	recordSet, err := parser.mapLines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	next := offset + int64(len(data))
+	s.pending = &next
+	logging.Or(s.Logger).Debug("loaded records", "path", s.FilePath, "from", offset, "to", next, "records", recordSet.Count())
+	return recordSet, nil
+}
+
+// Commit saves the offset reached by the last Load.
+func (s *OffsetSource) Commit() error {
+	if s.pending == nil {
+		return nil
+	}
+	if err := s.State.Set(s.key(), strconv.FormatInt(*s.pending, 10)); err != nil {
+		return fmt.Errorf("failed to save offset: %w", err)
+	}
+	s.pending = nil
+	return nil
+}
+
+func (s *OffsetSource) offset() (int64, error) {
+	saved, ok, err := s.State.Get(s.key())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read offset: %w", err)
+	}
+	if !ok {
+		return 0, nil
+	}
+	offset, err := strconv.ParseInt(saved, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid offset %q", saved)
+	}
+	return offset, nil
+}
+
+func (s *OffsetSource) key() string {
+	if s.Key != "" {
+		return s.Key
+	}
+	return s.FilePath
+}
@@ -0,0 +1,109 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/state"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// loadCommitted loads src and commits it, as a successful run does.
+func loadCommitted(t *testing.T, src *OffsetSource) []int64 {
+	t.Helper()
+	result, err := src.Load()
+	require.NoError(t, err)
+	require.NoError(t, src.Commit())
+	return ids(result)
+}
+
+func TestOffsetSource_Load(t *testing.T) {
+	t.Run("should only return lines appended since the committed offset", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.ndjson")
+		appendFile(t, path, "{\"id\": 1}\n{\"id\": 2}\n")
+		st := state.NewMemoryStore()
+		src := NewOffsetSource(path, ordersSchema, st)
+
+		assert.Equal(t, []int64{1, 2}, loadCommitted(t, src))
+		appendFile(t, path, "{\"id\": 3}\n")
+		assert.Equal(t, []int64{3}, loadCommitted(t, src))
+		assert.Equal(t, []int64{}, loadCommitted(t, src))
+
+		offset, _, _ := st.Get(path)
+		assert.Equal(t, "30", offset)
+	})
+
+	t.Run("should leave an incomplete line for the next run", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.ndjson")
+		appendFile(t, path, "{\"id\": 1}\n{\"id\"")
+		src := NewOffsetSource(path, ordersSchema, state.NewMemoryStore())
+
+		assert.Equal(t, []int64{1}, loadCommitted(t, src))
+		appendFile(t, path, ": 2}\n")
+		assert.Equal(t, []int64{2}, loadCommitted(t, src))
+	})
+
+	t.Run("should reload lines when not committed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.ndjson")
+		appendFile(t, path, "{\"id\": 1}\n")
+		src := NewOffsetSource(path, ordersSchema, state.NewMemoryStore())
+		_, err := src.Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, []int64{1}, loadCommitted(t, src))
+	})
+
+	t.Run("should read from the start when the file was truncated", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.ndjson")
+		appendFile(t, path, "{\"id\": 1}\n{\"id\": 2}\n")
+		src := NewOffsetSource(path, ordersSchema, state.NewMemoryStore())
+		loadCommitted(t, src)
+		require.NoError(t, os.WriteFile(path, []byte("{\"id\": 7}\n"), 0o644))
+
+		assert.Equal(t, []int64{7}, loadCommitted(t, src))
+	})
+
+	t.Run("should report the line of invalid records", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.ndjson")
+		appendFile(t, path, "{\"id\": 1}\n\n{\"id\": \"two\"}\n")
+
+		_, err := NewOffsetSource(path, ordersSchema, state.NewMemoryStore()).Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 2, recordErr.Index)
+		assert.Equal(t, path, recordErr.Source)
+	})
+
+	t.Run("should reject an invalid saved offset", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.ndjson")
+		appendFile(t, path, "{\"id\": 1}\n")
+		st := state.NewMemoryStore()
+		require.NoError(t, st.Set("orders", "-1"))
+		src := NewOffsetSource(path, ordersSchema, st)
+		src.Key = "orders"
+
+		_, err := src.Load()
+
+		assert.EqualError(t, err, `invalid offset "-1"`)
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		_, err := NewOffsetSource(filepath.Join(t.TempDir(), "missing.ndjson"), ordersSchema, state.NewMemoryStore()).Load()
+
+		assert.ErrorContains(t, err, "failed to read file")
+	})
+}
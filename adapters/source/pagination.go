@@ -0,0 +1,196 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pagination tells an HTTPSource which page to fetch after a page.
+type Pagination interface {
+	// Next returns the URL of the page following page, nil if it was the
+	// last one.
+	Next(page *Page) (*url.URL, error)
+}
+
+// firstPager is implemented by paginations setting up the first URL.
+type firstPager interface {
+	first(u *url.URL) *url.URL
+}
+
+// NextLink follows the URL found in the response body at Path, such as
+// "links.next". Relative URLs are resolved against the page URL. Pages stop
+// when the value is missing, null or empty.
+type NextLink struct {
+	Path string // Dot-separated path of the next page URL in the response
+}
+
+// Next returns the URL found at Path.
+func (p NextLink) Next(page *Page) (*url.URL, error) {
+	value, _ := lookupPath(page.Body, p.Path)
+	if value == nil || value == "" {
+		return nil, nil
+	}
+	link, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("next link %s: expected string, got %T", p.Path, value)
+	}
+	next, err := page.URL.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next link %q: %w", link, err)
+	}
+	return next, nil
+}
+
+// Cursor passes the cursor found in the response body at Path, such as
+// "meta.next_cursor", as the query parameter Param of the page URL. Pages
+// stop when the cursor is missing, null, false or empty.
+type Cursor struct {
+	Path  string // Dot-separated path of the next cursor in the response
+	Param string // Query parameter receiving the cursor
+}
+
+// Next returns the page URL with the cursor found at Path.
+func (p Cursor) Next(page *Page) (*url.URL, error) {
+	value, _ := lookupPath(page.Body, p.Path)
+	var cursor string
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		if v {
+			return nil, fmt.Errorf("cursor %s: expected string or number, got bool", p.Path)
+		}
+	case string:
+		cursor = v
+	case json.Number:
+		cursor = v.String()
+	case float64:
+		cursor = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return nil, fmt.Errorf("cursor %s: expected string or number, got %T", p.Path, value)
+	}
+	if cursor == "" {
+		return nil, nil
+	}
+	return withQuery(page.URL, p.Param, cursor), nil
+}
+
+// OffsetLimit pages with offset and limit query parameters, starting at the
+// offset of the URL, 0 if it has none. Pages stop at the first page holding
+// fewer than Limit records, or no record when Limit is zero.
+type OffsetLimit struct {
+	OffsetParam string // Query parameter of the offset, "offset" if empty
+	LimitParam  string // Query parameter of the limit, "limit" if empty
+	Limit       int    // Records per page, sent with every request unless zero
+}
+
+func (p OffsetLimit) first(u *url.URL) *url.URL {
+	if p.Limit > 0 {
+		u = withQuery(u, p.limitParam(), strconv.Itoa(p.Limit))
+	}
+	return u
+}
+
+// Next returns the page URL with the offset moved past its records.
+func (p OffsetLimit) Next(page *Page) (*url.URL, error) {
+	if page.Records == 0 || page.Records < p.Limit {
+		return nil, nil
+	}
+	offset := 0
+	if v := page.URL.Query().Get(p.offsetParam()); v != "" {
+		var err error
+		if offset, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	return withQuery(page.URL, p.offsetParam(), strconv.Itoa(offset+page.Records)), nil
+}
+
+func (p OffsetLimit) offsetParam() string {
+	if p.OffsetParam == "" {
+		return "offset"
+	}
+	return p.OffsetParam
+}
+
+func (p OffsetLimit) limitParam() string {
+	if p.LimitParam == "" {
+		return "limit"
+	}
+	return p.LimitParam
+}
+
+// LinkHeader follows the rel="next" link of the Link response header, as
+// sent by the GitHub API among others (RFC 8288).
+type LinkHeader struct{}
+
+// Next returns the URL of the next link, nil if there is none.
+func (LinkHeader) Next(page *Page) (*url.URL, error) {
+	for _, header := range page.Header.Values("Link") {
+		for _, link := range splitLinks(header) {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			if !isNextRel(params) {
+				continue
+			}
+			next, err := page.URL.Parse(target[1 : len(target)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid next link %q: %w", target, err)
+			}
+			return next, nil
+		}
+	}
+	return nil, nil
+}
+
+// splitLinks splits a Link header on the commas separating links, leaving
+// those inside <...> URLs.
+func splitLinks(header string) []string {
+	var links []string
+	depth, start := 0, 0
+	for i, c := range header {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				links = append(links, header[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(links, header[start:])
+}
+
+// isNextRel reports whether link parameters hold rel="next", rel being a
+// space-separated list.
+func isNextRel(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if strings.EqualFold(rel, "next") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withQuery returns a copy of u with the query parameter key set to value.
+func withQuery(u *url.URL, key, value string) *url.URL {
+	next := *u
+	query := next.Query()
+	query.Set(key, value)
+	next.RawQuery = query.Encode()
+	return &next
+}
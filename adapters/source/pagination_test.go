@@ -0,0 +1,76 @@
+package source
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkHeader_Next(t *testing.T) {
+	current, _ := url.Parse("https://api.example.com/users?page=2")
+
+	t.Run("should find the next link among others", func(t *testing.T) {
+		header := http.Header{"Link": {`<https://api.example.com/users?page=1&a=b,c>; rel="prev first", <https://api.example.com/users?page=3>; REL="next"`}}
+
+		next, err := LinkHeader{}.Next(&Page{URL: current, Header: header})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example.com/users?page=3", next.String())
+	})
+
+	t.Run("should stop without a next link", func(t *testing.T) {
+		header := http.Header{"Link": {`<https://api.example.com/users?page=1>; rel="prev"`}}
+
+		next, err := LinkHeader{}.Next(&Page{URL: current, Header: header})
+
+		require.NoError(t, err)
+		assert.Nil(t, next)
+	})
+}
+
+func TestNextLink_Next(t *testing.T) {
+	current, _ := url.Parse("https://api.example.com/users?page=2")
+
+	t.Run("should resolve relative links", func(t *testing.T) {
+		body := map[string]any{"next": "/users?page=3"}
+
+		next, err := NextLink{Path: "next"}.Next(&Page{URL: current, Body: body})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example.com/users?page=3", next.String())
+	})
+
+	t.Run("should reject links that are not strings", func(t *testing.T) {
+		body := map[string]any{"next": 3.0}
+
+		_, err := NextLink{Path: "next"}.Next(&Page{URL: current, Body: body})
+
+		assert.EqualError(t, err, "next link next: expected string, got float64")
+	})
+}
+
+func TestCursor_Next(t *testing.T) {
+	current, _ := url.Parse("https://api.example.com/users?cursor=10")
+
+	t.Run("should format numeric cursors exactly", func(t *testing.T) {
+		body := map[string]any{"meta": map[string]any{"next": json.Number("9007199254740993")}}
+
+		next, err := Cursor{Path: "meta.next", Param: "cursor"}.Next(&Page{URL: current, Body: body})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example.com/users?cursor=9007199254740993", next.String())
+	})
+
+	t.Run("should stop on a false cursor", func(t *testing.T) {
+		body := map[string]any{"meta": map[string]any{"next": false}}
+
+		next, err := Cursor{Path: "meta.next", Param: "cursor"}.Next(&Page{URL: current, Body: body})
+
+		require.NoError(t, err)
+		assert.Nil(t, next)
+	})
+}
@@ -0,0 +1,168 @@
+package source
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// WatermarkSource loads records incrementally from an inner source: it
+// only returns the records whose int or date Column is greater than the
+// high-water mark saved by the previous run.
+//
+// Records appended after a run with a value equal to the watermark, such as
+// a timestamp shared by several events, are skipped. IncludeTies returns
+// the records equal to the watermark again so that they are not lost, and
+// the store must then dedupe them, for instance by upserting on a key
+// column.
+//
+// Load computes the new watermark, the maximum of Column over the loaded
+// records, and Commit saves it. DataPipeline commits after the records were
+// stored, so records of a failed run are loaded again by the next one.
+// Records with a null or missing Column are only returned while there is no
+// watermark yet.
+type WatermarkSource struct {
+	Source ports.SourcePort     // Inner source, loaded in full on every run
+	Column string               // Int or date column the watermark tracks
+	State  ports.StateStorePort // Where the watermark is saved between runs
+	Key    string               // State key of the watermark, Column if empty
+	Logger *slog.Logger         // Receives the watermarks and the records filtered out, nil disables logging
+
+	IncludeTies bool // Also return the records equal to the watermark, for stores that dedupe
+
+	pending *string // Watermark to save on Commit
+}
+
+// NewWatermarkSource creates a WatermarkSource over src tracking column.
+func NewWatermarkSource(src ports.SourcePort, column string, state ports.StateStorePort) *WatermarkSource {
+	return &WatermarkSource{Source: src, Column: column, State: state}
+}
+
+// Load returns the records after the saved watermark, or at it with
+// IncludeTies.
+func (s *WatermarkSource) Load() (*domain.RecordSet, error) {
+	s.pending = nil
+
+	data, err := s.Source.Load()
+	if err != nil {
+		return nil, err
+	}
+	var col domain.SchemaColumn
+	if data.Schema != nil {
+		col = data.Schema.Column(s.Column)
+	}
+	if col == nil {
+		return nil, fmt.Errorf("column %s: %w", s.Column, domain.ErrUnknownColumn)
+	}
+	typ := col.GetType()
+	if col.IsArray() || (typ != domain.NativeTypeInt && typ != domain.NativeTypeDate) {
+		return nil, fmt.Errorf("column %s: watermark requires an int or date column", s.Column)
+	}
+
+	saved, ok, err := s.State.Get(s.key())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermark: %w", err)
+	}
+	var mark domain.Value
+	if ok {
+		if mark, err = parseWatermark(saved, typ.(domain.NativeType)); err != nil {
+			return nil, fmt.Errorf("invalid watermark %q: %w", saved, err)
+		}
+	}
+
+	result := domain.NewRecordSet(data.Schema)
+	highest := mark
+	for i, record := range data.Records {
+		value := record.Get(s.Column)
+		if value == nil || value.IsNull() {
+			if mark == nil {
+				result.Add(record)
+			}
+			continue
+		}
+		if !holds(typ, value) {
+			return nil, domain.NewRecordError(i, record, fmt.Errorf("column %s: expected %s, got %T", s.Column, typ.GetTypeName(), value))
+		}
+		if mark != nil && (after(mark, value) || (!s.IncludeTies && !after(value, mark))) {
+			continue
+		}
+		result.Add(record)
+		if highest == nil || after(value, highest) {
+			highest = value
+		}
+	}
+
+	if highest != nil && highest != mark {
+		formatted := formatWatermark(highest)
+		s.pending = &formatted
+	}
+	logging.Or(s.Logger).Debug("loaded records", "column", s.Column, "from", saved, "to", formatWatermark(highest),
+		"records", result.Count(), "filtered", data.Count()-result.Count())
+	return result, nil
+}
+
+// Commit saves the watermark computed by the last Load, if it moved.
+func (s *WatermarkSource) Commit() error {
+	if s.pending == nil {
+		return nil
+	}
+	if err := s.State.Set(s.key(), *s.pending); err != nil {
+		return fmt.Errorf("failed to save watermark: %w", err)
+	}
+	logging.Or(s.Logger).Info("saved watermark", "key", s.key(), "watermark", *s.pending)
+	s.pending = nil
+	return nil
+}
+
+func (s *WatermarkSource) key() string {
+	if s.Key != "" {
+		return s.Key
+	}
+	return s.Column
+}
+
+// holds reports whether v is a value of typ, an int or date type.
+func holds(typ domain.SchemaType, v domain.Value) bool {
+	switch v.(type) {
+	case domain.IntValue:
+		return typ == domain.NativeTypeInt
+	case domain.DateValue:
+		return typ == domain.NativeTypeDate
+	}
+	return false
+}
+
+// after reports whether a is greater than b, both ints or both dates.
+func after(a, b domain.Value) bool {
+	switch a := a.(type) {
+	case domain.IntValue:
+		return a > b.(domain.IntValue)
+	case domain.DateValue:
+		return time.Time(a).After(time.Time(b.(domain.DateValue)))
+	}
+	return false
+}
+
+func formatWatermark(v domain.Value) string {
+	switch v := v.(type) {
+	case domain.IntValue:
+		return strconv.FormatInt(int64(v), 10)
+	case domain.DateValue:
+		return time.Time(v).Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+func parseWatermark(s string, typ domain.NativeType) (domain.Value, error) {
+	if typ == domain.NativeTypeInt {
+		i, err := strconv.ParseInt(s, 10, 64)
+		return domain.IntValue(i), err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return domain.DateValue(t), err
+}
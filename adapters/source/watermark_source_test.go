@@ -0,0 +1,216 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/state"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ordersSchema = &domain.DataSchema{
+	ID: "Order",
+	Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+		domain.SchemaColumnSingle{ID: "updated_at", SchemaType: domain.NativeTypeDate},
+		domain.SchemaColumnSingle{ID: "status", SchemaType: domain.NativeTypeString},
+	},
+}
+
+// ordersSource returns the orders with the given ids, updated on consecutive
+// days from January 1st.
+type ordersSource []int64
+
+func (s ordersSource) Load() (*domain.RecordSet, error) {
+	rs := domain.NewRecordSet(ordersSchema)
+	for _, id := range s {
+		record := domain.NewRecord(ordersSchema)
+		record.Set("id", domain.IntValue(id))
+		record.Set("updated_at", domain.DateValue(time.Date(2024, 1, int(id), 0, 0, 0, 0, time.UTC)))
+		rs.Add(record)
+	}
+	return rs, nil
+}
+
+func ids(rs *domain.RecordSet) []int64 {
+	result := []int64{}
+	for _, r := range rs.Records {
+		result = append(result, r.GetInt("id"))
+	}
+	return result
+}
+
+// failingState fails every call with err.
+type failingState struct{ err error }
+
+func (s failingState) Get(string) (string, bool, error) { return "", false, s.err }
+func (s failingState) Set(string, string) error         { return s.err }
+
+func TestWatermarkSource_Load(t *testing.T) {
+	t.Run("should only return records after the committed watermark", func(t *testing.T) {
+		st := state.NewMemoryStore()
+		first := NewWatermarkSource(ordersSource{1, 3, 2}, "id", st)
+		result, err := first.Load()
+		require.NoError(t, err)
+		require.NoError(t, first.Commit())
+		assert.Equal(t, []int64{1, 3, 2}, ids(result))
+
+		second := NewWatermarkSource(ordersSource{1, 2, 3, 4, 5}, "id", st)
+		result, err = second.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{4, 5}, ids(result))
+		mark, _, _ := st.Get("id")
+		assert.Equal(t, "3", mark)
+	})
+
+	t.Run("should track date columns", func(t *testing.T) {
+		st := state.NewMemoryStore()
+		src := NewWatermarkSource(ordersSource{1, 2}, "updated_at", st)
+		_, err := src.Load()
+		require.NoError(t, err)
+		require.NoError(t, src.Commit())
+
+		src.Source = ordersSource{2, 3}
+		result, err := src.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(result))
+		mark, _, _ := st.Get("updated_at")
+		assert.Equal(t, "2024-01-02T00:00:00Z", mark)
+	})
+
+	t.Run("should not move the watermark before Commit", func(t *testing.T) {
+		st := state.NewMemoryStore()
+		src := NewWatermarkSource(ordersSource{1, 2}, "id", st)
+		_, err := src.Load()
+		require.NoError(t, err)
+
+		result, err := src.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, ids(result))
+		_, ok, _ := st.Get("id")
+		assert.False(t, ok)
+	})
+
+	t.Run("should return records tied with the watermark appended after a run with IncludeTies", func(t *testing.T) {
+		st := state.NewMemoryStore()
+		src := NewWatermarkSource(ordersSource{1, 2}, "updated_at", st)
+		src.IncludeTies = true
+		_, err := src.Load()
+		require.NoError(t, err)
+		require.NoError(t, src.Commit())
+
+		late, _ := ordersSource{1, 2}.Load()
+		tied := domain.NewRecord(ordersSchema)
+		tied.Set("id", domain.IntValue(20))
+		tied.Set("updated_at", domain.DateValue(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+		late.Add(tied)
+		src.Source = staticSource{late}
+		result, err := src.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2, 20}, ids(result))
+	})
+
+	t.Run("should log the watermarks and the records filtered out", func(t *testing.T) {
+		var buf bytes.Buffer
+		st := state.NewMemoryStore()
+		require.NoError(t, st.Set("id", "2"))
+		src := NewWatermarkSource(ordersSource{1, 2, 3}, "id", st)
+		src.Logger = logger.NewTextLogger(&buf)
+
+		_, err := src.Load()
+		require.NoError(t, err)
+		require.NoError(t, src.Commit())
+
+		assert.Contains(t, buf.String(), `msg="loaded records" column=id from=2 to=3 records=1 filtered=2`)
+		assert.Contains(t, buf.String(), `msg="saved watermark" key=id watermark=3`)
+	})
+
+	t.Run("should keep the watermark when no record is newer", func(t *testing.T) {
+		st := state.NewMemoryStore()
+		require.NoError(t, st.Set("orders", "5"))
+		src := NewWatermarkSource(ordersSource{4, 5}, "id", st)
+		src.Key = "orders"
+
+		result, err := src.Load()
+		require.NoError(t, err)
+		require.NoError(t, src.Commit())
+
+		assert.Equal(t, 0, result.Count())
+		mark, _, _ := st.Get("orders")
+		assert.Equal(t, "5", mark)
+	})
+
+	t.Run("should return records without value until a watermark exists", func(t *testing.T) {
+		rs, _ := ordersSource{1}.Load()
+		rs.Add(domain.NewRecord(ordersSchema))
+		st := state.NewMemoryStore()
+		src := NewWatermarkSource(staticSource{rs}, "id", st)
+
+		first, err := src.Load()
+		require.NoError(t, err)
+		require.NoError(t, src.Commit())
+		second, err := src.Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, first.Count())
+		assert.Equal(t, 0, second.Count())
+	})
+
+	t.Run("should reject columns that are not int or date", func(t *testing.T) {
+		_, err := NewWatermarkSource(ordersSource{1}, "status", state.NewMemoryStore()).Load()
+
+		assert.EqualError(t, err, "column status: watermark requires an int or date column")
+	})
+
+	t.Run("should reject unknown columns", func(t *testing.T) {
+		_, err := NewWatermarkSource(ordersSource{1}, "missing", state.NewMemoryStore()).Load()
+
+		assert.ErrorIs(t, err, domain.ErrUnknownColumn)
+	})
+
+	t.Run("should reject an invalid saved watermark", func(t *testing.T) {
+		st := state.NewMemoryStore()
+		require.NoError(t, st.Set("id", "yesterday"))
+
+		_, err := NewWatermarkSource(ordersSource{1}, "id", st).Load()
+
+		assert.ErrorContains(t, err, `invalid watermark "yesterday"`)
+	})
+
+	t.Run("should return record errors for values not matching the column", func(t *testing.T) {
+		rs, _ := ordersSource{1}.Load()
+		invalid := domain.NewRecord(ordersSchema)
+		invalid.Source = "orders.json"
+		invalid.Set("id", domain.StringValue("7"))
+		rs.Add(invalid)
+		st := state.NewMemoryStore()
+		require.NoError(t, st.Set("id", "1"))
+
+		_, err := NewWatermarkSource(staticSource{rs}, "id", st).Load()
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.EqualError(t, err, "record 1 (orders.json): column id: expected int, got domain.StringValue")
+	})
+
+	t.Run("should return state errors", func(t *testing.T) {
+		_, err := NewWatermarkSource(ordersSource{1}, "id", failingState{errors.New("locked")}).Load()
+
+		assert.EqualError(t, err, "failed to read watermark: locked")
+	})
+}
+
+// staticSource returns the same RecordSet on every Load.
+type staticSource struct{ rs *domain.RecordSet }
+
+func (s staticSource) Load() (*domain.RecordSet, error) { return s.rs, nil }
@@ -0,0 +1,122 @@
+// Package state provides implementations of ports.StateStorePort.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore keeps values in memory, for tests and single-process
+// schedulers. It is safe for concurrent use.
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: map[string]string{}}
+}
+
+// Get returns the value saved under key.
+func (s *MemoryStore) Get(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+// Set saves value under key.
+func (s *MemoryStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+// FileStore keeps values in a JSON object file, rewritten atomically on each
+// Set. It is safe for concurrent use within a process.
+type FileStore struct {
+	FilePath string
+
+	mu sync.Mutex
+}
+
+// NewFileStore creates a FileStore saving to filePath. The file and its
+// directory are created on the first Set.
+func NewFileStore(filePath string) *FileStore {
+	return &FileStore{FilePath: filePath}
+}
+
+// Get returns the value saved under key.
+func (s *FileStore) Get(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, err := s.read()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := values[key]
+	return value, ok, nil
+}
+
+// Set saves value under key.
+func (s *FileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, err := s.read()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return s.write(values)
+}
+
+func (s *FileStore) read() (map[string]string, error) {
+	values := map[string]string{}
+	data, err := os.ReadFile(s.FilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", s.FilePath, err)
+	}
+	return values, nil
+}
+
+// write replaces the file through a temporary file so that readers never
+// see a partial state.
+func (s *FileStore) write(values map[string]string) error {
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.FilePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.FilePath); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) ports.StateStorePort{
+		"MemoryStore": func(t *testing.T) ports.StateStorePort { return NewMemoryStore() },
+		"FileStore": func(t *testing.T) ports.StateStorePort {
+			return NewFileStore(filepath.Join(t.TempDir(), "state", "watermarks.json"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name+" should report missing keys", func(t *testing.T) {
+			_, ok, err := newStore(t).Get("orders")
+
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+
+		t.Run(name+" should replace saved values", func(t *testing.T) {
+			store := newStore(t)
+			require.NoError(t, store.Set("orders", "1"))
+			require.NoError(t, store.Set("orders", "2"))
+			require.NoError(t, store.Set("customers", "3"))
+
+			value, ok, err := store.Get("orders")
+
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, "2", value)
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	t.Run("should keep values across instances", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, NewFileStore(path).Set("orders", "42"))
+
+		value, ok, err := NewFileStore(path).Get("orders")
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "42", value)
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("should return error for a corrupt file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+
+		_, _, err := NewFileStore(path).Get("orders")
+
+		assert.ErrorContains(t, err, "failed to parse state")
+	})
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+)
+
+// CSVStore writes a RecordSet as CSV with a header row, to a file or a
+// stream. Columns follow the schema of the RecordSet, or the sorted column
+// IDs of its records when it has none. Null values are empty cells, dates
+// are RFC 3339, and arrays and nested records are written as JSON.
+type CSVStore struct {
+	FilePath    string
+	Comma       rune                    // Field delimiter, ',' if zero
+	Compression compression.Compression // Output compression, Auto picks it from the extension
+	Writer      io.Writer               // Output stream, takes precedence over FilePath when set
+	Logger      *slog.Logger            // Receives a debug event per Store, nil disables logging
+}
+
+// NewCSVStore creates a CSVStore writing to filePath, compressed when the
+// file extension names a codec.
+func NewCSVStore(filePath string) *CSVStore {
+	return &CSVStore{FilePath: filePath, Compression: compression.Auto}
+}
+
+// NewCSVStoreToWriter creates a CSVStore writing to w. The writer is not
+// closed by Store.
+func NewCSVStoreToWriter(w io.Writer) *CSVStore {
+	return &CSVStore{Writer: w}
+}
+
+// Store writes the header and one row per record.
+func (s *CSVStore) Store(data *domain.RecordSet) error {
+	if data == nil {
+		return fmt.Errorf("cannot store nil RecordSet")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if s.Comma != 0 {
+		w.Comma = s.Comma
+	}
+
+	columns := csvColumns(data)
+	if err := w.Write(columns); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	mapper := &JSONStore{}
+	row := make([]string, len(columns))
+	for i, record := range data.Records {
+		for j, col := range columns {
+			cell, err := csvCell(mapper, record.Get(col))
+			if err != nil {
+				return fmt.Errorf("failed to map record: %w", domain.NewRecordError(i, record, fmt.Errorf("column %s: %w", col, err)))
+			}
+			row[j] = cell
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	if err := writeOutput(s.FilePath, s.Writer, s.Compression, buf.Bytes(), s.Logger); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	logging.Or(s.Logger).Debug("stored records", "path", s.FilePath, "records", data.Count(), "bytes", buf.Len())
+	return nil
+}
+
+func csvColumns(data *domain.RecordSet) []string {
+	if data.Schema != nil {
+		return data.Schema.ColumnIDs()
+	}
+	seen := map[string]bool{}
+	var columns []string
+	for _, record := range data.Records {
+		for id := range record.Values {
+			if !seen[id] {
+				seen[id] = true
+				columns = append(columns, id)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// csvCell formats a value, mapper formatting it like JSONStore first.
+func csvCell(mapper *JSONStore, value domain.Value) (string, error) {
+	mapped, err := mapper.mapValue(value)
+	if err != nil {
+		return "", err
+	}
+	switch v := mapped.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	encoded, err := json.Marshal(mapped)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCSVStore(t *testing.T) {
+	t.Run("should create store with file path and automatic compression", func(t *testing.T) {
+		store := NewCSVStore("/path/to/file.csv")
+
+		assert.Equal(t, "/path/to/file.csv", store.FilePath)
+		assert.Equal(t, compression.Auto, store.Compression)
+	})
+}
+
+func TestCSVStore_Store(t *testing.T) {
+	t.Run("should write a header and one row per record in schema order", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+				domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeInt},
+				domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+				domain.SchemaColumnSingle{ID: "added", SchemaType: domain.NativeTypeDate},
+				domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+			},
+		}
+		recordSet := domain.NewRecordSet(schema)
+		record := domain.NewRecord(schema)
+		record.Set("name", domain.StringValue("Laptop, 15\""))
+		record.Set("price", domain.FloatValue(999.99))
+		record.Set("quantity", domain.IntValue(5))
+		record.Set("active", domain.BoolValue(true))
+		record.Set("added", domain.DateValue(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)))
+		record.Set("tags", domain.ArrayValue{ElementType: domain.NativeTypeString, Elements: []domain.Value{domain.StringValue("tech")}})
+		recordSet.Add(record)
+		empty := domain.NewRecord(schema)
+		empty.Set("name", domain.StringValue("Phone"))
+		empty.Set("price", domain.NullValue{Type: domain.NativeTypeFloat})
+		recordSet.Add(empty)
+		filePath := tempFilePath(t)
+
+		err := NewCSVStore(filePath).Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, "name,price,quantity,active,added,tags\n"+
+			"\"Laptop, 15\"\"\",999.99,5,true,2024-03-01T10:00:00Z,\"[\"\"tech\"\"]\"\n"+
+			"Phone,,,,,\n", string(readFile(t, filePath)))
+	})
+
+	t.Run("should use sorted record columns without a schema", func(t *testing.T) {
+		recordSet := domain.NewRecordSet(nil)
+		record := domain.NewRecord(nil)
+		record.Set("b", domain.IntValue(2))
+		record.Set("a", domain.IntValue(1))
+		recordSet.Add(record)
+		var buf bytes.Buffer
+		store := NewCSVStoreToWriter(&buf)
+		store.Comma = ';'
+
+		err := store.Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, "a;b\n1;2\n", buf.String())
+	})
+
+	t.Run("should reject nil RecordSet", func(t *testing.T) {
+		err := NewCSVStoreToWriter(&bytes.Buffer{}).Store(nil)
+
+		assert.EqualError(t, err, "cannot store nil RecordSet")
+	})
+}
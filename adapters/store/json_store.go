@@ -3,27 +3,44 @@ package store
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"log/slog"
 	"time"
 
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
 	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
 )
 
-// JSONStore writes a RecordSet to a JSON file.
+// JSONStore writes a RecordSet to a JSON file or a stream.
 type JSONStore struct {
-	FilePath string
-	Indent   bool
+	FilePath    string
+	Indent      bool
+	Compression compression.Compression // Output compression, Auto picks it from the extension
+	Writer      io.Writer               // Output stream, takes precedence over FilePath when set
+	Logger      *slog.Logger            // Receives a debug event per Store, nil disables logging
 }
 
 // NewJSONStore creates a new JSONStore.
+// The output is compressed when the file extension names a codec (e.g. ".json.gz").
 func NewJSONStore(filePath string) *JSONStore {
 	return &JSONStore{
-		FilePath: filePath,
-		Indent:   true,
+		FilePath:    filePath,
+		Indent:      true,
+		Compression: compression.Auto,
 	}
 }
 
-// Store writes the RecordSet to the JSON file.
+// NewJSONStoreToWriter creates a new JSONStore writing to w, such as
+// os.Stdout or an HTTP response. The writer is not closed by Store.
+func NewJSONStoreToWriter(w io.Writer) *JSONStore {
+	return &JSONStore{
+		Indent: true,
+		Writer: w,
+	}
+}
+
+// Store writes the RecordSet to the JSON output.
 func (s *JSONStore) Store(data *domain.RecordSet) error {
 	if data == nil {
 		return fmt.Errorf("cannot store nil RecordSet")
@@ -52,13 +69,40 @@ func (s *JSONStore) Store(data *domain.RecordSet) error {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	if err := os.WriteFile(s.FilePath, jsonBytes, 0644); err != nil {
+	if err := s.write(jsonBytes); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
+	logging.Or(s.Logger).Debug("stored records", "path", s.FilePath, "records", data.Count(), "bytes", len(jsonBytes))
 	return nil
 }
 
+func (s *JSONStore) write(data []byte) error {
+	return writeOutput(s.FilePath, s.Writer, s.Compression, data, s.Logger)
+}
+
+// writeOutput writes data to w when set, or to the file at filePath,
+// compressed with c. It warns when Auto writes plain bytes because the
+// codec of the extension cannot write.
+func writeOutput(filePath string, out io.Writer, c compression.Compression, data []byte, logger *slog.Logger) error {
+	if _, skipped := compression.Resolve(filePath, c); skipped != compression.None {
+		logging.Or(logger).Warn("writing uncompressed output, compression cannot write", "path", filePath, "compression", skipped)
+	}
+	if out == nil {
+		return compression.WriteFile(filePath, data, c)
+	}
+
+	w, err := compression.NewWriter(out, filePath, c)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 func (s *JSONStore) mapRecord(record *domain.Record) (map[string]any, error) {
 	result := make(map[string]any)
 
@@ -91,6 +135,9 @@ func (s *JSONStore) mapValue(value domain.Value) (any, error) {
 	case domain.DateValue:
 		return time.Time(v).Format(time.RFC3339), nil
 
+	case domain.BoolValue:
+		return bool(v), nil
+
 	case domain.ArrayValue:
 		return s.mapArrayValue(v)
 
@@ -1,13 +1,17 @@
 package store
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
 	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,6 +23,7 @@ func TestNewJSONStore(t *testing.T) {
 
 		assert.Equal(t, "/path/to/file.json", store.FilePath)
 		assert.True(t, store.Indent)
+		assert.Equal(t, compression.Auto, store.Compression)
 	})
 }
 
@@ -119,6 +124,29 @@ func TestJSONStore_Store(t *testing.T) {
 		assert.Equal(t, "2024-01-15T10:30:00Z", result[0]["date"])
 	})
 
+	t.Run("should store bools", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			},
+		}
+
+		recordSet := domain.NewRecordSet(schema)
+		record := domain.NewRecord(schema)
+		record.Set("active", domain.BoolValue(true))
+		recordSet.Add(record)
+
+		filePath := tempFilePath(t)
+		store := NewJSONStore(filePath)
+		store.Indent = false
+
+		err := store.Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, `[{"active":true}]`, string(readFile(t, filePath)))
+	})
+
 	t.Run("should store arrays", func(t *testing.T) {
 		schema := &domain.DataSchema{
 			ID: "Article",
@@ -452,6 +480,107 @@ func TestJSONStore_Store_Arrays(t *testing.T) {
 	})
 }
 
+func TestJSONStore_Store_Compressed(t *testing.T) {
+	t.Run("should compress output based on extension", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			},
+		}
+
+		recordSet := domain.NewRecordSet(schema)
+		record := domain.NewRecord(schema)
+		record.Set("name", domain.StringValue("Laptop"))
+		recordSet.Add(record)
+
+		filePath := filepath.Join(t.TempDir(), "output.json.gz")
+		store := NewJSONStore(filePath)
+		store.Indent = false
+
+		err := store.Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x1f, 0x8b}, readFile(t, filePath)[:2])
+
+		content, err := compression.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"name":"Laptop"}]`, string(content))
+	})
+
+	t.Run("should write plain output when compression is disabled", func(t *testing.T) {
+		schema := &domain.DataSchema{ID: "Product"}
+		recordSet := domain.NewRecordSet(schema)
+
+		filePath := filepath.Join(t.TempDir(), "output.json.gz")
+		store := NewJSONStore(filePath)
+		store.Compression = compression.None
+
+		err := store.Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(readFile(t, filePath)))
+	})
+
+	t.Run("should write plain output with a warning when the codec cannot write", func(t *testing.T) {
+		var buf bytes.Buffer
+		filePath := filepath.Join(t.TempDir(), "output.json.zst")
+		store := NewJSONStore(filePath)
+		store.Logger = logger.NewTextLogger(&buf)
+
+		err := store.Store(domain.NewRecordSet(nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(readFile(t, filePath)))
+		assert.Contains(t, buf.String(), `level=WARN msg="writing uncompressed output, compression cannot write" path=`)
+		assert.Contains(t, buf.String(), "compression=zstd")
+
+		content, err := compression.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(content))
+	})
+}
+
+func TestJSONStore_Store_Writer(t *testing.T) {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+		},
+	}
+
+	t.Run("should write to writer", func(t *testing.T) {
+		recordSet := domain.NewRecordSet(schema)
+		record := domain.NewRecord(schema)
+		record.Set("name", domain.StringValue("Laptop"))
+		recordSet.Add(record)
+
+		var buf bytes.Buffer
+		store := NewJSONStoreToWriter(&buf)
+		store.Indent = false
+
+		err := store.Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, `[{"name":"Laptop"}]`, buf.String())
+	})
+
+	t.Run("should compress to writer", func(t *testing.T) {
+		var buf bytes.Buffer
+		store := NewJSONStoreToWriter(&buf)
+		store.Compression = compression.Gzip
+
+		err := store.Store(domain.NewRecordSet(schema))
+
+		require.NoError(t, err)
+		r, err := compression.NewReader(&buf, "")
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(content))
+	})
+}
+
 func tempFilePath(t *testing.T) string {
 	t.Helper()
 	return filepath.Join(t.TempDir(), "output.json")
@@ -463,3 +592,17 @@ func readFile(t *testing.T, path string) []byte {
 	require.NoError(t, err)
 	return content
 }
+
+func TestJSONStore_Store_Logger(t *testing.T) {
+	t.Run("should log stored records", func(t *testing.T) {
+		var buf, out bytes.Buffer
+		store := NewJSONStoreToWriter(&out)
+		store.Indent = false
+		store.Logger = logger.NewTextLogger(&buf)
+
+		err := store.Store(domain.NewRecordSet(nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, `level=DEBUG msg="stored records" path="" records=0 bytes=2`+"\n", buf.String())
+	})
+}
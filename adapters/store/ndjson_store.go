@@ -0,0 +1,63 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+)
+
+// NDJSONStore writes a RecordSet as newline-delimited JSON, one object per
+// line, to a file or a stream. Values are written like JSONStore does.
+type NDJSONStore struct {
+	FilePath    string
+	Compression compression.Compression // Output compression, Auto picks it from the extension
+	Writer      io.Writer               // Output stream, takes precedence over FilePath when set
+	Logger      *slog.Logger            // Receives a debug event per Store, nil disables logging
+}
+
+// NewNDJSONStore creates an NDJSONStore writing to filePath, compressed
+// when the file extension names a codec.
+func NewNDJSONStore(filePath string) *NDJSONStore {
+	return &NDJSONStore{FilePath: filePath, Compression: compression.Auto}
+}
+
+// NewNDJSONStoreToWriter creates an NDJSONStore writing to w. The writer is
+// not closed by Store.
+func NewNDJSONStoreToWriter(w io.Writer) *NDJSONStore {
+	return &NDJSONStore{Writer: w}
+}
+
+// Store writes one line per record.
+func (s *NDJSONStore) Store(data *domain.RecordSet) error {
+	if data == nil {
+		return fmt.Errorf("cannot store nil RecordSet")
+	}
+
+	mapper := &JSONStore{}
+	var buf bytes.Buffer
+	for i, record := range data.Records {
+		mapped, err := mapper.mapRecord(record)
+		if err != nil {
+			return fmt.Errorf("failed to map record: %w", domain.NewRecordError(i, record, err))
+		}
+		line, err := json.Marshal(mapped)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := writeOutput(s.FilePath, s.Writer, s.Compression, buf.Bytes(), s.Logger); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	logging.Or(s.Logger).Debug("stored records", "path", s.FilePath, "records", data.Count(), "bytes", buf.Len())
+	return nil
+}
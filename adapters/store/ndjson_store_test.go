@@ -0,0 +1,62 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNDJSONStore(t *testing.T) {
+	t.Run("should create store with file path and automatic compression", func(t *testing.T) {
+		store := NewNDJSONStore("/path/to/file.ndjson")
+
+		assert.Equal(t, "/path/to/file.ndjson", store.FilePath)
+		assert.Equal(t, compression.Auto, store.Compression)
+	})
+}
+
+func TestNDJSONStore_Store(t *testing.T) {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeInt},
+		},
+	}
+
+	t.Run("should write one object per line", func(t *testing.T) {
+		recordSet := domain.NewRecordSet(schema)
+		for _, name := range []string{"Laptop", "Phone"} {
+			record := domain.NewRecord(schema)
+			record.Set("name", domain.StringValue(name))
+			record.Set("quantity", domain.IntValue(5))
+			recordSet.Add(record)
+		}
+		filePath := tempFilePath(t)
+
+		err := NewNDJSONStore(filePath).Store(recordSet)
+
+		require.NoError(t, err)
+		assert.Equal(t, "{\"name\":\"Laptop\",\"quantity\":5}\n{\"name\":\"Phone\",\"quantity\":5}\n", string(readFile(t, filePath)))
+	})
+
+	t.Run("should write nothing for an empty set", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := NewNDJSONStoreToWriter(&buf).Store(domain.NewRecordSet(schema))
+
+		require.NoError(t, err)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("should reject nil RecordSet", func(t *testing.T) {
+		err := NewNDJSONStoreToWriter(&bytes.Buffer{}).Store(nil)
+
+		assert.EqualError(t, err, "cannot store nil RecordSet")
+	})
+}
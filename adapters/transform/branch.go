@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Predicate selects records for a branch.
+type Predicate func(*domain.Record) bool
+
+// Case routes the records matching Predicate to Then. A nil Predicate
+// matches every record, and a nil Then passes records through unchanged.
+type Case struct {
+	Predicate Predicate           // Records the case applies to, nil for all
+	Then      ports.TransformPort // Transform applied to them, nil for none
+}
+
+// Default returns a Case matching every record, to end a Switch with.
+func Default(then ports.TransformPort) Case {
+	return Case{Then: then}
+}
+
+// SwitchTransform routes each record to the first Case whose predicate
+// matches, runs every case's transform on its records and merges the
+// results. Records matching no case pass through unchanged.
+//
+// When every branch returns as many records as it received, records keep
+// their input order. Otherwise the results are concatenated in case order,
+// followed by the unmatched records.
+//
+// Results are merged into a schema combining the input schema with the
+// schemas returned by the branches, which fails if they disagree on the
+// type of a column. A branch that matches no record is not run.
+type SwitchTransform struct {
+	Cases []Case
+}
+
+// NewSwitchTransform creates a SwitchTransform from cases, tried in order.
+func NewSwitchTransform(cases ...Case) *SwitchTransform {
+	return &SwitchTransform{Cases: cases}
+}
+
+// Transform routes, transforms and merges the input records.
+func (s *SwitchTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	// One group per case, plus one for unmatched records.
+	groups := make([][]int, len(s.Cases)+1)
+	for i, record := range input.Records {
+		branch := len(s.Cases)
+		for c, cs := range s.Cases {
+			if cs.Predicate == nil || cs.Predicate(record) {
+				branch = c
+				break
+			}
+		}
+		groups[branch] = append(groups[branch], i)
+	}
+
+	outputs := make([]*domain.RecordSet, len(groups))
+	schema := input.Schema
+	keepsOrder := true
+	for branch, indexes := range groups {
+		subset := domain.NewRecordSet(input.Schema)
+		for _, i := range indexes {
+			subset.Add(input.Records[i])
+		}
+		outputs[branch] = subset
+
+		if branch == len(s.Cases) || len(indexes) == 0 || s.Cases[branch].Then == nil {
+			continue
+		}
+
+		result, err := s.Cases[branch].Then.Transform(subset)
+		if err != nil {
+			return nil, fmt.Errorf("case %d: %w", branch, err)
+		}
+		if result == nil {
+			result = domain.NewRecordSet(input.Schema)
+		}
+		outputs[branch] = result
+		keepsOrder = keepsOrder && result.Count() == len(indexes)
+
+		if result.Schema != nil && result.Schema != schema {
+			if schema, err = schema.Merge(result.Schema); err != nil {
+				return nil, fmt.Errorf("case %d: %w", branch, err)
+			}
+		}
+	}
+
+	merged := domain.NewRecordSet(schema)
+	if keepsOrder {
+		records := make([]*domain.Record, input.Count())
+		for branch, indexes := range groups {
+			for j, i := range indexes {
+				records[i] = outputs[branch].Records[j]
+			}
+		}
+		merged.Records = records
+		return merged, nil
+	}
+
+	for _, output := range outputs {
+		merged.Records = append(merged.Records, output.Records...)
+	}
+	return merged, nil
+}
+
+// NewWhenTransform creates a transform applying then only to the records
+// matching predicate. The others pass through unchanged. It is a
+// SwitchTransform with a single case and follows the same merge rules.
+func NewWhenTransform(predicate Predicate, then ports.TransformPort) *SwitchTransform {
+	return NewSwitchTransform(Case{Predicate: predicate, Then: then})
+}
+
+// TryTransform runs Primary and, if it fails, runs Fallback on the original
+// input instead. A nil Fallback passes the input through.
+type TryTransform struct {
+	Primary  ports.TransformPort
+	Fallback ports.TransformPort
+}
+
+// NewTryTransform creates a TryTransform.
+func NewTryTransform(primary, fallback ports.TransformPort) *TryTransform {
+	return &TryTransform{Primary: primary, Fallback: fallback}
+}
+
+// Transform runs Primary, falling back on error.
+func (t *TryTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	result, err := t.Primary.Transform(input)
+	if err == nil {
+		return result, nil
+	}
+	if t.Fallback == nil {
+		return input, nil
+	}
+
+	result, fallbackErr := t.Fallback.Transform(input)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%w; fallback failed: %w", err, fallbackErr)
+	}
+	return result, nil
+}
@@ -0,0 +1,186 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	mocksource "github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	mocktransform "github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func below(n int64) Predicate {
+	return func(r *domain.Record) bool { return r.GetInt("quantity") < n }
+}
+
+// transformFunc adapts a function to ports.TransformPort.
+type transformFunc func(*domain.RecordSet) (*domain.RecordSet, error)
+
+func (f transformFunc) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	return f(input)
+}
+
+func TestSwitchTransform_Transform(t *testing.T) {
+	t.Run("should apply when transform to matching records only", func(t *testing.T) {
+		when := NewWhenTransform(below(10), mocktransform.NewAddIntTransform("quantity", 100))
+
+		result, err := when.Transform(mocksource.NewStock(5, 20, 7))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{105, 20, 107}, mocksource.Quantities(result))
+	})
+
+	t.Run("should route records to the first matching case", func(t *testing.T) {
+		s := NewSwitchTransform(
+			Case{Predicate: below(10), Then: mocktransform.NewAddIntTransform("quantity", 100)},
+			Case{Predicate: below(30), Then: mocktransform.NewAddIntTransform("quantity", 1000)},
+			Default(mocktransform.NewAddIntTransform("quantity", -1)),
+		)
+
+		result, err := s.Transform(mocksource.NewStock(5, 20, 50, 1))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{105, 1020, 49, 101}, mocksource.Quantities(result))
+	})
+
+	t.Run("should pass through unmatched records and nil transforms", func(t *testing.T) {
+		s := NewSwitchTransform(
+			Case{Predicate: below(10)},
+			Case{Predicate: below(30), Then: mocktransform.NewAddIntTransform("quantity", 1000)},
+		)
+
+		result, err := s.Transform(mocksource.NewStock(5, 20, 50))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{5, 1020, 50}, mocksource.Quantities(result))
+	})
+
+	t.Run("should concatenate branches when a branch changes the record count", func(t *testing.T) {
+		dropAll := transformFunc(func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			return domain.NewRecordSet(input.Schema), nil
+		})
+		s := NewSwitchTransform(
+			Case{Predicate: below(10), Then: dropAll},
+			Case{Predicate: below(30), Then: mocktransform.NewAddIntTransform("quantity", 1000)},
+		)
+
+		result, err := s.Transform(mocksource.NewStock(40, 5, 20, 50, 25))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1020, 1025, 40, 50}, mocksource.Quantities(result))
+	})
+
+	t.Run("should merge schemas returned by branches", func(t *testing.T) {
+		flag := transformFunc(func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			return input.WithColumn(domain.SchemaColumnSingle{ID: "low", SchemaType: domain.NativeTypeBool}, func(*domain.Record) domain.Value {
+				return domain.BoolValue(true)
+			}), nil
+		})
+
+		result, err := NewWhenTransform(below(10), flag).Transform(mocksource.NewStock(5, 20))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"quantity", "low"}, result.Schema.ColumnIDs())
+		assert.True(t, result.First().GetBool("low"))
+		assert.Nil(t, result.Get(1).Get("low"))
+	})
+
+	t.Run("should return error for conflicting branch schemas", func(t *testing.T) {
+		retype := transformFunc(func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			return domain.NewRecordSet(&domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeString},
+			}}), nil
+		})
+
+		_, err := NewWhenTransform(below(10), retype).Transform(mocksource.NewStock(5))
+
+		assert.ErrorIs(t, err, domain.ErrColumnConflict)
+		assert.ErrorContains(t, err, "case 0: column quantity")
+	})
+
+	t.Run("should not run branches without records", func(t *testing.T) {
+		result, err := NewWhenTransform(below(0), mocktransform.ErrorTransform{}).Transform(mocksource.NewStock(5))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{5}, mocksource.Quantities(result))
+	})
+
+	t.Run("should return error from a branch", func(t *testing.T) {
+		_, err := NewWhenTransform(below(10), mocktransform.ErrorTransform{}).Transform(mocksource.NewStock(5))
+
+		assert.EqualError(t, err, "case 0: transform error")
+	})
+
+	t.Run("should nest", func(t *testing.T) {
+		inner := NewWhenTransform(below(5), mocktransform.NewAddIntTransform("quantity", 100))
+		outer := NewWhenTransform(below(10), inner)
+
+		result, err := outer.Transform(mocksource.NewStock(1, 7, 20))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{101, 7, 20}, mocksource.Quantities(result))
+	})
+
+	t.Run("should return nil for nil input", func(t *testing.T) {
+		result, err := NewSwitchTransform().Transform(nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestTryTransform_Transform(t *testing.T) {
+	t.Run("should return primary result", func(t *testing.T) {
+		try := NewTryTransform(mocktransform.NewAddIntTransform("quantity", 1), mocktransform.ErrorTransform{})
+
+		result, err := try.Transform(mocksource.NewStock(1))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2}, mocksource.Quantities(result))
+	})
+
+	t.Run("should run fallback on the original input", func(t *testing.T) {
+		try := NewTryTransform(mocktransform.ErrorTransform{}, mocktransform.NewAddIntTransform("quantity", 10))
+
+		result, err := try.Transform(mocksource.NewStock(1))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{11}, mocksource.Quantities(result))
+	})
+
+	t.Run("should pass input through without fallback", func(t *testing.T) {
+		input := mocksource.NewStock(1)
+
+		result, err := NewTryTransform(mocktransform.ErrorTransform{}, nil).Transform(input)
+
+		require.NoError(t, err)
+		assert.Same(t, input, result)
+	})
+
+	t.Run("should return both errors when fallback fails", func(t *testing.T) {
+		_, err := NewTryTransform(mocktransform.ErrorTransform{}, mocktransform.ErrorTransform{}).Transform(mocksource.NewStock(1))
+
+		assert.EqualError(t, err, "transform error; fallback failed: transform error")
+	})
+}
+
+func TestTransformBuilder_Branches(t *testing.T) {
+	t.Run("should chain when, switch and try", func(t *testing.T) {
+		var pipeline ports.TransformPort = NewTransformBuilder().
+			When(below(10), mocktransform.NewAddIntTransform("quantity", 10)).
+			Switch(
+				Case{Predicate: below(15), Then: mocktransform.NewAddIntTransform("quantity", 100)},
+				Default(nil),
+			).
+			Try(mocktransform.ErrorTransform{}, mocktransform.NewAddIntTransform("quantity", 1)).
+			Build()
+
+		result, err := pipeline.Transform(mocksource.NewStock(1, 12, 30))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{112, 113, 31}, mocksource.Quantities(result))
+	})
+}
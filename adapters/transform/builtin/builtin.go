@@ -0,0 +1,113 @@
+// Package builtin provides ready-made, configurable transforms for the
+// reshaping and cleaning steps most pipelines need: renaming, casting,
+// filling nulls, clamping, arithmetic, timezone conversion, string
+// normalisation and column selection.
+//
+// Every transform can be built from code through its struct or constructor,
+// or from declarative configuration through a Spec:
+//
+//	t, err := builtin.ParseJSON([]byte(`[
+//	    {"type": "rename", "options": {"columns": {"qty": "stock"}}},
+//	    {"type": "clamp", "options": {"column": "stock", "min": 0}}
+//	]`))
+package builtin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// rewrite copies every record of input into a RecordSet of the given schema
+// and lets fn adjust the copy. Sources are preserved.
+func rewrite(input *domain.RecordSet, schema *domain.DataSchema, fn func(r *domain.Record) error) (*domain.RecordSet, error) {
+	result := domain.NewRecordSet(schema)
+	for i, record := range input.Records {
+		copied := domain.NewRecord(schema)
+		copied.Source = record.Source
+		for id, value := range record.Values {
+			copied.Set(id, value)
+		}
+		if err := fn(copied); err != nil {
+			return nil, domain.NewRecordError(i, record, err)
+		}
+		result.Add(copied)
+	}
+	return result, nil
+}
+
+// nativeColumn returns the native type of a column, failing if the column is
+// missing or holds nested records.
+func nativeColumn(schema *domain.DataSchema, id string) (domain.SchemaColumn, domain.NativeType, error) {
+	col := schema.Column(id)
+	if col == nil {
+		return nil, "", fmt.Errorf("column %s: %w", id, domain.ErrUnknownColumn)
+	}
+	native, ok := col.GetType().(domain.NativeType)
+	if !ok {
+		return nil, "", fmt.Errorf("column %s: type %s is not a native type", id, col.GetType().GetTypeName())
+	}
+	return col, native, nil
+}
+
+// numericColumn returns the type of a column, failing unless it is an int
+// or float column.
+func numericColumn(schema *domain.DataSchema, id string) (domain.SchemaColumn, domain.NativeType, error) {
+	col, native, err := nativeColumn(schema, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if native != domain.NativeTypeInt && native != domain.NativeTypeFloat {
+		return nil, "", fmt.Errorf("column %s: expected a numeric column, got %s", id, native)
+	}
+	return col, native, nil
+}
+
+// mapValue applies fn to a single value or to every element of an array,
+// leaving nulls untouched.
+func mapValue(value domain.Value, fn func(domain.Value) (domain.Value, error)) (domain.Value, error) {
+	switch v := value.(type) {
+	case nil, domain.NullValue:
+		return value, nil
+	case domain.ArrayValue:
+		elements := make([]domain.Value, len(v.Elements))
+		for i, element := range v.Elements {
+			mapped, err := mapValue(element, fn)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elements[i] = mapped
+		}
+		return domain.ArrayValue{ElementType: v.ElementType, Elements: elements}, nil
+	default:
+		return fn(value)
+	}
+}
+
+// valueOf converts a plain Go value, as written in code or decoded from
+// configuration, to a Value of the given native type with a strict cast.
+func valueOf(v any, typ domain.NativeType) (domain.Value, error) {
+	var value domain.Value
+	switch x := v.(type) {
+	case nil:
+		return domain.NullValue{Type: typ}, nil
+	case domain.Value:
+		value = x
+	case string:
+		value = domain.StringValue(x)
+	case bool:
+		value = domain.BoolValue(x)
+	case int:
+		value = domain.IntValue(x)
+	case int64:
+		value = domain.IntValue(x)
+	case float64:
+		value = domain.FloatValue(x)
+	case time.Time:
+		value = domain.DateValue(x)
+	default:
+		return nil, fmt.Errorf("unsupported value %v of type %T", v, v)
+	}
+	return domain.Cast(value, typ, domain.CastOptions{})
+}
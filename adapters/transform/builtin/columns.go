@@ -0,0 +1,101 @@
+package builtin
+
+import (
+	"fmt"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Rename renames columns. All renames apply at once, so columns can be
+// swapped.
+type Rename struct {
+	Columns map[string]string `json:"columns"` // Old column ID to new column ID
+}
+
+// NewRename creates a Rename transform from an old to new ID mapping.
+func NewRename(columns map[string]string) *Rename {
+	return &Rename{Columns: columns}
+}
+
+// Transform renames the configured columns, keeping their position.
+func (t *Rename) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	for oldID := range t.Columns {
+		if !input.Schema.HasColumn(oldID) {
+			return nil, fmt.Errorf("column %s: %w", oldID, domain.ErrUnknownColumn)
+		}
+	}
+
+	schema := &domain.DataSchema{ID: input.Schema.ID, Columns: make([]domain.SchemaColumn, 0, len(input.Schema.Columns))}
+	seen := make(map[string]bool, len(input.Schema.Columns))
+	for _, col := range input.Schema.Columns {
+		id := col.GetID()
+		if newID, ok := t.Columns[id]; ok {
+			id = newID
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("column %s: %w", id, domain.ErrDuplicateColumn)
+		}
+		seen[id] = true
+
+		switch c := col.(type) {
+		case domain.SchemaColumnArray:
+			schema.Columns = append(schema.Columns, domain.SchemaColumnArray{ID: id, RefSchema: c.RefSchema})
+		default:
+			schema.Columns = append(schema.Columns, domain.SchemaColumnSingle{ID: id, SchemaType: col.GetType()})
+		}
+	}
+
+	result := domain.NewRecordSet(schema)
+	for _, record := range input.Records {
+		renamed := domain.NewRecord(schema)
+		renamed.Source = record.Source
+		for id, value := range record.Values {
+			if newID, ok := t.Columns[id]; ok {
+				id = newID
+			}
+			renamed.Set(id, value)
+		}
+		result.Add(renamed)
+	}
+	return result, nil
+}
+
+// Drop removes columns.
+type Drop struct {
+	Columns []string `json:"columns"` // Column IDs to remove
+}
+
+// NewDrop creates a Drop transform.
+func NewDrop(columns ...string) *Drop {
+	return &Drop{Columns: columns}
+}
+
+// Transform removes the configured columns.
+func (t *Drop) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+	return input.Drop(t.Columns...)
+}
+
+// Keep keeps only the listed columns, in the listed order.
+type Keep struct {
+	Columns []string `json:"columns"` // Column IDs to keep
+}
+
+// NewKeep creates a Keep transform.
+func NewKeep(columns ...string) *Keep {
+	return &Keep{Columns: columns}
+}
+
+// Transform keeps the configured columns.
+func (t *Keep) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+	return input.Select(t.Columns...)
+}
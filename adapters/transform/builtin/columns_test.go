@@ -0,0 +1,108 @@
+package builtin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func productsFixture() *domain.RecordSet {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "updated", SchemaType: domain.NativeTypeDate},
+			domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+		},
+	}
+
+	rs := domain.NewRecordSet(schema)
+
+	apple := domain.NewRecord(schema)
+	apple.Source = "products.json"
+	apple.Set("name", domain.StringValue("  Green   Apple "))
+	apple.Set("price", domain.FloatValue(1.5))
+	apple.Set("stock", domain.IntValue(-3))
+	apple.Set("updated", domain.DateValue(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	apple.Set("tags", domain.ArrayValue{ElementType: domain.NativeTypeString, Elements: []domain.Value{
+		domain.StringValue(" Fruit"), domain.NullValue{Type: domain.NativeTypeString},
+	}})
+	rs.Add(apple)
+
+	pear := domain.NewRecord(schema)
+	pear.Source = "products.json"
+	pear.Set("name", domain.StringValue("pear"))
+	pear.Set("price", domain.NullValue{Type: domain.NativeTypeFloat})
+	pear.Set("stock", domain.IntValue(250))
+	pear.Set("updated", domain.NullValue{Type: domain.NativeTypeDate})
+	pear.Set("tags", domain.ArrayValue{ElementType: domain.NativeTypeString, Elements: []domain.Value{}})
+	rs.Add(pear)
+
+	return rs
+}
+
+func TestRename_Transform(t *testing.T) {
+	t.Run("should rename columns in place and keep values", func(t *testing.T) {
+		result, err := NewRename(map[string]string{"stock": "quantity", "tags": "labels"}).Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "price", "quantity", "updated", "labels"}, result.Schema.ColumnIDs())
+		assert.True(t, result.Schema.Column("labels").IsArray())
+		assert.Equal(t, int64(-3), result.First().GetInt("quantity"))
+		assert.Nil(t, result.First().Get("stock"))
+		assert.Equal(t, "products.json", result.First().Source)
+	})
+
+	t.Run("should swap columns", func(t *testing.T) {
+		result, err := NewRename(map[string]string{"name": "price", "price": "name"}).Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.NativeTypeFloat, result.Schema.Column("name").GetType())
+		assert.Equal(t, 1.5, result.First().GetFloat("name"))
+	})
+
+	t.Run("should return error for unknown or conflicting columns", func(t *testing.T) {
+		_, err := NewRename(map[string]string{"nope": "x"}).Transform(productsFixture())
+		assert.True(t, errors.Is(err, domain.ErrUnknownColumn))
+
+		_, err = NewRename(map[string]string{"stock": "price"}).Transform(productsFixture())
+		assert.True(t, errors.Is(err, domain.ErrDuplicateColumn))
+	})
+
+	t.Run("should return nil for nil input", func(t *testing.T) {
+		result, err := NewRename(nil).Transform(nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestDropKeep_Transform(t *testing.T) {
+	t.Run("should drop columns", func(t *testing.T) {
+		result, err := NewDrop("tags", "updated").Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "price", "stock"}, result.Schema.ColumnIDs())
+	})
+
+	t.Run("should keep columns in order", func(t *testing.T) {
+		result, err := NewKeep("stock", "name").Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"stock", "name"}, result.Schema.ColumnIDs())
+		assert.Len(t, result.First().Values, 2)
+	})
+
+	t.Run("should return error for unknown column", func(t *testing.T) {
+		_, err := NewKeep("nope").Transform(productsFixture())
+
+		assert.True(t, errors.Is(err, domain.ErrUnknownColumn))
+	})
+}
@@ -0,0 +1,116 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/transform"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Spec declares a transform by type name and options, as found in pipeline
+// configuration files.
+type Spec struct {
+	Type    string         `json:"type"`              // Registered transform name
+	Options map[string]any `json:"options,omitempty"` // Transform specific options
+}
+
+// Factory builds a transform from decoded options.
+type Factory func(options map[string]any) (ports.TransformPort, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"rename":     decoded(func() *Rename { return &Rename{} }),
+		"drop":       decoded(func() *Drop { return &Drop{} }),
+		"keep":       decoded(func() *Keep { return &Keep{} }),
+		"cast":       decoded(func() *Cast { return &Cast{} }),
+		"fill_null":  decoded(func() *FillNull { return &FillNull{} }),
+		"clamp":      decoded(func() *Clamp { return &Clamp{} }),
+		"arithmetic": decoded(func() *Arithmetic { return &Arithmetic{} }),
+		"normalize":  decoded(func() *Normalize { return &Normalize{} }),
+		"timezone":   newTimezoneFromOptions,
+	}
+)
+
+// Register adds or replaces a transform factory under name, so custom
+// transforms can be referenced from configuration. It is typically called
+// from an init function.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New builds the transform declared by spec.
+func New(spec Spec) (ports.TransformPort, error) {
+	mu.RLock()
+	factory, ok := factories[spec.Type]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown transform type %q", spec.Type)
+	}
+
+	t, err := factory(spec.Options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s options: %w", spec.Type, err)
+	}
+	return t, nil
+}
+
+// Chain builds the transforms declared by specs and chains them in order.
+func Chain(specs ...Spec) (ports.TransformPort, error) {
+	builder := transform.NewTransformBuilder()
+	for i, spec := range specs {
+		t, err := New(spec)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d: %w", i, err)
+		}
+		builder.Add(t)
+	}
+	return builder.Build(), nil
+}
+
+// ParseJSON builds a chain from a JSON array of specs.
+func ParseJSON(data []byte) (ports.TransformPort, error) {
+	var specs []Spec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("failed to parse transform specs: %w", err)
+	}
+	return Chain(specs...)
+}
+
+// decoded returns a Factory that decodes options into the json tagged fields
+// of a new transform, rejecting unknown options.
+func decoded[T ports.TransformPort](newTransform func() T) Factory {
+	return func(options map[string]any) (ports.TransformPort, error) {
+		t := newTransform()
+		if err := decodeOptions(options, t); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+}
+
+func decodeOptions(options map[string]any, target any) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+func newTimezoneFromOptions(options map[string]any) (ports.TransformPort, error) {
+	var opts struct {
+		Columns  []string `json:"columns"`
+		Location string   `json:"location"`
+	}
+	if err := decodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return NewTimezone(opts.Location, opts.Columns...)
+}
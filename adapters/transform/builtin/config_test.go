@@ -0,0 +1,94 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should build every builtin transform", func(t *testing.T) {
+		specs := []Spec{
+			{Type: "rename", Options: map[string]any{"columns": map[string]any{"a": "b"}}},
+			{Type: "drop", Options: map[string]any{"columns": []any{"a"}}},
+			{Type: "keep", Options: map[string]any{"columns": []any{"a"}}},
+			{Type: "cast", Options: map[string]any{"columns": map[string]any{"a": "int"}}},
+			{Type: "fill_null", Options: map[string]any{"values": map[string]any{"a": 0}}},
+			{Type: "clamp", Options: map[string]any{"column": "a", "min": 0}},
+			{Type: "arithmetic", Options: map[string]any{"column": "a", "op": "multiply", "operand": 2}},
+			{Type: "normalize", Options: map[string]any{"columns": []any{"a"}, "case": "lower"}},
+			{Type: "timezone", Options: map[string]any{"columns": []any{"a"}, "location": "Europe/Paris"}},
+		}
+
+		for _, spec := range specs {
+			transform, err := New(spec)
+
+			require.NoError(t, err, spec.Type)
+			assert.NotNil(t, transform, spec.Type)
+		}
+	})
+
+	t.Run("should decode options into the transform", func(t *testing.T) {
+		transform, err := New(Spec{Type: "clamp", Options: map[string]any{"column": "stock", "max": 10}})
+
+		require.NoError(t, err)
+		max := 10.0
+		assert.Equal(t, &Clamp{Column: "stock", Max: &max}, transform)
+	})
+
+	t.Run("should return error for unknown type or option", func(t *testing.T) {
+		_, err := New(Spec{Type: "explode"})
+		assert.EqualError(t, err, `unknown transform type "explode"`)
+
+		_, err = New(Spec{Type: "drop", Options: map[string]any{"cols": []any{"a"}}})
+		assert.EqualError(t, err, `invalid drop options: json: unknown field "cols"`)
+
+		_, err = New(Spec{Type: "timezone", Options: map[string]any{"location": "Nowhere"}})
+		assert.ErrorContains(t, err, "invalid timezone options: invalid timezone Nowhere")
+	})
+
+	t.Run("should use registered factories", func(t *testing.T) {
+		Register("noop", func(options map[string]any) (ports.TransformPort, error) {
+			return NewDrop(), nil
+		})
+
+		transform, err := New(Spec{Type: "noop"})
+
+		require.NoError(t, err)
+		assert.Equal(t, NewDrop(), transform)
+	})
+}
+
+func TestParseJSON(t *testing.T) {
+	t.Run("should chain transforms from JSON", func(t *testing.T) {
+		transform, err := ParseJSON([]byte(`[
+			{"type": "rename", "options": {"columns": {"stock": "quantity"}}},
+			{"type": "clamp", "options": {"column": "quantity", "min": 0, "max": 100}},
+			{"type": "fill_null", "options": {"values": {"price": 9.99}}},
+			{"type": "normalize", "options": {"columns": ["name"], "trim": true, "collapse_spaces": true, "case": "lower"}},
+			{"type": "keep", "options": {"columns": ["name", "price", "quantity"]}}
+		]`))
+		require.NoError(t, err)
+
+		result, err := transform.Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "price", "quantity"}, result.Schema.ColumnIDs())
+		assert.Equal(t, domain.StringValue("green apple"), result.First().Get("name"))
+		assert.Equal(t, domain.IntValue(0), result.First().Get("quantity"))
+		assert.Equal(t, domain.IntValue(100), result.Get(1).Get("quantity"))
+		assert.Equal(t, domain.FloatValue(9.99), result.Get(1).Get("price"))
+	})
+
+	t.Run("should return error with the failing transform index", func(t *testing.T) {
+		_, err := ParseJSON([]byte(`[{"type": "drop"}, {"type": "nope"}]`))
+		assert.EqualError(t, err, `transform 1: unknown transform type "nope"`)
+
+		_, err = ParseJSON([]byte(`{}`))
+		assert.ErrorContains(t, err, "failed to parse transform specs")
+	})
+}
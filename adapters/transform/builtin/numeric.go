@@ -0,0 +1,199 @@
+package builtin
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Clamp bounds a numeric column. A nil bound is not applied. Int columns are
+// clamped to the nearest integers inside the bounds.
+type Clamp struct {
+	Column string   `json:"column"` // Numeric column ID
+	Min    *float64 `json:"min"`    // Lower bound, nil for none
+	Max    *float64 `json:"max"`    // Upper bound, nil for none
+}
+
+// NewClamp creates a Clamp transform bounded on both sides.
+func NewClamp(column string, min, max float64) *Clamp {
+	return &Clamp{Column: column, Min: &min, Max: &max}
+}
+
+// Transform clamps every value of the column.
+func (t *Clamp) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if t.Min != nil && t.Max != nil && *t.Min > *t.Max {
+		return nil, fmt.Errorf("column %s: min %v is greater than max %v", t.Column, *t.Min, *t.Max)
+	}
+	if _, _, err := numericColumn(input.Schema, t.Column); err != nil {
+		return nil, err
+	}
+
+	return rewrite(input, input.Schema, func(r *domain.Record) error {
+		value, err := mapValue(r.Get(t.Column), func(v domain.Value) (domain.Value, error) {
+			switch n := v.(type) {
+			case domain.IntValue:
+				if t.Min != nil && float64(n) < *t.Min {
+					return domain.IntValue(math.Ceil(*t.Min)), nil
+				}
+				if t.Max != nil && float64(n) > *t.Max {
+					return domain.IntValue(math.Floor(*t.Max)), nil
+				}
+				return n, nil
+			case domain.FloatValue:
+				if t.Min != nil && float64(n) < *t.Min {
+					return domain.FloatValue(*t.Min), nil
+				}
+				if t.Max != nil && float64(n) > *t.Max {
+					return domain.FloatValue(*t.Max), nil
+				}
+				return n, nil
+			}
+			return nil, fmt.Errorf("cannot clamp %s", v.GetType().GetTypeName())
+		})
+		if err != nil {
+			return fmt.Errorf("column %s: %w", t.Column, err)
+		}
+		if value != nil {
+			r.Set(t.Column, value)
+		}
+		return nil
+	})
+}
+
+// Operator is an arithmetic operation applied by Arithmetic.
+type Operator string
+
+const (
+	// Add adds the operand.
+	Add Operator = "add"
+	// Subtract subtracts the operand.
+	Subtract Operator = "subtract"
+	// Multiply multiplies by the operand.
+	Multiply Operator = "multiply"
+	// Divide divides by the operand.
+	Divide Operator = "divide"
+)
+
+// Arithmetic applies an operation with a constant operand to a numeric
+// column. The column keeps its type: int values are computed exactly, and a
+// result with a fractional part or overflowing an int is an error.
+type Arithmetic struct {
+	Column  string   `json:"column"`  // Numeric column ID
+	Op      Operator `json:"op"`      // Operation to apply
+	Operand float64  `json:"operand"` // Right-hand operand
+}
+
+// NewArithmetic creates an Arithmetic transform.
+func NewArithmetic(column string, op Operator, operand float64) *Arithmetic {
+	return &Arithmetic{Column: column, Op: op, Operand: operand}
+}
+
+// Transform applies the operation to every value of the column.
+func (t *Arithmetic) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	var apply func(float64) float64
+	switch t.Op {
+	case Add:
+		apply = func(x float64) float64 { return x + t.Operand }
+	case Subtract:
+		apply = func(x float64) float64 { return x - t.Operand }
+	case Multiply:
+		apply = func(x float64) float64 { return x * t.Operand }
+	case Divide:
+		if t.Operand == 0 {
+			return nil, fmt.Errorf("column %s: division by zero", t.Column)
+		}
+		apply = func(x float64) float64 { return x / t.Operand }
+	default:
+		return nil, fmt.Errorf("column %s: unknown operator %q", t.Column, t.Op)
+	}
+	if _, _, err := numericColumn(input.Schema, t.Column); err != nil {
+		return nil, err
+	}
+
+	return rewrite(input, input.Schema, func(r *domain.Record) error {
+		value, err := mapValue(r.Get(t.Column), func(v domain.Value) (domain.Value, error) {
+			switch n := v.(type) {
+			case domain.IntValue:
+				i, err := t.applyInt(int64(n))
+				return domain.IntValue(i), err
+			case domain.FloatValue:
+				return domain.FloatValue(apply(float64(n))), nil
+			}
+			return nil, fmt.Errorf("cannot apply %s to %s", t.Op, v.GetType().GetTypeName())
+		})
+		if err != nil {
+			return fmt.Errorf("column %s: %w", t.Column, err)
+		}
+		if value != nil {
+			r.Set(t.Column, value)
+		}
+		return nil
+	})
+}
+
+// operators maps operations to their symbol, for error messages.
+var operators = map[Operator]string{Add: "+", Subtract: "-", Multiply: "*", Divide: "/"}
+
+// applyInt applies the operation to an int: with int64 arithmetic when the
+// operand is a whole number, exactly with rationals otherwise.
+func (t *Arithmetic) applyInt(n int64) (int64, error) {
+	expr := fmt.Sprintf("%d %s %v", n, operators[t.Op], t.Operand)
+	if t.Operand == math.Trunc(t.Operand) && t.Operand >= math.MinInt64 && t.Operand < math.MaxInt64 {
+		operand := int64(t.Operand)
+		var result int64
+		overflow := false
+		switch t.Op {
+		case Add:
+			result = n + operand
+			overflow = (operand > 0 && result < n) || (operand < 0 && result > n)
+		case Subtract:
+			result = n - operand
+			overflow = (operand > 0 && result > n) || (operand < 0 && result < n)
+		case Multiply:
+			result = n * operand
+			overflow = n != 0 && (result/n != operand || (n == -1 && operand == math.MinInt64))
+		case Divide:
+			if n%operand != 0 {
+				return 0, fmt.Errorf("%s is not an integer", expr)
+			}
+			result = n / operand
+			overflow = n == math.MinInt64 && operand == -1
+		}
+		if overflow {
+			return 0, fmt.Errorf("%s overflows int", expr)
+		}
+		return result, nil
+	}
+
+	operand := new(big.Rat)
+	if operand.SetFloat64(t.Operand) == nil {
+		return 0, fmt.Errorf("invalid operand %v", t.Operand)
+	}
+	result := new(big.Rat).SetInt64(n)
+	switch t.Op {
+	case Add:
+		result.Add(result, operand)
+	case Subtract:
+		result.Sub(result, operand)
+	case Multiply:
+		result.Mul(result, operand)
+	case Divide:
+		result.Quo(result, operand)
+	}
+	if !result.IsInt() {
+		return 0, fmt.Errorf("%s is not an integer", expr)
+	}
+	if !result.Num().IsInt64() {
+		return 0, fmt.Errorf("%s overflows int", expr)
+	}
+	return result.Num().Int64(), nil
+}
@@ -0,0 +1,116 @@
+package builtin
+
+import (
+	"math"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClamp_Transform(t *testing.T) {
+	t.Run("should clamp int column to both bounds", func(t *testing.T) {
+		result, err := NewClamp("stock", 0.5, 100).Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.IntValue(1), result.First().Get("stock"))
+		assert.Equal(t, domain.IntValue(100), result.Get(1).Get("stock"))
+	})
+
+	t.Run("should apply a single bound and keep nulls", func(t *testing.T) {
+		min := 2.0
+
+		result, err := (&Clamp{Column: "price", Min: &min}).Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.FloatValue(2), result.First().Get("price"))
+		assert.Equal(t, domain.NullValue{Type: domain.NativeTypeFloat}, result.Get(1).Get("price"))
+	})
+
+	t.Run("should return error for invalid configuration", func(t *testing.T) {
+		_, err := NewClamp("stock", 10, 0).Transform(productsFixture())
+		assert.EqualError(t, err, "column stock: min 10 is greater than max 0")
+
+		_, err = NewClamp("name", 0, 1).Transform(productsFixture())
+		assert.EqualError(t, err, "column name: expected a numeric column, got string")
+	})
+}
+
+func TestArithmetic_Transform(t *testing.T) {
+	tests := []struct {
+		op      Operator
+		operand float64
+		price   domain.Value
+		stock   domain.Value
+	}{
+		{Add, 1, domain.FloatValue(2.5), domain.IntValue(-2)},
+		{Subtract, 2, domain.FloatValue(-0.5), domain.IntValue(-5)},
+		{Multiply, 2, domain.FloatValue(3), domain.IntValue(-6)},
+		{Divide, -0.5, domain.FloatValue(-3), domain.IntValue(6)},
+	}
+
+	for _, tt := range tests {
+		t.Run("should "+string(tt.op), func(t *testing.T) {
+			prices, err := NewArithmetic("price", tt.op, tt.operand).Transform(productsFixture())
+			require.NoError(t, err)
+			stocks, err := NewArithmetic("stock", tt.op, tt.operand).Transform(productsFixture())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.price, prices.First().Get("price"))
+			assert.Equal(t, tt.stock, stocks.First().Get("stock"))
+			assert.Equal(t, domain.NullValue{Type: domain.NativeTypeFloat}, prices.Get(1).Get("price"))
+		})
+	}
+
+	t.Run("should compute ints exactly beyond float precision", func(t *testing.T) {
+		rs := productsFixture()
+		rs.Records = rs.Records[:1]
+		rs.First().Set("stock", domain.IntValue(1<<53+1))
+
+		added, err := NewArithmetic("stock", Add, 2).Transform(rs)
+		require.NoError(t, err)
+		rs.First().Set("stock", domain.IntValue(1<<60+4))
+		scaled, err := NewArithmetic("stock", Multiply, 0.75).Transform(rs)
+		require.NoError(t, err)
+
+		assert.Equal(t, domain.IntValue(1<<53+3), added.First().Get("stock"))
+		assert.Equal(t, domain.IntValue(3<<58+3), scaled.First().Get("stock"))
+	})
+
+	t.Run("should return errors for int results that are not integers or overflow", func(t *testing.T) {
+		tests := []struct {
+			op      Operator
+			operand float64
+			stock   int64
+			msg     string
+		}{
+			{Divide, 2, -3, "-3 / 2 is not an integer"},
+			{Multiply, 1.5, -3, "-3 * 1.5 is not an integer"},
+			{Subtract, 0.5, -3, "-3 - 0.5 is not an integer"},
+			{Add, 1, math.MaxInt64, "9223372036854775807 + 1 overflows int"},
+			{Subtract, 1, math.MinInt64, "-9223372036854775808 - 1 overflows int"},
+			{Multiply, 3, math.MaxInt64 / 2, "4611686018427387903 * 3 overflows int"},
+			{Divide, -1, math.MinInt64, "-9223372036854775808 / -1 overflows int"},
+			{Multiply, 1e19, 2, "2 * 1e+19 overflows int"},
+		}
+
+		for _, tt := range tests {
+			rs := productsFixture()
+			rs.First().Set("stock", domain.IntValue(tt.stock))
+
+			_, err := NewArithmetic("stock", tt.op, tt.operand).Transform(rs)
+
+			assert.EqualError(t, err, "record 0 (products.json): column stock: "+tt.msg)
+		}
+	})
+
+	t.Run("should return error for invalid configuration", func(t *testing.T) {
+		_, err := NewArithmetic("price", Divide, 0).Transform(productsFixture())
+		assert.EqualError(t, err, "column price: division by zero")
+
+		_, err = NewArithmetic("price", "power", 2).Transform(productsFixture())
+		assert.EqualError(t, err, `column price: unknown operator "power"`)
+	})
+}
@@ -0,0 +1,149 @@
+package builtin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Timezone converts date columns to another location. The instant is
+// unchanged, only its representation moves.
+type Timezone struct {
+	Columns  []string       // Date column IDs
+	Location *time.Location // Target location
+}
+
+// NewTimezone creates a Timezone transform for an IANA location name such as
+// "Europe/Paris".
+func NewTimezone(location string, columns ...string) (*Timezone, error) {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %s: %w", location, err)
+	}
+	return &Timezone{Columns: columns, Location: loc}, nil
+}
+
+// Transform converts every date of the configured columns.
+func (t *Timezone) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if t.Location == nil {
+		return nil, fmt.Errorf("timezone location is required")
+	}
+	for _, id := range t.Columns {
+		_, native, err := nativeColumn(input.Schema, id)
+		if err != nil {
+			return nil, err
+		}
+		if native != domain.NativeTypeDate {
+			return nil, fmt.Errorf("column %s: expected a date column, got %s", id, native)
+		}
+	}
+
+	return rewrite(input, input.Schema, func(r *domain.Record) error {
+		for _, id := range t.Columns {
+			value, err := mapValue(r.Get(id), func(v domain.Value) (domain.Value, error) {
+				date, ok := v.(domain.DateValue)
+				if !ok {
+					return nil, fmt.Errorf("cannot convert %s to a timezone", v.GetType().GetTypeName())
+				}
+				return domain.DateValue(time.Time(date).In(t.Location)), nil
+			})
+			if err != nil {
+				return fmt.Errorf("column %s: %w", id, err)
+			}
+			if value != nil {
+				r.Set(id, value)
+			}
+		}
+		return nil
+	})
+}
+
+// Case selects the letter case applied by Normalize.
+type Case string
+
+const (
+	// KeepCase leaves letter case unchanged.
+	KeepCase Case = ""
+	// Lower converts to lower case.
+	Lower Case = "lower"
+	// Upper converts to upper case.
+	Upper Case = "upper"
+)
+
+// Normalize cleans up string columns: it trims surrounding whitespace,
+// collapses inner runs of whitespace to a single space, and changes case.
+type Normalize struct {
+	Columns        []string `json:"columns"`         // String column IDs
+	Trim           bool     `json:"trim"`            // Remove leading and trailing whitespace
+	CollapseSpaces bool     `json:"collapse_spaces"` // Replace whitespace runs with one space
+	Case           Case     `json:"case"`            // Letter case to apply
+}
+
+// NewNormalize creates a Normalize transform that trims and collapses
+// whitespace in the given columns.
+func NewNormalize(columns ...string) *Normalize {
+	return &Normalize{Columns: columns, Trim: true, CollapseSpaces: true}
+}
+
+// Transform normalises every string of the configured columns.
+func (t *Normalize) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if t.Case != KeepCase && t.Case != Lower && t.Case != Upper {
+		return nil, fmt.Errorf("unknown case %q", t.Case)
+	}
+	for _, id := range t.Columns {
+		_, native, err := nativeColumn(input.Schema, id)
+		if err != nil {
+			return nil, err
+		}
+		if native != domain.NativeTypeString {
+			return nil, fmt.Errorf("column %s: expected a string column, got %s", id, native)
+		}
+	}
+
+	return rewrite(input, input.Schema, func(r *domain.Record) error {
+		for _, id := range t.Columns {
+			value, err := mapValue(r.Get(id), func(v domain.Value) (domain.Value, error) {
+				s, ok := v.(domain.StringValue)
+				if !ok {
+					return nil, fmt.Errorf("cannot normalize %s", v.GetType().GetTypeName())
+				}
+				return domain.StringValue(t.normalize(string(s))), nil
+			})
+			if err != nil {
+				return fmt.Errorf("column %s: %w", id, err)
+			}
+			if value != nil {
+				r.Set(id, value)
+			}
+		}
+		return nil
+	})
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+func (t *Normalize) normalize(s string) string {
+	if t.CollapseSpaces {
+		s = whitespace.ReplaceAllString(s, " ")
+	}
+	if t.Trim {
+		s = strings.TrimSpace(s)
+	}
+
+	switch t.Case {
+	case Lower:
+		s = strings.ToLower(s)
+	case Upper:
+		s = strings.ToUpper(s)
+	}
+	return s
+}
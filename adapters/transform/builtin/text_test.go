@@ -0,0 +1,92 @@
+package builtin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimezone_Transform(t *testing.T) {
+	t.Run("should convert dates to the location", func(t *testing.T) {
+		tz, err := NewTimezone("Europe/Paris", "updated")
+		require.NoError(t, err)
+
+		result, err := tz.Transform(productsFixture())
+
+		require.NoError(t, err)
+		updated := result.First().GetDate("updated")
+		assert.Equal(t, "2024-01-15T13:00:00+01:00", updated.Format(time.RFC3339))
+		assert.True(t, updated.Equal(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+		assert.Equal(t, domain.NullValue{Type: domain.NativeTypeDate}, result.Get(1).Get("updated"))
+	})
+
+	t.Run("should return error for invalid configuration", func(t *testing.T) {
+		_, err := NewTimezone("Mars/Olympus", "updated")
+		assert.ErrorContains(t, err, "invalid timezone Mars/Olympus")
+
+		tz, _ := NewTimezone("UTC", "name")
+		_, err = tz.Transform(productsFixture())
+		assert.EqualError(t, err, "column name: expected a date column, got string")
+	})
+
+	t.Run("should return error for values not matching their column", func(t *testing.T) {
+		input := productsFixture()
+		input.Get(1).Set("updated", domain.StringValue("yesterday"))
+		tz, err := NewTimezone("UTC", "updated")
+		require.NoError(t, err)
+
+		_, err = tz.Transform(input)
+
+		assert.EqualError(t, err, "record 1 (products.json): column updated: cannot convert string to a timezone")
+	})
+}
+
+func TestNormalize_Transform(t *testing.T) {
+	tests := []struct {
+		name      string
+		normalize *Normalize
+		expected  string
+	}{
+		{"should trim and collapse by default", NewNormalize("name"), "Green Apple"},
+		{"should only trim", &Normalize{Columns: []string{"name"}, Trim: true}, "Green   Apple"},
+		{"should collapse without trimming", &Normalize{Columns: []string{"name"}, CollapseSpaces: true}, " Green Apple "},
+		{"should change case", &Normalize{Columns: []string{"name"}, Trim: true, CollapseSpaces: true, Case: Upper}, "GREEN APPLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.normalize.Transform(productsFixture())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.First().GetString("name"))
+		})
+	}
+
+	t.Run("should normalize array elements", func(t *testing.T) {
+		result, err := (&Normalize{Columns: []string{"tags"}, Trim: true, Case: Lower}).Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Value{domain.StringValue("fruit"), domain.NullValue{Type: domain.NativeTypeString}}, result.First().GetArray("tags"))
+	})
+
+	t.Run("should return error for invalid configuration", func(t *testing.T) {
+		_, err := (&Normalize{Columns: []string{"name"}, Case: "title"}).Transform(productsFixture())
+		assert.EqualError(t, err, `unknown case "title"`)
+
+		_, err = NewNormalize("stock").Transform(productsFixture())
+		assert.EqualError(t, err, "column stock: expected a string column, got int")
+	})
+
+	t.Run("should return error for values not matching their column", func(t *testing.T) {
+		input := productsFixture()
+		input.First().Set("name", domain.IntValue(3))
+
+		_, err := NewNormalize("name").Transform(input)
+
+		assert.EqualError(t, err, "record 0 (products.json): column name: cannot normalize int")
+	})
+}
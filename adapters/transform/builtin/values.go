@@ -0,0 +1,130 @@
+package builtin
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Cast converts columns to another native type with domain.Cast and updates
+// the schema. Array columns are cast element by element.
+type Cast struct {
+	Columns map[string]domain.NativeType `json:"columns"` // Column ID to target type
+	Options domain.CastOptions           `json:"options"` // Strictness, rounding and overflow behaviour
+}
+
+// NewCast creates a Cast transform from a column to type mapping.
+func NewCast(columns map[string]domain.NativeType) *Cast {
+	return &Cast{Columns: columns}
+}
+
+// Transform casts the configured columns.
+func (t *Cast) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	ids := sortedKeys(t.Columns)
+	schema := input.Schema
+	for _, id := range ids {
+		col, from, err := nativeColumn(input.Schema, id)
+		if err != nil {
+			return nil, err
+		}
+		to := t.Columns[id]
+		if !domain.CanCast(from, to, t.Options.Mode) {
+			return nil, fmt.Errorf("column %s: cannot convert %s to %s", id, from, to)
+		}
+		if col.IsArray() {
+			schema = schema.WithColumn(domain.SchemaColumnArray{ID: id, RefSchema: to})
+		} else {
+			schema = schema.WithColumn(domain.SchemaColumnSingle{ID: id, SchemaType: to})
+		}
+	}
+
+	return rewrite(input, schema, func(r *domain.Record) error {
+		for _, id := range ids {
+			value := r.Get(id)
+			if value == nil {
+				continue
+			}
+			cast, err := domain.Cast(value, t.Columns[id], t.Options)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", id, err)
+			}
+			r.Set(id, cast)
+		}
+		return nil
+	})
+}
+
+// FillNull replaces null or missing values with defaults. Values are plain Go
+// values (string, bool, int, int64, float64, time.Time) or domain Values and
+// are converted to the column type. In array columns, null elements are
+// replaced.
+type FillNull struct {
+	Values map[string]any `json:"values"` // Column ID to default value
+}
+
+// NewFillNull creates a FillNull transform from a column to default mapping.
+func NewFillNull(values map[string]any) *FillNull {
+	return &FillNull{Values: values}
+}
+
+// Transform fills the configured columns.
+func (t *FillNull) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	ids := sortedKeys(t.Values)
+	defaults := make(map[string]domain.Value, len(ids))
+	arrays := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		col, native, err := nativeColumn(input.Schema, id)
+		if err != nil {
+			return nil, err
+		}
+		value, err := valueOf(t.Values[id], native)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", id, err)
+		}
+		defaults[id] = value
+		arrays[id] = col.IsArray()
+	}
+
+	return rewrite(input, input.Schema, func(r *domain.Record) error {
+		for _, id := range ids {
+			value := r.Get(id)
+			if !arrays[id] {
+				if value == nil || value.IsNull() {
+					r.Set(id, defaults[id])
+				}
+				continue
+			}
+			array, ok := value.(domain.ArrayValue)
+			if !ok {
+				continue
+			}
+			elements := make([]domain.Value, len(array.Elements))
+			for i, element := range array.Elements {
+				if element == nil || element.IsNull() {
+					element = defaults[id]
+				}
+				elements[i] = element
+			}
+			r.Set(id, domain.ArrayValue{ElementType: array.ElementType, Elements: elements})
+		}
+		return nil
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
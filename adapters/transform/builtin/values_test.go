@@ -0,0 +1,130 @@
+package builtin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCast_Transform(t *testing.T) {
+	t.Run("should cast columns and update the schema", func(t *testing.T) {
+		cast := NewCast(map[string]domain.NativeType{
+			"price":   domain.NativeTypeString,
+			"stock":   domain.NativeTypeFloat,
+			"updated": domain.NativeTypeString,
+		})
+
+		result, err := cast.Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.NativeTypeString, result.Schema.Column("price").GetType())
+		assert.Equal(t, domain.NativeTypeFloat, result.Schema.Column("stock").GetType())
+		assert.Equal(t, domain.StringValue("1.5"), result.First().Get("price"))
+		assert.Equal(t, domain.FloatValue(-3), result.First().Get("stock"))
+		assert.Equal(t, domain.StringValue("2024-01-15T12:00:00Z"), result.First().Get("updated"))
+		assert.Equal(t, domain.NullValue{Type: domain.NativeTypeString}, result.Get(1).Get("price"))
+	})
+
+	t.Run("should parse strings", func(t *testing.T) {
+		schema := &domain.DataSchema{ID: "Raw", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "n", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "b", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "d", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnArray{ID: "xs", RefSchema: domain.NativeTypeString},
+		}}
+		input := domain.NewRecordSet(schema)
+		record := domain.NewRecord(schema)
+		record.Set("n", domain.StringValue("42"))
+		record.Set("b", domain.StringValue("true"))
+		record.Set("d", domain.StringValue("2024-03-01"))
+		record.Set("xs", domain.ArrayValue{ElementType: domain.NativeTypeString, Elements: []domain.Value{domain.StringValue("1.5")}})
+		input.Add(record)
+
+		result, err := NewCast(map[string]domain.NativeType{
+			"n": domain.NativeTypeInt, "b": domain.NativeTypeBool, "d": domain.NativeTypeDate, "xs": domain.NativeTypeFloat,
+		}).Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.IntValue(42), result.First().Get("n"))
+		assert.Equal(t, domain.BoolValue(true), result.First().Get("b"))
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), result.First().GetDate("d"))
+		assert.Equal(t, domain.ArrayValue{ElementType: domain.NativeTypeFloat, Elements: []domain.Value{domain.FloatValue(1.5)}}, result.First().Get("xs"))
+		assert.True(t, result.Schema.Column("xs").IsArray())
+	})
+
+	t.Run("should apply cast options", func(t *testing.T) {
+		cast := &Cast{
+			Columns: map[string]domain.NativeType{"price": domain.NativeTypeInt, "updated": domain.NativeTypeInt},
+			Options: domain.CastOptions{Rounding: domain.RoundCeil, EpochUnit: time.Millisecond},
+		}
+
+		result, err := cast.Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.IntValue(2), result.First().Get("price"))
+		assert.Equal(t, domain.IntValue(1705320000000), result.First().Get("updated"))
+		assert.Equal(t, domain.NativeTypeInt, result.Schema.Column("price").GetType())
+	})
+
+	t.Run("should reject fractional values in strict mode", func(t *testing.T) {
+		_, err := NewCast(map[string]domain.NativeType{"price": domain.NativeTypeInt}).Transform(productsFixture())
+
+		assert.EqualError(t, err, "record 0 (products.json): column price: cannot convert 1.5 to int without rounding")
+	})
+
+	t.Run("should return error for invalid values", func(t *testing.T) {
+		_, err := NewCast(map[string]domain.NativeType{"name": domain.NativeTypeInt}).Transform(productsFixture())
+
+		assert.EqualError(t, err, `record 0 (products.json): column name: cannot parse "  Green   Apple " as int`)
+	})
+
+	t.Run("should return error for unsupported conversions", func(t *testing.T) {
+		_, err := NewCast(map[string]domain.NativeType{"updated": domain.NativeTypeBool}).Transform(productsFixture())
+		assert.EqualError(t, err, "column updated: cannot convert date to bool")
+
+		_, err = NewCast(map[string]domain.NativeType{"stock": "decimal"}).Transform(productsFixture())
+		assert.EqualError(t, err, "column stock: cannot convert int to decimal")
+	})
+}
+
+func TestFillNull_Transform(t *testing.T) {
+	t.Run("should fill null single values and array elements", func(t *testing.T) {
+		fill := NewFillNull(map[string]any{
+			"price":   float64(0),
+			"updated": "2024-01-01",
+			"tags":    "none",
+		})
+
+		result, err := fill.Transform(productsFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.FloatValue(1.5), result.First().Get("price"))
+		assert.Equal(t, domain.FloatValue(0), result.Get(1).Get("price"))
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), result.Get(1).GetDate("updated"))
+		assert.Equal(t, []domain.Value{domain.StringValue(" Fruit"), domain.StringValue("none")}, result.First().GetArray("tags"))
+	})
+
+	t.Run("should fill missing values", func(t *testing.T) {
+		input := productsFixture()
+		delete(input.First().Values, "stock")
+
+		result, err := NewFillNull(map[string]any{"stock": 0}).Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.IntValue(0), result.First().Get("stock"))
+		assert.Equal(t, domain.IntValue(250), result.Get(1).Get("stock"))
+	})
+
+	t.Run("should return error for unknown column or bad default", func(t *testing.T) {
+		_, err := NewFillNull(map[string]any{"nope": 1}).Transform(productsFixture())
+		assert.True(t, errors.Is(err, domain.ErrUnknownColumn))
+
+		_, err = NewFillNull(map[string]any{"stock": "many"}).Transform(productsFixture())
+		assert.EqualError(t, err, `column stock: cannot parse "many" as int`)
+	})
+}
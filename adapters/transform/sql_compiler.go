@@ -0,0 +1,1144 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// sqlRow holds one record per table of the FROM clause, in order. A record
+// is nil when a LEFT JOIN found no match.
+type sqlRow []*domain.Record
+
+// sqlContext is what an expression is evaluated against: a single row, or
+// a group of rows once GROUP BY or aggregates are involved.
+type sqlContext struct {
+	row   sqlRow
+	group []sqlRow
+}
+
+// sqlEval evaluates a compiled expression. NULL is returned as a nil Value.
+type sqlEval func(c *sqlContext) (domain.Value, error)
+
+// sqlType is the static type of an expression. A nil typ is the type of
+// NULL, which is compatible with every other type.
+type sqlType struct {
+	typ   domain.SchemaType
+	array bool
+}
+
+var (
+	sqlNullType   = sqlType{}
+	sqlStringType = sqlType{typ: domain.NativeTypeString}
+	sqlIntType    = sqlType{typ: domain.NativeTypeInt}
+	sqlFloatType  = sqlType{typ: domain.NativeTypeFloat}
+	sqlDateType   = sqlType{typ: domain.NativeTypeDate}
+	sqlBoolType   = sqlType{typ: domain.NativeTypeBool}
+)
+
+func (t sqlType) isNull() bool                     { return t.typ == nil }
+func (t sqlType) is(native domain.NativeType) bool { return !t.array && t.typ == native }
+func (t sqlType) numeric() bool {
+	return t.is(domain.NativeTypeInt) || t.is(domain.NativeTypeFloat)
+}
+
+// accepts reports whether a value of type other fits this type, NULL and
+// int to float widening included.
+func (t sqlType) accepts(other sqlType) bool {
+	return other.isNull() || t == other || (t.is(domain.NativeTypeFloat) && other.is(domain.NativeTypeInt))
+}
+
+// holds reports whether v is a value of type t, ints being accepted as
+// floats. Records may hold values not matching their schema, which
+// evaluation reports instead of panicking.
+func (t sqlType) holds(v domain.Value) bool {
+	if t.array {
+		_, ok := v.(domain.ArrayValue)
+		return ok
+	}
+	switch t.typ {
+	case domain.NativeTypeString:
+		_, ok := v.(domain.StringValue)
+		return ok
+	case domain.NativeTypeInt:
+		_, ok := v.(domain.IntValue)
+		return ok
+	case domain.NativeTypeFloat:
+		switch v.(type) {
+		case domain.IntValue, domain.FloatValue:
+			return true
+		}
+		return false
+	case domain.NativeTypeDate:
+		_, ok := v.(domain.DateValue)
+		return ok
+	case domain.NativeTypeBool:
+		_, ok := v.(domain.BoolValue)
+		return ok
+	}
+	if _, ok := t.typ.(domain.CustomType); ok {
+		_, ok := v.(domain.RecordValue)
+		return ok
+	}
+	return true
+}
+
+func (t sqlType) String() string {
+	switch {
+	case t.isNull():
+		return "null"
+	case t.array:
+		return "[]" + t.typ.GetTypeName()
+	default:
+		return t.typ.GetTypeName()
+	}
+}
+
+// sqlTable is a table of the FROM clause.
+type sqlTable struct {
+	alias  string
+	schema *domain.DataSchema
+	set    *domain.RecordSet // Records of the table, to report record errors
+}
+
+// sqlCompiler type-checks expressions against the tables in scope and
+// compiles them to sqlEval functions.
+type sqlCompiler struct {
+	tables []sqlTable
+	// grouped is true when expressions are evaluated once per group. Columns
+	// must then be part of groupBy or used inside an aggregate.
+	grouped bool
+	groupBy []sqlNode
+	// clause names the clause being compiled, for error messages.
+	clause string
+}
+
+func (c *sqlCompiler) rowCompiler() *sqlCompiler {
+	return &sqlCompiler{tables: c.tables, clause: c.clause}
+}
+
+func (c *sqlCompiler) errorf(node sqlNode, format string, args ...any) error {
+	return &SQLError{Pos: node.position(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c *sqlCompiler) compile(node sqlNode) (sqlEval, sqlType, error) {
+	if c.grouped {
+		for _, key := range c.groupBy {
+			if key.String() == node.String() {
+				fn, typ, err := c.rowCompiler().compile(key)
+				if err != nil {
+					return nil, sqlNullType, err
+				}
+				return func(ctx *sqlContext) (domain.Value, error) {
+					if len(ctx.group) == 0 {
+						return nil, nil
+					}
+					return fn(&sqlContext{row: ctx.group[0]})
+				}, typ, nil
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *sqlLiteral:
+		value := n.value
+		typ := sqlNullType
+		if value != nil {
+			typ = sqlType{typ: value.GetType()}
+		}
+		return func(*sqlContext) (domain.Value, error) { return value, nil }, typ, nil
+	case *sqlColumn:
+		return c.column(n)
+	case *sqlUnary:
+		return c.unary(n)
+	case *sqlBinary:
+		return c.binary(n)
+	case *sqlIsNull:
+		return c.isNull(n)
+	case *sqlIn:
+		return c.in(n)
+	case *sqlBetween:
+		return c.between(n)
+	case *sqlCase:
+		return c.caseExpr(n)
+	case *sqlCall:
+		if _, ok := sqlAggregates[n.name]; ok {
+			return c.aggregate(n)
+		}
+		return c.call(n)
+	}
+	return nil, sqlNullType, c.errorf(node, "unsupported expression")
+}
+
+// resolve finds the table and column a column reference points to, and
+// returns the remaining nested column names.
+func (c *sqlCompiler) resolve(n *sqlColumn) (int, domain.SchemaColumn, []string, error) {
+	if len(n.parts) > 1 {
+		for i, t := range c.tables {
+			if t.alias == n.parts[0] {
+				col := t.schema.Column(n.parts[1])
+				if col == nil {
+					return 0, nil, nil, c.errorf(n, "unknown column %s in table %s", n.parts[1], t.alias)
+				}
+				return i, col, n.parts[2:], nil
+			}
+		}
+	}
+
+	found := -1
+	var col domain.SchemaColumn
+	for i, t := range c.tables {
+		if candidate := t.schema.Column(n.parts[0]); candidate != nil {
+			if found >= 0 {
+				return 0, nil, nil, c.errorf(n, "ambiguous column %s, qualify it with a table name", n.parts[0])
+			}
+			found, col = i, candidate
+		}
+	}
+	if found < 0 {
+		return 0, nil, nil, c.errorf(n, "unknown column %s", n.String())
+	}
+	return found, col, n.parts[1:], nil
+}
+
+func (c *sqlCompiler) column(n *sqlColumn) (sqlEval, sqlType, error) {
+	if c.grouped {
+		return nil, sqlNullType, c.errorf(n, "column %s must appear in GROUP BY or be used in an aggregate function", n)
+	}
+
+	table, col, nested, err := c.resolve(n)
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	typ := sqlType{typ: col.GetType(), array: col.IsArray()}
+	for _, field := range nested {
+		custom, ok := typ.typ.(domain.CustomType)
+		if typ.array || !ok || custom.Schema == nil {
+			return nil, sqlNullType, c.errorf(n, "column %s of type %s has no columns", col.GetID(), typ)
+		}
+		nestedCol := custom.Schema.Column(field)
+		if nestedCol == nil {
+			return nil, sqlNullType, c.errorf(n, "unknown column %s in %s", field, custom.Name)
+		}
+		typ = sqlType{typ: nestedCol.GetType(), array: nestedCol.IsArray()}
+	}
+
+	id := col.GetID()
+	set := c.tables[table].set
+	return func(ctx *sqlContext) (domain.Value, error) {
+		record := ctx.row[table]
+		if record == nil {
+			return nil, nil
+		}
+		v := sqlNullable(record.Values[id])
+		for _, field := range nested {
+			if v == nil {
+				return nil, nil
+			}
+			nestedRecord, ok := v.(domain.RecordValue)
+			if !ok {
+				return nil, sqlRecordError(set, record, fmt.Errorf("column %s: expected record, got %T", n, v))
+			}
+			if nestedRecord.Record == nil {
+				return nil, nil
+			}
+			v = sqlNullable(nestedRecord.Record.Values[field])
+		}
+		if v != nil && !typ.holds(v) {
+			return nil, sqlRecordError(set, record, fmt.Errorf("column %s: expected %s, got %T", n, typ, v))
+		}
+		return v, nil
+	}, typ, nil
+}
+
+// sqlRecordError wraps err for record, found by identity in the table it
+// was read from.
+func sqlRecordError(set *domain.RecordSet, record *domain.Record, err error) error {
+	index := -1
+	if set != nil {
+		for i, r := range set.Records {
+			if r == record {
+				index = i
+				break
+			}
+		}
+	}
+	return domain.NewRecordError(index, record, err)
+}
+
+func (c *sqlCompiler) unary(n *sqlUnary) (sqlEval, sqlType, error) {
+	operand, typ, err := c.compile(n.operand)
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+
+	if n.op == "NOT" {
+		if !typ.is(domain.NativeTypeBool) && !typ.isNull() {
+			return nil, sqlNullType, c.errorf(n, "NOT expects a boolean, got %s", typ)
+		}
+		return func(ctx *sqlContext) (domain.Value, error) {
+			v, err := operand(ctx)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			return !v.(domain.BoolValue), nil
+		}, sqlBoolType, nil
+	}
+
+	if !typ.numeric() && !typ.isNull() {
+		return nil, sqlNullType, c.errorf(n, "- expects a number, got %s", typ)
+	}
+	return func(ctx *sqlContext) (domain.Value, error) {
+		v, err := operand(ctx)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		if i, ok := v.(domain.IntValue); ok {
+			return -i, nil
+		}
+		return -v.(domain.FloatValue), nil
+	}, typ, nil
+}
+
+func (c *sqlCompiler) binary(n *sqlBinary) (sqlEval, sqlType, error) {
+	left, lt, err := c.compile(n.left)
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	right, rt, err := c.compile(n.right)
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	mismatch := c.errorf(n, "%s does not apply to %s and %s", n.op, lt, rt)
+
+	switch n.op {
+	case "AND", "OR":
+		if (!lt.is(domain.NativeTypeBool) && !lt.isNull()) || (!rt.is(domain.NativeTypeBool) && !rt.isNull()) {
+			return nil, sqlNullType, mismatch
+		}
+		// Three-valued logic: FALSE wins over NULL for AND, TRUE for OR.
+		decisive := domain.BoolValue(n.op == "OR")
+		return func(ctx *sqlContext) (domain.Value, error) {
+			l, err := left(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if l == decisive {
+				return decisive, nil
+			}
+			r, err := right(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if r == decisive {
+				return decisive, nil
+			}
+			if l == nil || r == nil {
+				return nil, nil
+			}
+			return !decisive, nil
+		}, sqlBoolType, nil
+
+	case "=", "<>", "<", "<=", ">", ">=":
+		if left, lt, right, rt, err = c.coerceDates(n, left, lt, right, rt); err != nil {
+			return nil, sqlNullType, err
+		}
+		if !sqlComparable(lt, rt, n.op == "=" || n.op == "<>") {
+			return nil, sqlNullType, mismatch
+		}
+		op := n.op
+		return func(ctx *sqlContext) (domain.Value, error) {
+			l, r, err := sqlEvalBoth(left, right, ctx)
+			if err != nil || l == nil || r == nil {
+				return nil, err
+			}
+			cmp := sqlCompare(l, r)
+			switch op {
+			case "=":
+				return domain.BoolValue(cmp == 0), nil
+			case "<>":
+				return domain.BoolValue(cmp != 0), nil
+			case "<":
+				return domain.BoolValue(cmp < 0), nil
+			case "<=":
+				return domain.BoolValue(cmp <= 0), nil
+			case ">":
+				return domain.BoolValue(cmp > 0), nil
+			default:
+				return domain.BoolValue(cmp >= 0), nil
+			}
+		}, sqlBoolType, nil
+
+	case "LIKE":
+		if (!lt.is(domain.NativeTypeString) && !lt.isNull()) || (!rt.is(domain.NativeTypeString) && !rt.isNull()) {
+			return nil, sqlNullType, mismatch
+		}
+		var pattern *regexp.Regexp
+		if lit, ok := n.right.(*sqlLiteral); ok && lit.value != nil {
+			pattern = likePattern(string(lit.value.(domain.StringValue)))
+		}
+		return func(ctx *sqlContext) (domain.Value, error) {
+			l, r, err := sqlEvalBoth(left, right, ctx)
+			if err != nil || l == nil || r == nil {
+				return nil, err
+			}
+			re := pattern
+			if re == nil {
+				re = likePattern(string(r.(domain.StringValue)))
+			}
+			return domain.BoolValue(re.MatchString(string(l.(domain.StringValue)))), nil
+		}, sqlBoolType, nil
+
+	case "||":
+		if (!lt.is(domain.NativeTypeString) && !lt.isNull()) || (!rt.is(domain.NativeTypeString) && !rt.isNull()) {
+			return nil, sqlNullType, mismatch
+		}
+		return func(ctx *sqlContext) (domain.Value, error) {
+			l, r, err := sqlEvalBoth(left, right, ctx)
+			if err != nil || l == nil || r == nil {
+				return nil, err
+			}
+			return l.(domain.StringValue) + r.(domain.StringValue), nil
+		}, sqlStringType, nil
+	}
+
+	typ, ok := sqlArithmeticType(n.op, lt, rt)
+	if !ok {
+		return nil, sqlNullType, mismatch
+	}
+	op := n.op
+	return func(ctx *sqlContext) (domain.Value, error) {
+		l, r, err := sqlEvalBoth(left, right, ctx)
+		if err != nil || l == nil || r == nil {
+			return nil, err
+		}
+		return sqlArithmetic(op, l, r)
+	}, typ, nil
+}
+
+// coerceDates parses a string literal compared with a date, so that
+// created_at > '2024-01-01' works as analysts expect.
+func (c *sqlCompiler) coerceDates(n sqlNode, left sqlEval, lt sqlType, right sqlEval, rt sqlType) (sqlEval, sqlType, sqlEval, sqlType, error) {
+	coerce := func(fn sqlEval) (sqlEval, error) {
+		v, err := fn(&sqlContext{})
+		if err != nil {
+			return nil, err
+		}
+		date, err := domain.Cast(v, domain.NativeTypeDate, domain.CastOptions{Mode: domain.CastLenient})
+		if err != nil {
+			return nil, c.errorf(n, "%s", err)
+		}
+		return func(*sqlContext) (domain.Value, error) { return date, nil }, nil
+	}
+
+	var err error
+	if lt.is(domain.NativeTypeDate) && rt.is(domain.NativeTypeString) && isLiteral(n, false) {
+		right, err = coerce(right)
+		rt = sqlDateType
+	} else if rt.is(domain.NativeTypeDate) && lt.is(domain.NativeTypeString) && isLiteral(n, true) {
+		left, err = coerce(left)
+		lt = sqlDateType
+	}
+	return left, lt, right, rt, err
+}
+
+// isLiteral reports whether the left or right operand of a comparison is a
+// literal.
+func isLiteral(n sqlNode, leftSide bool) bool {
+	b, ok := n.(*sqlBinary)
+	if !ok {
+		return false
+	}
+	operand := b.right
+	if leftSide {
+		operand = b.left
+	}
+	_, ok = operand.(*sqlLiteral)
+	return ok
+}
+
+func (c *sqlCompiler) isNull(n *sqlIsNull) (sqlEval, sqlType, error) {
+	operand, _, err := c.compile(n.operand)
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	not := n.not
+	return func(ctx *sqlContext) (domain.Value, error) {
+		v, err := operand(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return domain.BoolValue((v == nil) != not), nil
+	}, sqlBoolType, nil
+}
+
+func (c *sqlCompiler) in(n *sqlIn) (sqlEval, sqlType, error) {
+	operand, typ, err := c.compile(n.operand)
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	list := make([]sqlEval, len(n.list))
+	for i, element := range n.list {
+		fn, et, err := c.compile(element)
+		if err != nil {
+			return nil, sqlNullType, err
+		}
+		if !sqlComparable(typ, et, true) {
+			return nil, sqlNullType, c.errorf(element, "cannot compare %s with %s", typ, et)
+		}
+		list[i] = fn
+	}
+
+	not := n.not
+	return func(ctx *sqlContext) (domain.Value, error) {
+		v, err := operand(ctx)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		sawNull := false
+		for _, fn := range list {
+			element, err := fn(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if element == nil {
+				sawNull = true
+				continue
+			}
+			if sqlCompare(v, element) == 0 {
+				return domain.BoolValue(!not), nil
+			}
+		}
+		if sawNull {
+			return nil, nil
+		}
+		return domain.BoolValue(not), nil
+	}, sqlBoolType, nil
+}
+
+func (c *sqlCompiler) between(n *sqlBetween) (sqlEval, sqlType, error) {
+	ge, _, err := c.compile(&sqlBinary{pos: n.pos, op: ">=", left: n.operand, right: n.lo})
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	le, _, err := c.compile(&sqlBinary{pos: n.pos, op: "<=", left: n.operand, right: n.hi})
+	if err != nil {
+		return nil, sqlNullType, err
+	}
+	not := n.not
+	return func(ctx *sqlContext) (domain.Value, error) {
+		l, r, err := sqlEvalBoth(ge, le, ctx)
+		if err != nil || l == nil || r == nil {
+			return nil, err
+		}
+		return domain.BoolValue((l.(domain.BoolValue) && r.(domain.BoolValue)) != domain.BoolValue(not)), nil
+	}, sqlBoolType, nil
+}
+
+func (c *sqlCompiler) caseExpr(n *sqlCase) (sqlEval, sqlType, error) {
+	type branch struct{ cond, then sqlEval }
+	branches := make([]branch, len(n.whens))
+	typ := sqlNullType
+	unify := func(node sqlNode, t sqlType) error {
+		switch {
+		case typ.accepts(t):
+		case t.accepts(typ):
+			typ = t
+		default:
+			return c.errorf(node, "CASE branches have different types %s and %s", typ, t)
+		}
+		return nil
+	}
+
+	for i, w := range n.whens {
+		cond, ct, err := c.compile(w.cond)
+		if err != nil {
+			return nil, sqlNullType, err
+		}
+		if !ct.is(domain.NativeTypeBool) && !ct.isNull() {
+			return nil, sqlNullType, c.errorf(w.cond, "WHEN expects a boolean, got %s", ct)
+		}
+		then, tt, err := c.compile(w.then)
+		if err != nil {
+			return nil, sqlNullType, err
+		}
+		if err := unify(w.then, tt); err != nil {
+			return nil, sqlNullType, err
+		}
+		branches[i] = branch{cond: cond, then: then}
+	}
+
+	var els sqlEval
+	if n.els != nil {
+		var et sqlType
+		var err error
+		if els, et, err = c.compile(n.els); err != nil {
+			return nil, sqlNullType, err
+		}
+		if err := unify(n.els, et); err != nil {
+			return nil, sqlNullType, err
+		}
+	}
+
+	widen := typ.is(domain.NativeTypeFloat)
+	return func(ctx *sqlContext) (domain.Value, error) {
+		result := els
+		for _, b := range branches {
+			cond, err := b.cond(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if cond == domain.BoolValue(true) {
+				result = b.then
+				break
+			}
+		}
+		if result == nil {
+			return nil, nil
+		}
+		v, err := result(ctx)
+		if err != nil || v == nil || !widen {
+			return v, err
+		}
+		return domain.FloatValue(sqlToFloat(v)), nil
+	}, typ, nil
+}
+
+// sqlAggregate accumulates the non-NULL values of a group.
+type sqlAggregate struct {
+	check  func(arg sqlType) (sqlType, bool)
+	reduce func(values []domain.Value, result sqlType) domain.Value
+}
+
+var sqlAggregates = map[string]sqlAggregate{
+	"COUNT": {
+		check: func(sqlType) (sqlType, bool) { return sqlIntType, true },
+		reduce: func(values []domain.Value, _ sqlType) domain.Value {
+			return domain.IntValue(len(values))
+		},
+	},
+	"SUM": {
+		check: func(arg sqlType) (sqlType, bool) { return arg, arg.numeric() },
+		reduce: func(values []domain.Value, result sqlType) domain.Value {
+			if len(values) == 0 {
+				return nil
+			}
+			if result.is(domain.NativeTypeInt) {
+				var sum domain.IntValue
+				for _, v := range values {
+					sum += v.(domain.IntValue)
+				}
+				return sum
+			}
+			var sum float64
+			for _, v := range values {
+				sum += sqlToFloat(v)
+			}
+			return domain.FloatValue(sum)
+		},
+	},
+	"AVG": {
+		check: func(arg sqlType) (sqlType, bool) { return sqlFloatType, arg.numeric() },
+		reduce: func(values []domain.Value, _ sqlType) domain.Value {
+			if len(values) == 0 {
+				return nil
+			}
+			var sum float64
+			for _, v := range values {
+				sum += sqlToFloat(v)
+			}
+			return domain.FloatValue(sum / float64(len(values)))
+		},
+	},
+	"MIN": {
+		check: func(arg sqlType) (sqlType, bool) { return arg, sqlComparable(arg, arg, false) },
+		reduce: func(values []domain.Value, _ sqlType) domain.Value {
+			return sqlExtreme(values, -1)
+		},
+	},
+	"MAX": {
+		check: func(arg sqlType) (sqlType, bool) { return arg, sqlComparable(arg, arg, false) },
+		reduce: func(values []domain.Value, _ sqlType) domain.Value {
+			return sqlExtreme(values, 1)
+		},
+	},
+}
+
+func (c *sqlCompiler) aggregate(n *sqlCall) (sqlEval, sqlType, error) {
+	if !c.grouped {
+		return nil, sqlNullType, c.errorf(n, "aggregate function %s is not allowed in %s", n.name, c.clause)
+	}
+	agg := sqlAggregates[n.name]
+
+	var arg sqlEval
+	argType := sqlIntType
+	switch {
+	case n.star && n.name == "COUNT":
+	case n.star:
+		return nil, sqlNullType, c.errorf(n, "%s(*) is not supported", n.name)
+	case len(n.args) != 1:
+		return nil, sqlNullType, c.errorf(n, "%s expects 1 argument, got %d", n.name, len(n.args))
+	default:
+		inner := c.rowCompiler()
+		inner.clause = "aggregate functions"
+		var err error
+		if arg, argType, err = inner.compile(n.args[0]); err != nil {
+			return nil, sqlNullType, err
+		}
+	}
+
+	typ, ok := agg.check(argType)
+	if !ok {
+		return nil, sqlNullType, c.errorf(n, "%s does not apply to %s", n.name, argType)
+	}
+
+	distinct := n.distinct
+	return func(ctx *sqlContext) (domain.Value, error) {
+		values := make([]domain.Value, 0, len(ctx.group))
+		seen := make(map[string]bool)
+		for _, row := range ctx.group {
+			v := domain.Value(domain.BoolValue(true))
+			if arg != nil {
+				var err error
+				if v, err = arg(&sqlContext{row: row}); err != nil {
+					return nil, err
+				}
+			}
+			if v == nil {
+				continue
+			}
+			if distinct {
+				key := sqlKey([]domain.Value{v})
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			values = append(values, v)
+		}
+		return agg.reduce(values, typ), nil
+	}, typ, nil
+}
+
+// sqlFunction is a scalar function. Unless nullable is set, a NULL argument
+// yields NULL without calling it.
+type sqlFunction struct {
+	minArgs, maxArgs int
+	nullable         bool
+	check            func(args []sqlType) (sqlType, error)
+	call             func(args []domain.Value, result sqlType) (domain.Value, error)
+}
+
+func sqlSignature(result sqlType, kinds ...func(sqlType) bool) func([]sqlType) (sqlType, error) {
+	return func(args []sqlType) (sqlType, error) {
+		for i, arg := range args {
+			if !arg.isNull() && !kinds[min(i, len(kinds)-1)](arg) {
+				return sqlNullType, fmt.Errorf("argument %d has unexpected type %s", i+1, arg)
+			}
+		}
+		return result, nil
+	}
+}
+
+func sqlIsString(t sqlType) bool { return t.is(domain.NativeTypeString) }
+func sqlIsInt(t sqlType) bool    { return t.is(domain.NativeTypeInt) }
+
+var sqlFunctions = map[string]sqlFunction{
+	"LOWER": {1, 1, false, sqlSignature(sqlStringType, sqlIsString), func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		return domain.StringValue(strings.ToLower(string(args[0].(domain.StringValue)))), nil
+	}},
+	"UPPER": {1, 1, false, sqlSignature(sqlStringType, sqlIsString), func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		return domain.StringValue(strings.ToUpper(string(args[0].(domain.StringValue)))), nil
+	}},
+	"TRIM": {1, 1, false, sqlSignature(sqlStringType, sqlIsString), func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		return domain.StringValue(strings.TrimSpace(string(args[0].(domain.StringValue)))), nil
+	}},
+	"LENGTH": {1, 1, false, sqlSignature(sqlIntType, sqlIsString), func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		return domain.IntValue(utf8.RuneCountInString(string(args[0].(domain.StringValue)))), nil
+	}},
+	"SUBSTR": {2, 3, false, sqlSignature(sqlStringType, sqlIsString, sqlIsInt), func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		// SQL positions start at 1.
+		runes := []rune(string(args[0].(domain.StringValue)))
+		start := max(0, min(int(args[1].(domain.IntValue))-1, len(runes)))
+		end := len(runes)
+		if len(args) == 3 {
+			end = max(start, min(start+int(args[2].(domain.IntValue)), len(runes)))
+		}
+		return domain.StringValue(runes[start:end]), nil
+	}},
+	"ABS": {1, 1, false, func(args []sqlType) (sqlType, error) {
+		return args[0], sqlCheck(args[0].numeric() || args[0].isNull(), "ABS expects a number")
+	}, func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		if i, ok := args[0].(domain.IntValue); ok {
+			if i < 0 {
+				return -i, nil
+			}
+			return i, nil
+		}
+		return domain.FloatValue(math.Abs(sqlToFloat(args[0]))), nil
+	}},
+	"ROUND": {1, 2, false, func(args []sqlType) (sqlType, error) {
+		if err := sqlCheck(args[0].numeric() || args[0].isNull(), "ROUND expects a number"); err != nil {
+			return sqlNullType, err
+		}
+		if len(args) == 2 && !args[1].is(domain.NativeTypeInt) && !args[1].isNull() {
+			return sqlNullType, fmt.Errorf("ROUND digits must be an integer")
+		}
+		return args[0], nil
+	}, func(args []domain.Value, _ sqlType) (domain.Value, error) {
+		if _, ok := args[0].(domain.IntValue); ok {
+			return args[0], nil
+		}
+		scale := 1.0
+		if len(args) == 2 {
+			scale = math.Pow(10, float64(args[1].(domain.IntValue)))
+		}
+		return domain.FloatValue(math.Round(sqlToFloat(args[0])*scale) / scale), nil
+	}},
+	"COALESCE": {1, -1, true, func(args []sqlType) (sqlType, error) {
+		result := sqlNullType
+		for _, arg := range args {
+			switch {
+			case result.accepts(arg):
+			case arg.accepts(result):
+				result = arg
+			default:
+				return sqlNullType, fmt.Errorf("arguments have different types %s and %s", result, arg)
+			}
+		}
+		return result, nil
+	}, func(args []domain.Value, result sqlType) (domain.Value, error) {
+		for _, arg := range args {
+			if arg == nil {
+				continue
+			}
+			if result.is(domain.NativeTypeFloat) {
+				return domain.FloatValue(sqlToFloat(arg)), nil
+			}
+			return arg, nil
+		}
+		return nil, nil
+	}},
+}
+
+func sqlCheck(ok bool, msg string) error {
+	if ok {
+		return nil
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func (c *sqlCompiler) call(n *sqlCall) (sqlEval, sqlType, error) {
+	fn, ok := sqlFunctions[n.name]
+	if !ok {
+		return nil, sqlNullType, c.errorf(n, "unknown function %s", n.name)
+	}
+	if n.star || n.distinct {
+		return nil, sqlNullType, c.errorf(n, "%s does not accept * or DISTINCT", n.name)
+	}
+	if len(n.args) < fn.minArgs || (fn.maxArgs >= 0 && len(n.args) > fn.maxArgs) {
+		return nil, sqlNullType, c.errorf(n, "%s does not accept %d arguments", n.name, len(n.args))
+	}
+
+	args := make([]sqlEval, len(n.args))
+	types := make([]sqlType, len(n.args))
+	for i, arg := range n.args {
+		var err error
+		if args[i], types[i], err = c.compile(arg); err != nil {
+			return nil, sqlNullType, err
+		}
+	}
+	typ, err := fn.check(types)
+	if err != nil {
+		return nil, sqlNullType, c.errorf(n, "%s: %s", n.name, err)
+	}
+
+	return func(ctx *sqlContext) (domain.Value, error) {
+		values := make([]domain.Value, len(args))
+		for i, arg := range args {
+			v, err := arg(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && !fn.nullable {
+				return nil, nil
+			}
+			values[i] = v
+		}
+		return fn.call(values, typ)
+	}, typ, nil
+}
+
+// hasAggregate reports whether the expression calls an aggregate function.
+func hasAggregate(node sqlNode) bool {
+	switch n := node.(type) {
+	case *sqlCall:
+		if _, ok := sqlAggregates[n.name]; ok {
+			return true
+		}
+		for _, arg := range n.args {
+			if hasAggregate(arg) {
+				return true
+			}
+		}
+	case *sqlUnary:
+		return hasAggregate(n.operand)
+	case *sqlBinary:
+		return hasAggregate(n.left) || hasAggregate(n.right)
+	case *sqlIsNull:
+		return hasAggregate(n.operand)
+	case *sqlIn:
+		if hasAggregate(n.operand) {
+			return true
+		}
+		for _, element := range n.list {
+			if hasAggregate(element) {
+				return true
+			}
+		}
+	case *sqlBetween:
+		return hasAggregate(n.operand) || hasAggregate(n.lo) || hasAggregate(n.hi)
+	case *sqlCase:
+		for _, w := range n.whens {
+			if hasAggregate(w.cond) || hasAggregate(w.then) {
+				return true
+			}
+		}
+		return n.els != nil && hasAggregate(n.els)
+	}
+	return false
+}
+
+func sqlComparable(a, b sqlType, equality bool) bool {
+	if a.isNull() || b.isNull() {
+		return true
+	}
+	if a.numeric() && b.numeric() {
+		return true
+	}
+	if a != b || a.array || !a.typ.IsNative() {
+		return false
+	}
+	return equality || !a.is(domain.NativeTypeBool)
+}
+
+func sqlArithmeticType(op string, lt, rt sqlType) (sqlType, bool) {
+	if lt.isNull() {
+		lt = rt
+	}
+	if rt.isNull() {
+		rt = lt
+	}
+	switch {
+	case lt.isNull():
+		return sqlNullType, true
+	case op == "%":
+		return sqlIntType, lt.is(domain.NativeTypeInt) && rt.is(domain.NativeTypeInt)
+	case lt.is(domain.NativeTypeInt) && rt.is(domain.NativeTypeInt):
+		return sqlIntType, true
+	case lt.numeric() && rt.numeric():
+		return sqlFloatType, true
+	}
+	return sqlNullType, false
+}
+
+func sqlArithmetic(op string, l, r domain.Value) (domain.Value, error) {
+	li, lok := l.(domain.IntValue)
+	ri, rok := r.(domain.IntValue)
+	if lok && rok {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return li / ri, nil
+		}
+		return li % ri, nil
+	}
+
+	lf, rf := sqlToFloat(l), sqlToFloat(r)
+	switch op {
+	case "+":
+		return domain.FloatValue(lf + rf), nil
+	case "-":
+		return domain.FloatValue(lf - rf), nil
+	case "*":
+		return domain.FloatValue(lf * rf), nil
+	}
+	if rf == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return domain.FloatValue(lf / rf), nil
+}
+
+func sqlEvalBoth(left, right sqlEval, ctx *sqlContext) (domain.Value, domain.Value, error) {
+	l, err := left(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := right(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+func sqlNullable(v domain.Value) domain.Value {
+	if v == nil || v.IsNull() {
+		return nil
+	}
+	return v
+}
+
+func sqlToFloat(v domain.Value) float64 {
+	switch n := v.(type) {
+	case domain.IntValue:
+		return float64(n)
+	case domain.FloatValue:
+		return float64(n)
+	}
+	return math.NaN()
+}
+
+// sqlCompare orders two non-NULL values of comparable types.
+func sqlCompare(a, b domain.Value) int {
+	switch av := a.(type) {
+	case domain.StringValue:
+		return strings.Compare(string(av), string(b.(domain.StringValue)))
+	case domain.DateValue:
+		return time.Time(av).Compare(time.Time(b.(domain.DateValue)))
+	case domain.BoolValue:
+		bv := b.(domain.BoolValue)
+		switch {
+		case av == bv:
+			return 0
+		case !bool(av):
+			return -1
+		}
+		return 1
+	}
+	if ai, ok := a.(domain.IntValue); ok {
+		if bi, ok := b.(domain.IntValue); ok {
+			switch {
+			case ai < bi:
+				return -1
+			case ai > bi:
+				return 1
+			}
+			return 0
+		}
+	}
+	af, bf := sqlToFloat(a), sqlToFloat(b)
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+func sqlExtreme(values []domain.Value, sign int) domain.Value {
+	var result domain.Value
+	for _, v := range values {
+		if result == nil || sqlCompare(v, result)*sign > 0 {
+			result = v
+		}
+	}
+	return result
+}
+
+// sqlKey encodes values so that equal values, 1 and 1.0 included, give equal
+// keys. It is used for GROUP BY, DISTINCT and COUNT(DISTINCT). Ints are
+// encoded exactly, and floats holding an integral value that an int64
+// represents exactly get the key of that int. Arrays and records are encoded
+// by their content, and dates by their instant.
+func sqlKey(values []domain.Value) string {
+	var b strings.Builder
+	for _, v := range values {
+		writeSQLKey(&b, v)
+	}
+	return b.String()
+}
+
+func writeSQLKey(b *strings.Builder, v domain.Value) {
+	switch n := sqlNullable(v).(type) {
+	case nil:
+		b.WriteString("n")
+	case domain.IntValue:
+		fmt.Fprintf(b, "i%d", int64(n))
+	case domain.FloatValue:
+		f := float64(n)
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			fmt.Fprintf(b, "i%d", int64(f))
+		} else {
+			fmt.Fprintf(b, "f%v", f)
+		}
+	case domain.StringValue:
+		fmt.Fprintf(b, "s%q", string(n))
+	case domain.BoolValue:
+		fmt.Fprintf(b, "b%t", bool(n))
+	case domain.DateValue:
+		t := time.Time(n)
+		fmt.Fprintf(b, "d%d.%d", t.Unix(), t.Nanosecond())
+	case domain.ArrayValue:
+		fmt.Fprintf(b, "a%d[", len(n.Elements))
+		for _, element := range n.Elements {
+			writeSQLKey(b, element)
+		}
+		b.WriteByte(']')
+	case domain.RecordValue:
+		if n.Record == nil {
+			b.WriteString("n")
+			break
+		}
+		ids := make([]string, 0, len(n.Record.Values))
+		for id, value := range n.Record.Values {
+			if sqlNullable(value) != nil {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		fmt.Fprintf(b, "r%d{", len(ids))
+		for _, id := range ids {
+			fmt.Fprintf(b, "%q", id)
+			writeSQLKey(b, n.Record.Values[id])
+		}
+		b.WriteByte('}')
+	default:
+		fmt.Fprintf(b, "%T%q", v, fmt.Sprint(v))
+	}
+	b.WriteByte(0)
+}
+
+// likePattern converts a LIKE pattern, where % matches any sequence and _
+// any character, to an anchored regular expression.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,933 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlEOF sqlTokenKind = iota
+	sqlIdent
+	sqlQuotedIdent
+	sqlInt
+	sqlFloat
+	sqlString
+	sqlOp
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string // Identifier, operator, unquoted string or number literal
+	pos  int    // Byte offset in the query
+}
+
+// keyword returns the uppercase text of an unquoted identifier, which is
+// how keywords are matched.
+func (t sqlToken) keyword() string {
+	if t.kind != sqlIdent {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+var sqlOperators = []string{"<=", ">=", "<>", "!=", "||", "=", "<", ">", "+", "-", "*", "/", "%", "(", ")", ",", ".", ";"}
+
+// sqlReserved are the keywords that cannot be used as implicit aliases.
+var sqlReserved = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "AS": true, "JOIN": true, "INNER": true, "LEFT": true,
+	"OUTER": true, "ON": true, "WHERE": true, "GROUP": true, "BY": true, "HAVING": true, "ORDER": true,
+	"ASC": true, "DESC": true, "LIMIT": true, "OFFSET": true, "AND": true, "OR": true, "NOT": true,
+	"IS": true, "NULL": true, "LIKE": true, "IN": true, "BETWEEN": true, "CASE": true, "WHEN": true,
+	"THEN": true, "ELSE": true, "END": true, "TRUE": true, "FALSE": true,
+}
+
+func sqlTokenize(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	i := 0
+	for i < len(query) {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(query) {
+				r, size := utf8.DecodeRuneInString(query[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: query[start:i], pos: start})
+
+		case r >= '0' && r <= '9':
+			start := i
+			kind := sqlInt
+			for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+				i++
+			}
+			if i+1 < len(query) && query[i] == '.' && query[i+1] >= '0' && query[i+1] <= '9' {
+				kind = sqlFloat
+				i++
+				for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+					i++
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: query[start:i], pos: start})
+
+		case r == '\'' || r == '"':
+			// Quotes are escaped by doubling them, as in standard SQL.
+			var b strings.Builder
+			start := i
+			i++
+			for {
+				if i >= len(query) {
+					return nil, &SQLError{Pos: start, Msg: "unterminated quoted text"}
+				}
+				if query[i] == byte(r) {
+					if i+1 < len(query) && query[i+1] == byte(r) {
+						b.WriteByte(byte(r))
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(query[i])
+				i++
+			}
+			kind := sqlString
+			if r == '"' {
+				kind = sqlQuotedIdent
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: b.String(), pos: start})
+
+		default:
+			op := ""
+			for _, candidate := range sqlOperators {
+				if strings.HasPrefix(query[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SQLError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlEOF, pos: len(query)}), nil
+}
+
+// sqlNode is an expression of the syntax tree. String returns a normalized
+// form, used to match SELECT and HAVING expressions with GROUP BY ones.
+type sqlNode interface {
+	position() int
+	String() string
+}
+
+type sqlLiteral struct {
+	pos   int
+	value domain.Value // Nil for NULL
+}
+
+// sqlColumn is a column reference, optionally qualified by a table and
+// followed by nested record columns.
+type sqlColumn struct {
+	pos   int
+	parts []string
+}
+
+type sqlUnary struct {
+	pos     int
+	op      string // "-" or "NOT"
+	operand sqlNode
+}
+
+type sqlBinary struct {
+	pos         int
+	op          string // Arithmetic, comparison, "||", "AND", "OR" or "LIKE"
+	left, right sqlNode
+}
+
+type sqlIsNull struct {
+	pos     int
+	operand sqlNode
+	not     bool
+}
+
+type sqlIn struct {
+	pos     int
+	operand sqlNode
+	list    []sqlNode
+	not     bool
+}
+
+type sqlBetween struct {
+	pos             int
+	operand, lo, hi sqlNode
+	not             bool
+}
+
+type sqlWhen struct {
+	cond, then sqlNode
+}
+
+type sqlCase struct {
+	pos   int
+	whens []sqlWhen
+	els   sqlNode // Nil when there is no ELSE
+}
+
+type sqlCall struct {
+	pos      int
+	name     string // Uppercase function name
+	args     []sqlNode
+	star     bool // COUNT(*)
+	distinct bool // COUNT(DISTINCT x)
+}
+
+func (n *sqlLiteral) position() int { return n.pos }
+func (n *sqlColumn) position() int  { return n.pos }
+func (n *sqlUnary) position() int   { return n.pos }
+func (n *sqlBinary) position() int  { return n.pos }
+func (n *sqlIsNull) position() int  { return n.pos }
+func (n *sqlIn) position() int      { return n.pos }
+func (n *sqlBetween) position() int { return n.pos }
+func (n *sqlCase) position() int    { return n.pos }
+func (n *sqlCall) position() int    { return n.pos }
+
+func (n *sqlLiteral) String() string {
+	switch v := n.value.(type) {
+	case nil:
+		return "NULL"
+	case domain.StringValue:
+		return "'" + strings.ReplaceAll(string(v), "'", "''") + "'"
+	case domain.BoolValue:
+		return strings.ToUpper(strconv.FormatBool(bool(v)))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (n *sqlColumn) String() string { return strings.Join(n.parts, ".") }
+
+func (n *sqlUnary) String() string {
+	if n.op == "NOT" {
+		return "NOT " + n.operand.String()
+	}
+	return n.op + n.operand.String()
+}
+
+func (n *sqlBinary) String() string {
+	return "(" + n.left.String() + " " + n.op + " " + n.right.String() + ")"
+}
+
+func (n *sqlIsNull) String() string {
+	if n.not {
+		return n.operand.String() + " IS NOT NULL"
+	}
+	return n.operand.String() + " IS NULL"
+}
+
+func (n *sqlIn) String() string {
+	op := " IN ("
+	if n.not {
+		op = " NOT IN ("
+	}
+	return n.operand.String() + op + joinNodes(n.list) + ")"
+}
+
+func (n *sqlBetween) String() string {
+	op := " BETWEEN "
+	if n.not {
+		op = " NOT BETWEEN "
+	}
+	return n.operand.String() + op + n.lo.String() + " AND " + n.hi.String()
+}
+
+func (n *sqlCase) String() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, w := range n.whens {
+		b.WriteString(" WHEN " + w.cond.String() + " THEN " + w.then.String())
+	}
+	if n.els != nil {
+		b.WriteString(" ELSE " + n.els.String())
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+func (n *sqlCall) String() string {
+	switch {
+	case n.star:
+		return n.name + "(*)"
+	case n.distinct:
+		return n.name + "(DISTINCT " + joinNodes(n.args) + ")"
+	default:
+		return n.name + "(" + joinNodes(n.args) + ")"
+	}
+}
+
+func joinNodes(nodes []sqlNode) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sqlSelect is a parsed SELECT statement.
+type sqlSelect struct {
+	distinct bool
+	items    []sqlItem
+	from     sqlTableRef
+	joins    []sqlJoin
+	where    sqlNode
+	groupBy  []sqlNode
+	having   sqlNode
+	orderBy  []sqlOrder
+	limit    int // -1 when there is no LIMIT
+	offset   int
+}
+
+type sqlItem struct {
+	pos   int
+	expr  sqlNode // Nil for * and table.*
+	alias string
+	star  string // Table of table.*, empty for * or an expression
+	all   bool   // True for * and table.*
+}
+
+type sqlTableRef struct {
+	pos   int
+	name  string
+	alias string
+}
+
+type sqlJoin struct {
+	table sqlTableRef
+	left  bool
+	on    sqlNode
+}
+
+type sqlOrder struct {
+	expr sqlNode
+	desc bool
+}
+
+type sqlParser struct {
+	tokens []sqlToken
+	i      int
+}
+
+func parseSQL(query string) (*sqlSelect, error) {
+	tokens, err := sqlTokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens}
+	stmt, err := p.selectStatement()
+	if err != nil {
+		return nil, err
+	}
+	p.acceptOp(";")
+	if tok := p.peek(); tok.kind != sqlEOF {
+		return nil, p.unexpected(tok)
+	}
+	return stmt, nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.i]
+}
+
+func (p *sqlParser) next() sqlToken {
+	tok := p.tokens[p.i]
+	if tok.kind != sqlEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *sqlParser) acceptKeyword(keywords ...string) (sqlToken, bool) {
+	tok := p.peek()
+	for _, k := range keywords {
+		if tok.keyword() == k {
+			p.next()
+			return tok, true
+		}
+	}
+	return tok, false
+}
+
+func (p *sqlParser) acceptOp(ops ...string) (sqlToken, bool) {
+	tok := p.peek()
+	if tok.kind != sqlOp {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.next()
+			return tok, true
+		}
+	}
+	return tok, false
+}
+
+func (p *sqlParser) expectKeyword(keyword string) error {
+	if _, ok := p.acceptKeyword(keyword); !ok {
+		return p.expected(keyword)
+	}
+	return nil
+}
+
+func (p *sqlParser) expectOp(op string) error {
+	if _, ok := p.acceptOp(op); !ok {
+		return p.expected(op)
+	}
+	return nil
+}
+
+func (p *sqlParser) expected(what string) error {
+	tok := p.peek()
+	if tok.kind == sqlEOF {
+		return &SQLError{Pos: tok.pos, Msg: fmt.Sprintf("expected %s, got end of query", what)}
+	}
+	return &SQLError{Pos: tok.pos, Msg: fmt.Sprintf("expected %s, got %q", what, tok.text)}
+}
+
+func (p *sqlParser) unexpected(tok sqlToken) error {
+	if tok.kind == sqlEOF {
+		return &SQLError{Pos: tok.pos, Msg: "unexpected end of query"}
+	}
+	return &SQLError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+// identifier reads a column, table or alias name.
+func (p *sqlParser) identifier(what string) (sqlToken, error) {
+	tok := p.peek()
+	if tok.kind == sqlQuotedIdent || (tok.kind == sqlIdent && !sqlReserved[tok.keyword()]) {
+		return p.next(), nil
+	}
+	return tok, p.expected(what)
+}
+
+// alias reads an optional alias, with or without AS.
+func (p *sqlParser) alias() (string, error) {
+	if _, ok := p.acceptKeyword("AS"); ok {
+		tok, err := p.identifier("alias")
+		return tok.text, err
+	}
+	if tok := p.peek(); tok.kind == sqlQuotedIdent || (tok.kind == sqlIdent && !sqlReserved[tok.keyword()]) {
+		return p.next().text, nil
+	}
+	return "", nil
+}
+
+func (p *sqlParser) integer(what string) (int, error) {
+	tok := p.peek()
+	if tok.kind != sqlInt {
+		return 0, p.expected(what)
+	}
+	p.next()
+	n, err := strconv.Atoi(tok.text)
+	if err != nil {
+		return 0, &SQLError{Pos: tok.pos, Msg: fmt.Sprintf("invalid %s %s", what, tok.text)}
+	}
+	return n, nil
+}
+
+func (p *sqlParser) selectStatement() (*sqlSelect, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	stmt := &sqlSelect{limit: -1}
+	_, stmt.distinct = p.acceptKeyword("DISTINCT")
+
+	for {
+		item, err := p.selectItem()
+		if err != nil {
+			return nil, err
+		}
+		stmt.items = append(stmt.items, item)
+		if _, ok := p.acceptOp(","); !ok {
+			break
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	from, err := p.tableRef()
+	if err != nil {
+		return nil, err
+	}
+	stmt.from = from
+
+	for {
+		join := sqlJoin{}
+		if _, ok := p.acceptKeyword("LEFT"); ok {
+			join.left = true
+			p.acceptKeyword("OUTER")
+		} else {
+			p.acceptKeyword("INNER")
+		}
+		if _, ok := p.acceptKeyword("JOIN"); !ok {
+			if join.left {
+				return nil, p.expected("JOIN")
+			}
+			break
+		}
+		if join.table, err = p.tableRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("ON"); err != nil {
+			return nil, err
+		}
+		if join.on, err = p.expr(); err != nil {
+			return nil, err
+		}
+		stmt.joins = append(stmt.joins, join)
+	}
+
+	if _, ok := p.acceptKeyword("WHERE"); ok {
+		if stmt.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := p.acceptKeyword("GROUP"); ok {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if stmt.groupBy, err = p.exprList(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := p.acceptKeyword("HAVING"); ok {
+		if stmt.having, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := p.acceptKeyword("ORDER"); ok {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			order := sqlOrder{expr: expr}
+			if _, ok := p.acceptKeyword("DESC"); ok {
+				order.desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			stmt.orderBy = append(stmt.orderBy, order)
+			if _, ok := p.acceptOp(","); !ok {
+				break
+			}
+		}
+	}
+
+	if _, ok := p.acceptKeyword("LIMIT"); ok {
+		if stmt.limit, err = p.integer("LIMIT count"); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := p.acceptKeyword("OFFSET"); ok {
+		if stmt.offset, err = p.integer("OFFSET count"); err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *sqlParser) selectItem() (sqlItem, error) {
+	tok := p.peek()
+	if _, ok := p.acceptOp("*"); ok {
+		return sqlItem{pos: tok.pos, all: true}, nil
+	}
+
+	// table.*
+	if (tok.kind == sqlIdent || tok.kind == sqlQuotedIdent) && p.tokens[p.i+1].text == "." && p.tokens[p.i+2].text == "*" && p.tokens[p.i+2].kind == sqlOp {
+		p.i += 3
+		return sqlItem{pos: tok.pos, all: true, star: tok.text}, nil
+	}
+
+	expr, err := p.expr()
+	if err != nil {
+		return sqlItem{}, err
+	}
+	alias, err := p.alias()
+	if err != nil {
+		return sqlItem{}, err
+	}
+	return sqlItem{pos: tok.pos, expr: expr, alias: alias}, nil
+}
+
+func (p *sqlParser) tableRef() (sqlTableRef, error) {
+	tok, err := p.identifier("table name")
+	if err != nil {
+		return sqlTableRef{}, err
+	}
+	alias, err := p.alias()
+	if err != nil {
+		return sqlTableRef{}, err
+	}
+	if alias == "" {
+		alias = tok.text
+	}
+	return sqlTableRef{pos: tok.pos, name: tok.text, alias: alias}, nil
+}
+
+func (p *sqlParser) exprList() ([]sqlNode, error) {
+	var nodes []sqlNode
+	for {
+		node, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if _, ok := p.acceptOp(","); !ok {
+			return nodes, nil
+		}
+	}
+}
+
+// expr parses an expression. From lowest to highest precedence: OR, AND,
+// NOT, comparisons, ||, + -, * / %, unary -, then primaries.
+func (p *sqlParser) expr() (sqlNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptKeyword("OR")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{pos: tok.pos, op: "OR", left: left, right: right}
+	}
+}
+
+func (p *sqlParser) and() (sqlNode, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptKeyword("AND")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{pos: tok.pos, op: "AND", left: left, right: right}
+	}
+}
+
+func (p *sqlParser) not() (sqlNode, error) {
+	if tok, ok := p.acceptKeyword("NOT"); ok {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlUnary{pos: tok.pos, op: "NOT", operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *sqlParser) comparison() (sqlNode, error) {
+	left, err := p.concat()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, ok := p.acceptOp("=", "<>", "!=", "<", "<=", ">", ">="); ok {
+		right, err := p.concat()
+		if err != nil {
+			return nil, err
+		}
+		op := tok.text
+		if op == "!=" {
+			op = "<>"
+		}
+		return &sqlBinary{pos: tok.pos, op: op, left: left, right: right}, nil
+	}
+
+	if tok, ok := p.acceptKeyword("IS"); ok {
+		_, not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &sqlIsNull{pos: tok.pos, operand: left, not: not}, nil
+	}
+
+	tok, not := p.acceptKeyword("NOT")
+	switch p.peek().keyword() {
+	case "LIKE":
+		p.next()
+		right, err := p.concat()
+		if err != nil {
+			return nil, err
+		}
+		var node sqlNode = &sqlBinary{pos: tok.pos, op: "LIKE", left: left, right: right}
+		if not {
+			node = &sqlUnary{pos: tok.pos, op: "NOT", operand: node}
+		}
+		return node, nil
+
+	case "IN":
+		p.next()
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+		list, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return &sqlIn{pos: tok.pos, operand: left, list: list, not: not}, nil
+
+	case "BETWEEN":
+		p.next()
+		lo, err := p.concat()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.concat()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlBetween{pos: tok.pos, operand: left, lo: lo, hi: hi, not: not}, nil
+	}
+	if not {
+		return nil, p.expected("LIKE, IN or BETWEEN")
+	}
+	return left, nil
+}
+
+func (p *sqlParser) concat() (sqlNode, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{pos: tok.pos, op: "||", left: left, right: right}
+	}
+}
+
+func (p *sqlParser) additive() (sqlNode, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{pos: tok.pos, op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) multiplicative() (sqlNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{pos: tok.pos, op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) unary() (sqlNode, error) {
+	if tok, ok := p.acceptOp("-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlUnary{pos: tok.pos, op: "-", operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *sqlParser) primary() (sqlNode, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case sqlInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, &SQLError{Pos: tok.pos, Msg: fmt.Sprintf("invalid integer %s", tok.text)}
+		}
+		return &sqlLiteral{pos: tok.pos, value: domain.IntValue(n)}, nil
+
+	case sqlFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &SQLError{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %s", tok.text)}
+		}
+		return &sqlLiteral{pos: tok.pos, value: domain.FloatValue(f)}, nil
+
+	case sqlString:
+		p.next()
+		return &sqlLiteral{pos: tok.pos, value: domain.StringValue(tok.text)}, nil
+
+	case sqlOp:
+		if tok.text == "(" {
+			p.next()
+			node, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+
+	case sqlIdent, sqlQuotedIdent:
+		switch tok.keyword() {
+		case "NULL":
+			p.next()
+			return &sqlLiteral{pos: tok.pos}, nil
+		case "TRUE", "FALSE":
+			p.next()
+			return &sqlLiteral{pos: tok.pos, value: domain.BoolValue(tok.keyword() == "TRUE")}, nil
+		case "CASE":
+			p.next()
+			return p.caseExpr(tok)
+		}
+		if tok.kind == sqlIdent && p.tokens[p.i+1].kind == sqlOp && p.tokens[p.i+1].text == "(" {
+			p.i += 2
+			return p.call(tok)
+		}
+		return p.column()
+	}
+	return nil, p.unexpected(tok)
+}
+
+func (p *sqlParser) caseExpr(start sqlToken) (sqlNode, error) {
+	node := &sqlCase{pos: start.pos}
+	for {
+		if _, ok := p.acceptKeyword("WHEN"); !ok {
+			break
+		}
+		cond, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		then, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		node.whens = append(node.whens, sqlWhen{cond: cond, then: then})
+	}
+	if len(node.whens) == 0 {
+		return nil, p.expected("WHEN")
+	}
+	if _, ok := p.acceptKeyword("ELSE"); ok {
+		els, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		node.els = els
+	}
+	if err := p.expectKeyword("END"); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *sqlParser) call(name sqlToken) (sqlNode, error) {
+	node := &sqlCall{pos: name.pos, name: strings.ToUpper(name.text)}
+	if _, ok := p.acceptOp("*"); ok {
+		node.star = true
+		return node, p.expectOp(")")
+	}
+	if _, ok := p.acceptOp(")"); ok {
+		return node, nil
+	}
+	_, node.distinct = p.acceptKeyword("DISTINCT")
+	args, err := p.exprList()
+	if err != nil {
+		return nil, err
+	}
+	node.args = args
+	return node, p.expectOp(")")
+}
+
+func (p *sqlParser) column() (sqlNode, error) {
+	first, err := p.identifier("column name")
+	if err != nil {
+		return nil, err
+	}
+	node := &sqlColumn{pos: first.pos, parts: []string{first.text}}
+	for {
+		if _, ok := p.acceptOp("."); !ok {
+			return node, nil
+		}
+		part, err := p.identifier("column name")
+		if err != nil {
+			return nil, err
+		}
+		node.parts = append(node.parts, part.text)
+	}
+}
@@ -0,0 +1,464 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// DefaultSQLInput is the table name of the transform input in SQL queries.
+const DefaultSQLInput = "input"
+
+// SQLError is returned when a query cannot be parsed or does not match the
+// table schemas.
+type SQLError struct {
+	Pos int    // Byte offset of the error in the query
+	Msg string // Description of the error
+}
+
+func (e *SQLError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// SQLTransform runs a SELECT statement over in-memory RecordSets, using
+// their DataSchema as table definitions. It implements ports.TransformPort.
+//
+// The transform input is available as the table named by Input, and Tables
+// adds named RecordSets for joins. The output schema is inferred from the
+// selected expressions.
+//
+// Supported clauses are SELECT [DISTINCT], FROM, [INNER | LEFT [OUTER]] JOIN
+// ... ON, WHERE, GROUP BY, HAVING, ORDER BY [ASC | DESC] and LIMIT ...
+// [OFFSET ...]. Expressions support arithmetic, || concatenation,
+// comparisons, AND, OR, NOT, IS [NOT] NULL, [NOT] LIKE, [NOT] IN (...),
+// [NOT] BETWEEN, CASE WHEN, the aggregates COUNT, SUM, AVG, MIN and MAX, and
+// the functions LOWER, UPPER, TRIM, LENGTH, SUBSTR, ABS, ROUND and COALESCE.
+// Nested record columns are read with dots, such as customer.address.city.
+// Date columns compared with a string literal parse it as a date.
+//
+// Example:
+//
+//	t := NewSQLTransform(`
+//	    SELECT c.country, COUNT(*) AS orders, SUM(o.amount) AS total
+//	    FROM input o
+//	    JOIN customers c ON o.customer_id = c.id
+//	    WHERE o.status = 'paid'
+//	    GROUP BY c.country
+//	    HAVING SUM(o.amount) > 1000
+//	    ORDER BY total DESC
+//	    LIMIT 10`).WithTable("customers", customers)
+type SQLTransform struct {
+	Query    string                       // SELECT statement
+	Input    string                       // Table name of the transform input
+	Tables   map[string]*domain.RecordSet // Additional tables by name
+	SchemaID string                       // ID of the output schema
+}
+
+// NewSQLTransform creates a new SQLTransform for the given query, reading
+// the transform input as the table "input".
+func NewSQLTransform(query string) *SQLTransform {
+	return &SQLTransform{
+		Query:    query,
+		Input:    DefaultSQLInput,
+		Tables:   make(map[string]*domain.RecordSet),
+		SchemaID: "Result",
+	}
+}
+
+// WithTable adds a named table available to the query and returns the
+// transform for chaining.
+func (t *SQLTransform) WithTable(name string, rs *domain.RecordSet) *SQLTransform {
+	if t.Tables == nil {
+		t.Tables = make(map[string]*domain.RecordSet)
+	}
+	t.Tables[name] = rs
+	return t
+}
+
+// Transform runs the query with input as the Input table.
+func (t *SQLTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	tables := make(map[string]*domain.RecordSet, len(t.Tables)+1)
+	for name, rs := range t.Tables {
+		tables[name] = rs
+	}
+	if input != nil {
+		name := t.Input
+		if name == "" {
+			name = DefaultSQLInput
+		}
+		tables[name] = input
+	}
+
+	schemaID := t.SchemaID
+	if schemaID == "" {
+		schemaID = "Result"
+	}
+	return runSQL(t.Query, tables, schemaID)
+}
+
+// sqlOutput is a result row before ORDER BY, DISTINCT and LIMIT are applied.
+type sqlOutput struct {
+	values []domain.Value
+	keys   []domain.Value // ORDER BY keys
+	source string
+}
+
+func runSQL(query string, tables map[string]*domain.RecordSet, schemaID string) (*domain.RecordSet, error) {
+	stmt, err := parseSQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve tables
+	refs := append([]sqlTableRef{stmt.from}, make([]sqlTableRef, len(stmt.joins))...)
+	for i, join := range stmt.joins {
+		refs[i+1] = join.table
+	}
+	scope := make([]sqlTable, len(refs))
+	sets := make([]*domain.RecordSet, len(refs))
+	for i, ref := range refs {
+		rs, ok := tables[ref.name]
+		if !ok || rs == nil {
+			return nil, &SQLError{Pos: ref.pos, Msg: fmt.Sprintf("unknown table %s", ref.name)}
+		}
+		for _, other := range scope[:i] {
+			if other.alias == ref.alias {
+				return nil, &SQLError{Pos: ref.pos, Msg: fmt.Sprintf("duplicate table alias %s", ref.alias)}
+			}
+		}
+		scope[i] = sqlTable{alias: ref.alias, schema: rs.Schema, set: rs}
+		sets[i] = rs
+	}
+
+	// FROM and JOIN
+	rows := make([]sqlRow, 0, sets[0].Count())
+	for _, r := range sets[0].Records {
+		rows = append(rows, sqlRow{r})
+	}
+	for i, join := range stmt.joins {
+		on, err := compileCondition(&sqlCompiler{tables: scope[:i+2], clause: "JOIN"}, join.on, "ON")
+		if err != nil {
+			return nil, err
+		}
+		if rows, err = joinRows(rows, sets[i+1], on, join.left); err != nil {
+			return nil, err
+		}
+	}
+
+	// WHERE
+	if stmt.where != nil {
+		where, err := compileCondition(&sqlCompiler{tables: scope, clause: "WHERE"}, stmt.where, "WHERE")
+		if err != nil {
+			return nil, err
+		}
+		filtered := rows[:0]
+		for _, row := range rows {
+			ok, err := where(&sqlContext{row: row})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	// GROUP BY
+	grouped := len(stmt.groupBy) > 0 || (stmt.having != nil)
+	for _, item := range stmt.items {
+		grouped = grouped || (item.expr != nil && hasAggregate(item.expr))
+	}
+	var contexts []*sqlContext
+	if grouped {
+		if contexts, err = groupRows(scope, stmt.groupBy, rows); err != nil {
+			return nil, err
+		}
+	} else {
+		contexts = make([]*sqlContext, len(rows))
+		for i, row := range rows {
+			contexts[i] = &sqlContext{row: row}
+		}
+	}
+	compiler := &sqlCompiler{tables: scope, grouped: grouped, groupBy: stmt.groupBy, clause: "SELECT"}
+
+	// HAVING
+	if stmt.having != nil {
+		having, err := compileCondition(&sqlCompiler{tables: scope, grouped: true, groupBy: stmt.groupBy, clause: "HAVING"}, stmt.having, "HAVING")
+		if err != nil {
+			return nil, err
+		}
+		kept := contexts[:0]
+		for _, ctx := range contexts {
+			ok, err := having(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				kept = append(kept, ctx)
+			}
+		}
+		contexts = kept
+	}
+
+	// SELECT
+	schema := &domain.DataSchema{ID: schemaID}
+	var projections []sqlEval
+	addColumn := func(pos int, name string, typ sqlType, fn sqlEval) error {
+		if typ.isNull() {
+			return &SQLError{Pos: pos, Msg: fmt.Sprintf("cannot infer the type of column %s", name)}
+		}
+		if schema.HasColumn(name) {
+			return &SQLError{Pos: pos, Msg: fmt.Sprintf("duplicate output column %s, use AS to rename it", name)}
+		}
+		if typ.array {
+			schema.Columns = append(schema.Columns, domain.SchemaColumnArray{ID: name, RefSchema: typ.typ})
+		} else {
+			schema.Columns = append(schema.Columns, domain.SchemaColumnSingle{ID: name, SchemaType: typ.typ})
+		}
+		projections = append(projections, fn)
+		return nil
+	}
+	for i, item := range stmt.items {
+		if item.all {
+			if grouped {
+				return nil, &SQLError{Pos: item.pos, Msg: "* cannot be used with GROUP BY or aggregate functions"}
+			}
+			matched := false
+			for _, table := range scope {
+				if item.star != "" && item.star != table.alias {
+					continue
+				}
+				matched = true
+				for _, col := range table.schema.Columns {
+					fn, typ, err := compiler.compile(&sqlColumn{pos: item.pos, parts: []string{table.alias, col.GetID()}})
+					if err != nil {
+						return nil, err
+					}
+					if err := addColumn(item.pos, col.GetID(), typ, fn); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if !matched {
+				return nil, &SQLError{Pos: item.pos, Msg: fmt.Sprintf("unknown table %s", item.star)}
+			}
+			continue
+		}
+
+		fn, typ, err := compiler.compile(item.expr)
+		if err != nil {
+			return nil, err
+		}
+		if err := addColumn(item.pos, outputName(item, i), typ, fn); err != nil {
+			return nil, err
+		}
+	}
+
+	// ORDER BY keys can name an output column, give its position, or be any
+	// expression over the input.
+	orderKeys := make([]func(ctx *sqlContext, values []domain.Value) (domain.Value, error), len(stmt.orderBy))
+	for i, order := range stmt.orderBy {
+		if index := outputIndex(schema, order.expr); index >= 0 {
+			orderKeys[i] = func(_ *sqlContext, values []domain.Value) (domain.Value, error) { return values[index], nil }
+			continue
+		}
+		compiler.clause = "ORDER BY"
+		fn, _, err := compiler.compile(order.expr)
+		if err != nil {
+			return nil, err
+		}
+		orderKeys[i] = func(ctx *sqlContext, _ []domain.Value) (domain.Value, error) { return fn(ctx) }
+	}
+
+	outputs := make([]sqlOutput, 0, len(contexts))
+	seen := make(map[string]bool)
+	for _, ctx := range contexts {
+		out := sqlOutput{values: make([]domain.Value, len(projections))}
+		for j, fn := range projections {
+			if out.values[j], err = fn(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if stmt.distinct {
+			key := sqlKey(out.values)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		for _, key := range orderKeys {
+			v, err := key(ctx, out.values)
+			if err != nil {
+				return nil, err
+			}
+			out.keys = append(out.keys, v)
+		}
+		if !grouped && ctx.row[0] != nil {
+			out.source = ctx.row[0].Source
+		}
+		outputs = append(outputs, out)
+	}
+
+	// ORDER BY, NULLs first
+	if len(stmt.orderBy) > 0 {
+		sort.SliceStable(outputs, func(a, b int) bool {
+			for k, order := range stmt.orderBy {
+				cmp := compareNullsFirst(outputs[a].keys[k], outputs[b].keys[k])
+				if order.desc {
+					cmp = -cmp
+				}
+				if cmp != 0 {
+					return cmp < 0
+				}
+			}
+			return false
+		})
+	}
+
+	// LIMIT and OFFSET
+	outputs = outputs[min(stmt.offset, len(outputs)):]
+	if stmt.limit >= 0 && stmt.limit < len(outputs) {
+		outputs = outputs[:stmt.limit]
+	}
+
+	result := domain.NewRecordSet(schema)
+	for _, out := range outputs {
+		record := domain.NewRecord(schema)
+		record.Source = out.source
+		for j, col := range schema.Columns {
+			value := out.values[j]
+			if value == nil {
+				value = domain.NullValue{Type: col.GetType()}
+			}
+			record.Set(col.GetID(), value)
+		}
+		result.Add(record)
+	}
+	return result, nil
+}
+
+// compileCondition compiles a boolean expression of a WHERE, ON or HAVING
+// clause. Rows are kept when it evaluates to TRUE.
+func compileCondition(c *sqlCompiler, node sqlNode, clause string) (func(*sqlContext) (bool, error), error) {
+	fn, typ, err := c.compile(node)
+	if err != nil {
+		return nil, err
+	}
+	if !typ.is(domain.NativeTypeBool) && !typ.isNull() {
+		return nil, &SQLError{Pos: node.position(), Msg: fmt.Sprintf("%s expects a boolean, got %s", clause, typ)}
+	}
+	return func(ctx *sqlContext) (bool, error) {
+		v, err := fn(ctx)
+		return v == domain.BoolValue(true), err
+	}, nil
+}
+
+func joinRows(rows []sqlRow, table *domain.RecordSet, on func(*sqlContext) (bool, error), left bool) ([]sqlRow, error) {
+	var joined []sqlRow
+	for _, row := range rows {
+		matched := false
+		for _, r := range table.Records {
+			candidate := append(append(make(sqlRow, 0, len(row)+1), row...), r)
+			ok, err := on(&sqlContext{row: candidate})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				joined = append(joined, candidate)
+				matched = true
+			}
+		}
+		if left && !matched {
+			joined = append(joined, append(append(make(sqlRow, 0, len(row)+1), row...), nil))
+		}
+	}
+	return joined, nil
+}
+
+// groupRows splits rows by GROUP BY values, keeping groups in order of first
+// appearance. Without GROUP BY, all rows form a single group, even when
+// there are none.
+func groupRows(scope []sqlTable, groupBy []sqlNode, rows []sqlRow) ([]*sqlContext, error) {
+	if len(groupBy) == 0 {
+		return []*sqlContext{{group: rows}}, nil
+	}
+
+	keys := make([]sqlEval, len(groupBy))
+	compiler := &sqlCompiler{tables: scope, clause: "GROUP BY"}
+	for i, node := range groupBy {
+		fn, _, err := compiler.compile(node)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = fn
+	}
+
+	var groups []*sqlContext
+	index := make(map[string]*sqlContext)
+	for _, row := range rows {
+		values := make([]domain.Value, len(keys))
+		for i, fn := range keys {
+			v, err := fn(&sqlContext{row: row})
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		key := sqlKey(values)
+		group, ok := index[key]
+		if !ok {
+			group = &sqlContext{}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.group = append(group.group, row)
+	}
+	return groups, nil
+}
+
+// outputName returns the name of a selected expression: its alias, the last
+// part of a column reference, the lowercase function name of a call, or
+// column_N.
+func outputName(item sqlItem, i int) string {
+	if item.alias != "" {
+		return item.alias
+	}
+	switch n := item.expr.(type) {
+	case *sqlColumn:
+		return n.parts[len(n.parts)-1]
+	case *sqlCall:
+		return strings.ToLower(n.name)
+	}
+	return fmt.Sprintf("column_%d", i+1)
+}
+
+// outputIndex returns the output column an ORDER BY expression refers to,
+// by name or by 1-based position, or -1.
+func outputIndex(schema *domain.DataSchema, node sqlNode) int {
+	switch n := node.(type) {
+	case *sqlColumn:
+		if len(n.parts) == 1 {
+			return schema.IndexOf(n.parts[0])
+		}
+	case *sqlLiteral:
+		if i, ok := n.value.(domain.IntValue); ok && int(i) >= 1 && int(i) <= len(schema.Columns) {
+			return int(i) - 1
+		}
+	}
+	return -1
+}
+
+func compareNullsFirst(a, b domain.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return sqlCompare(a, b)
+}
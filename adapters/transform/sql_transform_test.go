@@ -0,0 +1,450 @@
+package transform
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ordersFixture() *domain.RecordSet {
+	address := &domain.DataSchema{
+		ID: "Address",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+		},
+	}
+	schema := &domain.DataSchema{
+		ID: "Order",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "customer_id", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "status", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "amount", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "created_at", SchemaType: domain.NativeTypeDate},
+			domain.SchemaColumnSingle{ID: "shipping", SchemaType: domain.CustomType{Name: "Address", Schema: address}},
+		},
+	}
+
+	rs := domain.NewRecordSet(schema)
+	for _, o := range []struct {
+		id, customer int64
+		status       string
+		amount       float64
+		day          int
+		city         string
+	}{
+		{1, 1, "paid", 120, 1, "Paris"},
+		{2, 1, "paid", 80, 2, "Paris"},
+		{3, 2, "pending", 50, 3, ""},
+		{4, 2, "paid", 300, 4, "Lyon"},
+		{5, 3, "paid", 10, 5, "Nice"},
+		{6, 9, "cancelled", 99, 6, "Lyon"},
+	} {
+		record := domain.NewRecord(schema)
+		record.Source = "orders.json"
+		record.Set("id", domain.IntValue(o.id))
+		record.Set("customer_id", domain.IntValue(o.customer))
+		record.Set("status", domain.StringValue(o.status))
+		record.Set("amount", domain.FloatValue(o.amount))
+		record.Set("created_at", domain.DateValue(time.Date(2024, 1, o.day, 0, 0, 0, 0, time.UTC)))
+		if o.city != "" {
+			shipping := domain.NewRecord(address)
+			shipping.Set("city", domain.StringValue(o.city))
+			record.Set("shipping", domain.RecordValue{Record: shipping})
+		} else {
+			record.Set("shipping", domain.NullValue{Type: domain.CustomType{Name: "Address", Schema: address}})
+		}
+		rs.Add(record)
+	}
+	return rs
+}
+
+func customersFixture() *domain.RecordSet {
+	schema := &domain.DataSchema{
+		ID: "Customer",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "country", SchemaType: domain.NativeTypeString},
+		},
+	}
+	rs := domain.NewRecordSet(schema)
+	for _, c := range []struct {
+		id            int64
+		name, country string
+	}{
+		{1, "Alice", "FR"},
+		{2, "Bob", "DE"},
+		{3, "Chloe", "FR"},
+	} {
+		record := domain.NewRecord(schema)
+		record.Set("id", domain.IntValue(c.id))
+		record.Set("name", domain.StringValue(c.name))
+		record.Set("country", domain.StringValue(c.country))
+		rs.Add(record)
+	}
+	return rs
+}
+
+// rows returns the values of the given columns for every record.
+func rows(rs *domain.RecordSet, columns ...string) [][]any {
+	result := make([][]any, 0, rs.Count())
+	for _, r := range rs.Records {
+		row := make([]any, len(columns))
+		for i, col := range columns {
+			switch v := r.Get(col).(type) {
+			case domain.StringValue:
+				row[i] = string(v)
+			case domain.IntValue:
+				row[i] = int64(v)
+			case domain.FloatValue:
+				row[i] = float64(v)
+			case domain.BoolValue:
+				row[i] = bool(v)
+			case domain.NullValue:
+				row[i] = nil
+			default:
+				row[i] = v
+			}
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+func TestSQLTransform_Transform(t *testing.T) {
+	t.Run("should project and filter with inferred schema", func(t *testing.T) {
+		transform := NewSQLTransform("SELECT id, amount * 2 AS doubled, status = 'paid' paid FROM input WHERE amount >= 80 AND status <> 'cancelled'")
+
+		result, err := transform.Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, &domain.DataSchema{
+			ID: "Result",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+				domain.SchemaColumnSingle{ID: "doubled", SchemaType: domain.NativeTypeFloat},
+				domain.SchemaColumnSingle{ID: "paid", SchemaType: domain.NativeTypeBool},
+			},
+		}, result.Schema)
+		assert.Equal(t, [][]any{{int64(1), 240.0, true}, {int64(2), 160.0, true}, {int64(4), 600.0, true}}, rows(result, "id", "doubled", "paid"))
+		assert.Equal(t, "orders.json", result.First().Source)
+	})
+
+	t.Run("should select all columns", func(t *testing.T) {
+		input := ordersFixture()
+
+		result, err := NewSQLTransform("SELECT * FROM input LIMIT 1").Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, input.Schema.Columns, result.Schema.Columns)
+		assert.Equal(t, input.First().Values, result.First().Values)
+	})
+
+	t.Run("should group with aggregates, having and order", func(t *testing.T) {
+		transform := NewSQLTransform(`
+			SELECT status, COUNT(*) AS orders, SUM(amount) AS total, AVG(amount) avg, MIN(id), MAX(created_at) AS last
+			FROM input
+			GROUP BY status
+			HAVING COUNT(*) > 1 OR SUM(amount) < 60
+			ORDER BY total DESC`)
+
+		result, err := transform.Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"status", "orders", "total", "avg", "min", "last"}, result.Schema.ColumnIDs())
+		assert.Equal(t, domain.NativeTypeDate, result.Schema.Column("last").GetType())
+		assert.Equal(t, [][]any{
+			{"paid", int64(4), 510.0, 127.5, int64(1)},
+			{"pending", int64(1), 50.0, 50.0, int64(3)},
+		}, rows(result, "status", "orders", "total", "avg", "min"))
+		assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), result.First().GetDate("last"))
+	})
+
+	t.Run("should aggregate without group by", func(t *testing.T) {
+		result, err := NewSQLTransform("SELECT COUNT(*) n, COUNT(DISTINCT customer_id) AS customers, SUM(id) FROM input WHERE amount > 1000").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(0), int64(0), nil}}, rows(result, "n", "customers", "sum"))
+	})
+
+	t.Run("should join named tables", func(t *testing.T) {
+		transform := NewSQLTransform(`
+			SELECT c.country, COUNT(*) AS orders, SUM(o.amount) AS total
+			FROM input o
+			JOIN customers c ON o.customer_id = c.id
+			WHERE o.status = 'paid'
+			GROUP BY c.country
+			ORDER BY c.country`).WithTable("customers", customersFixture())
+
+		result, err := transform.Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{"DE", int64(1), 300.0}, {"FR", int64(3), 210.0}}, rows(result, "country", "orders", "total"))
+	})
+
+	t.Run("should keep unmatched rows in left join", func(t *testing.T) {
+		transform := NewSQLTransform(`
+			SELECT o.id, name, COALESCE(c.name, 'unknown') AS customer
+			FROM input AS o
+			LEFT OUTER JOIN customers c ON o.customer_id = c.id
+			WHERE o.id >= 5`).WithTable("customers", customersFixture())
+
+		result, err := transform.Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(5), "Chloe", "Chloe"}, {int64(6), nil, "unknown"}}, rows(result, "id", "name", "customer"))
+	})
+
+	t.Run("should support predicates and case", func(t *testing.T) {
+		transform := NewSQLTransform(`
+			SELECT id,
+				CASE WHEN amount >= 100 THEN 'large' WHEN amount >= 50 THEN 'medium' ELSE 'small' END AS size,
+				UPPER(SUBSTR(status, 1, 3)) || '-' || TRIM(' x ') AS code
+			FROM input
+			WHERE status LIKE 'p%'
+				AND id NOT IN (2, 3)
+				AND amount BETWEEN 10 AND 200
+				AND created_at < '2024-01-05'
+				AND created_at > '2023/12/31'`)
+
+		result, err := transform.Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(1), "large", "PAI-x"}}, rows(result, "id", "size", "code"))
+	})
+
+	t.Run("should read nested columns and test nulls", func(t *testing.T) {
+		result, err := NewSQLTransform("SELECT id, shipping.city FROM input WHERE shipping IS NULL OR shipping.city = 'Nice'").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(3), nil}, {int64(5), "Nice"}}, rows(result, "id", "city"))
+	})
+
+	t.Run("should apply distinct, order by position, limit and offset", func(t *testing.T) {
+		result, err := NewSQLTransform("SELECT DISTINCT shipping.city AS city FROM input WHERE shipping IS NOT NULL ORDER BY 1 DESC LIMIT 2 OFFSET 1").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{"Nice"}, {"Lyon"}}, rows(result, "city"))
+	})
+
+	t.Run("should group ints beyond float precision apart", func(t *testing.T) {
+		schema := &domain.DataSchema{ID: "Event", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "score", SchemaType: domain.NativeTypeFloat},
+		}}
+		input := domain.NewRecordSet(schema)
+		for i, id := range []int64{1 << 53, 1<<53 + 1, 1<<53 + 2, 1<<53 + 1, 1} {
+			record := domain.NewRecord(schema)
+			record.Set("id", domain.IntValue(id))
+			record.Set("score", domain.FloatValue(float64(i%2)))
+			input.Add(record)
+		}
+
+		result, err := NewSQLTransform("SELECT id, COUNT(*) AS n FROM input GROUP BY id ORDER BY id").Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{
+			{int64(1), int64(1)}, {int64(1 << 53), int64(1)}, {int64(1<<53 + 1), int64(2)}, {int64(1<<53 + 2), int64(1)},
+		}, rows(result, "id", "n"))
+
+		result, err = NewSQLTransform("SELECT COUNT(DISTINCT id) AS ids, COUNT(DISTINCT score) AS scores FROM input WHERE id = 1 OR score = 1.0 OR id > 1").Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(4), int64(2)}}, rows(result, "ids", "scores"))
+	})
+
+	t.Run("should treat integral floats and ints as the same key", func(t *testing.T) {
+		schema := &domain.DataSchema{ID: "Value", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "v", SchemaType: domain.NativeTypeFloat},
+		}}
+		input := domain.NewRecordSet(schema)
+		for _, v := range []domain.Value{domain.IntValue(1), domain.FloatValue(1), domain.FloatValue(1.5)} {
+			record := domain.NewRecord(schema)
+			record.Set("v", v)
+			input.Add(record)
+		}
+
+		result, err := NewSQLTransform("SELECT COUNT(DISTINCT v) AS n FROM input").Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.First().GetInt("n"))
+	})
+
+	t.Run("should treat equal nested records as the same key", func(t *testing.T) {
+		result, err := NewSQLTransform("SELECT shipping, COUNT(*) AS n FROM input GROUP BY shipping").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2, 1, 2, 1}, []int64{
+			result.Records[0].GetInt("n"), result.Records[1].GetInt("n"),
+			result.Records[2].GetInt("n"), result.Records[3].GetInt("n"),
+		})
+
+		result, err = NewSQLTransform("SELECT DISTINCT shipping FROM input").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, 4, result.Count())
+	})
+
+	t.Run("should key dates by instant beyond the nanosecond range", func(t *testing.T) {
+		schema := &domain.DataSchema{ID: "Value", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "v", SchemaType: domain.NativeTypeDate},
+		}}
+		early := time.Date(1700, 1, 1, 0, 0, 0, 0, time.UTC)
+		// Exactly 2^64 nanoseconds later, where UnixNano wraps around.
+		late := early.Add(math.MaxInt64).Add(math.MaxInt64).Add(2)
+		paris := time.FixedZone("CET", 3600)
+		input := domain.NewRecordSet(schema)
+		for _, v := range []time.Time{early, late, early.In(paris)} {
+			record := domain.NewRecord(schema)
+			record.Set("v", domain.DateValue(v))
+			input.Add(record)
+		}
+
+		result, err := NewSQLTransform("SELECT COUNT(DISTINCT v) AS n FROM input").Transform(input)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), result.First().GetInt("n"))
+	})
+
+	t.Run("should sort nulls first ascending", func(t *testing.T) {
+		result, err := NewSQLTransform("SELECT id FROM input ORDER BY shipping.city, id DESC").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(3)}, {int64(6)}, {int64(4)}, {int64(5)}, {int64(2)}, {int64(1)}}, rows(result, "id"))
+	})
+
+	t.Run("should use three-valued logic", func(t *testing.T) {
+		result, err := NewSQLTransform("SELECT id FROM input WHERE NOT (shipping.city = 'Paris')").Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{{int64(4)}, {int64(5)}, {int64(6)}}, rows(result, "id"))
+	})
+
+	t.Run("should use custom input name and schema ID", func(t *testing.T) {
+		transform := &SQLTransform{Query: "select count(*) from orders", Input: "orders", SchemaID: "Stats"}
+
+		result, err := transform.Transform(ordersFixture())
+
+		require.NoError(t, err)
+		assert.Equal(t, "Stats", result.Schema.ID)
+		assert.Equal(t, int64(6), result.First().GetInt("count"))
+	})
+
+	t.Run("should return error for division by zero", func(t *testing.T) {
+		_, err := NewSQLTransform("SELECT id / (id - 1) FROM input").Transform(ordersFixture())
+
+		assert.EqualError(t, err, "division by zero")
+	})
+}
+
+func TestSQLTransform_Errors(t *testing.T) {
+	tests := []struct {
+		query string
+		msg   string
+	}{
+		{"SELECT id FROM missing", "unknown table missing"},
+		{"SELECT nope FROM input", "unknown column nope"},
+		{"SELECT o.nope FROM input o", "unknown column nope in table o"},
+		{"SELECT shipping.zip FROM input", "unknown column zip in Address"},
+		{"SELECT id FROM input a JOIN input b ON a.id = b.id", "ambiguous column id, qualify it with a table name"},
+		{"SELECT a.id, b.id FROM input a JOIN input b ON a.id = b.id", "duplicate output column id, use AS to rename it"},
+		{"SELECT id FROM input a JOIN input a ON a.id = a.id", "duplicate table alias a"},
+		{"SELECT status, id FROM input GROUP BY status", "column id must appear in GROUP BY or be used in an aggregate function"},
+		{"SELECT * FROM input GROUP BY status", "* cannot be used with GROUP BY or aggregate functions"},
+		{"SELECT id FROM input WHERE COUNT(*) > 1", "aggregate function COUNT is not allowed in WHERE"},
+		{"SELECT SUM(COUNT(*)) FROM input", "aggregate function COUNT is not allowed in aggregate functions"},
+		{"SELECT SUM(status) FROM input", "SUM does not apply to string"},
+		{"SELECT id FROM input WHERE amount", "WHERE expects a boolean, got float"},
+		{"SELECT id FROM input WHERE status > 1", "> does not apply to string and int"},
+		{"SELECT id FROM input WHERE created_at > 'yesterday'", `cannot parse "yesterday" as date`},
+		{"SELECT NULL FROM input", "cannot infer the type of column column_1"},
+		{"SELECT FOO(id) FROM input", "unknown function FOO"},
+		{"SELECT LOWER(id) FROM input", "LOWER: argument 1 has unexpected type int"},
+		{"SELECT CASE WHEN id > 1 THEN 'a' ELSE 1 END FROM input", "CASE branches have different types string and int"},
+		{"SELECT id FROM", "expected table name, got end of query"},
+		{"SELECT id FROM input WHERE", "unexpected end of query"},
+		{"SELECT id FROM input LIMIT x", `expected LIMIT count, got "x"`},
+		{"SELECT id FROM input WHERE id NOT 1", `expected LIKE, IN or BETWEEN, got "1"`},
+		{"SELECT 'abc FROM input", "unterminated quoted text"},
+		{"SELECT id FROM input extra stuff", `unexpected "stuff"`},
+		{"DELETE FROM input", `expected SELECT, got "DELETE"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := NewSQLTransform(tt.query).Transform(ordersFixture())
+
+			var sqlErr *SQLError
+			require.True(t, errors.As(err, &sqlErr), "expected *SQLError, got %v", err)
+			assert.Equal(t, tt.msg, sqlErr.Msg)
+		})
+	}
+
+	t.Run("should return record errors for values not matching their column", func(t *testing.T) {
+		// Arrange
+		schema := &domain.DataSchema{
+			ID: "Mismatch",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+				domain.SchemaColumnSingle{ID: "score", SchemaType: domain.NativeTypeFloat},
+				domain.SchemaColumnSingle{ID: "day", SchemaType: domain.NativeTypeDate},
+			},
+		}
+		input := domain.NewRecordSet(schema)
+		valid := domain.NewRecord(schema)
+		valid.Set("name", domain.StringValue("abc"))
+		valid.Set("active", domain.BoolValue(true))
+		valid.Set("score", domain.IntValue(1))
+		valid.Set("day", domain.DateValue(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		input.Add(valid)
+		invalid := domain.NewRecord(schema)
+		invalid.Source = "mismatch.json"
+		invalid.Set("name", domain.IntValue(3))
+		invalid.Set("active", domain.StringValue("yes"))
+		invalid.Set("score", domain.StringValue("high"))
+		invalid.Set("day", domain.StringValue("monday"))
+		input.Add(invalid)
+
+		tests := []struct {
+			query string
+			msg   string
+		}{
+			{"SELECT name FROM input WHERE NOT active", "column active: expected bool, got domain.StringValue"},
+			{"SELECT -score AS score FROM input", "column score: expected float, got domain.StringValue"},
+			{"SELECT name FROM input WHERE name LIKE 'a%'", "column name: expected string, got domain.IntValue"},
+			{"SELECT name || '!' AS name FROM input", "column name: expected string, got domain.IntValue"},
+			{"SELECT LOWER(name) AS name FROM input", "column name: expected string, got domain.IntValue"},
+			{"SELECT UPPER(name) AS name FROM input", "column name: expected string, got domain.IntValue"},
+			{"SELECT TRIM(name) AS name FROM input", "column name: expected string, got domain.IntValue"},
+			{"SELECT LENGTH(name) AS n FROM input", "column name: expected string, got domain.IntValue"},
+			{"SELECT SUBSTR(name, 1, 2) AS name FROM input", "column name: expected string, got domain.IntValue"},
+			{"SELECT name FROM input WHERE name < 'b'", "column name: expected string, got domain.IntValue"},
+			{"SELECT name FROM input WHERE day > '2023-12-31'", "column day: expected date, got domain.StringValue"},
+		}
+
+		for _, tt := range tests {
+			// Act
+			_, err := NewSQLTransform(tt.query).Transform(input)
+
+			// Assert
+			var recordErr *domain.RecordError
+			require.True(t, errors.As(err, &recordErr), "%s: expected *domain.RecordError, got %v", tt.query, err)
+			assert.Equal(t, 1, recordErr.Index, tt.query)
+			assert.EqualError(t, err, "record 1 (mismatch.json): "+tt.msg, tt.query)
+		}
+	})
+
+	t.Run("should format error with position", func(t *testing.T) {
+		_, err := NewSQLTransform("SELECT nope FROM input").Transform(ordersFixture())
+
+		assert.EqualError(t, err, "invalid query at position 8: unknown column nope")
+	})
+}
@@ -1,7 +1,11 @@
 package transform
 
 import (
+	"fmt"
+	"log/slog"
+
 	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/stages"
 	"github.com/spaghettifactory-oss/pipeforge/ports"
 )
 
@@ -9,6 +13,9 @@ import (
 // If no transforms are added, it passes through the input unchanged.
 type TransformBuilder struct {
 	transforms []ports.TransformPort
+	names      []string
+	middleware []ports.Middleware
+	logger     *slog.Logger
 }
 
 // NewTransformBuilder creates a new empty TransformBuilder.
@@ -19,11 +26,62 @@ func NewTransformBuilder() *TransformBuilder {
 }
 
 // Add appends a transform to the pipeline and returns the builder for chaining.
+// The stage is named "transform N" after its zero-based position.
 func (b *TransformBuilder) Add(t ports.TransformPort) *TransformBuilder {
+	return b.AddNamed(fmt.Sprintf("transform %d", len(b.transforms)), t)
+}
+
+// AddNamed appends a transform under a stage name and returns the builder
+// for chaining. The name is passed to middleware and identifies the stage
+// in the *domain.StageError returned when the transform fails.
+func (b *TransformBuilder) AddNamed(name string, t ports.TransformPort) *TransformBuilder {
 	b.transforms = append(b.transforms, t)
+	b.names = append(b.names, name)
+	return b
+}
+
+// Use appends middleware wrapping every transform of the builder, the first
+// being the outermost, and returns the builder for chaining.
+func (b *TransformBuilder) Use(middleware ...ports.Middleware) *TransformBuilder {
+	b.middleware = append(b.middleware, middleware...)
 	return b
 }
 
+// WithStageMiddleware returns a copy of the builder whose transforms are
+// also wrapped by middleware, inside the middleware added with Use. The
+// builder itself is not modified.
+func (b *TransformBuilder) WithStageMiddleware(middleware ...ports.Middleware) ports.TransformPort {
+	staged := *b
+	staged.middleware = append(b.middleware[:len(b.middleware):len(b.middleware)], middleware...)
+	return &staged
+}
+
+// WithLogger logs the start, record counts, duration and errors of each
+// transform to logger, see pipeline.Logging, and returns the builder for
+// chaining. A nil logger disables logging, the default.
+func (b *TransformBuilder) WithLogger(logger *slog.Logger) *TransformBuilder {
+	b.logger = logger
+	return b
+}
+
+// When appends a transform applied only to the records matching predicate,
+// see NewWhenTransform, and returns the builder for chaining.
+func (b *TransformBuilder) When(predicate Predicate, t ports.TransformPort) *TransformBuilder {
+	return b.Add(NewWhenTransform(predicate, t))
+}
+
+// Switch appends a transform routing records to the first matching case,
+// see SwitchTransform, and returns the builder for chaining.
+func (b *TransformBuilder) Switch(cases ...Case) *TransformBuilder {
+	return b.Add(NewSwitchTransform(cases...))
+}
+
+// Try appends a transform falling back to fallback when t fails, see
+// TryTransform, and returns the builder for chaining.
+func (b *TransformBuilder) Try(t, fallback ports.TransformPort) *TransformBuilder {
+	return b.Add(NewTryTransform(t, fallback))
+}
+
 // Build returns the TransformBuilder as a TransformPort.
 // The builder itself implements TransformPort.
 func (b *TransformBuilder) Build() ports.TransformPort {
@@ -31,18 +89,24 @@ func (b *TransformBuilder) Build() ports.TransformPort {
 }
 
 // Transform executes all transforms in sequence.
-// If no transforms were added, returns the input unchanged.
+// If no transforms were added, returns the input unchanged. A failing
+// transform stops the chain with a *domain.StageError naming it.
 func (b *TransformBuilder) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
 	if len(b.transforms) == 0 {
 		return input, nil
 	}
 
+	middleware := b.middleware
+	if b.logger != nil {
+		middleware = append(middleware[:len(middleware):len(middleware)], stages.Logging(b.logger))
+	}
+
 	result := input
-	for _, t := range b.transforms {
+	for i, t := range b.transforms {
 		var err error
-		result, err = t.Transform(result)
+		result, err = stages.Wrap(b.names[i], t.Transform, middleware...)(result)
 		if err != nil {
-			return nil, err
+			return nil, &domain.StageError{Stage: b.names[i], Err: err}
 		}
 	}
 
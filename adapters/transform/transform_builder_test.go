@@ -1,10 +1,15 @@
 package transform
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
 	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
 	"github.com/spaghettifactory-oss/pipeforge/domain"
+	mocksource "github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -138,3 +143,113 @@ func TestTransformBuilder_Transform(t *testing.T) {
 		assert.Contains(t, err.Error(), "transform error")
 	})
 }
+
+func TestTransformBuilder_Use(t *testing.T) {
+	t.Run("should wrap every stage with middleware", func(t *testing.T) {
+		var events []ports.StageEvent
+		builder := NewTransformBuilder().
+			Add(transform.NewAddIntTransform("quantity", 1)).
+			Add(transform.NewAddIntTransform("quantity", 10)).
+			Use(pipeline.Hooks{After: func(e ports.StageEvent) { events = append(events, e) }}.Middleware())
+
+		result, err := builder.Transform(mocksource.NewStock(1))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{12}, mocksource.Quantities(result))
+		require.Len(t, events, 2)
+		assert.Equal(t, "transform 0", events[0].Stage)
+		assert.Equal(t, []int64{1}, mocksource.Quantities(events[0].Input))
+		assert.Equal(t, []int64{2}, mocksource.Quantities(events[0].Output))
+		assert.Equal(t, "transform 1", events[1].Stage)
+		assert.Same(t, result, events[1].Output)
+	})
+
+	t.Run("should report the failing stage", func(t *testing.T) {
+		var failed []string
+		builder := NewTransformBuilder().
+			Add(&transform.EmptyTransform{}).
+			Add(&transform.ErrorTransform{}).
+			Use(pipeline.Hooks{OnError: func(e ports.StageEvent) { failed = append(failed, e.Stage) }}.Middleware())
+
+		_, err := builder.Transform(mocksource.NewStock(1))
+
+		assert.Error(t, err)
+		assert.Equal(t, []string{"transform 1"}, failed)
+	})
+}
+
+func TestTransformBuilder_WithStageMiddleware(t *testing.T) {
+	t.Run("should wrap stages inside the builder middleware without modifying it", func(t *testing.T) {
+		var calls []string
+		record := func(name string) ports.Middleware {
+			return pipeline.Hooks{Before: func(e ports.StageEvent) { calls = append(calls, name+" "+e.Stage) }}.Middleware()
+		}
+		builder := NewTransformBuilder().
+			AddNamed("restock", transform.NewAddIntTransform("quantity", 1)).
+			Use(record("outer"))
+
+		result, err := builder.WithStageMiddleware(record("inner")).Transform(mocksource.NewStock(1))
+		require.NoError(t, err)
+		_, err = builder.Transform(mocksource.NewStock(1))
+		require.NoError(t, err)
+
+		assert.Equal(t, []int64{2}, mocksource.Quantities(result))
+		assert.Equal(t, []string{"outer restock", "inner restock", "outer restock"}, calls)
+	})
+}
+
+func TestTransformBuilder_AddNamed(t *testing.T) {
+	t.Run("should name stages for middleware", func(t *testing.T) {
+		var stages []string
+		builder := NewTransformBuilder().
+			AddNamed("restock", transform.NewAddIntTransform("quantity", 1)).
+			Add(transform.NewAddIntTransform("quantity", 10)).
+			Use(pipeline.Hooks{Before: func(e ports.StageEvent) { stages = append(stages, e.Stage) }}.Middleware())
+
+		_, err := builder.Transform(mocksource.NewStock(1))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"restock", "transform 1"}, stages)
+	})
+
+	t.Run("should return a stage error naming the failing stage", func(t *testing.T) {
+		builder := NewTransformBuilder().
+			AddNamed("restock", transform.NewAddIntTransform("quantity", 1)).
+			AddNamed("validate", &transform.ErrorTransform{})
+
+		_, err := builder.Transform(mocksource.NewStock(1))
+
+		var stageErr *domain.StageError
+		require.ErrorAs(t, err, &stageErr)
+		assert.Equal(t, "validate", stageErr.Stage)
+		assert.EqualError(t, err, "stage validate: transform error")
+	})
+
+	t.Run("should expose record errors of a stage", func(t *testing.T) {
+		filter := transformFunc(func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			return input.FilterExpr("quantity / 0 > 1")
+		})
+
+		_, err := NewTransformBuilder().AddNamed("filter", filter).Transform(mocksource.NewStock(3))
+
+		var recordErr *domain.RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 0, recordErr.Index)
+	})
+}
+
+func TestTransformBuilder_WithLogger(t *testing.T) {
+	t.Run("should log every stage by name", func(t *testing.T) {
+		var buf bytes.Buffer
+		builder := NewTransformBuilder().
+			AddNamed("restock", transform.NewAddIntTransform("quantity", 1)).
+			Add(&transform.ErrorTransform{}).
+			WithLogger(logger.NewTextLogger(&buf))
+
+		_, err := builder.Transform(mocksource.NewStock(1, 2))
+
+		assert.Error(t, err)
+		assert.Contains(t, buf.String(), `level=INFO msg="stage finished" stage=restock records_in=2 records_out=2`)
+		assert.Contains(t, buf.String(), `level=ERROR msg="stage failed" stage="transform 1"`)
+	})
+}
@@ -0,0 +1,221 @@
+// Package checkpoint persists the output of pipeline stages so that a failed
+// run can resume without redoing the stages that completed.
+//
+// A Checkpointer is a ports.Middleware saving each stage output as a
+// snapshot, keyed by a hash of the stage name, its input and the pipeline
+// configuration. In resume mode a stage whose key matches its saved
+// checkpoint is skipped and its saved output returned instead:
+//
+//	cp := checkpoint.New("/var/lib/orders/checkpoints")
+//	cp.Config = "v3"
+//	cp.Source = p.Source
+//	cp.Resume = true
+//	p.Use(cp.Middleware())
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/snapshot"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+const (
+	snapshotExt = ".snapshot"
+	keyExt      = ".key"
+)
+
+// Checkpointer saves stage outputs to a directory, one snapshot per stage
+// name, and restores them in resume mode.
+//
+// Load stages have no input: their key hashes the fingerprint of Source
+// instead, so that a changed source is loaded again. Load stages are never
+// resumed when Source does not implement ports.Fingerprinter or returns an
+// empty fingerprint.
+type Checkpointer struct {
+	Dir    string           // Directory holding the checkpoints, created if missing
+	Config string           // Fingerprint of the pipeline configuration, see HashConfig; changing it invalidates every checkpoint
+	Source ports.SourcePort // Source of the load stage, whose fingerprint is part of the load key
+	Resume bool             // Skip stages whose checkpoint matches their input and Config
+	Logger *slog.Logger     // Receives the stages saved and resumed, nil disables logging
+}
+
+// New creates a Checkpointer saving to dir, without resuming.
+func New(dir string) *Checkpointer {
+	return &Checkpointer{Dir: dir}
+}
+
+// Middleware returns a Middleware checkpointing every stage it wraps. Stage
+// outputs that are nil are not saved. Failing to save a checkpoint fails
+// the stage.
+func (c *Checkpointer) Middleware() ports.Middleware {
+	return func(stage string, next ports.StageFunc) ports.StageFunc {
+		return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			key, err := c.key(stage, input)
+			if err != nil {
+				return nil, fmt.Errorf("checkpoint %s: %w", stage, err)
+			}
+			if key == "" {
+				logging.Or(c.Logger).Debug("skipped checkpoint of source without fingerprint", "stage", stage)
+				return next(input)
+			}
+
+			if c.Resume {
+				output, err := c.restore(stage, key)
+				if err != nil {
+					return nil, fmt.Errorf("checkpoint %s: %w", stage, err)
+				}
+				if output != nil {
+					logging.Or(c.Logger).Info("resumed stage from checkpoint", "stage", stage, "records", output.Count())
+					return output, nil
+				}
+			}
+
+			output, err := next(input)
+			if err != nil || output == nil {
+				return output, err
+			}
+			if err := c.save(stage, key, output); err != nil {
+				return nil, fmt.Errorf("checkpoint %s: %w", stage, err)
+			}
+			logging.Or(c.Logger).Debug("saved checkpoint", "stage", stage, "records", output.Count())
+			return output, nil
+		}
+	}
+}
+
+// Clear deletes every checkpoint of the directory, for instance once a run
+// succeeded.
+func (c *Checkpointer) Clear() error {
+	entries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasSuffix(name, snapshotExt) || strings.HasSuffix(name, keyExt) {
+			if err := os.Remove(filepath.Join(c.Dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HashConfig returns a hex SHA-256 of the JSON encoding of v, such as the
+// transform specs of a pipeline, to use as Config.
+func HashConfig(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// key hashes the stage name, Config and the snapshot of the input, or the
+// fingerprint of Source for load stages. It returns "" for load stages
+// whose source has no fingerprint.
+func (c *Checkpointer) key(stage string, input *domain.RecordSet) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n%q\n", stage, c.Config)
+	if input == nil {
+		fingerprinter, ok := c.Source.(ports.Fingerprinter)
+		if !ok {
+			return "", nil
+		}
+		fingerprint, err := fingerprinter.Fingerprint()
+		if err != nil {
+			return "", fmt.Errorf("failed to fingerprint source: %w", err)
+		}
+		if fingerprint == "" {
+			return "", nil
+		}
+		fmt.Fprintf(h, "%q\n", fingerprint)
+	} else if err := snapshot.Encode(h, input); err != nil {
+		return "", fmt.Errorf("failed to hash input: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// restore returns the saved output of stage if it was saved under key, or
+// nil.
+func (c *Checkpointer) restore(stage, key string) (*domain.RecordSet, error) {
+	saved, err := os.ReadFile(c.path(stage, keyExt))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if string(saved) != key {
+		return nil, nil
+	}
+	return snapshot.NewSource(c.path(stage, snapshotExt)).Load()
+}
+
+// save writes the snapshot then the key, each atomically, so that a key is
+// only found next to the snapshot it was saved with.
+func (c *Checkpointer) save(stage, key string, output *domain.RecordSet) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	// Remove the key first so that an interrupted save is not resumed.
+	if err := os.Remove(c.path(stage, keyExt)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err := writeAtomic(c.path(stage, snapshotExt), func(w io.Writer) error {
+		return snapshot.Encode(w, output)
+	})
+	if err != nil {
+		return err
+	}
+	return writeAtomic(c.path(stage, keyExt), func(w io.Writer) error {
+		_, err := io.WriteString(w, key)
+		return err
+	})
+}
+
+// path returns the file of a stage checkpoint, escaping the stage name.
+func (c *Checkpointer) path(stage, ext string) string {
+	return filepath.Join(c.Dir, url.PathEscape(stage)+ext)
+}
+
+// writeAtomic writes a temporary file next to path and renames it to path.
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	buf := bufio.NewWriter(f)
+	if err := write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
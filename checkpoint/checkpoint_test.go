@@ -0,0 +1,243 @@
+package checkpoint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/transform"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	mocksource "github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	mocktransform "github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSource loads its quantities and counts the loads. Its fingerprint
+// is version.
+type countingSource struct {
+	quantities []int64
+	version    string
+	loads      int
+}
+
+func (s *countingSource) Load() (*domain.RecordSet, error) {
+	s.loads++
+	return mocksource.NewStock(s.quantities...), nil
+}
+
+func (s *countingSource) Fingerprint() (string, error) {
+	return s.version, nil
+}
+
+// newCheckpointer creates a Checkpointer of p saving to dir.
+func newCheckpointer(dir string, p *pipeline.DataPipeline, resume bool) *Checkpointer {
+	cp := New(dir)
+	cp.Source = p.Source
+	cp.Resume = resume
+	return cp
+}
+
+// countingTransform adds one to every quantity and counts the calls.
+type countingTransform struct {
+	calls int
+}
+
+func (t *countingTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	t.calls++
+	return mocktransform.NewAddIntTransform("quantity", 1).Transform(input)
+}
+
+// flakyStore fails while err is set and keeps the last stored RecordSet.
+type flakyStore struct {
+	err    error
+	stored *domain.RecordSet
+}
+
+func (s *flakyStore) Store(data *domain.RecordSet) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.stored = data
+	return nil
+}
+
+func TestCheckpointer_Middleware(t *testing.T) {
+	t.Run("should resume after the last completed stage", func(t *testing.T) {
+		dir := t.TempDir()
+		src, tr, dst := &countingSource{quantities: []int64{1, 2}, version: "v1"}, &countingTransform{}, &flakyStore{err: errors.New("disk full")}
+		p := &pipeline.DataPipeline{Source: src, Transform: tr, Store: dst}
+		p.Use(newCheckpointer(dir, p, false).Middleware())
+
+		require.Error(t, p.Run())
+
+		dst.err = nil
+		p.Middleware = []ports.Middleware{newCheckpointer(dir, p, true).Middleware()}
+
+		require.NoError(t, p.Run())
+		assert.Equal(t, 1, src.loads)
+		assert.Equal(t, 1, tr.calls)
+		assert.Equal(t, []int64{2, 3}, mocksource.Quantities(dst.stored))
+	})
+
+	t.Run("should save without skipping when not resuming", func(t *testing.T) {
+		dir := t.TempDir()
+		src := &countingSource{quantities: []int64{1}, version: "v1"}
+		p := &pipeline.DataPipeline{Source: src, Transform: &countingTransform{}, Store: &flakyStore{}}
+		p.Use(newCheckpointer(dir, p, false).Middleware())
+
+		require.NoError(t, p.Run())
+		require.NoError(t, p.Run())
+
+		assert.Equal(t, 2, src.loads)
+		assert.FileExists(t, filepath.Join(dir, "load.snapshot"))
+		assert.FileExists(t, filepath.Join(dir, "transform.key"))
+	})
+
+	t.Run("should rerun every stage when the configuration changed", func(t *testing.T) {
+		dir := t.TempDir()
+		src := &countingSource{quantities: []int64{1}, version: "v1"}
+		p := &pipeline.DataPipeline{Source: src, Transform: &countingTransform{}, Store: &flakyStore{}}
+		first := newCheckpointer(dir, p, false)
+		first.Config = "v1"
+		p.Use(first.Middleware())
+		require.NoError(t, p.Run())
+
+		second := newCheckpointer(dir, p, true)
+		second.Config = "v2"
+		p.Middleware = []ports.Middleware{second.Middleware()}
+		require.NoError(t, p.Run())
+
+		assert.Equal(t, 2, src.loads)
+	})
+
+	t.Run("should reload a source whose fingerprint changed", func(t *testing.T) {
+		dir := t.TempDir()
+		src, dst := &countingSource{quantities: []int64{1}, version: "v1"}, &flakyStore{}
+		p := &pipeline.DataPipeline{Source: src, Transform: &countingTransform{}, Store: dst}
+		p.Use(newCheckpointer(dir, p, true).Middleware())
+		require.NoError(t, p.Run())
+		require.NoError(t, p.Run())
+
+		src.quantities, src.version = []int64{7}, "v2"
+		require.NoError(t, p.Run())
+
+		assert.Equal(t, 2, src.loads)
+		assert.Equal(t, []int64{8}, mocksource.Quantities(dst.stored))
+	})
+
+	t.Run("should not resume the load of a source without fingerprint", func(t *testing.T) {
+		dir := t.TempDir()
+		src, tr, dst := &countingSource{quantities: []int64{1}}, &countingTransform{}, &flakyStore{}
+		p := &pipeline.DataPipeline{Source: src, Transform: tr, Store: dst}
+		p.Use(newCheckpointer(dir, p, true).Middleware())
+		require.NoError(t, p.Run())
+
+		src.quantities = []int64{7}
+		require.NoError(t, p.Run())
+
+		assert.Equal(t, 2, src.loads)
+		assert.Equal(t, 2, tr.calls)
+		assert.Equal(t, []int64{8}, mocksource.Quantities(dst.stored))
+		assert.NoFileExists(t, filepath.Join(dir, "load.snapshot"))
+	})
+
+	t.Run("should rerun a stage whose input changed", func(t *testing.T) {
+		tr := &countingTransform{}
+		cp := New(t.TempDir())
+		cp.Resume = true
+		chain := transform.NewTransformBuilder().AddNamed("restock", tr).Use(cp.Middleware())
+
+		_, err := chain.Transform(mocksource.NewStock(1))
+		require.NoError(t, err)
+		_, err = chain.Transform(mocksource.NewStock(1))
+		require.NoError(t, err)
+		result, err := chain.Transform(mocksource.NewStock(5))
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, tr.calls)
+		assert.Equal(t, []int64{6}, mocksource.Quantities(result))
+	})
+
+	t.Run("should not resume a stage whose save was interrupted", func(t *testing.T) {
+		dir := t.TempDir()
+		tr := &countingTransform{}
+		cp := New(dir)
+		cp.Resume = true
+		chain := transform.NewTransformBuilder().AddNamed("restock", tr).Use(cp.Middleware())
+		_, err := chain.Transform(mocksource.NewStock(1))
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(filepath.Join(dir, "restock.key")))
+
+		_, err = chain.Transform(mocksource.NewStock(1))
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, tr.calls)
+	})
+
+	t.Run("should escape stage names", func(t *testing.T) {
+		dir := t.TempDir()
+		chain := transform.NewTransformBuilder().AddNamed("a/b c", &countingTransform{}).Use(New(dir).Middleware())
+
+		_, err := chain.Transform(mocksource.NewStock(1))
+
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "a%2Fb%20c.snapshot"))
+	})
+
+	t.Run("should fail the stage when saving fails", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o644))
+		chain := transform.NewTransformBuilder().AddNamed("restock", &countingTransform{}).Use(New(file).Middleware())
+
+		_, err := chain.Transform(mocksource.NewStock(1))
+
+		assert.ErrorContains(t, err, "checkpoint restock: ")
+	})
+}
+
+func TestCheckpointer_Clear(t *testing.T) {
+	t.Run("should delete checkpoints only", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+		chain := transform.NewTransformBuilder().Add(&countingTransform{}).Use(New(dir).Middleware())
+		_, err := chain.Transform(mocksource.NewStock(1))
+		require.NoError(t, err)
+
+		require.NoError(t, New(dir).Clear())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "notes.txt", entries[0].Name())
+	})
+
+	t.Run("should ignore a missing directory", func(t *testing.T) {
+		assert.NoError(t, New(filepath.Join(t.TempDir(), "missing")).Clear())
+	})
+}
+
+func TestHashConfig(t *testing.T) {
+	t.Run("should hash equal configurations equally", func(t *testing.T) {
+		a, err := HashConfig(map[string]any{"type": "drop", "columns": []string{"a"}})
+		require.NoError(t, err)
+		b, err := HashConfig(map[string]any{"columns": []string{"a"}, "type": "drop"})
+		require.NoError(t, err)
+		c, err := HashConfig(map[string]any{"type": "keep", "columns": []string{"a"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, a, b)
+		assert.NotEqual(t, a, c)
+		assert.Len(t, a, 64)
+	})
+
+	t.Run("should return error for unencodable configurations", func(t *testing.T) {
+		_, err := HashConfig(func() {})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,447 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CastMode selects how forgiving a cast is.
+type CastMode string
+
+const (
+	// CastStrict only accepts conversions that are exact: canonical string
+	// forms, integral floats, 0 and 1 as booleans.
+	CastStrict CastMode = ""
+	// CastLenient trims strings, accepts common spellings of booleans and
+	// dates, and rounds or truncates where information is lost.
+	CastLenient CastMode = "lenient"
+)
+
+// Rounding selects how fractional numbers become integers.
+type Rounding string
+
+const (
+	// RoundNone rejects fractional values in strict mode and truncates them
+	// toward zero in lenient mode.
+	RoundNone Rounding = ""
+	// RoundTruncate rounds toward zero.
+	RoundTruncate Rounding = "truncate"
+	// RoundNearest rounds to the nearest integer, halves away from zero.
+	RoundNearest Rounding = "nearest"
+	// RoundHalfEven rounds to the nearest integer, halves to even.
+	RoundHalfEven Rounding = "half_even"
+	// RoundFloor rounds toward negative infinity.
+	RoundFloor Rounding = "floor"
+	// RoundCeil rounds toward positive infinity.
+	RoundCeil Rounding = "ceil"
+)
+
+// Overflow selects what happens when a number does not fit in an int.
+type Overflow string
+
+const (
+	// OverflowError rejects values out of the int64 range.
+	OverflowError Overflow = ""
+	// OverflowSaturate clamps values to the int64 range.
+	OverflowSaturate Overflow = "saturate"
+)
+
+// CastOptions configures Cast. The zero value casts strictly, rejects
+// fractional and out of range integers, and reads numeric dates as Unix
+// seconds in UTC.
+type CastOptions struct {
+	Mode        CastMode       `json:"mode,omitempty"`         // Strict or lenient conversions
+	Rounding    Rounding       `json:"rounding,omitempty"`     // Float to int rounding
+	Overflow    Overflow       `json:"overflow,omitempty"`     // Int range overflow behaviour
+	DateLayouts []string       `json:"date_layouts,omitempty"` // Extra layouts tried first when parsing dates
+	Location    *time.Location `json:"-"`                      // Location of dates parsed without zone, UTC if nil
+	EpochUnit   time.Duration  `json:"epoch_unit,omitempty"`   // Unit of numeric dates, time.Second if zero
+}
+
+// CastError reports a value that cannot be cast to a type.
+type CastError struct {
+	Value Value      // Value being cast
+	To    NativeType // Target type
+	Msg   string     // Reason the cast failed
+	Err   error      // ErrUnsupportedCast when the type pair never converts, nil otherwise
+}
+
+func (e *CastError) Error() string {
+	return e.Msg
+}
+
+func (e *CastError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnsupportedCast is wrapped by errors for type pairs that never convert.
+var ErrUnsupportedCast = errors.New("unsupported cast")
+
+// strictDateLayouts are accepted in both modes, lenientDateLayouts only in
+// lenient mode.
+var (
+	strictDateLayouts  = []string{time.RFC3339Nano, "2006-01-02"}
+	lenientDateLayouts = []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006/01/02"}
+)
+
+// maxExactFloat is the largest integer every smaller integer of which a
+// float64 represents exactly.
+const maxExactFloat = 1 << 53
+
+// CanCast reports whether values of one native type can be cast to another
+// in the given mode. A true result does not guarantee every value converts:
+// "abc" is a string that does not parse as an int.
+func CanCast(from, to NativeType, mode CastMode) bool {
+	if from == to {
+		return isNativeType(from)
+	}
+	if !isNativeType(from) || !isNativeType(to) {
+		return false
+	}
+	switch {
+	case to == NativeTypeString, from == NativeTypeString:
+		return true
+	case from == NativeTypeBool && to == NativeTypeDate, from == NativeTypeDate && to == NativeTypeBool:
+		return false
+	case from == NativeTypeFloat && to == NativeTypeDate, from == NativeTypeDate && to == NativeTypeFloat:
+		return mode == CastLenient
+	}
+	return true
+}
+
+// Cast converts a value to a native type. Nulls become nulls of the target
+// type and arrays are cast element by element. Failures are reported as
+// *CastError, wrapping ErrUnsupportedCast when the type pair never converts.
+func Cast(value Value, to NativeType, opts CastOptions) (Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return NullValue{Type: to}, nil
+	case NullValue:
+		return NullValue{Type: to}, nil
+	case ArrayValue:
+		elements := make([]Value, len(v.Elements))
+		for i, element := range v.Elements {
+			cast, err := Cast(element, to, opts)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elements[i] = cast
+		}
+		return ArrayValue{ElementType: to, Elements: elements}, nil
+	}
+
+	from, ok := value.GetType().(NativeType)
+	if !ok || !CanCast(from, to, opts.Mode) {
+		return nil, unsupportedCast(value, to)
+	}
+	if from == to {
+		return value, nil
+	}
+
+	c := caster{opts: opts, value: value, to: to}
+	switch v := value.(type) {
+	case StringValue:
+		return c.fromString(string(v))
+	case IntValue:
+		return c.fromInt(int64(v))
+	case FloatValue:
+		return c.fromFloat(float64(v))
+	case BoolValue:
+		return c.fromBool(bool(v))
+	case DateValue:
+		return c.fromDate(time.Time(v))
+	}
+	return nil, unsupportedCast(value, to)
+}
+
+func isNativeType(t NativeType) bool {
+	switch t {
+	case NativeTypeString, NativeTypeInt, NativeTypeFloat, NativeTypeDate, NativeTypeBool:
+		return true
+	}
+	return false
+}
+
+func unsupportedCast(value Value, to NativeType) error {
+	from := "unknown"
+	if t := value.GetType(); t != nil {
+		from = t.GetTypeName()
+	}
+	return &CastError{Value: value, To: to, Msg: fmt.Sprintf("cannot convert %s to %s", from, to), Err: ErrUnsupportedCast}
+}
+
+// show formats a value for error messages.
+func show(v Value) string {
+	switch x := v.(type) {
+	case StringValue:
+		return strconv.Quote(string(x))
+	case DateValue:
+		return time.Time(x).Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// caster holds the state of a single conversion.
+type caster struct {
+	opts  CastOptions
+	value Value
+	to    NativeType
+}
+
+func (c caster) fail(format string, args ...any) (Value, error) {
+	return nil, &CastError{Value: c.value, To: c.to, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c caster) lenient() bool {
+	return c.opts.Mode == CastLenient
+}
+
+func (c caster) fromString(s string) (Value, error) {
+	if c.lenient() {
+		s = strings.TrimSpace(s)
+	}
+
+	switch c.to {
+	case NativeTypeInt:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			return IntValue(n), nil
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return c.toInt(math.Inf(sign(strings.HasPrefix(s, "-"))))
+		}
+		if c.lenient() {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return c.fromFloat(f)
+			}
+		}
+		return c.fail("cannot parse %q as int", s)
+
+	case NativeTypeFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return c.fail("cannot parse %q as float", s)
+		}
+		return FloatValue(f), nil
+
+	case NativeTypeBool:
+		if b, ok := parseBool(s, c.lenient()); ok {
+			return BoolValue(b), nil
+		}
+		return c.fail("cannot parse %q as bool", s)
+
+	case NativeTypeDate:
+		if t, ok := c.parseDate(s); ok {
+			return DateValue(t), nil
+		}
+		return c.fail("cannot parse %q as date", s)
+	}
+	return nil, unsupportedCast(c.value, c.to)
+}
+
+func (c caster) fromInt(n int64) (Value, error) {
+	switch c.to {
+	case NativeTypeString:
+		return StringValue(strconv.FormatInt(n, 10)), nil
+	case NativeTypeFloat:
+		if !c.lenient() && (n > maxExactFloat || n < -maxExactFloat) {
+			return c.fail("%d cannot be represented exactly as float", n)
+		}
+		return FloatValue(n), nil
+	case NativeTypeBool:
+		if !c.lenient() && n != 0 && n != 1 {
+			return c.fail("cannot convert %d to bool", n)
+		}
+		return BoolValue(n != 0), nil
+	case NativeTypeDate:
+		return DateValue(c.fromEpoch(float64(n), n)), nil
+	}
+	return nil, unsupportedCast(c.value, c.to)
+}
+
+func (c caster) fromFloat(f float64) (Value, error) {
+	switch c.to {
+	case NativeTypeString:
+		return StringValue(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	case NativeTypeInt:
+		return c.toInt(f)
+	case NativeTypeBool:
+		if math.IsNaN(f) || (!c.lenient() && f != 0 && f != 1) {
+			return c.fail("cannot convert %v to bool", f)
+		}
+		return BoolValue(f != 0), nil
+	case NativeTypeDate:
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return c.fail("cannot convert %v to date", f)
+		}
+		return DateValue(c.fromEpoch(f, 0)), nil
+	}
+	return nil, unsupportedCast(c.value, c.to)
+}
+
+func (c caster) fromBool(b bool) (Value, error) {
+	n := int64(0)
+	if b {
+		n = 1
+	}
+	switch c.to {
+	case NativeTypeString:
+		return StringValue(strconv.FormatBool(b)), nil
+	case NativeTypeInt:
+		return IntValue(n), nil
+	case NativeTypeFloat:
+		return FloatValue(n), nil
+	}
+	return nil, unsupportedCast(c.value, c.to)
+}
+
+func (c caster) fromDate(t time.Time) (Value, error) {
+	unit := c.epochUnit()
+	seconds, nanos := t.Unix(), int64(t.Nanosecond())
+	switch c.to {
+	case NativeTypeString:
+		return StringValue(t.Format(time.RFC3339Nano)), nil
+	case NativeTypeInt:
+		if unit >= time.Second {
+			perUnit := int64(unit / time.Second)
+			if !c.lenient() && (nanos != 0 || seconds%perUnit != 0) {
+				return c.fail("%s is not a whole number of %s", t.Format(time.RFC3339Nano), unit)
+			}
+			return IntValue(floorDiv(seconds, perUnit)), nil
+		}
+		perSecond := int64(time.Second / unit)
+		if !c.lenient() && nanos%int64(unit) != 0 {
+			return c.fail("%s is not a whole number of %s", t.Format(time.RFC3339Nano), unit)
+		}
+		if seconds > math.MaxInt64/perSecond || seconds < math.MinInt64/perSecond {
+			return c.toInt(math.Inf(sign(seconds < 0)))
+		}
+		return IntValue(seconds*perSecond + nanos/int64(unit)), nil
+	case NativeTypeFloat:
+		return FloatValue(float64(seconds)*(float64(time.Second)/float64(unit)) + float64(nanos)/float64(unit)), nil
+	}
+	return nil, unsupportedCast(c.value, c.to)
+}
+
+// toInt rounds a float according to the options and checks its range.
+func (c caster) toInt(f float64) (Value, error) {
+	if math.IsNaN(f) {
+		return c.fail("cannot convert NaN to int")
+	}
+
+	if f != math.Trunc(f) {
+		switch c.opts.Rounding {
+		case RoundNone:
+			if !c.lenient() {
+				return c.fail("cannot convert %v to int without rounding", f)
+			}
+			f = math.Trunc(f)
+		case RoundTruncate:
+			f = math.Trunc(f)
+		case RoundNearest:
+			f = math.Round(f)
+		case RoundHalfEven:
+			f = math.RoundToEven(f)
+		case RoundFloor:
+			f = math.Floor(f)
+		case RoundCeil:
+			f = math.Ceil(f)
+		default:
+			return c.fail("unknown rounding %q", c.opts.Rounding)
+		}
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which is already out of range.
+	if f >= math.MaxInt64 || f < math.MinInt64 {
+		if c.opts.Overflow != OverflowSaturate {
+			return c.fail("%s overflows int", show(c.value))
+		}
+		if f > 0 {
+			return IntValue(math.MaxInt64), nil
+		}
+		return IntValue(math.MinInt64), nil
+	}
+	return IntValue(int64(f)), nil
+}
+
+func (c caster) epochUnit() time.Duration {
+	if c.opts.EpochUnit <= 0 {
+		return time.Second
+	}
+	return c.opts.EpochUnit
+}
+
+// fromEpoch converts a number of epoch units to a time. Integers are passed
+// in exact so that large nanosecond timestamps keep their precision.
+func (c caster) fromEpoch(f float64, exact int64) time.Time {
+	unit := c.epochUnit()
+	if f == float64(exact) && unit <= time.Second {
+		perSecond := int64(time.Second / unit)
+		return time.Unix(floorDiv(exact, perSecond), (exact-floorDiv(exact, perSecond)*perSecond)*int64(unit)).UTC()
+	}
+	seconds := f * unit.Seconds()
+	whole := math.Floor(seconds)
+	return time.Unix(int64(whole), int64((seconds-whole)*1e9)).UTC()
+}
+
+func (c caster) parseDate(s string) (time.Time, bool) {
+	loc := c.opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	layouts := append(append([]string{}, c.opts.DateLayouts...), strictDateLayouts...)
+	if c.lenient() {
+		layouts = append(layouts, lenientDateLayouts...)
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+
+	if c.lenient() {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return c.fromEpoch(float64(n), n), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseBool(s string, lenient bool) (bool, bool) {
+	if !lenient {
+		switch s {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+		return false, false
+	}
+	switch strings.ToLower(s) {
+	case "true", "t", "yes", "y", "on", "1":
+		return true, true
+	case "false", "f", "no", "n", "off", "0":
+		return false, true
+	}
+	return false, false
+}
+
+func sign(negative bool) int {
+	if negative {
+		return -1
+	}
+	return 1
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
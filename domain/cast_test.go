@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanCast(t *testing.T) {
+	s, i, f, d, b := NativeTypeString, NativeTypeInt, NativeTypeFloat, NativeTypeDate, NativeTypeBool
+
+	// Rows are source types, columns target types: string, int, float, date, bool.
+	types := []NativeType{s, i, f, d, b}
+	strict := map[NativeType][]bool{
+		s: {true, true, true, true, true},
+		i: {true, true, true, true, true},
+		f: {true, true, true, false, true},
+		d: {true, true, false, true, false},
+		b: {true, true, true, false, true},
+	}
+	lenient := map[NativeType][]bool{
+		s: {true, true, true, true, true},
+		i: {true, true, true, true, true},
+		f: {true, true, true, true, true},
+		d: {true, true, true, true, false},
+		b: {true, true, true, false, true},
+	}
+
+	for _, from := range types {
+		for j, to := range types {
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				assert.Equal(t, strict[from][j], CanCast(from, to, CastStrict))
+				assert.Equal(t, lenient[from][j], CanCast(from, to, CastLenient))
+			})
+		}
+	}
+
+	t.Run("should reject unknown types", func(t *testing.T) {
+		assert.False(t, CanCast("decimal", NativeTypeString, CastLenient))
+		assert.False(t, CanCast(NativeTypeString, "decimal", CastLenient))
+		assert.False(t, CanCast("decimal", "decimal", CastLenient))
+	})
+}
+
+func TestCast(t *testing.T) {
+	lenient := CastOptions{Mode: CastLenient}
+	jan := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	paris, _ := time.LoadLocation("Europe/Paris")
+
+	tests := []struct {
+		name     string
+		value    Value
+		to       NativeType
+		opts     CastOptions
+		expected Value
+		err      string
+	}{
+		// Identity and nulls
+		{"string to string", StringValue("a"), NativeTypeString, CastOptions{}, StringValue("a"), ""},
+		{"null keeps null with target type", NullValue{Type: NativeTypeString}, NativeTypeInt, CastOptions{}, NullValue{Type: NativeTypeInt}, ""},
+		{"nil becomes null", nil, NativeTypeDate, CastOptions{}, NullValue{Type: NativeTypeDate}, ""},
+
+		// string to int
+		{"string to int", StringValue("-42"), NativeTypeInt, CastOptions{}, IntValue(-42), ""},
+		{"string to int rejects spaces in strict mode", StringValue(" 42 "), NativeTypeInt, CastOptions{}, nil, `cannot parse " 42 " as int`},
+		{"string to int trims in lenient mode", StringValue(" 42 "), NativeTypeInt, lenient, IntValue(42), ""},
+		{"string to int rejects decimals in strict mode", StringValue("4.7"), NativeTypeInt, CastOptions{}, nil, `cannot parse "4.7" as int`},
+		{"string to int truncates decimals in lenient mode", StringValue("4.7"), NativeTypeInt, lenient, IntValue(4), ""},
+		{"string to int rounds decimals", StringValue("4.5"), NativeTypeInt, CastOptions{Mode: CastLenient, Rounding: RoundHalfEven}, IntValue(4), ""},
+		{"string to int overflows", StringValue("9223372036854775808"), NativeTypeInt, CastOptions{}, nil, `"9223372036854775808" overflows int`},
+		{"string to int saturates", StringValue("-9223372036854775809"), NativeTypeInt, CastOptions{Overflow: OverflowSaturate}, IntValue(math.MinInt64), ""},
+		{"string to int rejects text", StringValue("abc"), NativeTypeInt, lenient, nil, `cannot parse "abc" as int`},
+
+		// string to float
+		{"string to float", StringValue("1.25e2"), NativeTypeFloat, CastOptions{}, FloatValue(125), ""},
+		{"string to float trims in lenient mode", StringValue("\t1.5\n"), NativeTypeFloat, lenient, FloatValue(1.5), ""},
+		{"string to float rejects text", StringValue("1,5"), NativeTypeFloat, CastOptions{}, nil, `cannot parse "1,5" as float`},
+
+		// string to bool
+		{"string to bool", StringValue("true"), NativeTypeBool, CastOptions{}, BoolValue(true), ""},
+		{"string to bool rejects spellings in strict mode", StringValue("yes"), NativeTypeBool, CastOptions{}, nil, `cannot parse "yes" as bool`},
+		{"string to bool accepts spellings in lenient mode", StringValue("Off"), NativeTypeBool, lenient, BoolValue(false), ""},
+		{"string to bool accepts digits in lenient mode", StringValue("1"), NativeTypeBool, lenient, BoolValue(true), ""},
+
+		// string to date
+		{"string to date from RFC 3339", StringValue("2024-01-15T11:30:00+01:00"), NativeTypeDate, CastOptions{}, DateValue(time.Date(2024, 1, 15, 11, 30, 0, 0, time.FixedZone("", 3600))), ""},
+		{"string to date from ISO date", StringValue("2024-01-15"), NativeTypeDate, CastOptions{}, DateValue(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)), ""},
+		{"string to date rejects other layouts in strict mode", StringValue("2024-01-15 10:30:00"), NativeTypeDate, CastOptions{}, nil, `cannot parse "2024-01-15 10:30:00" as date`},
+		{"string to date accepts other layouts in lenient mode", StringValue("2024-01-15 10:30:00"), NativeTypeDate, lenient, DateValue(jan), ""},
+		{"string to date uses location", StringValue("2024-01-15"), NativeTypeDate, CastOptions{Location: paris}, DateValue(time.Date(2024, 1, 15, 0, 0, 0, 0, paris)), ""},
+		{"string to date uses custom layouts", StringValue("15/01/2024 10:30"), NativeTypeDate, CastOptions{DateLayouts: []string{"02/01/2006 15:04"}}, DateValue(jan), ""},
+		{"string to date reads epochs in lenient mode", StringValue("1705314600"), NativeTypeDate, lenient, DateValue(jan), ""},
+
+		// int
+		{"int to string", IntValue(-7), NativeTypeString, CastOptions{}, StringValue("-7"), ""},
+		{"int to float", IntValue(3), NativeTypeFloat, CastOptions{}, FloatValue(3), ""},
+		{"int to float rejects precision loss in strict mode", IntValue(1<<53 + 1), NativeTypeFloat, CastOptions{}, nil, "9007199254740993 cannot be represented exactly as float"},
+		{"int to float accepts precision loss in lenient mode", IntValue(1<<53 + 1), NativeTypeFloat, lenient, FloatValue(1 << 53), ""},
+		{"int to bool", IntValue(1), NativeTypeBool, CastOptions{}, BoolValue(true), ""},
+		{"int to bool rejects other values in strict mode", IntValue(2), NativeTypeBool, CastOptions{}, nil, "cannot convert 2 to bool"},
+		{"int to bool accepts other values in lenient mode", IntValue(-2), NativeTypeBool, lenient, BoolValue(true), ""},
+		{"int to date from epoch seconds", IntValue(1705314600), NativeTypeDate, CastOptions{}, DateValue(jan), ""},
+		{"int to date from epoch milliseconds", IntValue(1705314600123), NativeTypeDate, CastOptions{EpochUnit: time.Millisecond}, DateValue(jan.Add(123 * time.Millisecond)), ""},
+		{"int to date before epoch", IntValue(-1500), NativeTypeDate, CastOptions{EpochUnit: time.Millisecond}, DateValue(time.Date(1969, 12, 31, 23, 59, 58, 500000000, time.UTC)), ""},
+
+		// float
+		{"float to string", FloatValue(0.1), NativeTypeString, CastOptions{}, StringValue("0.1"), ""},
+		{"float to int when integral", FloatValue(-12), NativeTypeInt, CastOptions{}, IntValue(-12), ""},
+		{"float to int rejects fractions in strict mode", FloatValue(2.5), NativeTypeInt, CastOptions{}, nil, "cannot convert 2.5 to int without rounding"},
+		{"float to int truncates in lenient mode", FloatValue(-2.7), NativeTypeInt, lenient, IntValue(-2), ""},
+		{"float to int truncates", FloatValue(-2.7), NativeTypeInt, CastOptions{Rounding: RoundTruncate}, IntValue(-2), ""},
+		{"float to int rounds to nearest", FloatValue(-2.5), NativeTypeInt, CastOptions{Rounding: RoundNearest}, IntValue(-3), ""},
+		{"float to int rounds half to even", FloatValue(2.5), NativeTypeInt, CastOptions{Rounding: RoundHalfEven}, IntValue(2), ""},
+		{"float to int rounds down", FloatValue(-2.1), NativeTypeInt, CastOptions{Rounding: RoundFloor}, IntValue(-3), ""},
+		{"float to int rounds up", FloatValue(2.1), NativeTypeInt, CastOptions{Rounding: RoundCeil}, IntValue(3), ""},
+		{"float to int rejects unknown rounding", FloatValue(2.1), NativeTypeInt, CastOptions{Rounding: "banker"}, nil, `unknown rounding "banker"`},
+		{"float to int overflows", FloatValue(1e19), NativeTypeInt, CastOptions{}, nil, "1e+19 overflows int"},
+		{"float to int saturates", FloatValue(math.Inf(1)), NativeTypeInt, CastOptions{Overflow: OverflowSaturate}, IntValue(math.MaxInt64), ""},
+		{"float to int rejects NaN", FloatValue(math.NaN()), NativeTypeInt, CastOptions{Overflow: OverflowSaturate}, nil, "cannot convert NaN to int"},
+		{"float to bool", FloatValue(0), NativeTypeBool, CastOptions{}, BoolValue(false), ""},
+		{"float to bool rejects other values in strict mode", FloatValue(0.5), NativeTypeBool, CastOptions{}, nil, "cannot convert 0.5 to bool"},
+		{"float to bool accepts other values in lenient mode", FloatValue(0.5), NativeTypeBool, lenient, BoolValue(true), ""},
+		{"float to date in lenient mode", FloatValue(1705314600.5), NativeTypeDate, lenient, DateValue(jan.Add(500 * time.Millisecond)), ""},
+		{"float to date in strict mode", FloatValue(1705314600), NativeTypeDate, CastOptions{}, nil, "cannot convert float to date"},
+
+		// bool
+		{"bool to string", BoolValue(false), NativeTypeString, CastOptions{}, StringValue("false"), ""},
+		{"bool to int", BoolValue(true), NativeTypeInt, CastOptions{}, IntValue(1), ""},
+		{"bool to float", BoolValue(true), NativeTypeFloat, CastOptions{}, FloatValue(1), ""},
+		{"bool to date", BoolValue(true), NativeTypeDate, lenient, nil, "cannot convert bool to date"},
+
+		// date
+		{"date to string", DateValue(jan.Add(time.Millisecond)), NativeTypeString, CastOptions{}, StringValue("2024-01-15T10:30:00.001Z"), ""},
+		{"date to int as epoch seconds", DateValue(jan), NativeTypeInt, CastOptions{}, IntValue(1705314600), ""},
+		{"date to int as epoch milliseconds", DateValue(jan.Add(5 * time.Millisecond)), NativeTypeInt, CastOptions{EpochUnit: time.Millisecond}, IntValue(1705314600005), ""},
+		{"date to int rejects precision loss in strict mode", DateValue(jan.Add(time.Millisecond)), NativeTypeInt, CastOptions{}, nil, "2024-01-15T10:30:00.001Z is not a whole number of 1s"},
+		{"date to int floors in lenient mode", DateValue(time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC)), NativeTypeInt, lenient, IntValue(-1), ""},
+		{"date to float in lenient mode", DateValue(jan.Add(250 * time.Millisecond)), NativeTypeFloat, lenient, FloatValue(1705314600.25), ""},
+		{"date to bool", DateValue(jan), NativeTypeBool, lenient, nil, "cannot convert date to bool"},
+
+		// Arrays and unsupported values
+		{"array element by element", ArrayValue{ElementType: NativeTypeString, Elements: []Value{StringValue("1"), NullValue{Type: NativeTypeString}}}, NativeTypeInt, CastOptions{}, ArrayValue{ElementType: NativeTypeInt, Elements: []Value{IntValue(1), NullValue{Type: NativeTypeInt}}}, ""},
+		{"array with invalid element", ArrayValue{ElementType: NativeTypeString, Elements: []Value{StringValue("1"), StringValue("x")}}, NativeTypeInt, CastOptions{}, nil, `element 1: cannot parse "x" as int`},
+		{"record", RecordValue{Record: NewRecord(&DataSchema{ID: "Address"})}, NativeTypeString, lenient, nil, "cannot convert Address to string"},
+		{"unknown target type", IntValue(1), "decimal", lenient, nil, "cannot convert int to decimal"},
+	}
+
+	for _, tt := range tests {
+		t.Run("should cast "+tt.name, func(t *testing.T) {
+			result, err := Cast(tt.value, tt.to, tt.opts)
+
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				var castErr *CastError
+				assert.True(t, errors.As(err, &castErr))
+				return
+			}
+			require.NoError(t, err)
+			if expected, ok := tt.expected.(DateValue); ok {
+				actual, ok := result.(DateValue)
+				require.True(t, ok, "expected a DateValue, got %T", result)
+				assert.True(t, time.Time(expected).Equal(time.Time(actual)), "expected %v, got %v", time.Time(expected), time.Time(actual))
+				return
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	t.Run("should wrap ErrUnsupportedCast only for unsupported pairs", func(t *testing.T) {
+		_, err := Cast(BoolValue(true), NativeTypeDate, CastOptions{})
+		assert.True(t, errors.Is(err, ErrUnsupportedCast))
+
+		_, err = Cast(StringValue("x"), NativeTypeInt, CastOptions{})
+		assert.False(t, errors.Is(err, ErrUnsupportedCast))
+	})
+}
@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownColumn is returned when a column ID is not part of a schema.
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrDuplicateColumn is returned when a column ID is already part of a schema.
+	ErrDuplicateColumn = errors.New("duplicate column")
+	// ErrColumnConflict is returned when two schemas define a column with different types.
+	ErrColumnConflict = errors.New("conflicting column types")
+)
+
+// Column returns the column with the given ID, or nil if the schema has none.
+func (s *DataSchema) Column(id string) SchemaColumn {
+	if i := s.IndexOf(id); i >= 0 {
+		return s.Columns[i]
+	}
+	return nil
+}
+
+// HasColumn returns true if the schema has a column with the given ID.
+func (s *DataSchema) HasColumn(id string) bool {
+	return s.IndexOf(id) >= 0
+}
+
+// IndexOf returns the position of the column with the given ID, or -1.
+func (s *DataSchema) IndexOf(id string) int {
+	for i, col := range s.Columns {
+		if col.GetID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ColumnIDs returns the IDs of all columns, in schema order.
+func (s *DataSchema) ColumnIDs() []string {
+	ids := make([]string, len(s.Columns))
+	for i, col := range s.Columns {
+		ids[i] = col.GetID()
+	}
+	return ids
+}
+
+// Select returns a new schema with only the given columns, in the given order.
+func (s *DataSchema) Select(ids ...string) (*DataSchema, error) {
+	result := &DataSchema{ID: s.ID, Columns: make([]SchemaColumn, 0, len(ids))}
+	for _, id := range ids {
+		col := s.Column(id)
+		if col == nil {
+			return nil, fmt.Errorf("column %s: %w", id, ErrUnknownColumn)
+		}
+		if result.HasColumn(id) {
+			return nil, fmt.Errorf("column %s: %w", id, ErrDuplicateColumn)
+		}
+		result.Columns = append(result.Columns, col)
+	}
+	return result, nil
+}
+
+// Drop returns a new schema without the given columns.
+func (s *DataSchema) Drop(ids ...string) (*DataSchema, error) {
+	dropped := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !s.HasColumn(id) {
+			return nil, fmt.Errorf("column %s: %w", id, ErrUnknownColumn)
+		}
+		dropped[id] = true
+	}
+
+	result := &DataSchema{ID: s.ID, Columns: make([]SchemaColumn, 0, len(s.Columns))}
+	for _, col := range s.Columns {
+		if !dropped[col.GetID()] {
+			result.Columns = append(result.Columns, col)
+		}
+	}
+	return result, nil
+}
+
+// Rename returns a new schema where the column oldID is renamed to newID,
+// keeping its type and position.
+func (s *DataSchema) Rename(oldID, newID string) (*DataSchema, error) {
+	i := s.IndexOf(oldID)
+	if i < 0 {
+		return nil, fmt.Errorf("column %s: %w", oldID, ErrUnknownColumn)
+	}
+	if oldID != newID && s.HasColumn(newID) {
+		return nil, fmt.Errorf("column %s: %w", newID, ErrDuplicateColumn)
+	}
+
+	result := s.clone()
+	result.Columns[i] = renameColumn(s.Columns[i], newID)
+	return result, nil
+}
+
+// AddColumn returns a new schema with the given column appended.
+func (s *DataSchema) AddColumn(col SchemaColumn) (*DataSchema, error) {
+	if s.HasColumn(col.GetID()) {
+		return nil, fmt.Errorf("column %s: %w", col.GetID(), ErrDuplicateColumn)
+	}
+
+	result := s.clone()
+	result.Columns = append(result.Columns, col)
+	return result, nil
+}
+
+// WithColumn returns a new schema where the column with the same ID is
+// replaced by col, or where col is appended if there is none.
+func (s *DataSchema) WithColumn(col SchemaColumn) *DataSchema {
+	result := s.clone()
+	if i := s.IndexOf(col.GetID()); i >= 0 {
+		result.Columns[i] = col
+	} else {
+		result.Columns = append(result.Columns, col)
+	}
+	return result
+}
+
+// Merge returns a new schema with the columns of s followed by the columns
+// of other that s does not have. Columns present in both must have the same
+// type and the same arity.
+func (s *DataSchema) Merge(other *DataSchema) (*DataSchema, error) {
+	result := s.clone()
+	for _, col := range other.Columns {
+		existing := s.Column(col.GetID())
+		if existing == nil {
+			result.Columns = append(result.Columns, col)
+			continue
+		}
+		if !sameColumnType(existing, col) {
+			return nil, fmt.Errorf("column %s: %w", col.GetID(), ErrColumnConflict)
+		}
+	}
+	return result, nil
+}
+
+func (s *DataSchema) clone() *DataSchema {
+	columns := make([]SchemaColumn, len(s.Columns))
+	copy(columns, s.Columns)
+	return &DataSchema{ID: s.ID, Columns: columns}
+}
+
+func renameColumn(col SchemaColumn, id string) SchemaColumn {
+	if col.IsArray() {
+		return SchemaColumnArray{ID: id, RefSchema: col.GetType()}
+	}
+	return SchemaColumnSingle{ID: id, SchemaType: col.GetType()}
+}
+
+func sameColumnType(a, b SchemaColumn) bool {
+	if a.IsArray() != b.IsArray() {
+		return false
+	}
+	ta, tb := a.GetType(), b.GetType()
+	if ta == nil || tb == nil {
+		return ta == tb
+	}
+	return ta.IsNative() == tb.IsNative() && ta.GetTypeName() == tb.GetTypeName()
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataSchema_Lookups(t *testing.T) {
+	schema := createTestSchema()
+
+	t.Run("should find column by ID", func(t *testing.T) {
+		assert.Equal(t, SchemaColumnSingle{ID: "price", SchemaType: NativeTypeFloat}, schema.Column("price"))
+		assert.True(t, schema.HasColumn("price"))
+		assert.Equal(t, 1, schema.IndexOf("price"))
+	})
+
+	t.Run("should return nil for unknown column", func(t *testing.T) {
+		assert.Nil(t, schema.Column("missing"))
+		assert.False(t, schema.HasColumn("missing"))
+		assert.Equal(t, -1, schema.IndexOf("missing"))
+	})
+
+	t.Run("should list column IDs in order", func(t *testing.T) {
+		assert.Equal(t, []string{"name", "price", "quantity"}, schema.ColumnIDs())
+	})
+}
+
+func TestDataSchema_Select(t *testing.T) {
+	t.Run("should keep given columns in given order", func(t *testing.T) {
+		schema := createTestSchema()
+
+		result, err := schema.Select("quantity", "name")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Product", result.ID)
+		assert.Equal(t, []string{"quantity", "name"}, result.ColumnIDs())
+		assert.Len(t, schema.Columns, 3, "original schema should be unchanged")
+	})
+
+	t.Run("should return error for unknown column", func(t *testing.T) {
+		_, err := createTestSchema().Select("name", "missing")
+
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+		assert.Contains(t, err.Error(), "column missing")
+	})
+
+	t.Run("should return error for repeated column", func(t *testing.T) {
+		_, err := createTestSchema().Select("name", "name")
+
+		assert.ErrorIs(t, err, ErrDuplicateColumn)
+	})
+}
+
+func TestDataSchema_Drop(t *testing.T) {
+	t.Run("should remove given columns", func(t *testing.T) {
+		result, err := createTestSchema().Drop("price")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "quantity"}, result.ColumnIDs())
+	})
+
+	t.Run("should return error for unknown column", func(t *testing.T) {
+		_, err := createTestSchema().Drop("missing")
+
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+}
+
+func TestDataSchema_Rename(t *testing.T) {
+	t.Run("should rename column keeping type and position", func(t *testing.T) {
+		schema := &DataSchema{ID: "Test", Columns: []SchemaColumn{
+			SchemaColumnSingle{ID: "a", SchemaType: NativeTypeInt},
+			SchemaColumnArray{ID: "tags", RefSchema: NativeTypeString},
+		}}
+
+		result, err := schema.Rename("tags", "labels")
+
+		require.NoError(t, err)
+		assert.Equal(t, SchemaColumnArray{ID: "labels", RefSchema: NativeTypeString}, result.Columns[1])
+		assert.Equal(t, "tags", schema.Columns[1].GetID(), "original schema should be unchanged")
+	})
+
+	t.Run("should return error for unknown column", func(t *testing.T) {
+		_, err := createTestSchema().Rename("missing", "other")
+
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+
+	t.Run("should return error when new ID exists", func(t *testing.T) {
+		_, err := createTestSchema().Rename("name", "price")
+
+		assert.ErrorIs(t, err, ErrDuplicateColumn)
+	})
+}
+
+func TestDataSchema_AddColumn(t *testing.T) {
+	t.Run("should append column", func(t *testing.T) {
+		schema := createTestSchema()
+
+		result, err := schema.AddColumn(SchemaColumnSingle{ID: "active", SchemaType: NativeTypeBool})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "price", "quantity", "active"}, result.ColumnIDs())
+		assert.Len(t, schema.Columns, 3, "original schema should be unchanged")
+	})
+
+	t.Run("should return error for existing column", func(t *testing.T) {
+		_, err := createTestSchema().AddColumn(SchemaColumnSingle{ID: "name", SchemaType: NativeTypeString})
+
+		assert.ErrorIs(t, err, ErrDuplicateColumn)
+	})
+}
+
+func TestDataSchema_WithColumn(t *testing.T) {
+	t.Run("should replace existing column in place", func(t *testing.T) {
+		result := createTestSchema().WithColumn(SchemaColumnSingle{ID: "price", SchemaType: NativeTypeInt})
+
+		assert.Equal(t, []string{"name", "price", "quantity"}, result.ColumnIDs())
+		assert.Equal(t, NativeTypeInt, result.Column("price").GetType())
+	})
+
+	t.Run("should append new column", func(t *testing.T) {
+		result := createTestSchema().WithColumn(SchemaColumnSingle{ID: "total", SchemaType: NativeTypeFloat})
+
+		assert.Equal(t, []string{"name", "price", "quantity", "total"}, result.ColumnIDs())
+	})
+}
+
+func TestDataSchema_Merge(t *testing.T) {
+	t.Run("should append columns missing from the first schema", func(t *testing.T) {
+		other := &DataSchema{ID: "Stock", Columns: []SchemaColumn{
+			SchemaColumnSingle{ID: "name", SchemaType: NativeTypeString},
+			SchemaColumnSingle{ID: "warehouse", SchemaType: NativeTypeString},
+		}}
+
+		result, err := createTestSchema().Merge(other)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Product", result.ID)
+		assert.Equal(t, []string{"name", "price", "quantity", "warehouse"}, result.ColumnIDs())
+	})
+
+	t.Run("should return error for conflicting types", func(t *testing.T) {
+		other := &DataSchema{ID: "Other", Columns: []SchemaColumn{
+			SchemaColumnArray{ID: "name", RefSchema: NativeTypeString},
+		}}
+
+		_, err := createTestSchema().Merge(other)
+
+		assert.ErrorIs(t, err, ErrColumnConflict)
+		assert.Contains(t, err.Error(), "column name")
+	})
+}
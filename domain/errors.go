@@ -0,0 +1,64 @@
+package domain
+
+import "fmt"
+
+// StageError reports a failure in a named pipeline stage, such as "load" or
+// a named transform of a TransformBuilder.
+type StageError struct {
+	Stage string // Stage name
+	Err   error  // Underlying error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// RecordError reports a failure on a single record.
+type RecordError struct {
+	Index  int    // Zero-based position of the record in its RecordSet or file
+	Source string // Origin of the record, such as a file path, empty if unknown
+	Err    error  // Underlying error
+}
+
+// NewRecordError wraps err for the record at index, taking the source from
+// the record when there is one.
+func NewRecordError(index int, record *Record, err error) *RecordError {
+	e := &RecordError{Index: index, Err: err}
+	if record != nil {
+		e.Source = record.Source
+	}
+	return e
+}
+
+func (e *RecordError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("record %d (%s): %v", e.Index, e.Source, e.Err)
+	}
+	return fmt.Sprintf("record %d: %v", e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError reports data that does not conform to a schema.
+type SchemaError struct {
+	Schema string // ID of the schema the data was checked against
+	Column string // Column path such as "address.zip" or "tags[2]", empty for the whole record
+	Err    error  // What does not conform
+}
+
+func (e *SchemaError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("column %s: %v", e.Column, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageError(t *testing.T) {
+	t.Run("should name the stage and unwrap", func(t *testing.T) {
+		cause := errors.New("boom")
+		err := error(&StageError{Stage: "load", Err: cause})
+
+		assert.EqualError(t, err, "stage load: boom")
+		assert.ErrorIs(t, err, cause)
+	})
+}
+
+func TestRecordError(t *testing.T) {
+	t.Run("should include the source when known", func(t *testing.T) {
+		record := &Record{Source: "products.json"}
+
+		err := NewRecordError(3, record, errors.New("boom"))
+
+		assert.EqualError(t, err, "record 3 (products.json): boom")
+		assert.Equal(t, "products.json", err.Source)
+	})
+
+	t.Run("should omit an unknown source", func(t *testing.T) {
+		err := NewRecordError(3, nil, errors.New("boom"))
+
+		assert.EqualError(t, err, "record 3: boom")
+	})
+}
+
+func TestSchemaError(t *testing.T) {
+	t.Run("should prefix the column path", func(t *testing.T) {
+		err := &SchemaError{Schema: "Product", Column: "items[1].price", Err: errors.New("expected number, got string")}
+
+		assert.EqualError(t, err, "column items[1].price: expected number, got string")
+	})
+
+	t.Run("should report whole record errors without a column", func(t *testing.T) {
+		err := &SchemaError{Schema: "Product", Err: errors.New("expected object")}
+
+		assert.EqualError(t, err, "expected object")
+	})
+
+	t.Run("should be found through nested errors", func(t *testing.T) {
+		cause := &SchemaError{Schema: "Product", Column: "price", Err: ErrUnknownColumn}
+		err := fmt.Errorf("wrapped: %w", &StageError{Stage: "load", Err: &RecordError{Index: 2, Err: cause}})
+
+		var stageErr *StageError
+		var recordErr *RecordError
+		var schemaErr *SchemaError
+		require.ErrorAs(t, err, &stageErr)
+		require.ErrorAs(t, err, &recordErr)
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, "load", stageErr.Stage)
+		assert.Equal(t, 2, recordErr.Index)
+		assert.Equal(t, "price", schemaErr.Column)
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+}
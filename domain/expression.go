@@ -0,0 +1,734 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Expr is an expression compiled and type-checked against a DataSchema.
+//
+// Expressions read columns by ID and navigate into nested records with "."
+// and into arrays with "[index]", where negative indexes count from the end:
+//
+//	stock > 0 and category == 'food'
+//	lower(customer.address.city) in ['paris', 'lyon']
+//	items[0].price * (1 + tax_rate)
+//	year(created_at) >= 2024 or coalesce(discount, 0) > 10
+//
+// Supported operators are arithmetic (+ - * / %), comparisons (== != < <= >
+// >=), in with a list or an array column, and boolean logic (and or not,
+// also written && || !). Identifiers that clash with keywords or contain
+// special characters can be quoted with backticks.
+//
+// Navigation is null-safe: a missing or null value anywhere in a path, or an
+// out of range index, yields null. Arithmetic and functions propagate null,
+// except coalesce and is_null. Comparisons never return null: == and != treat
+// null as a regular value, and ordering comparisons with null are false.
+// Boolean operators treat null as false.
+type Expr struct {
+	Source string     // Expression source
+	Type   SchemaType // Result type, nil if the expression is always null
+	Array  bool       // True if the result is an array
+
+	eval evalFunc
+}
+
+// ExprError is returned when an expression cannot be parsed or type-checked.
+type ExprError struct {
+	Source string // Expression source
+	Pos    int    // Byte offset of the error in the source
+	Msg    string // Description of the error
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("invalid expression %q at position %d: %s", e.Source, e.Pos+1, e.Msg)
+}
+
+// CompileExpr parses source and type-checks it against schema.
+// It returns an *ExprError if the expression is invalid.
+func CompileExpr(source string, schema *DataSchema) (*Expr, error) {
+	node, err := parseExpr(source)
+	if err == nil {
+		var fn evalFunc
+		var typ exprType
+		fn, typ, err = (&exprCompiler{schema: schema}).compile(node)
+		if err == nil {
+			return &Expr{Source: source, Type: typ.typ, Array: typ.array, eval: fn}, nil
+		}
+	}
+
+	var exprErr *ExprError
+	if errors.As(err, &exprErr) {
+		exprErr.Source = source
+	}
+	return nil, err
+}
+
+// Eval evaluates the expression against record. Null results are returned
+// as a NullValue of the expression type.
+func (e *Expr) Eval(record *Record) (Value, error) {
+	v, err := e.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return NullValue{Type: e.Type}, nil
+	}
+	return v, nil
+}
+
+// Match evaluates the expression against record and returns true only if
+// the result is the boolean true.
+func (e *Expr) Match(record *Record) (bool, error) {
+	v, err := e.eval(record)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(BoolValue)
+	return ok && bool(b), nil
+}
+
+// Column returns a schema column with the given ID holding the expression
+// result.
+func (e *Expr) Column(id string) (SchemaColumn, error) {
+	if e.Type == nil {
+		return nil, &ExprError{Source: e.Source, Msg: "cannot infer the type of a null expression"}
+	}
+	if e.Array {
+		return SchemaColumnArray{ID: id, RefSchema: e.Type}, nil
+	}
+	return SchemaColumnSingle{ID: id, SchemaType: e.Type}, nil
+}
+
+// evalFunc evaluates a compiled node. Null is returned as a nil Value.
+type evalFunc func(r *Record) (Value, error)
+
+// exprType is the static type of a node. A nil typ is the type of the null
+// literal, which is compatible with every other type.
+type exprType struct {
+	typ   SchemaType
+	array bool
+}
+
+var (
+	typeNull   = exprType{}
+	typeString = exprType{typ: NativeTypeString}
+	typeInt    = exprType{typ: NativeTypeInt}
+	typeFloat  = exprType{typ: NativeTypeFloat}
+	typeDate   = exprType{typ: NativeTypeDate}
+	typeBool   = exprType{typ: NativeTypeBool}
+)
+
+func (t exprType) isNull() bool              { return t.typ == nil }
+func (t exprType) is(native NativeType) bool { return !t.array && t.typ == native }
+func (t exprType) numeric() bool             { return t.is(NativeTypeInt) || t.is(NativeTypeFloat) }
+
+// holds reports whether v is a value of type t, ints being accepted as
+// floats. Records may hold values not matching their schema, which
+// evaluation reports instead of panicking.
+func (t exprType) holds(v Value) bool {
+	if t.array {
+		_, ok := v.(ArrayValue)
+		return ok
+	}
+	switch t.typ {
+	case NativeTypeString:
+		_, ok := v.(StringValue)
+		return ok
+	case NativeTypeInt:
+		_, ok := v.(IntValue)
+		return ok
+	case NativeTypeFloat:
+		return isNumber(v)
+	case NativeTypeDate:
+		_, ok := v.(DateValue)
+		return ok
+	case NativeTypeBool:
+		_, ok := v.(BoolValue)
+		return ok
+	}
+	if _, ok := t.typ.(CustomType); ok {
+		_, ok := v.(RecordValue)
+		return ok
+	}
+	return true
+}
+
+func (t exprType) String() string {
+	switch {
+	case t.isNull():
+		return "null"
+	case t.array:
+		return "[]" + t.typ.GetTypeName()
+	default:
+		return t.typ.GetTypeName()
+	}
+}
+
+// equatable reports whether values of both types can be tested for equality.
+func equatable(a, b exprType) bool {
+	if a.isNull() || b.isNull() {
+		return true
+	}
+	if a.array || b.array || !a.typ.IsNative() || !b.typ.IsNative() {
+		return false
+	}
+	return a.typ == b.typ || (a.numeric() && b.numeric())
+}
+
+// ordered reports whether values of both types can be ordered.
+func ordered(a, b exprType) bool {
+	if a.numeric() && b.numeric() {
+		return true
+	}
+	return (a.is(NativeTypeString) || a.is(NativeTypeDate)) && a == b
+}
+
+type exprCompiler struct {
+	schema *DataSchema
+}
+
+func (c *exprCompiler) compile(node exprNode) (evalFunc, exprType, error) {
+	switch n := node.(type) {
+	case *literalNode:
+		value := n.value
+		if value == nil {
+			return func(*Record) (Value, error) { return nil, nil }, typeNull, nil
+		}
+		return func(*Record) (Value, error) { return value, nil }, exprType{typ: value.GetType()}, nil
+	case *pathNode:
+		return c.path(n)
+	case *unaryNode:
+		return c.unary(n)
+	case *binaryNode:
+		if n.op == "in" {
+			return c.in(n)
+		}
+		return c.binary(n)
+	case *callNode:
+		return c.call(n)
+	case *listNode:
+		return nil, typeNull, &ExprError{Pos: n.pos, Msg: "lists are only allowed after in"}
+	}
+	return nil, typeNull, &ExprError{Pos: node.position(), Msg: "unsupported expression"}
+}
+
+func (c *exprCompiler) path(n *pathNode) (evalFunc, exprType, error) {
+	type step struct {
+		field string
+		index evalFunc
+	}
+
+	first := n.steps[0]
+	col := c.schema.Column(first.field)
+	if col == nil {
+		return nil, typeNull, &ExprError{Pos: first.pos, Msg: fmt.Sprintf("unknown column %s", first.field)}
+	}
+	typ := exprType{typ: col.GetType(), array: col.IsArray()}
+	name := first.field
+
+	steps := make([]step, 0, len(n.steps)-1)
+	for _, s := range n.steps[1:] {
+		if s.index != nil {
+			if !typ.array {
+				return nil, typeNull, &ExprError{Pos: s.pos, Msg: fmt.Sprintf("cannot index %s of type %s", name, typ)}
+			}
+			index, indexType, err := c.compile(s.index)
+			if err != nil {
+				return nil, typeNull, err
+			}
+			if !indexType.is(NativeTypeInt) && !indexType.isNull() {
+				return nil, typeNull, &ExprError{Pos: s.index.position(), Msg: fmt.Sprintf("index must be int, got %s", indexType)}
+			}
+			steps = append(steps, step{index: index})
+			typ = exprType{typ: typ.typ}
+			name += "[]"
+			continue
+		}
+
+		custom, ok := typ.typ.(CustomType)
+		if typ.array || !ok || custom.Schema == nil {
+			return nil, typeNull, &ExprError{Pos: s.pos, Msg: fmt.Sprintf("%s of type %s has no columns", name, typ)}
+		}
+		col := custom.Schema.Column(s.field)
+		if col == nil {
+			return nil, typeNull, &ExprError{Pos: s.pos, Msg: fmt.Sprintf("unknown column %s in %s", s.field, custom.Name)}
+		}
+		steps = append(steps, step{field: s.field})
+		typ = exprType{typ: col.GetType(), array: col.IsArray()}
+		name += "." + s.field
+	}
+
+	return func(r *Record) (Value, error) {
+		v := nullable(r.Values[first.field])
+		for _, s := range steps {
+			if v == nil {
+				return nil, nil
+			}
+			if s.index == nil {
+				nested, ok := v.(RecordValue)
+				if !ok {
+					return nil, fmt.Errorf("column %s: expected record, got %T", s.field, v)
+				}
+				if nested.Record == nil {
+					return nil, nil
+				}
+				v = nullable(nested.Record.Values[s.field])
+				continue
+			}
+			arr, ok := v.(ArrayValue)
+			if !ok {
+				return nil, fmt.Errorf("expected array, got %T", v)
+			}
+			index, err := s.index(r)
+			if err != nil || index == nil {
+				return nil, err
+			}
+			n, err := asInt(index)
+			if err != nil {
+				return nil, err
+			}
+			i := int(n)
+			if i < 0 {
+				i += len(arr.Elements)
+			}
+			if i < 0 || i >= len(arr.Elements) {
+				return nil, nil
+			}
+			v = nullable(arr.Elements[i])
+		}
+		if v != nil && !typ.holds(v) {
+			return nil, fmt.Errorf("column %s: expected %s, got %T", name, typ, v)
+		}
+		return v, nil
+	}, typ, nil
+}
+
+func (c *exprCompiler) unary(n *unaryNode) (evalFunc, exprType, error) {
+	operand, typ, err := c.compile(n.operand)
+	if err != nil {
+		return nil, typeNull, err
+	}
+
+	if n.op == "not" {
+		if !typ.is(NativeTypeBool) && !typ.isNull() {
+			return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("operator not expects bool, got %s", typ)}
+		}
+		return func(r *Record) (Value, error) {
+			v, err := operand(r)
+			if err != nil {
+				return nil, err
+			}
+			return BoolValue(!truthy(v)), nil
+		}, typeBool, nil
+	}
+
+	if !typ.numeric() && !typ.isNull() {
+		return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("operator - expects a number, got %s", typ)}
+	}
+	return func(r *Record) (Value, error) {
+		v, err := operand(r)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		switch n := v.(type) {
+		case IntValue:
+			return -n, nil
+		case FloatValue:
+			return -n, nil
+		}
+		return nil, unexpectedValue("a number", v)
+	}, typ, nil
+}
+
+func (c *exprCompiler) binary(n *binaryNode) (evalFunc, exprType, error) {
+	left, lt, err := c.compile(n.left)
+	if err != nil {
+		return nil, typeNull, err
+	}
+	right, rt, err := c.compile(n.right)
+	if err != nil {
+		return nil, typeNull, err
+	}
+	mismatch := &ExprError{Pos: n.pos, Msg: fmt.Sprintf("operator %s does not apply to %s and %s", n.op, lt, rt)}
+
+	switch n.op {
+	case "and", "or":
+		if (!lt.is(NativeTypeBool) && !lt.isNull()) || (!rt.is(NativeTypeBool) && !rt.isNull()) {
+			return nil, typeNull, mismatch
+		}
+		or := n.op == "or"
+		return func(r *Record) (Value, error) {
+			l, err := left(r)
+			if err != nil {
+				return nil, err
+			}
+			if truthy(l) == or {
+				return BoolValue(or), nil
+			}
+			v, err := right(r)
+			if err != nil {
+				return nil, err
+			}
+			return BoolValue(truthy(v)), nil
+		}, typeBool, nil
+
+	case "==", "!=":
+		if !equatable(lt, rt) {
+			return nil, typeNull, mismatch
+		}
+		negate := n.op == "!="
+		return func(r *Record) (Value, error) {
+			l, r2, err := evalBoth(left, right, r)
+			if err != nil {
+				return nil, err
+			}
+			return BoolValue(equal(l, r2) != negate), nil
+		}, typeBool, nil
+
+	case "<", "<=", ">", ">=":
+		if !ordered(lt, rt) {
+			return nil, typeNull, mismatch
+		}
+		op := n.op
+		return func(r *Record) (Value, error) {
+			l, r2, err := evalBoth(left, right, r)
+			if err != nil || l == nil || r2 == nil {
+				return BoolValue(false), err
+			}
+			cmp, err := compare(l, r2)
+			if err != nil {
+				return nil, err
+			}
+			switch op {
+			case "<":
+				return BoolValue(cmp < 0), nil
+			case "<=":
+				return BoolValue(cmp <= 0), nil
+			case ">":
+				return BoolValue(cmp > 0), nil
+			default:
+				return BoolValue(cmp >= 0), nil
+			}
+		}, typeBool, nil
+	}
+
+	typ, ok := arithmeticType(n.op, lt, rt)
+	if !ok {
+		return nil, typeNull, mismatch
+	}
+	op := n.op
+	return func(r *Record) (Value, error) {
+		l, r2, err := evalBoth(left, right, r)
+		if err != nil || l == nil || r2 == nil {
+			return nil, err
+		}
+		return arithmetic(op, l, r2)
+	}, typ, nil
+}
+
+// arithmeticType returns the result type of an arithmetic operator. Int
+// operands give an int, mixing int and float gives a float, and + also
+// concatenates strings.
+func arithmeticType(op string, lt, rt exprType) (exprType, bool) {
+	if lt.isNull() && rt.isNull() {
+		return typeNull, true
+	}
+	if lt.isNull() {
+		lt = rt
+	}
+	if rt.isNull() {
+		rt = lt
+	}
+	switch {
+	case op == "+" && lt.is(NativeTypeString) && rt.is(NativeTypeString):
+		return typeString, true
+	case op == "%":
+		return typeInt, lt.is(NativeTypeInt) && rt.is(NativeTypeInt)
+	case lt.is(NativeTypeInt) && rt.is(NativeTypeInt):
+		return typeInt, true
+	case lt.numeric() && rt.numeric():
+		return typeFloat, true
+	}
+	return typeNull, false
+}
+
+func arithmetic(op string, l, r Value) (Value, error) {
+	if ls, ok := l.(StringValue); ok {
+		rs, err := asString(r)
+		if err != nil {
+			return nil, err
+		}
+		return ls + StringValue(rs), nil
+	}
+
+	li, lok := l.(IntValue)
+	ri, rok := r.(IntValue)
+	if lok && rok {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return li / ri, nil
+		}
+		return li % ri, nil
+	}
+
+	if !isNumber(l) || !isNumber(r) {
+		return nil, fmt.Errorf("operator %s does not apply to %T and %T", op, l, r)
+	}
+	lf, rf := toFloat(l), toFloat(r)
+	switch op {
+	case "+":
+		return FloatValue(lf + rf), nil
+	case "-":
+		return FloatValue(lf - rf), nil
+	case "*":
+		return FloatValue(lf * rf), nil
+	}
+	if rf == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return FloatValue(lf / rf), nil
+}
+
+func (c *exprCompiler) in(n *binaryNode) (evalFunc, exprType, error) {
+	left, lt, err := c.compile(n.left)
+	if err != nil {
+		return nil, typeNull, err
+	}
+
+	var elements []evalFunc
+	var array evalFunc
+	if list, ok := n.right.(*listNode); ok {
+		for _, element := range list.elements {
+			fn, et, err := c.compile(element)
+			if err != nil {
+				return nil, typeNull, err
+			}
+			if !equatable(lt, et) {
+				return nil, typeNull, &ExprError{Pos: element.position(), Msg: fmt.Sprintf("cannot compare %s with %s", lt, et)}
+			}
+			elements = append(elements, fn)
+		}
+	} else {
+		fn, rt, err := c.compile(n.right)
+		if err != nil {
+			return nil, typeNull, err
+		}
+		if !rt.array {
+			return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("operator in expects a list or an array, got %s", rt)}
+		}
+		if !equatable(lt, exprType{typ: rt.typ}) {
+			return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("cannot compare %s with %s", lt, rt)}
+		}
+		array = fn
+	}
+
+	return func(r *Record) (Value, error) {
+		l, err := left(r)
+		if err != nil || l == nil {
+			return BoolValue(false), err
+		}
+		if array != nil {
+			v, err := array(r)
+			if err != nil || v == nil {
+				return BoolValue(false), err
+			}
+			arr, ok := v.(ArrayValue)
+			if !ok {
+				return nil, unexpectedValue("array", v)
+			}
+			return BoolValue(containsValue(arr, l)), nil
+		}
+		for _, element := range elements {
+			v, err := element(r)
+			if err != nil {
+				return nil, err
+			}
+			if equal(l, v) {
+				return BoolValue(true), nil
+			}
+		}
+		return BoolValue(false), nil
+	}, typeBool, nil
+}
+
+func (c *exprCompiler) call(n *callNode) (evalFunc, exprType, error) {
+	fn, ok := exprFuncs[n.name]
+	if !ok {
+		return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("unknown function %s", n.name)}
+	}
+	if len(n.args) < fn.minArgs || (fn.maxArgs >= 0 && len(n.args) > fn.maxArgs) {
+		return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("function %s expects %s, got %d", n.name, arity(fn.minArgs, fn.maxArgs), len(n.args))}
+	}
+
+	args := make([]evalFunc, len(n.args))
+	types := make([]exprType, len(n.args))
+	for i, arg := range n.args {
+		var err error
+		if args[i], types[i], err = c.compile(arg); err != nil {
+			return nil, typeNull, err
+		}
+	}
+	typ, err := fn.check(types)
+	if err != nil {
+		return nil, typeNull, &ExprError{Pos: n.pos, Msg: fmt.Sprintf("function %s: %s", n.name, err)}
+	}
+
+	name := n.name
+	return func(r *Record) (Value, error) {
+		values := make([]Value, len(args))
+		for i, arg := range args {
+			v, err := arg(r)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && !fn.nullable {
+				return nil, nil
+			}
+			values[i] = v
+		}
+		v, err := fn.call(values, typ)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return v, nil
+	}, typ, nil
+}
+
+func arity(min, max int) string {
+	switch {
+	case max < 0:
+		return fmt.Sprintf("at least %d arguments", min)
+	case min == max && min == 1:
+		return "1 argument"
+	case min == max:
+		return fmt.Sprintf("%d arguments", min)
+	default:
+		return fmt.Sprintf("%d to %d arguments", min, max)
+	}
+}
+
+func evalBoth(left, right evalFunc, r *Record) (Value, Value, error) {
+	l, err := left(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := right(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, v, nil
+}
+
+// nullable returns nil for missing and null values.
+func nullable(v Value) Value {
+	if v == nil || v.IsNull() {
+		return nil
+	}
+	return v
+}
+
+func truthy(v Value) bool {
+	b, ok := v.(BoolValue)
+	return ok && bool(b)
+}
+
+func isNumber(v Value) bool {
+	switch v.(type) {
+	case IntValue, FloatValue:
+		return true
+	}
+	return false
+}
+
+func toFloat(v Value) float64 {
+	switch n := v.(type) {
+	case IntValue:
+		return float64(n)
+	case FloatValue:
+		return float64(n)
+	}
+	return math.NaN()
+}
+
+func equal(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	switch av := a.(type) {
+	case IntValue:
+		if bv, ok := b.(IntValue); ok {
+			return av == bv
+		}
+		return toFloat(a) == toFloat(b)
+	case FloatValue:
+		return toFloat(a) == toFloat(b)
+	case DateValue:
+		bv, ok := b.(DateValue)
+		return ok && time.Time(av).Equal(time.Time(bv))
+	case StringValue, BoolValue:
+		return a == b
+	}
+	return false
+}
+
+// compare orders two numbers, strings or dates.
+func compare(a, b Value) (int, error) {
+	switch av := a.(type) {
+	case StringValue:
+		bs, err := asString(b)
+		if err != nil {
+			return 0, err
+		}
+		return strings.Compare(string(av), bs), nil
+	case DateValue:
+		bd, err := asDate(b)
+		if err != nil {
+			return 0, err
+		}
+		return time.Time(av).Compare(bd), nil
+	case IntValue:
+		if bi, ok := b.(IntValue); ok {
+			switch {
+			case av < bi:
+				return -1, nil
+			case av > bi:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if !isNumber(a) || !isNumber(b) {
+		return 0, fmt.Errorf("cannot compare %T with %T", a, b)
+	}
+	af, bf := toFloat(a), toFloat(b)
+	switch {
+	case af < bf:
+		return -1, nil
+	case af > bf:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func containsValue(arr ArrayValue, v Value) bool {
+	for _, element := range arr.Elements {
+		if equal(nullable(element), v) {
+			return true
+		}
+	}
+	return false
+}
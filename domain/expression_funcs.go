@@ -0,0 +1,361 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// exprFunc is a function callable from expressions.
+type exprFunc struct {
+	minArgs, maxArgs int  // Accepted number of arguments, maxArgs is -1 for variadic functions
+	nullable         bool // True if call receives null arguments, otherwise a null argument yields null
+	check            func(args []exprType) (exprType, error)
+	call             func(args []Value, result exprType) (Value, error)
+}
+
+// argKind accepts the type of a function argument. The null literal is
+// accepted by every kind.
+type argKind struct {
+	name   string
+	accept func(exprType) bool
+}
+
+var (
+	argString  = argKind{"string", func(t exprType) bool { return t.is(NativeTypeString) }}
+	argInt     = argKind{"int", func(t exprType) bool { return t.is(NativeTypeInt) }}
+	argNumber  = argKind{"a number", exprType.numeric}
+	argDate    = argKind{"date", func(t exprType) bool { return t.is(NativeTypeDate) }}
+	argScalar  = argKind{"a native value", func(t exprType) bool { return !t.array && t.typ.IsNative() }}
+	argSizable = argKind{"string or array", func(t exprType) bool { return t.is(NativeTypeString) || t.array }}
+)
+
+// signature checks the arguments against kinds, the last kind applying to
+// any extra argument, and returns result.
+func signature(result exprType, kinds ...argKind) func([]exprType) (exprType, error) {
+	return func(args []exprType) (exprType, error) {
+		for i, arg := range args {
+			kind := kinds[min(i, len(kinds)-1)]
+			if !arg.isNull() && !kind.accept(arg) {
+				return typeNull, fmt.Errorf("argument %d must be %s, got %s", i+1, kind.name, arg)
+			}
+		}
+		return result, nil
+	}
+}
+
+// sameAsArg checks the arguments against kind and returns the type of the
+// first one, such as int for abs(int).
+func sameAsArg(kind argKind) func([]exprType) (exprType, error) {
+	return func(args []exprType) (exprType, error) {
+		if _, err := signature(typeNull, kind)(args); err != nil {
+			return typeNull, err
+		}
+		return args[0], nil
+	}
+}
+
+func stringFunc(fn func(string) string) exprFunc {
+	return exprFunc{
+		minArgs: 1, maxArgs: 1,
+		check: signature(typeString, argString),
+		call: func(args []Value, _ exprType) (Value, error) {
+			s, err := asString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return StringValue(fn(s)), nil
+		},
+	}
+}
+
+func stringPredicate(fn func(s, arg string) bool) exprFunc {
+	return exprFunc{
+		minArgs: 2, maxArgs: 2,
+		check: signature(typeBool, argString, argString),
+		call: func(args []Value, _ exprType) (Value, error) {
+			s, err := asString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			arg, err := asString(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return BoolValue(fn(s, arg)), nil
+		},
+	}
+}
+
+func datePart(fn func(time.Time) int) exprFunc {
+	return exprFunc{
+		minArgs: 1, maxArgs: 1,
+		check: signature(typeInt, argDate),
+		call: func(args []Value, _ exprType) (Value, error) {
+			t, err := asDate(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return IntValue(fn(t)), nil
+		},
+	}
+}
+
+func rounding(fn func(float64) float64) exprFunc {
+	return exprFunc{
+		minArgs: 1, maxArgs: 1,
+		check: sameAsArg(argNumber),
+		call: func(args []Value, _ exprType) (Value, error) {
+			switch n := args[0].(type) {
+			case FloatValue:
+				return FloatValue(fn(float64(n))), nil
+			case IntValue:
+				return n, nil
+			}
+			return nil, unexpectedValue("a number", args[0])
+		},
+	}
+}
+
+// conversion converts values accepted by kind to the target type with a
+// lenient Cast.
+func conversion(to NativeType, kind argKind) exprFunc {
+	return exprFunc{
+		minArgs: 1, maxArgs: 1,
+		check: signature(exprType{typ: to}, kind),
+		call: func(args []Value, _ exprType) (Value, error) {
+			return Cast(args[0], to, CastOptions{Mode: CastLenient})
+		},
+	}
+}
+
+// exprFuncs are the functions available in expressions, by lowercase name.
+var exprFuncs = map[string]exprFunc{
+	"lower":       stringFunc(strings.ToLower),
+	"upper":       stringFunc(strings.ToUpper),
+	"trim":        stringFunc(strings.TrimSpace),
+	"starts_with": stringPredicate(strings.HasPrefix),
+	"ends_with":   stringPredicate(strings.HasSuffix),
+
+	"len": {
+		minArgs: 1, maxArgs: 1,
+		check: signature(typeInt, argSizable),
+		call: func(args []Value, _ exprType) (Value, error) {
+			if arr, ok := args[0].(ArrayValue); ok {
+				return IntValue(len(arr.Elements)), nil
+			}
+			s, err := asString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return IntValue(utf8.RuneCountInString(s)), nil
+		},
+	},
+
+	"contains": {
+		minArgs: 2, maxArgs: 2,
+		check: func(args []exprType) (exprType, error) {
+			if args[0].array {
+				if !equatable(exprType{typ: args[0].typ}, args[1]) {
+					return typeNull, fmt.Errorf("cannot look for %s in %s", args[1], args[0])
+				}
+				return typeBool, nil
+			}
+			return signature(typeBool, argSizable, argString)(args)
+		},
+		call: func(args []Value, _ exprType) (Value, error) {
+			if arr, ok := args[0].(ArrayValue); ok {
+				return BoolValue(containsValue(arr, args[1])), nil
+			}
+			s, err := asString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			substr, err := asString(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return BoolValue(strings.Contains(s, substr)), nil
+		},
+	},
+
+	"substr": {
+		minArgs: 2, maxArgs: 3,
+		check: signature(typeString, argString, argInt),
+		call: func(args []Value, _ exprType) (Value, error) {
+			s, err := asString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			from, err := asInt(args[1])
+			if err != nil {
+				return nil, err
+			}
+			runes := []rune(s)
+			start := clamp(int(from), len(runes))
+			end := len(runes)
+			if len(args) == 3 {
+				n, err := asInt(args[2])
+				if err != nil {
+					return nil, err
+				}
+				end = clamp(start+int(n), len(runes))
+			}
+			if end < start {
+				return StringValue(""), nil
+			}
+			return StringValue(runes[start:end]), nil
+		},
+	},
+
+	"replace": {
+		minArgs: 3, maxArgs: 3,
+		check: signature(typeString, argString),
+		call: func(args []Value, _ exprType) (Value, error) {
+			strs := make([]string, len(args))
+			for i, arg := range args {
+				var err error
+				if strs[i], err = asString(arg); err != nil {
+					return nil, err
+				}
+			}
+			return StringValue(strings.ReplaceAll(strs[0], strs[1], strs[2])), nil
+		},
+	},
+
+	"year":    datePart(time.Time.Year),
+	"month":   datePart(func(t time.Time) int { return int(t.Month()) }),
+	"day":     datePart(time.Time.Day),
+	"hour":    datePart(time.Time.Hour),
+	"minute":  datePart(time.Time.Minute),
+	"second":  datePart(time.Time.Second),
+	"weekday": datePart(func(t time.Time) int { return int(t.Weekday()) }),
+
+	"date": conversion(NativeTypeDate, argString),
+
+	"now": {
+		minArgs: 0, maxArgs: 0,
+		check: signature(typeDate),
+		call: func([]Value, exprType) (Value, error) {
+			return DateValue(time.Now()), nil
+		},
+	},
+
+	"days_between": {
+		minArgs: 2, maxArgs: 2,
+		check: signature(typeInt, argDate),
+		call: func(args []Value, _ exprType) (Value, error) {
+			from, err := asDate(args[0])
+			if err != nil {
+				return nil, err
+			}
+			to, err := asDate(args[1])
+			if err != nil {
+				return nil, err
+			}
+			d := to.Sub(from)
+			return IntValue(d / (24 * time.Hour)), nil
+		},
+	},
+
+	"abs": {
+		minArgs: 1, maxArgs: 1,
+		check: sameAsArg(argNumber),
+		call: func(args []Value, _ exprType) (Value, error) {
+			switch n := args[0].(type) {
+			case IntValue:
+				if n < 0 {
+					return -n, nil
+				}
+				return n, nil
+			case FloatValue:
+				return FloatValue(math.Abs(float64(n))), nil
+			}
+			return nil, unexpectedValue("a number", args[0])
+		},
+	},
+	"round": rounding(math.Round),
+	"floor": rounding(math.Floor),
+	"ceil":  rounding(math.Ceil),
+
+	"int":    conversion(NativeTypeInt, argScalar),
+	"float":  conversion(NativeTypeFloat, argScalar),
+	"string": conversion(NativeTypeString, argScalar),
+
+	"coalesce": {
+		minArgs: 1, maxArgs: -1, nullable: true,
+		check: func(args []exprType) (exprType, error) {
+			result := typeNull
+			for i, arg := range args {
+				switch {
+				case arg.isNull():
+				case result.isNull():
+					result = arg
+				case result.numeric() && arg.numeric():
+					if arg != result {
+						result = typeFloat
+					}
+				case arg != result:
+					return typeNull, fmt.Errorf("argument %d must be %s, got %s", i+1, result, arg)
+				}
+			}
+			return result, nil
+		},
+		call: func(args []Value, result exprType) (Value, error) {
+			for _, arg := range args {
+				if arg == nil {
+					continue
+				}
+				if result.is(NativeTypeFloat) {
+					return FloatValue(toFloat(arg)), nil
+				}
+				return arg, nil
+			}
+			return nil, nil
+		},
+	},
+
+	"is_null": {
+		minArgs: 1, maxArgs: 1, nullable: true,
+		check: func([]exprType) (exprType, error) { return typeBool, nil },
+		call: func(args []Value, _ exprType) (Value, error) {
+			return BoolValue(args[0] == nil), nil
+		},
+	},
+}
+
+// asString, asInt and asDate return the Go value of an argument, or an
+// error when a record holds a value not matching its column type.
+func asString(v Value) (string, error) {
+	s, ok := v.(StringValue)
+	if !ok {
+		return "", unexpectedValue("string", v)
+	}
+	return string(s), nil
+}
+
+func asInt(v Value) (int64, error) {
+	i, ok := v.(IntValue)
+	if !ok {
+		return 0, unexpectedValue("int", v)
+	}
+	return int64(i), nil
+}
+
+func asDate(v Value) (time.Time, error) {
+	d, ok := v.(DateValue)
+	if !ok {
+		return time.Time{}, unexpectedValue("date", v)
+	}
+	return time.Time(d), nil
+}
+
+func unexpectedValue(expected string, v Value) error {
+	return fmt.Errorf("expected %s, got %T", expected, v)
+}
+
+func clamp(i, n int) int {
+	return max(0, min(i, n))
+}
@@ -0,0 +1,500 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind   tokenKind
+	text   string // Identifier name, operator, unquoted string or number literal
+	pos    int    // Byte offset in the source
+	quoted bool   // True for identifiers quoted with backticks, which are never keywords
+}
+
+// tokenize splits an expression source into tokens.
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+
+		case r == '`':
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, &ExprError{Pos: i, Msg: "unterminated quoted identifier"}
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i+1 : i+1+end], pos: i, quoted: true})
+			i += end + 2
+
+		case r >= '0' && r <= '9':
+			start := i
+			kind := tokenInt
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && src[i+1] >= '0' && src[i+1] <= '9' {
+				kind = tokenFloat
+				i++
+				for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = tokenFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: kind, text: src[start:i], pos: start})
+
+		case r == '\'' || r == '"':
+			text, n, err := unquote(src[i:], byte(r))
+			if err != nil {
+				return nil, &ExprError{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i += n
+
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "(", ")", "[", "]", ",", "."} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &ExprError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// unquote reads a quoted string at the start of src and returns its content
+// and the number of bytes consumed.
+func unquote(src string, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '\'', '"':
+				b.WriteByte(src[i])
+			default:
+				return "", 0, fmt.Errorf("unknown escape sequence \\%c", src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// exprNode is a node of the expression syntax tree.
+type exprNode interface {
+	position() int
+}
+
+type literalNode struct {
+	pos   int
+	value Value // Nil for the null literal
+}
+
+// pathNode reads a column, then navigates into nested records and arrays.
+type pathNode struct {
+	pos   int
+	steps []pathStep
+}
+
+type pathStep struct {
+	pos   int
+	field string   // Column ID, empty for an index step
+	index exprNode // Index expression, nil for a field step
+}
+
+type unaryNode struct {
+	pos     int
+	op      string
+	operand exprNode
+}
+
+type binaryNode struct {
+	pos         int
+	op          string
+	left, right exprNode
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []exprNode
+}
+
+type listNode struct {
+	pos      int
+	elements []exprNode
+}
+
+func (n *literalNode) position() int { return n.pos }
+func (n *pathNode) position() int    { return n.pos }
+func (n *unaryNode) position() int   { return n.pos }
+func (n *binaryNode) position() int  { return n.pos }
+func (n *callNode) position() int    { return n.pos }
+func (n *listNode) position() int    { return n.pos }
+
+// parser is a recursive descent parser. From lowest to highest precedence:
+// or, and, not, comparisons and in, + -, * / %, unary -, then primaries.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func parseExpr(src string) (exprNode, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords, and returns its normalized operator.
+func (p *parser) accept(ops ...string) (token, string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp && (tok.kind != tokenIdent || tok.quoted) {
+		return tok, "", false
+	}
+	text := tok.text
+	if tok.kind == tokenIdent {
+		text = strings.ToLower(text)
+	}
+	for _, op := range ops {
+		if text == op {
+			p.next()
+			return tok, normalizeOp(op), true
+		}
+	}
+	return tok, "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, _, ok := p.accept(op); !ok {
+		tok := p.peek()
+		if tok.kind == tokenEOF {
+			return &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("expected %q, got end of expression", op)}
+		}
+		return &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("expected %q, got %q", op, tok.text)}
+	}
+	return nil
+}
+
+func (p *parser) unexpected(tok token) error {
+	if tok.kind == tokenEOF {
+		return &ExprError{Pos: tok.pos, Msg: "unexpected end of expression"}
+	}
+	return &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+func normalizeOp(op string) string {
+	switch op {
+	case "&&":
+		return "and"
+	case "||":
+		return "or"
+	case "!":
+		return "not"
+	}
+	return op
+}
+
+func (p *parser) or() (exprNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, op, ok := p.accept("or", "||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) and() (exprNode, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, op, ok := p.accept("and", "&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) not() (exprNode, error) {
+	if tok, op, ok := p.accept("not", "!"); ok {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: tok.pos, op: op, operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (exprNode, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	tok, op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "in")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{pos: tok.pos, op: op, left: left, right: right}, nil
+}
+
+func (p *parser) additive() (exprNode, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) multiplicative() (exprNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (exprNode, error) {
+	if tok, op, ok := p.accept("-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: tok.pos, op: op, operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("invalid integer %s", tok.text)}
+		}
+		return &literalNode{pos: tok.pos, value: IntValue(n)}, nil
+
+	case tokenFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %s", tok.text)}
+		}
+		return &literalNode{pos: tok.pos, value: FloatValue(f)}, nil
+
+	case tokenString:
+		return &literalNode{pos: tok.pos, value: StringValue(tok.text)}, nil
+
+	case tokenIdent:
+		if tok.quoted {
+			return p.path(tok)
+		}
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &literalNode{pos: tok.pos, value: BoolValue(true)}, nil
+		case "false":
+			return &literalNode{pos: tok.pos, value: BoolValue(false)}, nil
+		case "null":
+			return &literalNode{pos: tok.pos}, nil
+		case "and", "or", "not", "in":
+			return nil, p.unexpected(tok)
+		}
+		if _, _, ok := p.accept("("); ok {
+			return p.call(tok)
+		}
+		return p.path(tok)
+
+	case tokenOp:
+		switch tok.text {
+		case "(":
+			node, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			elements, err := p.list("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{pos: tok.pos, elements: elements}, nil
+		}
+	}
+	return nil, p.unexpected(tok)
+}
+
+func (p *parser) call(name token) (exprNode, error) {
+	args, err := p.list(")")
+	if err != nil {
+		return nil, err
+	}
+	return &callNode{pos: name.pos, name: strings.ToLower(name.text), args: args}, nil
+}
+
+// list parses comma-separated expressions up to the closing operator.
+func (p *parser) list(closing string) ([]exprNode, error) {
+	var nodes []exprNode
+	if _, _, ok := p.accept(closing); ok {
+		return nodes, nil
+	}
+	for {
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if _, _, ok := p.accept(closing); ok {
+			return nodes, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) path(first token) (exprNode, error) {
+	node := &pathNode{pos: first.pos, steps: []pathStep{{pos: first.pos, field: first.text}}}
+	for {
+		if tok, _, ok := p.accept("."); ok {
+			field := p.next()
+			if field.kind != tokenIdent {
+				return nil, &ExprError{Pos: field.pos, Msg: "expected column name after ."}
+			}
+			node.steps = append(node.steps, pathStep{pos: tok.pos, field: field.text})
+			continue
+		}
+		if tok, _, ok := p.accept("["); ok {
+			index, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node.steps = append(node.steps, pathStep{pos: tok.pos, index: index})
+			continue
+		}
+		return node, nil
+	}
+}
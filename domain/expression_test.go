@@ -0,0 +1,393 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createExprSchema() *DataSchema {
+	address := &DataSchema{
+		ID: "Address",
+		Columns: []SchemaColumn{
+			SchemaColumnSingle{ID: "city", SchemaType: NativeTypeString},
+		},
+	}
+	item := &DataSchema{
+		ID: "Item",
+		Columns: []SchemaColumn{
+			SchemaColumnSingle{ID: "sku", SchemaType: NativeTypeString},
+			SchemaColumnSingle{ID: "price", SchemaType: NativeTypeFloat},
+		},
+	}
+	return &DataSchema{
+		ID: "Order",
+		Columns: []SchemaColumn{
+			SchemaColumnSingle{ID: "name", SchemaType: NativeTypeString},
+			SchemaColumnSingle{ID: "category", SchemaType: NativeTypeString},
+			SchemaColumnSingle{ID: "stock", SchemaType: NativeTypeInt},
+			SchemaColumnSingle{ID: "price", SchemaType: NativeTypeFloat},
+			SchemaColumnSingle{ID: "active", SchemaType: NativeTypeBool},
+			SchemaColumnSingle{ID: "created_at", SchemaType: NativeTypeDate},
+			SchemaColumnSingle{ID: "discount", SchemaType: NativeTypeInt},
+			SchemaColumnSingle{ID: "address", SchemaType: CustomType{Name: "Address", Schema: address}},
+			SchemaColumnArray{ID: "items", RefSchema: CustomType{Name: "Item", Schema: item}},
+			SchemaColumnArray{ID: "tags", RefSchema: NativeTypeString},
+		},
+	}
+}
+
+func createExprRecord(schema *DataSchema) *Record {
+	address := NewRecord(schema.Column("address").GetType().(CustomType).Schema)
+	address.Set("city", StringValue("Paris"))
+
+	itemSchema := schema.Column("items").GetType().(CustomType).Schema
+	first := NewRecord(itemSchema)
+	first.Set("sku", StringValue("A1"))
+	first.Set("price", FloatValue(9.5))
+	second := NewRecord(itemSchema)
+	second.Set("sku", StringValue("B2"))
+	second.Set("price", FloatValue(20))
+
+	record := NewRecord(schema)
+	record.Set("name", StringValue("Apple"))
+	record.Set("category", StringValue("food"))
+	record.Set("stock", IntValue(7))
+	record.Set("price", FloatValue(2.5))
+	record.Set("active", BoolValue(true))
+	record.Set("created_at", DateValue(time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)))
+	record.Set("discount", NullValue{Type: NativeTypeInt})
+	record.Set("address", RecordValue{Record: address})
+	record.Set("items", ArrayValue{
+		ElementType: CustomType{Name: "Item", Schema: itemSchema},
+		Elements:    []Value{RecordValue{Record: first}, RecordValue{Record: second}},
+	})
+	record.Set("tags", ArrayValue{ElementType: NativeTypeString, Elements: []Value{StringValue("fresh"), StringValue("bio")}})
+	return record
+}
+
+func TestExpr_Eval(t *testing.T) {
+	schema := createExprSchema()
+	record := createExprRecord(schema)
+
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		// Literals and arithmetic
+		{"1 + 2 * 3", IntValue(7)},
+		{"(1 + 2) * 3", IntValue(9)},
+		{"7 / 2", IntValue(3)},
+		{"7 % 4", IntValue(3)},
+		{"stock * price", FloatValue(17.5)},
+		{"-stock + 1.5", FloatValue(-5.5)},
+		{"1e2", FloatValue(100)},
+		{"name + ' ' + category", StringValue("Apple food")},
+		{`"it's"`, StringValue("it's")},
+
+		// Comparisons and logic
+		{"stock > 0 and category == 'food'", BoolValue(true)},
+		{"stock >= 8 or not active", BoolValue(false)},
+		{"stock == 7.0", BoolValue(true)},
+		{"name < 'Banana'", BoolValue(true)},
+		{"created_at > date('2024-01-01')", BoolValue(true)},
+		{"stock > 0 && !(price != 2.5)", BoolValue(true)},
+		{"category in ['food', 'drink']", BoolValue(true)},
+		{"'bio' in tags", BoolValue(true)},
+		{"'meat' in tags", BoolValue(false)},
+		{"name == 'Apple' AND TRUE", BoolValue(true)},
+
+		// Nulls
+		{"discount", NullValue{Type: NativeTypeInt}},
+		{"discount + 1", NullValue{Type: NativeTypeInt}},
+		{"discount == null", BoolValue(true)},
+		{"discount != null", BoolValue(false)},
+		{"discount > 0", BoolValue(false)},
+		{"discount < 0", BoolValue(false)},
+		{"coalesce(discount, 5)", IntValue(5)},
+		{"coalesce(discount, price)", FloatValue(2.5)},
+		{"is_null(discount) and not is_null(stock)", BoolValue(true)},
+		{"null", NullValue{}},
+
+		// Navigation
+		{"address.city", StringValue("Paris")},
+		{"items[0].sku", StringValue("A1")},
+		{"items[-1].price", FloatValue(20)},
+		{"items[5].price", NullValue{Type: NativeTypeFloat}},
+		{"items[stock - 6].sku", StringValue("B2")},
+		{"tags[1]", StringValue("bio")},
+
+		// Functions
+		{"upper(name)", StringValue("APPLE")},
+		{"lower(address.city) in ['paris', 'lyon']", BoolValue(true)},
+		{"trim('  x ')", StringValue("x")},
+		{"len(name)", IntValue(5)},
+		{"len(items)", IntValue(2)},
+		{"contains(name, 'ppl')", BoolValue(true)},
+		{"contains(tags, 'fresh')", BoolValue(true)},
+		{"starts_with(name, 'Ap') and ends_with(name, 'le')", BoolValue(true)},
+		{"substr(name, 1, 3)", StringValue("ppl")},
+		{"substr(name, 3)", StringValue("le")},
+		{"substr(name, 10)", StringValue("")},
+		{"replace(name, 'p', 'P')", StringValue("APPle")},
+		{"year(created_at)", IntValue(2024)},
+		{"month(created_at) * 100 + day(created_at)", IntValue(315)},
+		{"hour(created_at)", IntValue(10)},
+		{"weekday(created_at)", IntValue(5)},
+		{"days_between(date('2024-03-01'), created_at)", IntValue(14)},
+		{"date('2024-03-15T10:30:00Z') == created_at", BoolValue(true)},
+		{"date('2024/03/15') < created_at", BoolValue(true)},
+		{"abs(-3)", IntValue(3)},
+		{"round(price)", FloatValue(3)},
+		{"floor(price) + ceil(price)", FloatValue(5)},
+		{"int(price)", IntValue(2)},
+		{"int('42')", IntValue(42)},
+		{"float(stock) / 2", FloatValue(3.5)},
+		{"string(stock) + '!'", StringValue("7!")},
+		{"`name`", StringValue("Apple")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := CompileExpr(tt.source, schema)
+			require.NoError(t, err)
+
+			value, err := expr.Eval(record)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	t.Run("should navigate null-safely through missing records", func(t *testing.T) {
+		expr, err := CompileExpr("address.city", schema)
+		require.NoError(t, err)
+
+		value, err := expr.Eval(NewRecord(schema))
+
+		require.NoError(t, err)
+		assert.Equal(t, NullValue{Type: NativeTypeString}, value)
+	})
+
+	t.Run("should return error for division by zero", func(t *testing.T) {
+		expr, err := CompileExpr("stock / (stock - 7)", schema)
+		require.NoError(t, err)
+
+		_, err = expr.Eval(record)
+
+		assert.EqualError(t, err, "division by zero")
+	})
+
+	t.Run("should return error for unparsable date", func(t *testing.T) {
+		expr, err := CompileExpr("date(name)", schema)
+		require.NoError(t, err)
+
+		_, err = expr.Eval(record)
+
+		assert.EqualError(t, err, `date: cannot parse "Apple" as date`)
+	})
+}
+
+func TestExpr_Eval_MismatchedValues(t *testing.T) {
+	schema := createExprSchema()
+	record := createExprRecord(schema)
+	record.Set("name", IntValue(3))
+	record.Set("stock", StringValue("many"))
+	record.Set("price", StringValue("cheap"))
+	record.Set("created_at", StringValue("2024-03-15"))
+	record.Set("tags", StringValue("fresh"))
+	record.Set("address", StringValue("Paris"))
+
+	for _, tc := range []struct {
+		source string
+		err    string
+	}{
+		{"lower(name)", "column name: expected string, got domain.IntValue"},
+		{"name + 'x'", "column name: expected string, got domain.IntValue"},
+		{"stock + 1", "column stock: expected int, got domain.StringValue"},
+		{"-price", "column price: expected float, got domain.StringValue"},
+		{"price > 1", "column price: expected float, got domain.StringValue"},
+		{"year(created_at)", "column created_at: expected date, got domain.StringValue"},
+		{"'fresh' in tags", "column tags: expected []string, got domain.StringValue"},
+		{"address.city == 'Paris'", "column city: expected record, got domain.StringValue"},
+	} {
+		t.Run("should return error instead of panicking for "+tc.source, func(t *testing.T) {
+			expr, err := CompileExpr(tc.source, schema)
+			require.NoError(t, err)
+
+			_, err = expr.Eval(record)
+
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+
+	t.Run("should return error from functions receiving mismatched values", func(t *testing.T) {
+		_, err := exprFuncs["substr"].call([]Value{StringValue("apple"), StringValue("1")}, typeString)
+
+		assert.EqualError(t, err, "expected int, got domain.StringValue")
+	})
+}
+
+func TestCompileExpr(t *testing.T) {
+	schema := createExprSchema()
+
+	t.Run("should expose result type", func(t *testing.T) {
+		expr, err := CompileExpr("items[0]", schema)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Item", expr.Type.GetTypeName())
+		assert.False(t, expr.Array)
+
+		expr, err = CompileExpr("tags", schema)
+		require.NoError(t, err)
+
+		assert.Equal(t, NativeTypeString, expr.Type)
+		assert.True(t, expr.Array)
+	})
+
+	tests := []struct {
+		source string
+		pos    int
+		msg    string
+	}{
+		{"stok > 0", 1, "unknown column stok"},
+		{"stock >", 8, "unexpected end of expression"},
+		{"stock > 0 category", 11, `unexpected "category"`},
+		{"(stock > 0", 11, `expected ")", got end of expression`},
+		{"stock ? 1", 7, `unexpected character '?'`},
+		{"'abc", 1, "unterminated string"},
+		{"name > 1", 6, "operator > does not apply to string and int"},
+		{"name + stock", 6, "operator + does not apply to string and int"},
+		{"price % 2", 7, "operator % does not apply to float and int"},
+		{"stock and active", 7, "operator and does not apply to int and bool"},
+		{"not stock", 1, "operator not expects bool, got int"},
+		{"-name", 1, "operator - expects a number, got string"},
+		{"address == null or address == address", 28, "operator == does not apply to Address and Address"},
+		{"tags == tags", 6, "operator == does not apply to []string and []string"},
+		{"name in [1, 2]", 10, "cannot compare string with int"},
+		{"stock in name", 7, "operator in expects a list or an array, got string"},
+		{"[1, 2]", 1, "lists are only allowed after in"},
+		{"address.zip", 8, "unknown column zip in Address"},
+		{"name.first", 5, "name of type string has no columns"},
+		{"items.sku", 6, "items of type []Item has no columns"},
+		{"stock[0]", 6, "cannot index stock of type int"},
+		{"tags['a']", 6, "index must be int, got string"},
+		{"foo(name)", 1, "unknown function foo"},
+		{"upper(name, 1)", 1, "function upper expects 1 argument, got 2"},
+		{"upper(stock)", 1, "function upper: argument 1 must be string, got int"},
+		{"coalesce(name, stock)", 1, "function coalesce: argument 2 must be string, got int"},
+		{"contains(tags, 1)", 1, "function contains: cannot look for int in []string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := CompileExpr(tt.source, schema)
+
+			var exprErr *ExprError
+			require.True(t, errors.As(err, &exprErr), "expected *ExprError, got %v", err)
+			assert.Equal(t, tt.source, exprErr.Source)
+			assert.Equal(t, tt.pos, exprErr.Pos+1)
+			assert.Equal(t, tt.msg, exprErr.Msg)
+		})
+	}
+
+	t.Run("should format error with source and position", func(t *testing.T) {
+		_, err := CompileExpr("stok > 0", schema)
+
+		assert.EqualError(t, err, `invalid expression "stok > 0" at position 1: unknown column stok`)
+	})
+}
+
+func TestExpr_Match(t *testing.T) {
+	schema := createExprSchema()
+	record := createExprRecord(schema)
+
+	t.Run("should match true result only", func(t *testing.T) {
+		matching, _ := CompileExpr("active", schema)
+		null, _ := CompileExpr("discount > 0 or null", schema)
+
+		ok, err := matching.Match(record)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = null.Match(record)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestRecordSet_FilterExpr(t *testing.T) {
+	t.Run("should keep matching records", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecordWithQuantity(schema, "Apple", 1.5, 10))
+		rs.Add(createTestRecordWithQuantity(schema, "Banana", 0.5, 0))
+		rs.Add(createTestRecordWithQuantity(schema, "Cherry", 8, 3))
+
+		result, err := rs.FilterExpr("quantity > 0 and price < 5")
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Count())
+		assert.Equal(t, "Apple", result.First().GetString("name"))
+		assert.Same(t, schema, result.Schema)
+	})
+
+	t.Run("should return error for non-bool expression", func(t *testing.T) {
+		_, err := NewRecordSet(createTestSchema()).FilterExpr("price * 2")
+
+		assert.EqualError(t, err, `invalid expression "price * 2" at position 1: filter must be a bool expression, got float`)
+	})
+
+	t.Run("should return error with failing record index", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecordWithQuantity(schema, "Apple", 1.5, 1))
+		rs.Add(createTestRecordWithQuantity(schema, "Banana", 0.5, 0))
+
+		_, err := rs.FilterExpr("10 / quantity > 1")
+
+		assert.EqualError(t, err, "record 1: division by zero")
+	})
+}
+
+func TestRecordSet_WithColumnExpr(t *testing.T) {
+	t.Run("should add typed computed column", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecordWithQuantity(schema, "Apple", 1.5, 10))
+		rs.Add(createTestRecord(schema, "Banana", 0.5))
+
+		result, err := rs.WithColumnExpr("total", "price * quantity")
+
+		require.NoError(t, err)
+		assert.Equal(t, SchemaColumnSingle{ID: "total", SchemaType: NativeTypeFloat}, result.Schema.Column("total"))
+		assert.Equal(t, 15.0, result.Get(0).GetFloat("total"))
+		assert.Equal(t, NullValue{Type: NativeTypeFloat}, result.Get(1).Get("total"))
+	})
+
+	t.Run("should return record error for a value not matching its column", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecord(schema, "Apple", 1.5))
+		mismatched := createTestRecord(schema, "Banana", 0.5)
+		mismatched.Set("name", IntValue(42))
+		rs.Add(mismatched)
+
+		_, err := rs.WithColumnExpr("lower_name", "lower(name)")
+
+		var recordErr *RecordError
+		require.ErrorAs(t, err, &recordErr)
+		assert.Equal(t, 1, recordErr.Index)
+		assert.EqualError(t, err, "record 1: column name: expected string, got domain.IntValue")
+	})
+
+	t.Run("should return error for null expression", func(t *testing.T) {
+		_, err := NewRecordSet(createTestSchema()).WithColumnExpr("nothing", "null")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot infer the type of a null expression")
+	})
+}
@@ -80,6 +80,7 @@ type RawRecord struct {
 type Record struct {
 	Schema *DataSchema      // Schema this record conforms to
 	Values map[string]Value // Column ID -> Value mapping
+	Source string           // Origin of the record (e.g., "file.json"), empty if unknown
 }
 
 // NewRecord creates a new empty Record for the given schema.
@@ -1,5 +1,7 @@
 package domain
 
+import "fmt"
+
 // RecordSet represents a collection of records sharing the same schema.
 type RecordSet struct {
 	Schema  *DataSchema // Schema all records conform to
@@ -134,3 +136,128 @@ func (rs *RecordSet) Reduce(initial any, reducer func(accumulator any, record *R
 	}
 	return result
 }
+
+// Select returns a new RecordSet with only the given columns, in the given
+// order. Both the schema and every record are rewritten.
+func (rs *RecordSet) Select(ids ...string) (*RecordSet, error) {
+	schema, err := rs.Schema.Select(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return rs.reshape(schema, nil), nil
+}
+
+// Drop returns a new RecordSet without the given columns.
+// Both the schema and every record are rewritten.
+func (rs *RecordSet) Drop(ids ...string) (*RecordSet, error) {
+	schema, err := rs.Schema.Drop(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return rs.reshape(schema, nil), nil
+}
+
+// Rename returns a new RecordSet where the column oldID is renamed to newID.
+// Both the schema and every record are rewritten.
+func (rs *RecordSet) Rename(oldID, newID string) (*RecordSet, error) {
+	schema, err := rs.Schema.Rename(oldID, newID)
+	if err != nil {
+		return nil, err
+	}
+	return rs.reshape(schema, map[string]string{newID: oldID}), nil
+}
+
+// WithColumn returns a new RecordSet where col is added to the schema, or
+// replaces the column with the same ID, and its value is computed for every
+// record by fn.
+//
+// Example - Total price:
+//
+//	rs = rs.WithColumn(SchemaColumnSingle{ID: "total", SchemaType: NativeTypeFloat}, func(r *Record) Value {
+//	    return FloatValue(r.GetFloat("price") * float64(r.GetInt("quantity")))
+//	})
+func (rs *RecordSet) WithColumn(col SchemaColumn, fn func(*Record) Value) *RecordSet {
+	schema := rs.Schema.WithColumn(col)
+	result := rs.reshape(schema, nil)
+	for i, r := range result.Records {
+		r.Set(col.GetID(), fn(rs.Records[i]))
+	}
+	return result
+}
+
+// reshape copies every record to the given schema, keeping the values of its
+// columns. Renamed maps a new column ID to the ID it had in the old schema.
+func (rs *RecordSet) reshape(schema *DataSchema, renamed map[string]string) *RecordSet {
+	result := NewRecordSet(schema)
+	for _, r := range rs.Records {
+		record := NewRecord(schema)
+		record.Source = r.Source
+		for _, col := range schema.Columns {
+			oldID := col.GetID()
+			if id, ok := renamed[oldID]; ok {
+				oldID = id
+			}
+			if value, ok := r.Values[oldID]; ok {
+				record.Set(col.GetID(), value)
+			}
+		}
+		result.Add(record)
+	}
+	return result
+}
+
+// FilterExpr returns a new RecordSet containing only records for which the
+// boolean expression is true. See Expr for the expression syntax.
+//
+// Example:
+//
+//	inStock, err := rs.FilterExpr("stock > 0 and category == 'food'")
+func (rs *RecordSet) FilterExpr(source string) (*RecordSet, error) {
+	expr, err := CompileExpr(source, rs.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Type != NativeTypeBool || expr.Array {
+		return nil, &ExprError{Source: source, Msg: fmt.Sprintf("filter must be a bool expression, got %s", exprType{typ: expr.Type, array: expr.Array})}
+	}
+
+	result := NewRecordSet(rs.Schema)
+	for i, r := range rs.Records {
+		ok, err := expr.Match(r)
+		if err != nil {
+			return nil, NewRecordError(i, r, err)
+		}
+		if ok {
+			result.Add(r)
+		}
+	}
+	return result, nil
+}
+
+// WithColumnExpr returns a new RecordSet with a column computed by the
+// expression, typed after the expression result. See Expr for the
+// expression syntax.
+//
+// Example:
+//
+//	withTotal, err := rs.WithColumnExpr("total", "price * quantity")
+func (rs *RecordSet) WithColumnExpr(id, source string) (*RecordSet, error) {
+	expr, err := CompileExpr(source, rs.Schema)
+	if err != nil {
+		return nil, err
+	}
+	col, err := expr.Column(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := rs.reshape(rs.Schema.WithColumn(col), nil)
+	for i, r := range rs.Records {
+		value, err := expr.Eval(r)
+		if err != nil {
+			return nil, NewRecordError(i, r, err)
+		}
+		result.Records[i].Set(id, value)
+	}
+	return result, nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func createTestSchema() *DataSchema {
@@ -461,3 +462,101 @@ func TestRecordSet_Reduce(t *testing.T) {
 		assert.Equal(t, int64(50), max)
 	})
 }
+
+func TestRecordSet_Select(t *testing.T) {
+	t.Run("should rewrite schema and records", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		record := createTestRecordWithQuantity(schema, "A", 10, 5)
+		record.Source = "products.json"
+		rs.Add(record)
+
+		result, err := rs.Select("quantity", "name")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"quantity", "name"}, result.Schema.ColumnIDs())
+		assert.Same(t, result.Schema, result.First().Schema)
+		assert.Equal(t, map[string]Value{"quantity": IntValue(5), "name": StringValue("A")}, result.First().Values)
+		assert.Equal(t, "products.json", result.First().Source)
+		assert.Len(t, record.Values, 3, "original record should be unchanged")
+	})
+
+	t.Run("should return error for unknown column", func(t *testing.T) {
+		_, err := NewRecordSet(createTestSchema()).Select("missing")
+
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+}
+
+func TestRecordSet_Drop(t *testing.T) {
+	t.Run("should remove column from schema and records", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecordWithQuantity(schema, "A", 10, 5))
+
+		result, err := rs.Drop("price")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "quantity"}, result.Schema.ColumnIDs())
+		assert.Nil(t, result.First().Get("price"))
+		assert.Equal(t, int64(5), result.First().GetInt("quantity"))
+	})
+
+	t.Run("should return error for unknown column", func(t *testing.T) {
+		_, err := NewRecordSet(createTestSchema()).Drop("missing")
+
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+	})
+}
+
+func TestRecordSet_Rename(t *testing.T) {
+	t.Run("should rename column in schema and records", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecord(schema, "A", 10))
+
+		result, err := rs.Rename("name", "title")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"title", "price", "quantity"}, result.Schema.ColumnIDs())
+		assert.Equal(t, "A", result.First().GetString("title"))
+		assert.Nil(t, result.First().Get("name"))
+	})
+
+	t.Run("should return error when new ID exists", func(t *testing.T) {
+		_, err := NewRecordSet(createTestSchema()).Rename("name", "price")
+
+		assert.ErrorIs(t, err, ErrDuplicateColumn)
+	})
+}
+
+func TestRecordSet_WithColumn(t *testing.T) {
+	t.Run("should add computed column", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecordWithQuantity(schema, "A", 10, 5))
+		rs.Add(createTestRecordWithQuantity(schema, "B", 2.5, 2))
+
+		result := rs.WithColumn(SchemaColumnSingle{ID: "total", SchemaType: NativeTypeFloat}, func(r *Record) Value {
+			return FloatValue(r.GetFloat("price") * float64(r.GetInt("quantity")))
+		})
+
+		assert.Equal(t, []string{"name", "price", "quantity", "total"}, result.Schema.ColumnIDs())
+		assert.Equal(t, 50.0, result.Get(0).GetFloat("total"))
+		assert.Equal(t, 5.0, result.Get(1).GetFloat("total"))
+		assert.Nil(t, rs.First().Get("total"), "original record should be unchanged")
+	})
+
+	t.Run("should replace existing column and its type", func(t *testing.T) {
+		schema := createTestSchema()
+		rs := NewRecordSet(schema)
+		rs.Add(createTestRecord(schema, "A", 10.4))
+
+		result := rs.WithColumn(SchemaColumnSingle{ID: "price", SchemaType: NativeTypeInt}, func(r *Record) Value {
+			return IntValue(int64(r.GetFloat("price")))
+		})
+
+		assert.Equal(t, NativeTypeInt, result.Schema.Column("price").GetType())
+		assert.Equal(t, int64(10), result.First().GetInt("price"))
+	})
+}
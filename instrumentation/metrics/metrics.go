@@ -0,0 +1,303 @@
+// Package metrics instruments pipelines with counters and histograms exposed
+// in the OpenMetrics text format, as scraped by Prometheus.
+//
+// Stages are measured by a ports.Middleware, attached to a DataPipeline or a
+// TransformBuilder with Use, and runs by Metrics.Run:
+//
+//	m := metrics.New()
+//	p.Use(m.Middleware("orders"))
+//	http.Handle("/metrics", m.Handler())
+//	_, err := m.Run("orders", p)
+//
+// All metrics are labelled by pipeline name, and stage metrics by stage
+// name as well.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// ContentType is the media type of the exposition written by WriteTo.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// DefaultBuckets are the upper bounds in seconds of the duration histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Run outcomes used as the outcome label of pipeforge_runs_total.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Metrics collects pipeline metrics. It is safe for concurrent use.
+type Metrics struct {
+	stageRecords  *counter
+	stageRejected *counter
+	stageErrors   *counter
+	stageDuration *histogram
+	runs          *counter
+	runDuration   *histogram
+}
+
+// New creates an empty Metrics with DefaultBuckets.
+func New() *Metrics {
+	return NewWithBuckets(DefaultBuckets)
+}
+
+// NewWithBuckets creates an empty Metrics whose duration histograms use the
+// given upper bounds in seconds, sorted in increasing order.
+func NewWithBuckets(buckets []float64) *Metrics {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Metrics{
+		stageRecords:  newCounter("pipeforge_stage_records", "Records leaving a stage: loaded, transformed or stored.", "pipeline", "stage"),
+		stageRejected: newCounter("pipeforge_stage_rejected_records", "Records entering a stage and missing from its output.", "pipeline", "stage"),
+		stageErrors:   newCounter("pipeforge_stage_errors", "Stage executions that returned an error.", "pipeline", "stage"),
+		stageDuration: newHistogram("pipeforge_stage_duration_seconds", "Wall time of stage executions.", sorted, "pipeline", "stage"),
+		runs:          newCounter("pipeforge_runs", "Pipeline runs by outcome.", "pipeline", "outcome"),
+		runDuration:   newHistogram("pipeforge_run_duration_seconds", "Wall time of pipeline runs.", sorted, "pipeline"),
+	}
+}
+
+// Middleware returns a Middleware recording the records, rejects, errors and
+// duration of each stage under the given pipeline name.
+func (m *Metrics) Middleware(name string) ports.Middleware {
+	return func(stage string, next ports.StageFunc) ports.StageFunc {
+		return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			start := time.Now()
+			output, err := next(input)
+			m.stageDuration.observe(time.Since(start).Seconds(), name, stage)
+
+			if err != nil {
+				m.stageErrors.add(1, name, stage)
+				return output, err
+			}
+			in, out := count(input), count(output)
+			m.stageRecords.add(float64(out), name, stage)
+			if out < in {
+				m.stageRejected.add(float64(in-out), name, stage)
+			}
+			return output, nil
+		}
+	}
+}
+
+// Run runs the pipeline with the stage middleware of Middleware and records
+// the outcome and duration of the run. The pipeline itself is not modified.
+func (m *Metrics) Run(name string, p *pipeline.DataPipeline) (*domain.RecordSet, error) {
+	run := *p
+	run.Middleware = append(append([]ports.Middleware{}, p.Middleware...), m.Middleware(name))
+
+	start := time.Now()
+	result, err := run.RunWithResult()
+	m.runDuration.observe(time.Since(start).Seconds(), name)
+
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	m.runs.add(1, name, outcome)
+	return result, err
+}
+
+// WriteTo writes every metric in the OpenMetrics text format, terminated by
+// "# EOF". Series are sorted by label values.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	m.stageRecords.write(cw)
+	m.stageRejected.write(cw)
+	m.stageErrors.write(cw)
+	m.stageDuration.write(cw)
+	m.runs.write(cw)
+	m.runDuration.write(cw)
+	cw.WriteString("# EOF\n")
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// Handler returns an http.Handler serving the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		m.WriteTo(w)
+	})
+}
+
+func count(rs *domain.RecordSet) int {
+	if rs == nil {
+		return 0
+	}
+	return rs.Count()
+}
+
+// family holds the name and labels shared by the series of a metric.
+type family struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// key joins label values into a map key.
+func key(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func (f *family) header(w *countingWriter, typ string) {
+	w.WriteString("# TYPE " + f.name + " " + typ + "\n")
+	w.WriteString("# HELP " + f.name + " " + escape(f.help) + "\n")
+}
+
+// labelSet formats label values, followed by extra name/value pairs.
+func (f *family) labelSet(values []string, extra ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range f.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="` + escape(values[i]) + `"`)
+	}
+	for i := 0; i < len(extra); i += 2 {
+		b.WriteString(`,` + extra[i] + `="` + extra[i+1] + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+type counter struct {
+	family
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	total  float64
+}
+
+func newCounter(name, help string, labels ...string) *counter {
+	return &counter{family: family{name: name, help: help, labels: labels}, series: map[string]*counterSeries{}}
+}
+
+func (c *counter) add(v float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := key(values)
+	s, ok := c.series[k]
+	if !ok {
+		s = &counterSeries{values: values}
+		c.series[k] = s
+	}
+	s.total += v
+}
+
+func (c *counter) write(w *countingWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w, "counter")
+	for _, k := range sortedKeys(c.series) {
+		s := c.series[k]
+		w.WriteString(c.name + "_total" + c.labelSet(s.values) + " " + formatFloat(s.total) + "\n")
+	}
+}
+
+type histogram struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name, help string, buckets []float64, labels ...string) *histogram {
+	return &histogram{family: family{name: name, help: help, labels: labels}, buckets: buckets, series: map[string]*histogramSeries{}}
+}
+
+func (h *histogram) observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := key(values)
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{values: values, counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogram) write(w *countingWriter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w, "histogram")
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			w.WriteString(h.name + "_bucket" + h.labelSet(s.values, "le", formatFloat(bound)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		w.WriteString(h.name + "_bucket" + h.labelSet(s.values, "le", "+Inf") + " " + strconv.FormatUint(s.count, 10) + "\n")
+		w.WriteString(h.name + "_sum" + h.labelSet(s.values) + " " + formatFloat(s.sum) + "\n")
+		w.WriteString(h.name + "_count" + h.labelSet(s.values) + " " + strconv.FormatUint(s.count, 10) + "\n")
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// escape escapes backslashes, newlines and double quotes in help texts and
+// label values.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// countingWriter writes strings, counting bytes and keeping the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) WriteString(s string) {
+	if c.err != nil {
+		return
+	}
+	n, err := c.w.WriteString(s)
+	c.n += int64(n)
+	c.err = err
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/transform"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
+	mocktransform "github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inStock keeps records with a positive quantity.
+type inStock struct{}
+
+func (inStock) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	return input.FilterExpr("quantity > 0")
+}
+
+func exposition(t *testing.T, m *Metrics) string {
+	t.Helper()
+	var b strings.Builder
+	n, err := m.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	return b.String()
+}
+
+func TestMetrics_Run(t *testing.T) {
+	t.Run("should count records, rejects and runs per stage", func(t *testing.T) {
+		m := NewWithBuckets([]float64{60})
+		p := &pipeline.DataPipeline{
+			Source:    source.StockSource{0, 3, 0, 5},
+			Transform: inStock{},
+			Store:     &store.EmptyStore{},
+		}
+
+		_, err := m.Run("orders", p)
+		require.NoError(t, err)
+		_, err = m.Run("orders", p)
+		require.NoError(t, err)
+
+		out := exposition(t, m)
+		assert.Contains(t, out, `pipeforge_stage_records_total{pipeline="orders",stage="load"} 8`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_records_total{pipeline="orders",stage="transform"} 4`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_records_total{pipeline="orders",stage="store"} 4`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_rejected_records_total{pipeline="orders",stage="transform"} 4`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_duration_seconds_bucket{pipeline="orders",stage="load",le="60"} 2`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_duration_seconds_bucket{pipeline="orders",stage="load",le="+Inf"} 2`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_duration_seconds_count{pipeline="orders",stage="load"} 2`+"\n")
+		assert.Contains(t, out, `pipeforge_runs_total{pipeline="orders",outcome="success"} 2`+"\n")
+		assert.Contains(t, out, `pipeforge_run_duration_seconds_count{pipeline="orders"} 2`+"\n")
+		assert.Empty(t, p.Middleware)
+	})
+
+	t.Run("should count errors and failed runs", func(t *testing.T) {
+		m := New()
+		p := &pipeline.DataPipeline{
+			Source:    &source.EmptySource{},
+			Transform: &mocktransform.ErrorTransform{},
+			Store:     &store.EmptyStore{},
+		}
+
+		_, err := m.Run("orders", p)
+
+		require.Error(t, err)
+		out := exposition(t, m)
+		assert.Contains(t, out, `pipeforge_stage_errors_total{pipeline="orders",stage="transform"} 1`+"\n")
+		assert.Contains(t, out, `pipeforge_runs_total{pipeline="orders",outcome="error"} 1`+"\n")
+		assert.NotContains(t, out, `stage="store"`)
+	})
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	t.Run("should label transform builder stages", func(t *testing.T) {
+		m := New()
+		chain := transform.NewTransformBuilder().
+			AddNamed("in stock", inStock{}).
+			Use(m.Middleware("orders"))
+
+		_, err := chain.Transform(source.NewStock(0, 1, 2))
+
+		require.NoError(t, err)
+		out := exposition(t, m)
+		assert.Contains(t, out, `pipeforge_stage_records_total{pipeline="orders",stage="in stock"} 2`+"\n")
+		assert.Contains(t, out, `pipeforge_stage_rejected_records_total{pipeline="orders",stage="in stock"} 1`+"\n")
+	})
+}
+
+func TestMetrics_WriteTo(t *testing.T) {
+	t.Run("should write families and terminate with EOF", func(t *testing.T) {
+		out := exposition(t, New())
+
+		assert.True(t, strings.HasPrefix(out, "# TYPE pipeforge_stage_records counter\n# HELP pipeforge_stage_records "))
+		assert.Contains(t, out, "# TYPE pipeforge_stage_duration_seconds histogram\n")
+		assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+	})
+
+	t.Run("should escape label values", func(t *testing.T) {
+		m := New()
+		m.runs.add(1, "a\"b\\c\nd", OutcomeSuccess)
+
+		assert.Contains(t, exposition(t, m), `pipeforge_runs_total{pipeline="a\"b\\c\nd",outcome="success"} 1`)
+	})
+
+	t.Run("should sort series by label values", func(t *testing.T) {
+		m := New()
+		m.runs.add(1, "b", OutcomeSuccess)
+		m.runs.add(1, "a", OutcomeSuccess)
+
+		out := exposition(t, m)
+
+		assert.Less(t, strings.Index(out, `pipeline="a"`), strings.Index(out, `pipeline="b"`))
+	})
+
+	t.Run("should accumulate histogram buckets", func(t *testing.T) {
+		m := NewWithBuckets([]float64{1, 0.1})
+		m.runDuration.observe(0.05, "orders")
+		m.runDuration.observe(0.5, "orders")
+		m.runDuration.observe(2, "orders")
+
+		assert.Contains(t, exposition(t, m), strings.Join([]string{
+			`pipeforge_run_duration_seconds_bucket{pipeline="orders",le="0.1"} 1`,
+			`pipeforge_run_duration_seconds_bucket{pipeline="orders",le="1"} 2`,
+			`pipeforge_run_duration_seconds_bucket{pipeline="orders",le="+Inf"} 3`,
+			`pipeforge_run_duration_seconds_sum{pipeline="orders"} 2.55`,
+			`pipeforge_run_duration_seconds_count{pipeline="orders"} 3`,
+		}, "\n"))
+	})
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	t.Run("should serve the exposition", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		New().Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+		assert.True(t, strings.HasSuffix(rec.Body.String(), "# EOF\n"))
+	})
+}
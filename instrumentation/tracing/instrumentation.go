@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Instrumentation traces pipeline runs and their stages. It is safe for
+// concurrent use: each Run hands the span contexts to its own stages, so
+// runs of one Instrumentation proceed in parallel.
+type Instrumentation struct {
+	tracer Tracer
+}
+
+// New creates an Instrumentation starting spans with tracer.
+func New(tracer Tracer) *Instrumentation {
+	return &Instrumentation{tracer: tracer}
+}
+
+// Run runs the pipeline in a span named after the pipeline, child of the
+// span in ctx, with a child span per stage. When the transform is
+// ports.Staged, each of its stages gets a span child of the transform
+// span. The pipeline itself is not modified.
+func (in *Instrumentation) Run(ctx context.Context, name string, p *pipeline.DataPipeline) (*domain.RecordSet, error) {
+	ctx, span := in.tracer.Start(ctx, name)
+	span.SetAttributes(String(AttrPipeline, name))
+	defer span.End()
+
+	stages := &runStages{tracer: in.tracer, parent: ctx}
+	run := *p
+	run.Middleware = append(append([]ports.Middleware{}, p.Middleware...), stages.middleware)
+	if staged, ok := p.Transform.(ports.Staged); ok {
+		run.Transform = staged.WithStageMiddleware(stages.middleware)
+	}
+
+	result, err := run.RunWithResult()
+	if result != nil {
+		span.SetAttributes(Int(AttrRecordsOut, result.Count()), String(AttrSchemaOut, schemaID(result)))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return result, err
+}
+
+// runStages parents the stage spans of one run. Stages of a run are called
+// one at a time, nested stages within their parent stage, so parent is the
+// context of the innermost stage running, or of the run.
+type runStages struct {
+	tracer Tracer
+	parent context.Context
+}
+
+func (r *runStages) middleware(stage string, next ports.StageFunc) ports.StageFunc {
+	return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+		ctx, span := r.tracer.Start(r.parent, stage)
+		outer := r.parent
+		r.parent = ctx
+		defer func() { r.parent = outer }()
+		return trace(span, stage, next, input)
+	}
+}
+
+// trace runs next in span, recording the records and schemas in and out
+// and the error, and ends the span.
+func trace(span Span, stage string, next ports.StageFunc, input *domain.RecordSet) (*domain.RecordSet, error) {
+	defer span.End()
+	span.SetAttributes(String(AttrStage, stage))
+	if input != nil {
+		span.SetAttributes(Int(AttrRecordsIn, input.Count()), String(AttrSchemaIn, schemaID(input)))
+	}
+
+	output, err := next(input)
+	if output != nil {
+		span.SetAttributes(Int(AttrRecordsOut, output.Count()), String(AttrSchemaOut, schemaID(output)))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return output, err
+}
+
+func schemaID(rs *domain.RecordSet) string {
+	if rs.Schema == nil {
+		return ""
+	}
+	return rs.Schema.ID
+}
@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/transform"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
+	mocktransform "github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inStock keeps records with a positive quantity.
+type inStock struct{}
+
+func (inStock) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	return input.FilterExpr("quantity > 0")
+}
+
+// panicTransform panics on every call.
+type panicTransform struct{}
+
+func (panicTransform) Transform(*domain.RecordSet) (*domain.RecordSet, error) {
+	panic("boom")
+}
+
+// byName indexes spans by name.
+func byName(spans []SpanData) map[string]SpanData {
+	result := make(map[string]SpanData, len(spans))
+	for _, s := range spans {
+		result[s.Name] = s
+	}
+	return result
+}
+
+func TestInstrumentation_Run(t *testing.T) {
+	t.Run("should trace the run, its stages and transform builder stages", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		tr := New(NewTracer(exporter))
+		chain := transform.NewTransformBuilder().
+			AddNamed("in stock", inStock{}).
+			AddNamed("restock", mocktransform.NewAddIntTransform("quantity", 1))
+		p := &pipeline.DataPipeline{Source: source.StockSource{0, 3, 5}, Transform: chain, Store: &store.EmptyStore{}}
+
+		_, err := tr.Run(context.Background(), "orders", p)
+
+		require.NoError(t, err)
+		spans := byName(exporter.Spans())
+		require.Len(t, spans, 6)
+		run := spans["orders"]
+		assert.False(t, run.Parent.IsValid())
+		assert.Equal(t, "orders", run.Attribute(AttrPipeline))
+		assert.Equal(t, 2, run.Attribute(AttrRecordsOut))
+
+		for _, stage := range []string{pipeline.StageLoad, pipeline.StageTransform, pipeline.StageStore} {
+			assert.Equal(t, run.Context, spans[stage].Parent, stage)
+			assert.Equal(t, stage, spans[stage].Attribute(AttrStage))
+		}
+		for _, stage := range []string{"in stock", "restock"} {
+			assert.Equal(t, spans[pipeline.StageTransform].Context, spans[stage].Parent, stage)
+			assert.Equal(t, run.Context.TraceID, spans[stage].Context.TraceID, stage)
+		}
+
+		assert.Nil(t, spans[pipeline.StageLoad].Attribute(AttrRecordsIn))
+		assert.Equal(t, 3, spans[pipeline.StageLoad].Attribute(AttrRecordsOut))
+		assert.Equal(t, "Product", spans[pipeline.StageLoad].Attribute(AttrSchemaOut))
+		assert.Equal(t, 3, spans["in stock"].Attribute(AttrRecordsIn))
+		assert.Equal(t, 2, spans["in stock"].Attribute(AttrRecordsOut))
+		assert.Equal(t, "Product", spans["in stock"].Attribute(AttrSchemaIn))
+		assert.Empty(t, p.Middleware)
+	})
+
+	t.Run("should join the trace of the context", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		remote, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		require.NoError(t, err)
+		p := &pipeline.DataPipeline{Source: source.StockSource{1}, Transform: inStock{}, Store: &store.EmptyStore{}}
+
+		_, err = New(NewTracer(exporter)).Run(ContextWithSpanContext(context.Background(), remote), "orders", p)
+
+		require.NoError(t, err)
+		spans := exporter.Spans()
+		require.Len(t, spans, 4)
+		for _, s := range spans {
+			assert.Equal(t, remote.TraceID, s.Context.TraceID, s.Name)
+		}
+		assert.Equal(t, remote, byName(spans)["orders"].Parent)
+	})
+
+	t.Run("should trace concurrent runs separately", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		tr := New(NewTracer(exporter))
+		chain := transform.NewTransformBuilder().AddNamed("in stock", inStock{})
+
+		var wg sync.WaitGroup
+		for _, name := range []string{"orders", "returns", "refunds", "invoices"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p := &pipeline.DataPipeline{Source: source.StockSource{1}, Transform: chain, Store: &store.EmptyStore{}}
+				_, err := tr.Run(context.Background(), name, p)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		spans := exporter.Spans()
+		require.Len(t, spans, 20)
+		contexts := map[SpanID]SpanData{}
+		for _, s := range spans {
+			contexts[s.Context.SpanID] = s
+		}
+		for _, s := range spans {
+			if s.Attribute(AttrPipeline) != nil {
+				assert.False(t, s.Parent.IsValid(), s.Name)
+				continue
+			}
+			parent, ok := contexts[s.Parent.SpanID]
+			require.True(t, ok, s.Name)
+			assert.Equal(t, parent.Context.TraceID, s.Context.TraceID, s.Name)
+			if s.Name == "in stock" {
+				assert.Equal(t, pipeline.StageTransform, parent.Name)
+			}
+		}
+	})
+
+	t.Run("should end spans and restore parents when a stage panics", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		tr := New(NewTracer(exporter))
+		chain := transform.NewTransformBuilder().AddNamed("boom", panicTransform{})
+		p := &pipeline.DataPipeline{Source: source.StockSource{1}, Transform: chain, Store: &store.EmptyStore{}}
+
+		assert.Panics(t, func() { tr.Run(context.Background(), "orders", p) })
+
+		spans := byName(exporter.Spans())
+		require.Len(t, spans, 4)
+		assert.Equal(t, spans[pipeline.StageTransform].Context, spans["boom"].Parent)
+		assert.Equal(t, spans["orders"].Context, spans[pipeline.StageTransform].Parent)
+	})
+
+	t.Run("should record errors on the failing stage and the run", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		p := &pipeline.DataPipeline{Source: source.StockSource{1}, Transform: &mocktransform.ErrorTransform{}, Store: &store.EmptyStore{}}
+
+		_, err := New(NewTracer(exporter)).Run(context.Background(), "orders", p)
+
+		require.Error(t, err)
+		spans := byName(exporter.Spans())
+		require.Len(t, spans, 3)
+		assert.EqualError(t, spans[pipeline.StageTransform].Err, "transform error")
+		assert.Equal(t, err, spans["orders"].Err)
+		assert.NoError(t, spans[pipeline.StageLoad].Err)
+	})
+}
@@ -0,0 +1,110 @@
+// Package oteltracing implements tracing.Tracer with OpenTelemetry, so that
+// pipeline runs join the trace of OpenTelemetry-instrumented callers, such
+// as the request spans of otelhttp, and their spans are exported by the
+// configured OpenTelemetry SDK:
+//
+//	tr := tracing.New(oteltracing.NewTracer(otel.Tracer("pipeforge")))
+//	_, err := tr.Run(r.Context(), "orders", p)
+//
+// It is a separate module, so that the pipeforge module does not depend on
+// OpenTelemetry.
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spaghettifactory-oss/pipeforge/instrumentation/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer starts OpenTelemetry spans. Spans are children of the
+// OpenTelemetry span found in the context given to Start, or else of its
+// tracing.SpanContext, such as a remote parent parsed from a traceparent
+// header.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer creates a Tracer starting spans with tracer.
+func NewTracer(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start starts a span.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if parent := tracing.SpanContextFromContext(ctx); parent.IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, otelSpanContext(parent))
+		}
+	}
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, span{s}
+}
+
+// span adapts an OpenTelemetry span to tracing.Span.
+type span struct {
+	span trace.Span
+}
+
+func (s span) SpanContext() tracing.SpanContext {
+	sc := s.span.SpanContext()
+	return tracing.SpanContext{
+		TraceID: tracing.TraceID(sc.TraceID()),
+		SpanID:  tracing.SpanID(sc.SpanID()),
+		Sampled: sc.IsSampled(),
+	}
+}
+
+func (s span) SetAttributes(attributes ...tracing.Attribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		kvs = append(kvs, keyValue(a))
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+// RecordError records err as an exception event and sets the span status
+// to Error.
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+// keyValue converts an attribute, formatting values of other types than
+// strings, ints, floats and bools.
+func keyValue(a tracing.Attribute) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	}
+	return attribute.String(a.Key, fmt.Sprint(a.Value))
+}
+
+func otelSpanContext(sc tracing.SpanContext) trace.SpanContext {
+	var flags trace.TraceFlags
+	if sc.Sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(sc.TraceID),
+		SpanID:     trace.SpanID(sc.SpanID),
+		TraceFlags: flags,
+		Remote:     true,
+	})
+}
@@ -0,0 +1,141 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/transform"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/instrumentation/tracing"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
+	mocktransform "github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// inStock keeps records with a positive quantity.
+type inStock struct{}
+
+func (inStock) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	return input.FilterExpr("quantity > 0")
+}
+
+// failing fails every call.
+type failing struct{}
+
+func (failing) Transform(*domain.RecordSet) (*domain.RecordSet, error) {
+	return nil, errors.New("transform error")
+}
+
+func newProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+// byName indexes spans by name.
+func byName(spans tracetest.SpanStubs) map[string]tracetest.SpanStub {
+	result := make(map[string]tracetest.SpanStub, len(spans))
+	for _, s := range spans {
+		result[s.Name] = s
+	}
+	return result
+}
+
+// attr returns the value of the attribute with the given key, or an
+// invalid value.
+func attr(s tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("should export the run and its stages under the span of the context", func(t *testing.T) {
+		// Arrange
+		provider, exporter := newProvider()
+		tr := tracing.New(NewTracer(provider.Tracer("pipeforge")))
+		chain := transform.NewTransformBuilder().
+			AddNamed("in stock", inStock{}).
+			AddNamed("restock", mocktransform.NewAddIntTransform("quantity", 1))
+		p := &pipeline.DataPipeline{Source: source.StockSource{0, 3, 5}, Transform: chain, Store: &store.EmptyStore{}}
+		ctx, request := provider.Tracer("http").Start(context.Background(), "GET /run")
+
+		// Act
+		_, err := tr.Run(ctx, "orders", p)
+		request.End()
+
+		// Assert
+		require.NoError(t, err)
+		spans := byName(exporter.GetSpans())
+		require.Len(t, spans, 7)
+		run := spans["orders"]
+		assert.Equal(t, request.SpanContext().SpanID(), run.Parent.SpanID())
+		assert.Equal(t, request.SpanContext().TraceID(), run.SpanContext.TraceID())
+		assert.Equal(t, "orders", attr(run, tracing.AttrPipeline).AsString())
+		assert.Equal(t, int64(2), attr(run, tracing.AttrRecordsOut).AsInt64())
+
+		for _, stage := range []string{pipeline.StageLoad, pipeline.StageTransform, pipeline.StageStore} {
+			assert.Equal(t, run.SpanContext.SpanID(), spans[stage].Parent.SpanID(), stage)
+		}
+		for _, stage := range []string{"in stock", "restock"} {
+			assert.Equal(t, spans[pipeline.StageTransform].SpanContext.SpanID(), spans[stage].Parent.SpanID(), stage)
+		}
+		assert.Equal(t, int64(3), attr(spans["in stock"], tracing.AttrRecordsIn).AsInt64())
+		assert.Equal(t, "Product", attr(spans[pipeline.StageLoad], tracing.AttrSchemaOut).AsString())
+	})
+
+	t.Run("should record errors with an error status", func(t *testing.T) {
+		provider, exporter := newProvider()
+		tr := tracing.New(NewTracer(provider.Tracer("pipeforge")))
+		p := &pipeline.DataPipeline{Source: source.StockSource{1}, Transform: failing{}, Store: &store.EmptyStore{}}
+
+		_, err := tr.Run(context.Background(), "orders", p)
+
+		require.Error(t, err)
+		spans := byName(exporter.GetSpans())
+		for _, name := range []string{"orders", pipeline.StageTransform} {
+			assert.Equal(t, codes.Error, spans[name].Status.Code, name)
+			require.Len(t, spans[name].Events, 1, name)
+			assert.Equal(t, "exception", spans[name].Events[0].Name, name)
+		}
+		assert.Equal(t, codes.Unset, spans[pipeline.StageLoad].Status.Code)
+	})
+
+	t.Run("should join a traceparent parent without span in the context", func(t *testing.T) {
+		provider, exporter := newProvider()
+		tr := tracing.New(NewTracer(provider.Tracer("pipeforge")))
+		p := &pipeline.DataPipeline{Source: source.StockSource{1}, Transform: inStock{}, Store: &store.EmptyStore{}}
+		parent, err := tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		require.NoError(t, err)
+
+		_, err = tr.Run(tracing.ContextWithSpanContext(context.Background(), parent), "orders", p)
+
+		require.NoError(t, err)
+		run := byName(exporter.GetSpans())["orders"]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", run.SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", run.Parent.SpanID().String())
+		assert.True(t, run.Parent.IsRemote())
+	})
+
+	t.Run("should expose the OpenTelemetry span context", func(t *testing.T) {
+		provider, _ := newProvider()
+
+		_, span := NewTracer(provider.Tracer("pipeforge")).Start(context.Background(), "orders")
+		span.End()
+
+		sc := span.SpanContext()
+		assert.True(t, sc.IsValid())
+		assert.True(t, sc.Sampled)
+	})
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SpanData is a finished span as received by an Exporter.
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanContext // Zero for root spans
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Err        error // Error recorded on the span, nil if it succeeded
+}
+
+// Attribute returns the value of the attribute with the given key, or nil.
+// The last value set wins.
+func (s SpanData) Attribute(key string) any {
+	var value any
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			value = a.Value
+		}
+	}
+	return value
+}
+
+// Exporter receives spans when they end.
+type Exporter interface {
+	Export(span SpanData)
+}
+
+// InMemoryExporter keeps exported spans in memory. It is safe for
+// concurrent use.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// Export appends span.
+func (e *InMemoryExporter) Export(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset drops the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// BasicTracer is a Tracer generating random IDs and exporting spans when
+// they end. Spans are children of the span context found in the context
+// given to Start, or start a new sampled trace.
+type BasicTracer struct {
+	Exporter Exporter
+}
+
+// NewTracer creates a BasicTracer exporting to exporter.
+func NewTracer(exporter Exporter) *BasicTracer {
+	return &BasicTracer{Exporter: exporter}
+}
+
+// Start starts a span.
+func (t *BasicTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+	if !parent.IsValid() {
+		sc.TraceID, sc.Sampled = newTraceID(), true
+	}
+
+	s := &basicSpan{exporter: t.Exporter, data: SpanData{Name: name, Context: sc, Parent: parent, Start: time.Now()}}
+	return ContextWithSpanContext(ctx, sc), s
+}
+
+type basicSpan struct {
+	exporter Exporter
+	mu       sync.Mutex
+	data     SpanData
+	ended    bool
+}
+
+func (s *basicSpan) SpanContext() SpanContext {
+	return s.data.Context
+}
+
+func (s *basicSpan) SetAttributes(attributes ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attributes...)
+}
+
+func (s *basicSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+// End exports the span, once.
+func (s *basicSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.exporter != nil && data.Context.Sampled {
+		s.exporter.Export(data)
+	}
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	t.Run("should parse and format a traceparent", func(t *testing.T) {
+		value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		sc, err := ParseTraceParent(value)
+
+		require.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+		assert.True(t, sc.Sampled)
+		assert.Equal(t, value, sc.TraceParent())
+	})
+
+	t.Run("should read the sampled flag", func(t *testing.T) {
+		sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		require.NoError(t, err)
+		assert.False(t, sc.Sampled)
+	})
+
+	t.Run("should reject malformed values", func(t *testing.T) {
+		for _, value := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+		} {
+			_, err := ParseTraceParent(value)
+
+			assert.ErrorIs(t, err, ErrInvalidTraceParent, value)
+		}
+	})
+}
+
+func TestBasicTracer_Start(t *testing.T) {
+	t.Run("should start a new trace without parent", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+
+		_, span := NewTracer(exporter).Start(context.Background(), "run")
+		span.SetAttributes(String("a", "b"), Int("n", 1))
+		span.End()
+		span.End()
+
+		spans := exporter.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "run", spans[0].Name)
+		assert.True(t, spans[0].Context.IsValid())
+		assert.False(t, spans[0].Parent.IsValid())
+		assert.Equal(t, "b", spans[0].Attribute("a"))
+		assert.Equal(t, 1, spans[0].Attribute("n"))
+		assert.Nil(t, spans[0].Attribute("missing"))
+		assert.False(t, spans[0].End.Before(spans[0].Start))
+	})
+
+	t.Run("should parent spans on the context", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		tracer := NewTracer(exporter)
+		remote, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		require.NoError(t, err)
+
+		ctx, parent := tracer.Start(ContextWithSpanContext(context.Background(), remote), "parent")
+		_, child := tracer.Start(ctx, "child")
+		child.RecordError(errors.New("boom"))
+		child.End()
+		parent.End()
+
+		spans := exporter.Spans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, remote.TraceID, spans[1].Context.TraceID)
+		assert.Equal(t, remote, spans[1].Parent)
+		assert.Equal(t, spans[1].Context, spans[0].Parent)
+		assert.Equal(t, parent.SpanContext(), SpanContextFromContext(ctx))
+		assert.EqualError(t, spans[0].Err, "boom")
+	})
+
+	t.Run("should not export unsampled traces", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		remote, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		require.NoError(t, err)
+
+		_, span := NewTracer(exporter).Start(ContextWithSpanContext(context.Background(), remote), "run")
+		span.End()
+
+		assert.Empty(t, exporter.Spans())
+	})
+}
+
+func TestInMemoryExporter_Reset(t *testing.T) {
+	t.Run("should drop exported spans", func(t *testing.T) {
+		exporter := &InMemoryExporter{}
+		exporter.Export(SpanData{Name: "run"})
+
+		exporter.Reset()
+
+		assert.Empty(t, exporter.Spans())
+	})
+}
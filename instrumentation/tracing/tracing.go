@@ -0,0 +1,164 @@
+// Package tracing traces pipeline runs with a span per run and a child span
+// per stage, carrying record counts, schema IDs and errors.
+//
+// Spans are created through the Tracer interface, and the package provides
+// a tracer exporting finished spans, for instance to an InMemoryExporter in
+// tests:
+//
+//	exporter := &tracing.InMemoryExporter{}
+//	t := tracing.New(tracing.NewTracer(exporter))
+//	_, err := t.Run(ctx, "orders", p)
+//
+// Spans started by Run are children of the SpanContext found in ctx, so a
+// pipeline triggered by an HTTP request carrying a traceparent header joins
+// the trace of the request. The oteltracing module provides a Tracer
+// starting OpenTelemetry spans, children of the OpenTelemetry span in ctx.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Span attribute keys set by the middleware and Run.
+const (
+	AttrPipeline   = "pipeforge.pipeline"
+	AttrStage      = "pipeforge.stage"
+	AttrRecordsIn  = "pipeforge.records.in"
+	AttrRecordsOut = "pipeforge.records.out"
+	AttrSchemaIn   = "pipeforge.schema.in"
+	AttrSchemaOut  = "pipeforge.schema.out"
+)
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span named name, child of the span in ctx if any, and
+	// returns a context holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation in a trace. It must be ended once.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttributes(attributes ...Attribute)
+	// RecordError records err and marks the span as failed.
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key/value pair describing a span. Values are strings,
+// ints, floats or bools.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string Attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an int Attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// SpanContext identifies a span, possibly in another process.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent formats sc as a W3C traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ErrInvalidTraceParent is returned by ParseTraceParent for malformed values.
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// ParseTraceParent parses a W3C traceparent header value such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceParent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if err := decodeHex(sc.TraceID[:], parts[1]); err != nil {
+		return SpanContext{}, fmt.Errorf("%w: trace id: %v", ErrInvalidTraceParent, err)
+	}
+	if err := decodeHex(sc.SpanID[:], parts[2]); err != nil {
+		return SpanContext{}, fmt.Errorf("%w: span id: %v", ErrInvalidTraceParent, err)
+	}
+	if err := decodeHex(flags[:], parts[3]); err != nil {
+		return SpanContext{}, fmt.Errorf("%w: flags: %v", ErrInvalidTraceParent, err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("%w: zero id", ErrInvalidTraceParent)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex s into dst, which it must fill exactly.
+func decodeHex(dst []byte, s string) error {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return fmt.Errorf("expected %d lowercase hex digits, got %q", 2*len(dst), s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a context whose spans started by a
+// BasicTracer are children of sc, typically a remote parent parsed with
+// ParseTraceParent.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context stored in ctx, or a zero
+// SpanContext.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
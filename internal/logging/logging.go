@@ -0,0 +1,16 @@
+// Package logging holds helpers shared by the components accepting an
+// optional *slog.Logger.
+package logging
+
+import "log/slog"
+
+// nop discards every record.
+var nop = slog.New(slog.DiscardHandler)
+
+// Or returns logger, or a logger discarding everything when it is nil.
+func Or(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return nop
+	}
+	return logger
+}
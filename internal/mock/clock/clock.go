@@ -0,0 +1,74 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock whose time only moves with Advance. Sleep blocks
+// until the clock was advanced past its deadline.
+type Clock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers map[chan struct{}]time.Time
+}
+
+// New creates a Clock set to now.
+func New(now time.Time) *Clock {
+	return &Clock{now: now, sleepers: map[chan struct{}]time.Time{}}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	if d <= 0 {
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+	wake := make(chan struct{})
+	c.sleepers[wake] = c.now.Add(d)
+	c.mu.Unlock()
+
+	select {
+	case <-wake:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.sleepers, wake)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Advance moves the clock forward and wakes the sleepers whose deadline
+// passed.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for wake, deadline := range c.sleepers {
+		if !deadline.After(c.now) {
+			delete(c.sleepers, wake)
+			close(wake)
+		}
+	}
+}
+
+// BlockUntil waits until n goroutines are sleeping.
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		sleeping := len(c.sleepers)
+		c.mu.Unlock()
+		if sleeping >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
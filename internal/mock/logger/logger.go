@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"io"
+	"log/slog"
+)
+
+// NewTextLogger creates a logger writing every level to w as text, without
+// timestamps so that output can be compared in tests.
+func NewTextLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
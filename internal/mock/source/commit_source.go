@@ -0,0 +1,22 @@
+package source
+
+import "github.com/spaghettifactory-oss/pipeforge/domain"
+
+// CommitSource loads an empty RecordSet and counts its commits, failing
+// them with Err when set.
+type CommitSource struct {
+	Commits int
+	Err     error
+}
+
+func (s *CommitSource) Load() (*domain.RecordSet, error) {
+	return domain.NewRecordSet(nil), nil
+}
+
+func (s *CommitSource) Commit() error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Commits++
+	return nil
+}
@@ -0,0 +1,38 @@
+package source
+
+import "github.com/spaghettifactory-oss/pipeforge/domain"
+
+// StockSource loads one Product record per quantity, see NewStock.
+type StockSource []int64
+
+// Load returns the records of the quantities.
+func (s StockSource) Load() (*domain.RecordSet, error) {
+	return NewStock(s...), nil
+}
+
+// NewStock returns a RecordSet of Product records holding the quantities
+// in an int "quantity" column.
+func NewStock(quantities ...int64) *domain.RecordSet {
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeInt},
+		},
+	}
+	rs := domain.NewRecordSet(schema)
+	for _, q := range quantities {
+		record := domain.NewRecord(schema)
+		record.Set("quantity", domain.IntValue(q))
+		rs.Add(record)
+	}
+	return rs
+}
+
+// Quantities returns the quantity of every record of rs.
+func Quantities(rs *domain.RecordSet) []int64 {
+	result := make([]int64, 0, rs.Count())
+	for _, r := range rs.Records {
+		result = append(result, r.GetInt("quantity"))
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package stages
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Logging returns a Middleware logging each stage to logger: its start at
+// debug level, its record counts and duration at info level once it
+// finished, or its error at error level.
+func Logging(logger *slog.Logger) ports.Middleware {
+	return func(stage string, next ports.StageFunc) ports.StageFunc {
+		return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			logger.Debug("stage started", "stage", stage, "records_in", count(input))
+
+			start := time.Now()
+			output, err := next(input)
+			duration := time.Since(start)
+
+			if err != nil {
+				logger.Error("stage failed", "stage", stage, "duration", duration, "error", err)
+				return output, err
+			}
+			logger.Info("stage finished", "stage", stage, "records_in", count(input), "records_out", count(output), "duration", duration)
+			return output, nil
+		}
+	}
+}
+
+func count(rs *domain.RecordSet) int {
+	if rs == nil {
+		return 0
+	}
+	return rs.Count()
+}
@@ -0,0 +1,41 @@
+package stages
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogging(t *testing.T) {
+	t.Run("should log stage start and finish with record counts", func(t *testing.T) {
+		var buf bytes.Buffer
+		input := domain.NewRecordSet(nil)
+		input.Add(domain.NewRecord(nil))
+
+		_, err := Wrap("transform", func(in *domain.RecordSet) (*domain.RecordSet, error) {
+			return domain.NewRecordSet(nil), nil
+		}, Logging(logger.NewTextLogger(&buf)))(input)
+
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `level=DEBUG msg="stage started" stage=transform records_in=1`+"\n")
+		assert.Contains(t, buf.String(), `level=INFO msg="stage finished" stage=transform records_in=1 records_out=0 duration=`)
+	})
+
+	t.Run("should log stage errors", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		_, err := Wrap("load", func(*domain.RecordSet) (*domain.RecordSet, error) {
+			return nil, errors.New("boom")
+		}, Logging(logger.NewTextLogger(&buf)))(nil)
+
+		assert.Error(t, err)
+		assert.Contains(t, buf.String(), `level=ERROR msg="stage failed" stage=load duration=`)
+		assert.Contains(t, buf.String(), "error=boom\n")
+		assert.NotContains(t, buf.String(), "stage finished")
+	})
+}
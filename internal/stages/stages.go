@@ -0,0 +1,14 @@
+// Package stages runs pipeline stages through their middleware, for the
+// pipeline and the transform builder.
+package stages
+
+import "github.com/spaghettifactory-oss/pipeforge/ports"
+
+// Wrap applies middlewares to a stage. The first middleware is the
+// outermost: it runs first before the stage and last after it.
+func Wrap(stage string, fn ports.StageFunc, middlewares ...ports.Middleware) ports.StageFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		fn = middlewares[i](stage, fn)
+	}
+	return fn
+}
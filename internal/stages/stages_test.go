@@ -0,0 +1,62 @@
+package stages
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	t.Run("should run middleware outermost first", func(t *testing.T) {
+		var calls []string
+		trace := func(name string) ports.Middleware {
+			return func(stage string, next ports.StageFunc) ports.StageFunc {
+				return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+					calls = append(calls, name+" before "+stage)
+					output, err := next(input)
+					calls = append(calls, name+" after "+stage)
+					return output, err
+				}
+			}
+		}
+		stage := func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			calls = append(calls, "stage")
+			return input, nil
+		}
+
+		_, err := Wrap("load", stage, trace("a"), trace("b"))(nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a before load", "b before load", "stage", "b after load", "a after load"}, calls)
+	})
+
+	t.Run("should let middleware replace the output", func(t *testing.T) {
+		replacement := domain.NewRecordSet(nil)
+		replace := func(stage string, next ports.StageFunc) ports.StageFunc {
+			return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+				return replacement, nil
+			}
+		}
+
+		output, err := Wrap("load", func(*domain.RecordSet) (*domain.RecordSet, error) {
+			return nil, errors.New("not called")
+		}, replace)(nil)
+
+		require.NoError(t, err)
+		assert.Same(t, replacement, output)
+	})
+
+	t.Run("should return the stage itself without middleware", func(t *testing.T) {
+		input := domain.NewRecordSet(nil)
+
+		output, err := Wrap("load", func(in *domain.RecordSet) (*domain.RecordSet, error) { return in, nil })(input)
+
+		require.NoError(t, err)
+		assert.Same(t, input, output)
+	})
+}
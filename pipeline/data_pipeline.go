@@ -2,15 +2,43 @@ package pipeline
 
 import (
 	"errors"
+	"log/slog"
 
 	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/stages"
 	"github.com/spaghettifactory-oss/pipeforge/ports"
 )
 
+// Stage names used by DataPipeline when calling middleware.
+const (
+	StageLoad      = "load"
+	StageTransform = "transform"
+	StageStore     = "store"
+
+	// StageCommit names the StageError of a source failing to commit, see
+	// ports.Committer. Commits are not wrapped by middleware.
+	StageCommit = "commit"
+)
+
 type DataPipeline struct {
 	Source    ports.SourcePort
 	Transform ports.TransformPort
 	Store     ports.StorePort
+
+	// Middleware wraps the load, transform and store stages, the first
+	// being the outermost. Store stages output the stored RecordSet.
+	Middleware []ports.Middleware
+
+	// Logger receives the start, record counts, duration and errors of each
+	// stage, see pipeline.Logging. Nil disables logging.
+	Logger *slog.Logger
+}
+
+// Use appends middleware wrapping every stage and returns the pipeline for
+// chaining.
+func (s *DataPipeline) Use(middleware ...ports.Middleware) *DataPipeline {
+	s.Middleware = append(s.Middleware, middleware...)
+	return s
 }
 
 // Run executes the pipeline: Load → Transform → Store.
@@ -20,28 +48,55 @@ func (s *DataPipeline) Run() error {
 }
 
 // RunWithResult executes the pipeline and returns the final RecordSet.
+// Errors from a stage are returned as a *domain.StageError naming it.
+//
+// When the source is a ports.Committer, it is committed once the records
+// were stored, so that a failed run loads them again.
 func (s *DataPipeline) RunWithResult() (*domain.RecordSet, error) {
 	if s.Source == nil || s.Transform == nil || s.Store == nil {
 		return nil, errors.New("Empty source, transform or store")
 	}
 
+	middleware := s.Middleware
+	if s.Logger != nil {
+		middleware = append(middleware[:len(middleware):len(middleware)], stages.Logging(s.Logger))
+	}
+
+	load := stages.Wrap(StageLoad, func(*domain.RecordSet) (*domain.RecordSet, error) {
+		return s.Source.Load()
+	}, middleware...)
+	transform := stages.Wrap(StageTransform, s.Transform.Transform, middleware...)
+	store := stages.Wrap(StageStore, func(data *domain.RecordSet) (*domain.RecordSet, error) {
+		if err := s.Store.Store(data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}, middleware...)
+
 	// Load data from source
-	data, err := s.Source.Load()
+	data, err := load(nil)
 	if err != nil {
-		return nil, err
+		return nil, &domain.StageError{Stage: StageLoad, Err: err}
 	}
 
 	// Transform data
-	transformed, err := s.Transform.Transform(data)
+	transformed, err := transform(data)
 	if err != nil {
-		return nil, err
+		return nil, &domain.StageError{Stage: StageTransform, Err: err}
 	}
 
 	// Store data
-	err = s.Store.Store(transformed)
+	stored, err := store(transformed)
 	if err != nil {
-		return nil, err
+		return nil, &domain.StageError{Stage: StageStore, Err: err}
+	}
+
+	// Commit what the source loaded
+	if committer, ok := s.Source.(ports.Committer); ok {
+		if err := committer.Commit(); err != nil {
+			return nil, &domain.StageError{Stage: StageCommit, Err: err}
+		}
 	}
 
-	return transformed, nil
+	return stored, nil
 }
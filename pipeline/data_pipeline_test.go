@@ -1,13 +1,19 @@
 package pipeline
 
 import (
+	"bytes"
+	"errors"
 	"testing"
 
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
 	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
 	"github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
 	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRun(t *testing.T) {
@@ -71,6 +77,29 @@ func TestRun(t *testing.T) {
 	})
 }
 
+func TestRun_StageErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline DataPipeline
+		stage    string
+	}{
+		{"load", DataPipeline{Source: &source.ErrorSource{}, Transform: &transform.EmptyTransform{}, Store: &store.EmptyStore{}}, StageLoad},
+		{"transform", DataPipeline{Source: &source.EmptySource{}, Transform: &transform.ErrorTransform{}, Store: &store.EmptyStore{}}, StageTransform},
+		{"store", DataPipeline{Source: &source.EmptySource{}, Transform: &transform.EmptyTransform{}, Store: &store.ErrorStore{}}, StageStore},
+	}
+
+	for _, tt := range tests {
+		t.Run("should name the failing "+tt.name+" stage", func(t *testing.T) {
+			err := tt.pipeline.Run()
+
+			var stageErr *domain.StageError
+			require.True(t, errors.As(err, &stageErr))
+			assert.Equal(t, tt.stage, stageErr.Stage)
+			assert.NotNil(t, stageErr.Err)
+		})
+	}
+}
+
 func TestRunWithResult(t *testing.T) {
 	t.Run("should return RecordSet on success", func(t *testing.T) {
 		pipeline := DataPipeline{
@@ -94,3 +123,118 @@ func TestRunWithResult(t *testing.T) {
 		assert.Nil(t, result)
 	})
 }
+
+func TestRun_Commit(t *testing.T) {
+	t.Run("should commit the source after storing", func(t *testing.T) {
+		src := &source.CommitSource{}
+		pipeline := DataPipeline{Source: src, Transform: &transform.EmptyTransform{}, Store: &store.EmptyStore{}}
+
+		require.NoError(t, pipeline.Run())
+
+		assert.Equal(t, 1, src.Commits)
+	})
+
+	t.Run("should not commit the source when storing fails", func(t *testing.T) {
+		src := &source.CommitSource{}
+		pipeline := DataPipeline{Source: src, Transform: &transform.EmptyTransform{}, Store: &store.ErrorStore{}}
+
+		require.Error(t, pipeline.Run())
+
+		assert.Equal(t, 0, src.Commits)
+	})
+
+	t.Run("should name the commit stage when committing fails", func(t *testing.T) {
+		src := &source.CommitSource{Err: errors.New("state unavailable")}
+		pipeline := DataPipeline{Source: src, Transform: &transform.EmptyTransform{}, Store: &store.EmptyStore{}}
+
+		err := pipeline.Run()
+
+		assert.EqualError(t, err, "stage commit: state unavailable")
+	})
+}
+
+func TestRun_Middleware(t *testing.T) {
+	t.Run("should wrap load, transform and store stages", func(t *testing.T) {
+		var stages []string
+		pipeline := &DataPipeline{
+			Source:    &source.EmptySource{},
+			Transform: &transform.EmptyTransform{},
+			Store:     &store.EmptyStore{},
+		}
+		pipeline.Use(Hooks{
+			Before: func(e ports.StageEvent) { stages = append(stages, "before "+e.Stage) },
+			After: func(e ports.StageEvent) {
+				stages = append(stages, "after "+e.Stage)
+				assert.NotNil(t, e.Output)
+			},
+		}.Middleware())
+
+		err := pipeline.Run()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"before load", "after load",
+			"before transform", "after transform",
+			"before store", "after store",
+		}, stages)
+	})
+
+	t.Run("should report the failing stage", func(t *testing.T) {
+		var failed []string
+		pipeline := &DataPipeline{
+			Source:    &source.EmptySource{},
+			Transform: &transform.EmptyTransform{},
+			Store:     &store.ErrorStore{},
+		}
+		pipeline.Use(Hooks{
+			OnError: func(e ports.StageEvent) { failed = append(failed, e.Stage+": "+e.Err.Error()) },
+		}.Middleware())
+
+		err := pipeline.Run()
+
+		assert.EqualError(t, err, "stage store: store error")
+		assert.Equal(t, []string{"store: store error"}, failed)
+	})
+
+	t.Run("should let middleware replace stage output", func(t *testing.T) {
+		replacement := domain.NewRecordSet(nil)
+		pipeline := &DataPipeline{
+			Source:    &source.ErrorSource{},
+			Transform: &transform.EmptyTransform{},
+			Store:     &store.EmptyStore{},
+			Middleware: []ports.Middleware{
+				func(stage string, next ports.StageFunc) ports.StageFunc {
+					if stage != StageLoad {
+						return next
+					}
+					return func(*domain.RecordSet) (*domain.RecordSet, error) { return replacement, nil }
+				},
+			},
+		}
+
+		result, err := pipeline.RunWithResult()
+
+		assert.NoError(t, err)
+		assert.Same(t, replacement, result)
+	})
+}
+
+func TestRun_Logger(t *testing.T) {
+	t.Run("should log every stage", func(t *testing.T) {
+		var buf bytes.Buffer
+		pipeline := DataPipeline{
+			Source:    &source.EmptySource{},
+			Transform: &transform.EmptyTransform{},
+			Store:     &store.ErrorStore{},
+			Logger:    logger.NewTextLogger(&buf),
+		}
+
+		err := pipeline.Run()
+
+		assert.Error(t, err)
+		assert.Contains(t, buf.String(), `msg="stage finished" stage=load`)
+		assert.Contains(t, buf.String(), `msg="stage finished" stage=transform`)
+		assert.Contains(t, buf.String(), `msg="stage failed" stage=store`)
+		assert.Empty(t, pipeline.Middleware)
+	})
+}
@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/stages"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Hooks are callbacks observing stages, turned into a Middleware by
+// Middleware. Nil callbacks are skipped.
+type Hooks struct {
+	Before  func(event ports.StageEvent) // Called before the stage runs
+	After   func(event ports.StageEvent) // Called after the stage succeeds
+	OnError func(event ports.StageEvent) // Called after the stage fails, instead of After
+}
+
+// Middleware returns a Middleware calling the hooks around each stage.
+func (h Hooks) Middleware() ports.Middleware {
+	return func(stage string, next ports.StageFunc) ports.StageFunc {
+		return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			event := ports.StageEvent{Stage: stage, Input: input}
+			if h.Before != nil {
+				h.Before(event)
+			}
+
+			start := time.Now()
+			output, err := next(input)
+			event.Output, event.Duration, event.Err = output, time.Since(start), err
+
+			if err != nil {
+				if h.OnError != nil {
+					h.OnError(event)
+				}
+				return output, err
+			}
+			if h.After != nil {
+				h.After(event)
+			}
+			return output, nil
+		}
+	}
+}
+
+// Logging returns a Middleware logging each stage to logger: its start at
+// debug level, its record counts and duration at info level once it
+// finished, or its error at error level.
+func Logging(logger *slog.Logger) ports.Middleware {
+	return stages.Logging(logger)
+}
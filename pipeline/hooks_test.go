@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/stages"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_Middleware(t *testing.T) {
+	t.Run("should call before and after with the stage event", func(t *testing.T) {
+		input, output := domain.NewRecordSet(nil), domain.NewRecordSet(nil)
+		var before, after []ports.StageEvent
+		hooks := Hooks{
+			Before: func(e ports.StageEvent) { before = append(before, e) },
+			After:  func(e ports.StageEvent) { after = append(after, e) },
+			OnError: func(e ports.StageEvent) {
+				t.Fatal("OnError should not be called")
+			},
+		}
+
+		_, err := stages.Wrap("transform", func(*domain.RecordSet) (*domain.RecordSet, error) {
+			time.Sleep(time.Millisecond)
+			return output, nil
+		}, hooks.Middleware())(input)
+
+		require.NoError(t, err)
+		require.Len(t, before, 1)
+		assert.Equal(t, ports.StageEvent{Stage: "transform", Input: input}, before[0])
+		require.Len(t, after, 1)
+		assert.Equal(t, "transform", after[0].Stage)
+		assert.Same(t, input, after[0].Input)
+		assert.Same(t, output, after[0].Output)
+		assert.GreaterOrEqual(t, after[0].Duration, time.Millisecond)
+		assert.NoError(t, after[0].Err)
+	})
+
+	t.Run("should call on error instead of after", func(t *testing.T) {
+		stageErr := errors.New("boom")
+		var failed []ports.StageEvent
+		hooks := Hooks{
+			After:   func(ports.StageEvent) { t.Fatal("After should not be called") },
+			OnError: func(e ports.StageEvent) { failed = append(failed, e) },
+		}
+
+		_, err := stages.Wrap("store", func(*domain.RecordSet) (*domain.RecordSet, error) {
+			return nil, stageErr
+		}, hooks.Middleware())(nil)
+
+		assert.Same(t, stageErr, err)
+		require.Len(t, failed, 1)
+		assert.Same(t, stageErr, failed[0].Err)
+		assert.Equal(t, "store", failed[0].Stage)
+	})
+
+	t.Run("should skip nil callbacks", func(t *testing.T) {
+		_, err := stages.Wrap("load", func(*domain.RecordSet) (*domain.RecordSet, error) {
+			return nil, nil
+		}, Hooks{}.Middleware())(nil)
+
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// RunReport describes one run of a DataPipeline. It marshals to JSON with
+// durations in nanoseconds.
+type RunReport struct {
+	Start          time.Time     `json:"start"`           // When the run started
+	Duration       time.Duration `json:"duration"`        // Wall time of the whole run
+	AllocatedBytes uint64        `json:"allocated_bytes"` // Heap bytes allocated by the whole process during the run, zero unless measured, see StageReport.AllocatedBytes
+	Stages         []StageReport `json:"stages"`          // Stages in the order they started, nested stages after their parent
+	Error          string        `json:"error,omitempty"` // Error that stopped the run, empty on success
+}
+
+// StageReport describes one stage of a run.
+//
+// AllocatedBytes is only measured with ReportOptions.Allocations. It is
+// read from runtime.MemStats, which counts the whole process: it includes
+// the allocations of other goroutines, such as other pipelines run
+// concurrently by a scheduler or a server, and of nested stages.
+type StageReport struct {
+	Stage          string        `json:"stage"`           // Stage name, prefixed by "transform/" for the stages of a ports.Staged transform
+	Input          int           `json:"input"`           // Records entering the stage, zero for load stages
+	Output         int           `json:"output"`          // Records leaving the stage
+	Rejected       int           `json:"rejected"`        // Input records missing from the output, zero when the stage adds records
+	Duration       time.Duration `json:"duration"`        // Wall time of the stage
+	AllocatedBytes uint64        `json:"allocated_bytes"` // Heap bytes allocated by the whole process during the stage, zero unless measured
+	Error          string        `json:"error,omitempty"` // Error returned by the stage
+
+	// Attempts lists the calls made by the adapter of the stage when it
+	// retries failures, see ports.Retrier.
+	Attempts []ports.Attempt `json:"attempts,omitempty"`
+}
+
+// RejectedRatio returns the share of input records rejected by the stage,
+// between 0 and 1, or 0 when the stage received no records.
+func (s StageReport) RejectedRatio() float64 {
+	if s.Input == 0 {
+		return 0
+	}
+	return float64(s.Rejected) / float64(s.Input)
+}
+
+// Stage returns the report of the named stage, or nil if it did not run.
+func (r *RunReport) Stage(name string) *StageReport {
+	for i := range r.Stages {
+		if r.Stages[i].Stage == name {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// ReportOptions configures what RunWithReportOptions measures.
+type ReportOptions struct {
+	// Allocations measures AllocatedBytes. Reading them stops the world
+	// twice per stage and per run, so it is left out by default.
+	Allocations bool
+}
+
+// record returns a Middleware appending a StageReport for each stage it
+// wraps, named prefix followed by the stage, with the attempts of the
+// retriers of the stages. Reports are appended when their stage starts, so
+// that nested stages follow their parent.
+func (r *RunReport) record(prefix string, retriers map[string]ports.Retrier, opts ReportOptions) ports.Middleware {
+	return func(stage string, next ports.StageFunc) ports.StageFunc {
+		return func(input *domain.RecordSet) (*domain.RecordSet, error) {
+			report := StageReport{Stage: prefix + stage, Input: count(input)}
+			i := len(r.Stages)
+			r.Stages = append(r.Stages, report)
+			allocated := totalAlloc(opts)
+			start := time.Now()
+
+			output, err := next(input)
+
+			report.Duration = time.Since(start)
+			report.AllocatedBytes = totalAlloc(opts) - allocated
+			report.Output = count(output)
+			if err != nil {
+				report.Error = err.Error()
+			} else if report.Output < report.Input {
+				report.Rejected = report.Input - report.Output
+			}
+			if retrier, ok := retriers[stage]; ok {
+				report.Attempts = retrier.Attempts()
+			}
+			r.Stages[i] = report
+			return output, err
+		}
+	}
+}
+
+// RunWithReport executes the pipeline like RunWithResult and also returns a
+// report of the run. The report is returned even when the run fails, with
+// the stages that ran. Middleware of the pipeline wraps the measured stages.
+// Stages whose adapter is a ports.Retrier report its attempts. When the
+// transform is ports.Staged, such as a TransformBuilder, each of its stages
+// is also reported, named "transform/" followed by the stage name.
+// Allocations are not measured, see RunWithReportOptions.
+func (s *DataPipeline) RunWithReport() (*domain.RecordSet, *RunReport, error) {
+	return s.RunWithReportOptions(ReportOptions{})
+}
+
+// RunWithReportOptions executes the pipeline like RunWithReport, measuring
+// what opts enables.
+func (s *DataPipeline) RunWithReportOptions(opts ReportOptions) (*domain.RecordSet, *RunReport, error) {
+	report := &RunReport{Start: time.Now()}
+	allocated := totalAlloc(opts)
+
+	retriers := map[string]ports.Retrier{}
+	for stage, adapter := range map[string]any{StageLoad: s.Source, StageTransform: s.Transform, StageStore: s.Store} {
+		if retrier, ok := adapter.(ports.Retrier); ok {
+			retriers[stage] = retrier
+		}
+	}
+
+	run := *s
+	run.Middleware = append(append([]ports.Middleware{}, s.Middleware...), report.record("", retriers, opts))
+	if staged, ok := s.Transform.(ports.Staged); ok {
+		run.Transform = staged.WithStageMiddleware(report.record(StageTransform+"/", nil, opts))
+	}
+	result, err := run.RunWithResult()
+
+	report.Duration = time.Since(report.Start)
+	report.AllocatedBytes = totalAlloc(opts) - allocated
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return result, report, err
+}
+
+func count(rs *domain.RecordSet) int {
+	if rs == nil {
+		return 0
+	}
+	return rs.Count()
+}
+
+// totalAlloc returns the cumulative bytes allocated on the heap when opts
+// measures allocations, zero otherwise.
+func totalAlloc(opts ReportOptions) uint64 {
+	if !opts.Allocations {
+		return 0
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.TotalAlloc
+}
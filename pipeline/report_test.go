@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/internal/stages"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inStock keeps records with a positive quantity.
+type inStock struct{}
+
+func (inStock) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	return input.FilterExpr("quantity > 0")
+}
+
+// retryingSource is a ports.Retrier reporting fixed attempts.
+type retryingSource struct {
+	source.StockSource
+	attempts []ports.Attempt
+}
+
+func (s retryingSource) Attempts() []ports.Attempt { return s.attempts }
+
+// stagedTransform is a ports.Staged transform running its transforms in
+// sequence, as stages named by names.
+type stagedTransform struct {
+	names      []string
+	transforms []ports.TransformPort
+	middleware []ports.Middleware
+}
+
+func (s stagedTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	for i, t := range s.transforms {
+		var err error
+		if input, err = stages.Wrap(s.names[i], t.Transform, s.middleware...)(input); err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+func (s stagedTransform) WithStageMiddleware(middleware ...ports.Middleware) ports.TransformPort {
+	s.middleware = append(s.middleware[:len(s.middleware):len(s.middleware)], middleware...)
+	return s
+}
+
+func TestRunWithReport(t *testing.T) {
+	t.Run("should report counts of every stage", func(t *testing.T) {
+		pipeline := &DataPipeline{
+			Source:    source.StockSource{0, 3, 0, 0, 5},
+			Transform: inStock{},
+			Store:     &store.EmptyStore{},
+		}
+
+		result, report, err := pipeline.RunWithReport()
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Count())
+		require.Len(t, report.Stages, 3)
+		assert.Equal(t, StageReport{Stage: StageLoad, Output: 5}, withoutMeasures(report.Stages[0]))
+		assert.Equal(t, StageReport{Stage: StageTransform, Input: 5, Output: 2, Rejected: 3}, withoutMeasures(report.Stages[1]))
+		assert.Equal(t, StageReport{Stage: StageStore, Input: 2, Output: 2}, withoutMeasures(report.Stages[2]))
+		assert.InDelta(t, 0.6, report.Stage(StageTransform).RejectedRatio(), 1e-9)
+		assert.False(t, report.Start.IsZero())
+		assert.Positive(t, report.Duration)
+		assert.Zero(t, report.AllocatedBytes)
+		assert.Empty(t, report.Error)
+	})
+
+	t.Run("should measure allocations when enabled", func(t *testing.T) {
+		pipeline := &DataPipeline{
+			Source:    source.StockSource{0, 3, 0, 0, 5},
+			Transform: inStock{},
+			Store:     &store.EmptyStore{},
+		}
+
+		_, report, err := pipeline.RunWithReportOptions(ReportOptions{Allocations: true})
+
+		require.NoError(t, err)
+		assert.Positive(t, report.AllocatedBytes)
+		assert.Positive(t, report.Stage(StageLoad).AllocatedBytes)
+	})
+
+	t.Run("should report the stages of a staged transform after the transform stage", func(t *testing.T) {
+		staged := stagedTransform{
+			names:      []string{"in stock", "noop"},
+			transforms: []ports.TransformPort{inStock{}, &transform.EmptyTransform{}},
+		}
+		pipeline := &DataPipeline{Source: source.StockSource{0, 3, 0, 0, 5}, Transform: staged, Store: &store.EmptyStore{}}
+
+		_, report, err := pipeline.RunWithReport()
+
+		require.NoError(t, err)
+		require.Len(t, report.Stages, 5)
+		assert.Equal(t, StageReport{Stage: StageTransform, Input: 5, Output: 2, Rejected: 3}, withoutMeasures(report.Stages[1]))
+		assert.Equal(t, StageReport{Stage: "transform/in stock", Input: 5, Output: 2, Rejected: 3}, withoutMeasures(report.Stages[2]))
+		assert.Equal(t, StageReport{Stage: "transform/noop", Input: 2, Output: 2}, withoutMeasures(report.Stages[3]))
+		assert.Equal(t, StageStore, report.Stages[4].Stage)
+		assert.Empty(t, staged.middleware)
+	})
+
+	t.Run("should report the stages that ran before an error", func(t *testing.T) {
+		pipeline := &DataPipeline{
+			Source:    source.StockSource{1},
+			Transform: &transform.ErrorTransform{},
+			Store:     &store.EmptyStore{},
+		}
+
+		_, report, err := pipeline.RunWithReport()
+
+		require.Error(t, err)
+		require.NotNil(t, report)
+		require.Len(t, report.Stages, 2)
+		assert.Equal(t, "transform error", report.Stages[1].Error)
+		assert.Zero(t, report.Stages[1].Rejected)
+		assert.Equal(t, err.Error(), report.Error)
+		assert.Nil(t, report.Stage(StageStore))
+	})
+
+	t.Run("should keep pipeline middleware", func(t *testing.T) {
+		var stages []string
+		pipeline := &DataPipeline{
+			Source:    &source.EmptySource{},
+			Transform: &transform.EmptyTransform{},
+			Store:     &store.EmptyStore{},
+		}
+		pipeline.Use(Hooks{After: func(e ports.StageEvent) { stages = append(stages, e.Stage) }}.Middleware())
+
+		_, _, err := pipeline.RunWithReport()
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{StageLoad, StageTransform, StageStore}, stages)
+		assert.Len(t, pipeline.Middleware, 1)
+	})
+
+	t.Run("should report the attempts of retrying adapters", func(t *testing.T) {
+		attempts := []ports.Attempt{{Error: "connection reset", Delay: time.Second}, {}}
+		pipeline := &DataPipeline{
+			Source:    retryingSource{source.StockSource{1}, attempts},
+			Transform: &transform.EmptyTransform{},
+			Store:     &store.EmptyStore{},
+		}
+
+		_, report, err := pipeline.RunWithReport()
+
+		require.NoError(t, err)
+		assert.Equal(t, attempts, report.Stage(StageLoad).Attempts)
+		assert.Nil(t, report.Stage(StageStore).Attempts)
+	})
+
+	t.Run("should marshal to JSON", func(t *testing.T) {
+		report := RunReport{Stages: []StageReport{{Stage: StageTransform, Input: 10, Output: 1, Rejected: 9, Error: "boom"}}}
+
+		data, err := json.Marshal(report)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"start": "0001-01-01T00:00:00Z",
+			"duration": 0,
+			"allocated_bytes": 0,
+			"stages": [{"stage": "transform", "input": 10, "output": 1, "rejected": 9, "duration": 0, "allocated_bytes": 0, "error": "boom"}]
+		}`, string(data))
+	})
+}
+
+func TestStageReport_RejectedRatio(t *testing.T) {
+	t.Run("should return zero without input", func(t *testing.T) {
+		assert.Zero(t, StageReport{Stage: StageLoad, Output: 3}.RejectedRatio())
+	})
+}
+
+// withoutMeasures clears the fields that vary between runs.
+func withoutMeasures(s StageReport) StageReport {
+	s.Duration, s.AllocatedBytes = 0, 0
+	return s
+}
@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// StageFunc executes a pipeline stage. Load stages receive a nil input.
+type StageFunc func(input *domain.RecordSet) (*domain.RecordSet, error)
+
+// Middleware wraps the execution of a named stage. It may inspect or replace
+// the input and output, measure the stage, or skip calling next entirely.
+type Middleware func(stage string, next StageFunc) StageFunc
+
+// Staged is implemented by transforms running named stages of their own,
+// such as transform.TransformBuilder, so that a run can observe each of
+// them, not only the transform stage.
+type Staged interface {
+	// WithStageMiddleware returns a copy of the transform whose stages are
+	// also wrapped by middleware, inside the middleware they already have.
+	WithStageMiddleware(middleware ...Middleware) TransformPort
+}
+
+// StageEvent describes one execution of a stage.
+type StageEvent struct {
+	Stage    string            // Stage name
+	Input    *domain.RecordSet // Records entering the stage, nil for load stages
+	Output   *domain.RecordSet // Records leaving the stage, nil before it ran
+	Duration time.Duration     // Time spent in the stage, zero before it ran
+	Err      error             // Error returned by the stage
+}
@@ -0,0 +1,19 @@
+package ports
+
+import "time"
+
+// Attempt describes one call made by an adapter retrying failed calls. It
+// marshals to JSON with durations in nanoseconds.
+type Attempt struct {
+	Error    string        `json:"error,omitempty"` // Error of the call, empty if it succeeded
+	Duration time.Duration `json:"duration"`        // Wall time of the call
+	Delay    time.Duration `json:"delay,omitempty"` // Wait before the next attempt, zero for the last one
+}
+
+// Retrier is implemented by adapters retrying failed calls, such as the
+// wrappers of the retry package. DataPipeline.RunWithReport reports their
+// attempts.
+type Retrier interface {
+	// Attempts returns the attempts of the last Load, Transform or Store.
+	Attempts() []Attempt
+}
@@ -7,3 +7,12 @@ type SourcePort interface {
 	// Load reads data from the source and returns a RecordSet.
 	Load() (*domain.RecordSet, error)
 }
+
+// Fingerprinter is implemented by sources able to tell whether their data
+// changed without loading it, such as file sources.
+type Fingerprinter interface {
+	// Fingerprint returns a string that changes when the data returned by
+	// Load changes, such as the path, size and modification time of a file.
+	// An empty fingerprint means the source cannot tell.
+	Fingerprint() (string, error)
+}
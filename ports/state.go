@@ -0,0 +1,18 @@
+package ports
+
+// StateStorePort defines the interface for persisting small values between
+// runs, such as the watermark of an incremental source.
+type StateStorePort interface {
+	// Get returns the value saved under key, and false if there is none.
+	Get(key string) (string, bool, error)
+	// Set saves value under key, replacing any previous value.
+	Set(key, value string) error
+}
+
+// Committer is implemented by sources that track progress, such as
+// incremental sources. DataPipeline calls Commit once the loaded records
+// were stored, so that a failed run loads the same records again.
+type Committer interface {
+	// Commit persists the progress of the last Load.
+	Commit() error
+}
@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Source retries the loads of an inner source. Commits of incremental
+// sources, see ports.Committer, and fingerprints, see ports.Fingerprinter,
+// are forwarded and retried too.
+type Source struct {
+	Inner   ports.SourcePort // Source loaded on each attempt
+	Policy  Policy
+	Context context.Context // Interrupts the waits between attempts when done, nil for none
+	Logger  *slog.Logger    // Receives a warning for each retry, nil disables logging
+
+	attempts []ports.Attempt
+}
+
+// NewSource creates a Source retrying src with policy.
+func NewSource(src ports.SourcePort, policy Policy) *Source {
+	return &Source{Inner: src, Policy: policy}
+}
+
+// Load loads the inner source, retrying its failures.
+func (s *Source) Load() (*domain.RecordSet, error) {
+	var data *domain.RecordSet
+	var err error
+	s.attempts, err = s.Policy.do(s.Context, s.Logger, func() error {
+		var loadErr error
+		data, loadErr = s.Inner.Load()
+		return loadErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Commit commits the inner source if it is a ports.Committer.
+func (s *Source) Commit() error {
+	committer, ok := s.Inner.(ports.Committer)
+	if !ok {
+		return nil
+	}
+	_, err := s.Policy.do(s.Context, s.Logger, committer.Commit)
+	return err
+}
+
+// Fingerprint returns the fingerprint of the inner source if it is a
+// ports.Fingerprinter, or else an empty fingerprint.
+func (s *Source) Fingerprint() (string, error) {
+	fingerprinter, ok := s.Inner.(ports.Fingerprinter)
+	if !ok {
+		return "", nil
+	}
+	var fingerprint string
+	_, err := s.Policy.do(s.Context, s.Logger, func() error {
+		var fingerprintErr error
+		fingerprint, fingerprintErr = fingerprinter.Fingerprint()
+		return fingerprintErr
+	})
+	return fingerprint, err
+}
+
+// Attempts returns the attempts of the last Load.
+func (s *Source) Attempts() []ports.Attempt {
+	return s.attempts
+}
+
+// Transform retries an inner transform, for transforms calling external
+// services. Transforms must not modify their input for retries to see it
+// unchanged.
+type Transform struct {
+	Inner   ports.TransformPort // Transform run on each attempt
+	Policy  Policy
+	Context context.Context // Interrupts the waits between attempts when done, nil for none
+	Logger  *slog.Logger    // Receives a warning for each retry, nil disables logging
+
+	attempts []ports.Attempt
+}
+
+// NewTransform creates a Transform retrying t with policy.
+func NewTransform(t ports.TransformPort, policy Policy) *Transform {
+	return &Transform{Inner: t, Policy: policy}
+}
+
+// Transform runs the inner transform, retrying its failures.
+func (t *Transform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	var output *domain.RecordSet
+	var err error
+	t.attempts, err = t.Policy.do(t.Context, t.Logger, func() error {
+		var transformErr error
+		output, transformErr = t.Inner.Transform(input)
+		return transformErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Attempts returns the attempts of the last Transform.
+func (t *Transform) Attempts() []ports.Attempt {
+	return t.attempts
+}
+
+// Store retries the writes of an inner store. The inner store must replace
+// its output, or write it idempotently, for a retry after a partial write
+// not to duplicate records.
+type Store struct {
+	Inner   ports.StorePort // Store written on each attempt
+	Policy  Policy
+	Context context.Context // Interrupts the waits between attempts when done, nil for none
+	Logger  *slog.Logger    // Receives a warning for each retry, nil disables logging
+
+	attempts []ports.Attempt
+}
+
+// NewStore creates a Store retrying store with policy.
+func NewStore(store ports.StorePort, policy Policy) *Store {
+	return &Store{Inner: store, Policy: policy}
+}
+
+// Store stores data in the inner store, retrying its failures.
+func (s *Store) Store(data *domain.RecordSet) error {
+	var err error
+	s.attempts, err = s.Policy.do(s.Context, s.Logger, func() error {
+		return s.Inner.Store(data)
+	})
+	return err
+}
+
+// Attempts returns the attempts of the last Store.
+func (s *Store) Attempts() []ports.Attempt {
+	return s.attempts
+}
@@ -0,0 +1,169 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySource fails its first loads.
+type flakySource struct {
+	failures int
+	loads    int
+}
+
+func (s *flakySource) Load() (*domain.RecordSet, error) {
+	s.loads++
+	if s.loads <= s.failures {
+		return nil, Transient(errors.New("connection reset"))
+	}
+	rs := domain.NewRecordSet(nil)
+	rs.Add(domain.NewRecord(nil))
+	return rs, nil
+}
+
+// fingerprintSource fails its first fingerprints.
+type fingerprintSource struct {
+	flakySource
+	fingerprints int
+}
+
+func (s *fingerprintSource) Fingerprint() (string, error) {
+	s.fingerprints++
+	if s.fingerprints <= s.failures {
+		return "", Transient(errors.New("connection reset"))
+	}
+	return "v1", nil
+}
+
+// flakyTransform fails its first calls.
+type flakyTransform struct {
+	failures int
+	calls    int
+}
+
+func (t *flakyTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	t.calls++
+	if t.calls <= t.failures {
+		return nil, Transient(errors.New("rate limited"))
+	}
+	return input, nil
+}
+
+// flakyStore fails its first writes.
+type flakyStore struct {
+	failures int
+	writes   int
+}
+
+func (s *flakyStore) Store(*domain.RecordSet) error {
+	s.writes++
+	if s.writes <= s.failures {
+		return Transient(errors.New("disk busy"))
+	}
+	return nil
+}
+
+// invalidRecord rejects the first record.
+type invalidRecord struct{}
+
+func (invalidRecord) Transform(*domain.RecordSet) (*domain.RecordSet, error) {
+	return nil, domain.NewRecordError(0, nil, errors.New("negative quantity"))
+}
+
+func TestSource(t *testing.T) {
+	t.Run("should retry failed loads and record the attempts", func(t *testing.T) {
+		inner := &flakySource{failures: 1}
+		src := NewSource(inner, quickPolicy(3))
+
+		result, err := src.Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Count())
+		assert.Equal(t, 2, inner.loads)
+		require.Len(t, src.Attempts(), 2)
+		assert.Equal(t, "connection reset", src.Attempts()[0].Error)
+	})
+
+	t.Run("should return nil when every attempt fails", func(t *testing.T) {
+		src := NewSource(&flakySource{failures: 5}, quickPolicy(2))
+
+		result, err := src.Load()
+
+		assert.Nil(t, result)
+		assert.EqualError(t, err, "failed after 2 attempts: connection reset")
+	})
+
+	t.Run("should forward commits to incremental sources", func(t *testing.T) {
+		inner := &source.CommitSource{}
+
+		require.NoError(t, NewSource(inner, quickPolicy(1)).Commit())
+		require.NoError(t, NewSource(&flakySource{}, quickPolicy(1)).Commit())
+
+		assert.Equal(t, 1, inner.Commits)
+	})
+
+	t.Run("should forward and retry fingerprints", func(t *testing.T) {
+		inner := &fingerprintSource{flakySource: flakySource{failures: 1}}
+
+		fingerprint, err := NewSource(inner, quickPolicy(2)).Fingerprint()
+		require.NoError(t, err)
+		none, err := NewSource(&flakySource{}, quickPolicy(1)).Fingerprint()
+		require.NoError(t, err)
+
+		assert.Equal(t, "v1", fingerprint)
+		assert.Equal(t, 2, inner.fingerprints)
+		assert.Empty(t, none)
+	})
+}
+
+func TestTransform(t *testing.T) {
+	t.Run("should retry failed transforms with the same input", func(t *testing.T) {
+		inner := &flakyTransform{failures: 2}
+		input := domain.NewRecordSet(nil)
+		tr := NewTransform(inner, quickPolicy(3))
+
+		result, err := tr.Transform(input)
+
+		require.NoError(t, err)
+		assert.Same(t, input, result)
+		assert.Len(t, tr.Attempts(), 3)
+	})
+
+	t.Run("should not retry record errors", func(t *testing.T) {
+		tr := NewTransform(invalidRecord{}, quickPolicy(3))
+
+		_, err := tr.Transform(domain.NewRecordSet(nil))
+
+		assert.EqualError(t, err, "record 0: negative quantity")
+		assert.Len(t, tr.Attempts(), 1)
+	})
+
+	t.Run("should not retry plain errors", func(t *testing.T) {
+		tr := NewTransform(&transform.ErrorTransform{}, quickPolicy(2))
+
+		_, err := tr.Transform(domain.NewRecordSet(nil))
+
+		assert.Error(t, err)
+		assert.Len(t, tr.Attempts(), 1)
+	})
+}
+
+func TestStore(t *testing.T) {
+	t.Run("should retry failed writes", func(t *testing.T) {
+		inner := &flakyStore{failures: 2}
+		st := NewStore(inner, quickPolicy(3))
+
+		err := st.Store(domain.NewRecordSet(nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, inner.writes)
+		assert.Len(t, st.Attempts(), 3)
+	})
+}
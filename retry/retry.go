@@ -0,0 +1,203 @@
+// Package retry retries the transient failures of sources, transforms and
+// stores with exponential backoff and jitter.
+//
+// Source, Transform and Store wrap an adapter and call it again, up to
+// Policy.MaxAttempts times, while it fails with a retryable error:
+//
+//	p := &pipeline.DataPipeline{
+//		Source:    retry.NewSource(src, retry.DefaultPolicy()),
+//		Transform: chain,
+//		Store:     retry.NewStore(dst, retry.DefaultPolicy()),
+//	}
+//
+// Errors decide whether they are retryable by implementing RetryableError;
+// see IsRetryable for the other errors.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Policy configures how failed calls are retried. The wait after the n-th
+// failed attempt is InitialDelay * Multiplier^(n-1), bounded by MaxDelay,
+// then reduced by a random share of up to Jitter.
+type Policy struct {
+	MaxAttempts  int              // Calls made at most, the first one included; below 1 means 1
+	InitialDelay time.Duration    // Wait after the first failed attempt
+	MaxDelay     time.Duration    // Upper bound of the waits, zero for none
+	Multiplier   float64          // Growth of the wait after each failed attempt, 1 if below 1
+	Jitter       float64          // Share of each wait picked at random, between 0 and 1, spreading the retries of concurrent runs
+	Retryable    func(error) bool // Decides which errors are retried, IsRetryable if nil
+}
+
+// DefaultPolicy returns a Policy making 3 attempts, waiting up to 100ms
+// then 200ms, with 20% jitter.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:  3,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+// Delay returns the wait after the given failed attempt, counted from 1.
+func (p Policy) Delay(attempt int) time.Duration {
+	multiplier := max(p.Multiplier, 1)
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(max(attempt, 1)-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		delay -= delay * jitter * rand.Float64()
+	}
+	if delay >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// Do calls fn until it succeeds, fails with an error that is not
+// retryable, or MaxAttempts calls were made, waiting between attempts. It
+// returns the attempts made and the last error, as an *Error when fn was
+// called more than once. A cancelled ctx interrupts the wait and is
+// returned along with the last error.
+func (p Policy) Do(ctx context.Context, fn func() error) ([]ports.Attempt, error) {
+	return p.do(ctx, nil, fn)
+}
+
+func (p Policy) do(ctx context.Context, logger *slog.Logger, fn func() error) ([]ports.Attempt, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	var attempts []ports.Attempt
+	for n := 1; ; n++ {
+		start := time.Now()
+		err := fn()
+		attempt := ports.Attempt{Duration: time.Since(start)}
+		if err == nil {
+			return append(attempts, attempt), nil
+		}
+		attempt.Error = err.Error()
+
+		if n >= p.MaxAttempts || !retryable(err) {
+			attempts = append(attempts, attempt)
+			if n > 1 {
+				return attempts, &Error{Attempts: n, Err: err}
+			}
+			return attempts, err
+		}
+
+		attempt.Delay = p.Delay(n)
+		attempts = append(attempts, attempt)
+		logging.Or(logger).Warn("retrying", "attempt", n, "delay", attempt.Delay, "error", err)
+		if waitErr := wait(ctx, attempt.Delay); waitErr != nil {
+			return attempts, fmt.Errorf("%w: %w", waitErr, err)
+		}
+	}
+}
+
+// wait sleeps for d, or until ctx is done.
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Error is returned when a call still fails after several attempts.
+type Error struct {
+	Attempts int   // Calls made
+	Err      error // Error of the last call
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RetryableError is implemented by errors knowing whether the call that
+// failed with them may succeed if made again.
+type RetryableError interface {
+	error
+	Retryable() bool
+}
+
+// IsRetryable reports whether a call failing with err may succeed if made
+// again. The first error of the chain implementing RetryableError decides,
+// as for the errors of Transient, Permanent and the StatusError of HTTP
+// sources. Otherwise only network errors and timeouts are retryable:
+// cancellations, deadlines of the caller's context, the record and schema
+// errors of the domain package and any other error, such as a missing
+// file or invalid JSON, which retrying cannot fix, are not.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var retryable RetryableError
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	var recordErr *domain.RecordError
+	var schemaErr *domain.SchemaError
+	if errors.Is(err, context.Canceled) || errors.As(err, &recordErr) || errors.As(err, &schemaErr) {
+		return false
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() && any(timeout) != any(context.DeadlineExceeded) {
+		return true
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		return !dnsErr.IsNotFound
+	case errors.As(err, &opErr):
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+// Permanent marks err as not retryable.
+func Permanent(err error) error {
+	return &classified{err: err, retryable: false}
+}
+
+// Transient marks err as retryable.
+func Transient(err error) error {
+	return &classified{err: err, retryable: true}
+}
+
+type classified struct {
+	err       error
+	retryable bool
+}
+
+func (e *classified) Error() string   { return e.err.Error() }
+func (e *classified) Unwrap() error   { return e.err }
+func (e *classified) Retryable() bool { return e.retryable }
@@ -0,0 +1,182 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// quickPolicy retries without waiting noticeably.
+func quickPolicy(attempts int) Policy {
+	return Policy{MaxAttempts: attempts, InitialDelay: time.Microsecond, Multiplier: 2}
+}
+
+// failing returns a function failing with the given errors, then
+// succeeding, and the number of calls made.
+func failing(errs ...error) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return nil
+	}, &calls
+}
+
+func TestPolicy_Do(t *testing.T) {
+	t.Run("should retry until the call succeeds", func(t *testing.T) {
+		fn, calls := failing(Transient(errors.New("timeout")), Transient(errors.New("timeout")))
+
+		attempts, err := quickPolicy(3).Do(context.Background(), fn)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, *calls)
+		require.Len(t, attempts, 3)
+		assert.Equal(t, "timeout", attempts[0].Error)
+		assert.Equal(t, time.Microsecond, attempts[0].Delay)
+		assert.Equal(t, 2*time.Microsecond, attempts[1].Delay)
+		assert.Empty(t, attempts[2].Error)
+		assert.Zero(t, attempts[2].Delay)
+	})
+
+	t.Run("should return the last error once attempts are exhausted", func(t *testing.T) {
+		cause := Transient(errors.New("connection reset"))
+		fn, calls := failing(Transient(errors.New("timeout")), cause, cause)
+
+		attempts, err := quickPolicy(2).Do(context.Background(), fn)
+
+		assert.Equal(t, 2, *calls)
+		assert.Len(t, attempts, 2)
+		assert.EqualError(t, err, "failed after 2 attempts: connection reset")
+		assert.ErrorIs(t, err, cause)
+		var retryErr *Error
+		require.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 2, retryErr.Attempts)
+	})
+
+	t.Run("should not retry errors that are not retryable", func(t *testing.T) {
+		cause := Permanent(errors.New("bad credentials"))
+		fn, calls := failing(cause)
+
+		_, err := quickPolicy(3).Do(context.Background(), fn)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, cause, err)
+	})
+
+	t.Run("should use the policy classification", func(t *testing.T) {
+		fn, calls := failing(Transient(errors.New("timeout")))
+		policy := quickPolicy(3)
+		policy.Retryable = func(error) bool { return false }
+
+		_, err := policy.Do(context.Background(), fn)
+
+		assert.Equal(t, 1, *calls)
+		assert.EqualError(t, err, "timeout")
+	})
+
+	t.Run("should make one attempt when MaxAttempts is not set", func(t *testing.T) {
+		fn, calls := failing(errors.New("timeout"))
+
+		_, err := Policy{}.Do(context.Background(), fn)
+
+		assert.Equal(t, 1, *calls)
+		assert.EqualError(t, err, "timeout")
+	})
+
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cause := Transient(errors.New("timeout"))
+		calls := 0
+		policy := Policy{MaxAttempts: 3, InitialDelay: time.Hour}
+
+		_, err := policy.Do(ctx, func() error {
+			calls++
+			cancel()
+			return cause
+		})
+
+		assert.Equal(t, 1, calls)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, err, cause)
+	})
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	t.Run("should grow exponentially up to MaxDelay", func(t *testing.T) {
+		policy := Policy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 3}
+
+		assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+		assert.Equal(t, 300*time.Millisecond, policy.Delay(2))
+		assert.Equal(t, 900*time.Millisecond, policy.Delay(3))
+		assert.Equal(t, time.Second, policy.Delay(4))
+		assert.Equal(t, time.Second, policy.Delay(1000))
+	})
+
+	t.Run("should reduce delays by up to Jitter", func(t *testing.T) {
+		policy := Policy{InitialDelay: time.Second, Multiplier: 1, Jitter: 0.5}
+
+		for range 100 {
+			delay := policy.Delay(1)
+			assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+			assert.LessOrEqual(t, delay, time.Second)
+		}
+	})
+
+	t.Run("should not overflow without MaxDelay", func(t *testing.T) {
+		policy := Policy{InitialDelay: time.Second, Multiplier: 10}
+
+		assert.Positive(t, policy.Delay(100))
+	})
+}
+
+// throttled is a RetryableError.
+type throttled struct{ retryable bool }
+
+func (e throttled) Error() string   { return "throttled" }
+func (e throttled) Retryable() bool { return e.retryable }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain errors", errors.New("timeout"), false},
+		{"missing files", fmt.Errorf("failed to open file: %w", fs.ErrNotExist), false},
+		{"permission errors", &fs.PathError{Op: "open", Path: "data.json", Err: fs.ErrPermission}, false},
+		{"syntax errors", &json.SyntaxError{Offset: 3}, false},
+		{"network errors", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"connection resets", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"unknown hosts", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Name: "example.invalid", IsNotFound: true}}, false},
+		{"timeouts", &url.Error{Op: "Get", URL: "http://example.com", Err: os.ErrDeadlineExceeded}, true},
+		{"errors implementing RetryableError", fmt.Errorf("load: %w", throttled{retryable: false}), false},
+		{"transient errors", Transient(context.DeadlineExceeded), true},
+		{"permanent errors", Permanent(errors.New("timeout")), false},
+		{"cancellations", fmt.Errorf("load: %w", context.Canceled), false},
+		{"deadlines", context.DeadlineExceeded, false},
+		{"record errors", domain.NewRecordError(0, nil, errors.New("bad value")), false},
+		{"schema errors", &domain.SchemaError{Column: "price", Err: errors.New("bad value")}, false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run("should classify "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
@@ -2,7 +2,8 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"sort"
 
 	mockstore "github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
@@ -27,12 +28,14 @@ func main() {
 		Source:    source.NewJSONSource("samples/logs/count_by_hour/logs.json", logSchema),
 		Transform: &CountByHourTransform{},
 		Store:     &mockstore.EmptyStore{},
+		Logger:    slog.Default(),
 	}
 
 	// Run the pipeline
 	result, err := p.RunWithResult()
 	if err != nil {
-		log.Fatalf("Pipeline failed: %v", err)
+		slog.Error("pipeline failed", "error", err)
+		os.Exit(1)
 	}
 
 	// Sort hours for display
@@ -2,7 +2,8 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/spaghettifactory-oss/pipeforge/adapters/source"
@@ -26,7 +27,8 @@ func main() {
 	// Create timezone transform (UTC -> Paris)
 	tzTransform, err := NewTimezoneTransform("timestamp", "Europe/Paris")
 	if err != nil {
-		log.Fatalf("Failed to create transform: %v", err)
+		slog.Error("failed to create transform", "error", err)
+		os.Exit(1)
 	}
 
 	// Create the pipeline
@@ -35,13 +37,15 @@ func main() {
 		Transform: transform.NewTransformBuilder().
 			Add(tzTransform).
 			Build(),
-		Store: store.NewJSONStore("samples/logs/utc_to_paris/logs_paris.json"),
+		Store:  store.NewJSONStore("samples/logs/utc_to_paris/logs_paris.json"),
+		Logger: slog.Default(),
 	}
 
 	// Run the pipeline
 	result, err := p.RunWithResult()
 	if err != nil {
-		log.Fatalf("Pipeline failed: %v", err)
+		slog.Error("pipeline failed", "error", err)
+		os.Exit(1)
 	}
 
 	// Display results
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// InStockTransform keeps only records with a positive stock using Filter.
+type InStockTransform struct {
+	FieldID string
+}
+
+// NewInStockTransform creates a new InStockTransform.
+func NewInStockTransform(fieldID string) *InStockTransform {
+	return &InStockTransform{FieldID: fieldID}
+}
+
+// Transform drops records whose stock is zero or negative.
+func (t *InStockTransform) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	return input.Filter(func(r *domain.Record) bool {
+		return r.GetInt(t.FieldID) > 0
+	}), nil
+}
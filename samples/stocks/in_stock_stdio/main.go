@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/source"
+	"github.com/spaghettifactory-oss/pipeforge/adapters/store"
+	"github.com/spaghettifactory-oss/pipeforge/adapters/transform"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+)
+
+func main() {
+	// Define the product schema
+	schema := &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeInt},
+		},
+	}
+
+	// Create the pipeline: Read stdin -> Keep products in stock -> Write stdout
+	p := pipeline.DataPipeline{
+		Source: source.NewJSONSourceFromReader(os.Stdin, schema),
+		Transform: transform.NewTransformBuilder().
+			Add(NewInStockTransform("stock")).
+			Build(),
+		Store:  store.NewJSONStoreToWriter(os.Stdout),
+		Logger: slog.Default(),
+	}
+
+	// Run the pipeline
+	if err := p.Run(); err != nil {
+		slog.Error("pipeline failed", "error", err)
+		os.Exit(1)
+	}
+}
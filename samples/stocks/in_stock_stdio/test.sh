@@ -0,0 +1,33 @@
+#!/bin/bash
+set -e
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+PROJECT_ROOT="$(cd "$SCRIPT_DIR/../../.." && pwd)"
+
+cd "$PROJECT_ROOT"
+
+# Run the pipeline, piping compressed input through stdin
+echo "Running in-stock pipeline over stdin/stdout..."
+OUTPUT=$(gzip -c "$SCRIPT_DIR/products.json" | go run "$SCRIPT_DIR"/*.go)
+
+echo "$OUTPUT"
+echo ""
+
+# Verify content
+echo "Verifying output..."
+
+# Check product count (only products in stock kept)
+COUNT=$(echo "$OUTPUT" | jq 'length')
+if [[ "$COUNT" != "2" ]]; then
+    echo "FAIL: Expected 2 products, got $COUNT"
+    exit 1
+fi
+
+# Check kept products
+NAMES=$(echo "$OUTPUT" | jq -r '[.[].name] | join(",")')
+if [[ "$NAMES" != "Laptop,Keyboard" ]]; then
+    echo "FAIL: Expected Laptop,Keyboard, got $NAMES"
+    exit 1
+fi
+
+echo "PASS: All validations successful"
@@ -2,7 +2,8 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 
 	"github.com/spaghettifactory-oss/pipeforge/adapters/source"
 	"github.com/spaghettifactory-oss/pipeforge/adapters/store"
@@ -36,13 +37,15 @@ func main() {
 		Transform: transform.NewTransformBuilder().
 			Add(NewMultiplyStockTransform(3)).
 			Build(),
-		Store: store.NewJSONStore("samples/stocks/inflation_complex_object/store_inflated.json"),
+		Store:  store.NewJSONStore("samples/stocks/inflation_complex_object/store_inflated.json"),
+		Logger: slog.Default(),
 	}
 
 	// Run the pipeline
 	result, err := p.RunWithResult()
 	if err != nil {
-		log.Fatalf("Pipeline failed: %v", err)
+		slog.Error("pipeline failed", "error", err)
+		os.Exit(1)
 	}
 
 	// Display results
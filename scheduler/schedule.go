@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the run times of a job.
+type Schedule interface {
+	// Next returns the first run time strictly after t, or the zero time
+	// when there is none.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a schedule specification: a cron expression, see ParseCron,
+// or "@every" followed by a time.Duration, such as "@every 90s".
+func Parse(spec string) (Schedule, error) {
+	if rest, ok := strings.CutPrefix(strings.TrimSpace(spec), "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be positive", spec)
+		}
+		return Every(d), nil
+	}
+	return ParseCron(spec)
+}
+
+// Every returns a Schedule running every d. It panics if d is not positive.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("scheduler: non-positive interval for Every")
+	}
+	return interval(d)
+}
+
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+func (i interval) String() string {
+	return "@every " + time.Duration(i).String()
+}
+
+// Cron is a Schedule parsed from a cron expression. Run times are computed
+// in the location of the times given to Next; local times skipped by a
+// daylight saving change do not run.
+type Cron struct {
+	expr                         string
+	minute, hour, dom, month     uint64 // Bit sets of the allowed values
+	dow                          uint64 // Bit set of the allowed weekdays, Sunday being 0
+	domRestricted, dowRestricted bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+	dayNames   = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+)
+
+// ParseCron parses a standard five-field cron expression: minute, hour,
+// day of month, month and day of week. Fields accept "*", values, ranges
+// ("1-5"), steps ("*/15", "0-30/10") and comma-separated lists; months and
+// days of week also accept English abbreviations ("JAN", "MON"), and 7 is
+// Sunday like 0. As in cron, when neither day field starts with "*", a day
+// matching either of them runs. The macros @yearly, @annually, @monthly,
+// @weekly, @daily, @midnight and @hourly are accepted too.
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &Cron{expr: expr}
+	var err error
+	parsers := []struct {
+		name     string
+		min, max int
+		names    map[string]int
+		set      *uint64
+	}{
+		{"minute", 0, 59, nil, &c.minute},
+		{"hour", 0, 23, nil, &c.hour},
+		{"day of month", 1, 31, nil, &c.dom},
+		{"month", 1, 12, monthNames, &c.month},
+		{"day of week", 0, 7, dayNames, &c.dow},
+	}
+	for i, p := range parsers {
+		if *p.set, err = parseField(fields[i], p.min, p.max, p.names); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %s: %w", expr, p.name, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domRestricted = !strings.HasPrefix(fields[2], "*")
+	c.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseField returns the bit set of the values a field allows.
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(first, min, max, names); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if hi, err = parseValue(last, min, max, names); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("invalid range %q", rng)
+				}
+			case !hasStep:
+				hi = lo
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
+
+// Next returns the first minute strictly after t matching the expression,
+// or the zero time when none matches within five years, as for "0 0 30 2 *".
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(c.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(c.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(c.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay applies the day of month and day of week fields, either being
+// enough when both are restricted.
+func (c *Cron) matchDay(t time.Time) bool {
+	dom, dow := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// String returns the expression the Cron was parsed from.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
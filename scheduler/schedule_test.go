@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	// 2024-01-01 is a Monday.
+	from := time.Date(2024, 1, 1, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"5 * * * *", time.Date(2024, 1, 1, 11, 5, 0, 0, time.UTC)},
+		{"0 6,18 * * *", time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2024, 1, 1, 13, 30, 0, 0, time.UTC)},
+		{"0 0 * * SAT", time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 mar *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * FRI", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */10 * MON", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run("should schedule "+tt.expr, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, cron.Next(from))
+		})
+	}
+
+	t.Run("should return the zero time when no day matches", func(t *testing.T) {
+		cron, err := ParseCron("0 0 30 2 *")
+		require.NoError(t, err)
+
+		assert.True(t, cron.Next(from).IsZero())
+	})
+
+	t.Run("should skip run times missing on daylight saving days", func(t *testing.T) {
+		paris, err := time.LoadLocation("Europe/Paris")
+		require.NoError(t, err)
+		cron, err := ParseCron("30 2 * * *")
+		require.NoError(t, err)
+
+		// Clocks skip from 2:00 to 3:00 on 2024-03-31 in Paris.
+		next := cron.Next(time.Date(2024, 3, 30, 12, 0, 0, 0, paris))
+
+		assert.Equal(t, time.Date(2024, 4, 1, 2, 30, 0, 0, paris), next)
+	})
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"* * * *", `invalid cron expression "* * * *": expected 5 fields, got 4`},
+		{"60 * * * *", `invalid cron expression "60 * * * *": minute: value 60 out of range 0-59`},
+		{"* * 0 * *", `invalid cron expression "* * 0 * *": day of month: value 0 out of range 1-31`},
+		{"* * * * MONDAY", `invalid cron expression "* * * * MONDAY": day of week: invalid value "MONDAY"`},
+		{"*/0 * * * *", `invalid cron expression "*/0 * * * *": minute: invalid step "0"`},
+		{"* 5-1 * * *", `invalid cron expression "* 5-1 * * *": hour: invalid range "5-1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run("should reject "+tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr)
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("should parse intervals", func(t *testing.T) {
+		schedule, err := Parse("@every 90s")
+		require.NoError(t, err)
+
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, from.Add(90*time.Second), schedule.Next(from))
+	})
+
+	t.Run("should parse cron expressions", func(t *testing.T) {
+		schedule, err := Parse("0 6 * * MON-FRI")
+		require.NoError(t, err)
+
+		assert.IsType(t, &Cron{}, schedule)
+	})
+
+	t.Run("should reject invalid intervals", func(t *testing.T) {
+		_, err := Parse("@every -1m")
+
+		assert.EqualError(t, err, `invalid schedule "@every -1m": interval must be positive`)
+	})
+}
@@ -0,0 +1,311 @@
+// Package scheduler runs pipelines on cron expressions or intervals from a
+// single process.
+//
+// Each job runs at most once at a time: a run due while the previous one is
+// still going is skipped. Runs missed because the process fell behind, such
+// as after a suspend, are handled by the job's MissedRunPolicy:
+//
+//	s := scheduler.New()
+//	s.Add(scheduler.Job{Name: "orders", Pipeline: orders, Schedule: scheduler.Every(5 * time.Minute)})
+//	s.Schedule("reports", "0 6 * * MON-FRI", reports)
+//	s.Run(ctx) // until ctx is cancelled
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+)
+
+// Clock tells the time and waits. Tests inject a fake clock to control
+// when jobs run.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for d, or returns ctx.Err() as soon as ctx is done.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock is the Clock of the operating system.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MissedRunPolicy decides what a job does when the scheduler wakes up after
+// several of its run times have passed.
+type MissedRunPolicy int
+
+const (
+	// MissedRunOnce runs the job once for all the missed run times.
+	MissedRunOnce MissedRunPolicy = iota
+	// MissedSkip skips the missed runs and waits for the next run time.
+	MissedSkip
+)
+
+// Job is a pipeline registered with its schedule.
+type Job struct {
+	Name     string                 // Unique name of the job
+	Pipeline *pipeline.DataPipeline // Pipeline run, by one run at a time
+	Schedule Schedule               // Run times of the pipeline
+	Jitter   time.Duration          // Random delay of up to Jitter added to each run time, spreading jobs scheduled together
+	Missed   MissedRunPolicy        // What to do with missed run times, running once by default
+}
+
+// Status describes the runs of a job. It marshals to JSON with durations in
+// nanoseconds.
+type Status struct {
+	Name         string              `json:"name"`
+	Running      bool                `json:"running"`               // Whether a run is in progress
+	Next         time.Time           `json:"next"`                  // Next run time, before jitter; zero when there is none
+	LastStart    time.Time           `json:"last_start"`            // Start of the last run, zero before the first one
+	LastDuration time.Duration       `json:"last_duration"`         // Wall time of the last finished run
+	LastError    string              `json:"last_error,omitempty"`  // Error of the last finished run, empty on success
+	LastReport   *pipeline.RunReport `json:"last_report,omitempty"` // Report of the last finished run
+	Runs         int                 `json:"runs"`                  // Runs finished
+	Failures     int                 `json:"failures"`              // Runs finished with an error
+	Overlaps     int                 `json:"overlaps"`              // Runs skipped because the previous one was still going
+	Missed       int                 `json:"missed"`                // Times the job fell behind its schedule
+}
+
+type job struct {
+	Job
+	status Status
+}
+
+// Scheduler runs registered jobs on their schedules. It is safe for
+// concurrent use.
+type Scheduler struct {
+	Clock  Clock        // Time source, nil for the system clock
+	Logger *slog.Logger // Receives runs, skips and failures, nil disables logging
+
+	mu      sync.Mutex
+	jobs    []*job
+	running bool
+	ctx     context.Context // Context of Run, nil when jobs must not start
+	wg      sync.WaitGroup
+}
+
+// New creates a Scheduler without jobs, using the system clock.
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job. Jobs added while Run is running start immediately.
+func (s *Scheduler) Add(j Job) error {
+	if j.Name == "" || j.Pipeline == nil || j.Schedule == nil {
+		return errors.New("job requires a name, a pipeline and a schedule")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.jobs {
+		if existing.Name == j.Name {
+			return fmt.Errorf("job %s already registered", j.Name)
+		}
+	}
+	added := &job{Job: j, status: Status{Name: j.Name}}
+	s.jobs = append(s.jobs, added)
+	if s.ctx != nil {
+		s.start(s.ctx, added)
+	}
+	return nil
+}
+
+// Schedule registers p under name with a specification accepted by Parse.
+func (s *Scheduler) Schedule(name, spec string, p *pipeline.DataPipeline) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return s.Add(Job{Name: name, Pipeline: p, Schedule: schedule})
+}
+
+// Run runs the jobs until ctx is done, then waits for the runs in progress
+// to finish before returning. Pipelines are not interrupted.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("scheduler already running")
+	}
+	s.running, s.ctx = true, ctx
+	for _, j := range s.jobs {
+		s.start(ctx, j)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.ctx = nil
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+	return nil
+}
+
+// Status returns the status of the named job.
+func (s *Scheduler) Status(name string) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j.status, true
+		}
+	}
+	return Status{}, false
+}
+
+// Statuses returns the status of every job, in registration order.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status)
+	}
+	return statuses
+}
+
+// start starts the loop of a job. s.mu must be held.
+func (s *Scheduler) start(ctx context.Context, j *job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx, j)
+	}()
+}
+
+// loop waits for each run time of a job and runs it, until ctx is done.
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	clock := s.clock()
+	logger := logging.Or(s.Logger).With("job", j.Name)
+	next := j.Schedule.Next(clock.Now())
+
+	for {
+		s.update(j, func(status *Status) { status.Next = next })
+		if next.IsZero() {
+			logger.Warn("no next run time, job stopped")
+			return
+		}
+
+		delay := next.Sub(clock.Now())
+		if j.Jitter > 0 {
+			delay += rand.N(j.Jitter)
+		}
+		if err := clock.Sleep(ctx, delay); err != nil {
+			return
+		}
+
+		now := clock.Now()
+		following := j.Schedule.Next(next)
+		missed := !following.IsZero() && !following.After(now)
+		if missed {
+			following = j.Schedule.Next(now)
+			s.update(j, func(status *Status) { status.Missed++ })
+			logger.Warn("missed runs", "since", next, "policy", j.Missed.String())
+		}
+		if !missed || j.Missed == MissedRunOnce {
+			s.run(j, logger, now)
+		}
+		next = following
+	}
+}
+
+// run starts a run of the job unless one is in progress.
+func (s *Scheduler) run(j *job, logger *slog.Logger, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.status.Running {
+		j.status.Overlaps++
+		logger.Warn("skipped run, previous run still in progress")
+		return
+	}
+	j.status.Running = true
+	j.status.LastStart = now
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		logger.Info("run started")
+		report, err := runPipeline(j.Pipeline, logger)
+
+		s.update(j, func(status *Status) {
+			status.Running = false
+			status.Runs++
+			status.LastDuration = report.Duration
+			status.LastReport = report
+			status.LastError = ""
+			if err != nil {
+				status.Failures++
+				status.LastError = err.Error()
+			}
+		})
+		if err != nil {
+			logger.Error("run failed", "duration", report.Duration, "error", err)
+			return
+		}
+		logger.Info("run finished", "duration", report.Duration)
+	}()
+}
+
+// runPipeline runs p and returns its report. A panic is recovered and
+// returned as the run error, so that a failing job cannot stop the others.
+func runPipeline(p *pipeline.DataPipeline, logger *slog.Logger) (report *pipeline.RunReport, err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("run panicked: %v", r)
+			report = &pipeline.RunReport{Start: start, Duration: time.Since(start), Error: err.Error()}
+			logger.Error("run panicked", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	_, report, err = p.RunWithReport()
+	return report, err
+}
+
+func (s *Scheduler) update(j *job, change func(*Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(&j.status)
+}
+
+func (s *Scheduler) clock() Clock {
+	if s.Clock != nil {
+		return s.Clock
+	}
+	return SystemClock
+}
+
+func (p MissedRunPolicy) String() string {
+	switch p {
+	case MissedRunOnce:
+		return "run once"
+	case MissedSkip:
+		return "skip"
+	}
+	return fmt.Sprintf("MissedRunPolicy(%d)", int(p))
+}
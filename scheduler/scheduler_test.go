@@ -0,0 +1,298 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/clock"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var midnight = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// signalStore sends on stored for each store, then waits for gate when
+// set, and fails with err when set.
+type signalStore struct {
+	stored chan struct{}
+	gate   chan struct{}
+	err    error
+}
+
+func (s *signalStore) Store(*domain.RecordSet) error {
+	s.stored <- struct{}{}
+	if s.gate != nil {
+		<-s.gate
+	}
+	return s.err
+}
+
+// panicTransform panics on every call.
+type panicTransform struct{}
+
+func (panicTransform) Transform(*domain.RecordSet) (*domain.RecordSet, error) {
+	panic("boom")
+}
+
+func newPipeline() (*pipeline.DataPipeline, *signalStore) {
+	store := &signalStore{stored: make(chan struct{}, 10)}
+	return &pipeline.DataPipeline{Source: &source.EmptySource{}, Transform: &transform.EmptyTransform{}, Store: store}, store
+}
+
+// start runs s in the background and returns a function stopping it.
+func start(t *testing.T, s *Scheduler) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	return func() {
+		cancel()
+		require.NoError(t, <-done)
+	}
+}
+
+func waitRunning(t *testing.T, s *Scheduler) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.running
+	}, time.Second, time.Millisecond)
+}
+
+func status(t *testing.T, s *Scheduler, name string) Status {
+	t.Helper()
+	st, ok := s.Status(name)
+	require.True(t, ok)
+	return st
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("should run pipelines on their schedule", func(t *testing.T) {
+		clk := clock.New(midnight)
+		p, store := newPipeline()
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		assert.Equal(t, midnight.Add(time.Hour), status(t, s, "orders").Next)
+		clk.Advance(time.Hour)
+		<-store.stored
+		clk.BlockUntil(1)
+		stop()
+
+		st := status(t, s, "orders")
+		assert.Equal(t, 1, st.Runs)
+		assert.False(t, st.Running)
+		assert.Equal(t, midnight.Add(time.Hour), st.LastStart)
+		assert.Equal(t, midnight.Add(2*time.Hour), st.Next)
+		require.NotNil(t, st.LastReport)
+		assert.Len(t, st.LastReport.Stages, 3)
+	})
+
+	t.Run("should schedule cron expressions", func(t *testing.T) {
+		clk := clock.New(midnight.Add(5 * time.Minute))
+		p, store := newPipeline()
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Schedule("orders", "*/15 * * * *", p))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		clk.Advance(10 * time.Minute)
+		<-store.stored
+		clk.BlockUntil(1)
+		stop()
+
+		assert.Equal(t, midnight.Add(30*time.Minute), status(t, s, "orders").Next)
+	})
+
+	t.Run("should skip runs while the previous one is in progress", func(t *testing.T) {
+		clk := clock.New(midnight)
+		p, store := newPipeline()
+		store.gate = make(chan struct{})
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Hour)
+		<-store.stored
+		clk.BlockUntil(1)
+		clk.Advance(time.Hour)
+		clk.BlockUntil(1)
+
+		st := status(t, s, "orders")
+		assert.True(t, st.Running)
+		assert.Equal(t, 1, st.Overlaps)
+		close(store.gate)
+		stop()
+		assert.Equal(t, 1, status(t, s, "orders").Runs)
+		assert.Empty(t, store.stored)
+	})
+
+	t.Run("should run once for missed run times", func(t *testing.T) {
+		clk := clock.New(midnight)
+		p, store := newPipeline()
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		clk.Advance(3 * time.Hour)
+		<-store.stored
+		clk.BlockUntil(1)
+		stop()
+
+		st := status(t, s, "orders")
+		assert.Equal(t, 1, st.Runs)
+		assert.Equal(t, 1, st.Missed)
+		assert.Equal(t, midnight.Add(4*time.Hour), st.Next)
+	})
+
+	t.Run("should skip missed run times with MissedSkip", func(t *testing.T) {
+		clk := clock.New(midnight)
+		p, store := newPipeline()
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour), Missed: MissedSkip}))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		clk.Advance(3 * time.Hour)
+		clk.BlockUntil(1)
+		stop()
+
+		st := status(t, s, "orders")
+		assert.Zero(t, st.Runs)
+		assert.Equal(t, 1, st.Missed)
+		assert.Equal(t, midnight.Add(4*time.Hour), st.Next)
+		assert.Empty(t, store.stored)
+	})
+
+	t.Run("should delay runs by up to Jitter", func(t *testing.T) {
+		clk := clock.New(midnight)
+		p, store := newPipeline()
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour), Jitter: 10 * time.Minute}))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Hour + 10*time.Minute)
+		<-store.stored
+		clk.BlockUntil(1)
+		stop()
+
+		st := status(t, s, "orders")
+		assert.Equal(t, 1, st.Runs)
+		assert.Zero(t, st.Missed)
+		assert.Equal(t, midnight.Add(2*time.Hour), st.Next)
+	})
+
+	t.Run("should record failed runs", func(t *testing.T) {
+		clk := clock.New(midnight)
+		p, store := newPipeline()
+		store.err = errors.New("disk full")
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}))
+		stop := start(t, s)
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Hour)
+		<-store.stored
+		clk.BlockUntil(1)
+		stop()
+
+		st := status(t, s, "orders")
+		assert.Equal(t, 1, st.Failures)
+		assert.Equal(t, "stage store: disk full", st.LastError)
+	})
+
+	t.Run("should recover panicking runs and keep running other jobs", func(t *testing.T) {
+		clk := clock.New(midnight)
+		panicking := &pipeline.DataPipeline{Source: &source.EmptySource{}, Transform: panicTransform{}, Store: &signalStore{}}
+		p, store := newPipeline()
+		s := New()
+		s.Clock = clk
+		require.NoError(t, s.Add(Job{Name: "broken", Pipeline: panicking, Schedule: Every(time.Hour)}))
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}))
+		stop := start(t, s)
+
+		clk.BlockUntil(2)
+		clk.Advance(time.Hour)
+		<-store.stored
+		require.Eventually(t, func() bool { return status(t, s, "broken").Runs == 1 }, time.Second, time.Millisecond)
+		clk.BlockUntil(2)
+		clk.Advance(time.Hour)
+		<-store.stored
+		stop()
+
+		st := status(t, s, "broken")
+		assert.Equal(t, 2, st.Runs)
+		assert.Equal(t, 2, st.Failures)
+		assert.False(t, st.Running)
+		assert.Equal(t, "run panicked: boom", st.LastError)
+		assert.Equal(t, "run panicked: boom", st.LastReport.Error)
+		assert.Equal(t, 2, status(t, s, "orders").Runs)
+	})
+
+	t.Run("should start jobs added while running", func(t *testing.T) {
+		clk := clock.New(midnight)
+		s := New()
+		s.Clock = clk
+		stop := start(t, s)
+		p, store := newPipeline()
+
+		waitRunning(t, s)
+		require.NoError(t, s.Add(Job{Name: "late", Pipeline: p, Schedule: Every(time.Minute)}))
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		<-store.stored
+		stop()
+
+		assert.Equal(t, 1, status(t, s, "late").Runs)
+	})
+}
+
+func TestScheduler_Errors(t *testing.T) {
+	t.Run("should reject duplicate and incomplete jobs", func(t *testing.T) {
+		p, _ := newPipeline()
+		s := New()
+		require.NoError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}))
+
+		assert.EqualError(t, s.Add(Job{Name: "orders", Pipeline: p, Schedule: Every(time.Hour)}), "job orders already registered")
+		assert.Error(t, s.Add(Job{Name: "reports", Pipeline: p}))
+		assert.Error(t, s.Schedule("reports", "never", p))
+		assert.Len(t, s.Statuses(), 1)
+	})
+
+	t.Run("should reject a second Run", func(t *testing.T) {
+		s := New()
+		s.Clock = clock.New(midnight)
+		stop := start(t, s)
+		defer stop()
+
+		waitRunning(t, s)
+
+		assert.EqualError(t, s.Run(context.Background()), "scheduler already running")
+	})
+
+	t.Run("should report unknown jobs", func(t *testing.T) {
+		_, ok := New().Status("missing")
+
+		assert.False(t, ok)
+	})
+}
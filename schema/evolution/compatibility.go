@@ -0,0 +1,265 @@
+// Package evolution compares successive versions of a domain.DataSchema and
+// upgrades records from one version to the next.
+//
+// Every column of a DataSchema is optional and nullable, as read by
+// JSONSource, so adding or removing a column never breaks a reader. Retyping
+// a column is only compatible when the reader can read the values written
+// with the other type: an int column can be read as float and a date column
+// as string.
+package evolution
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Mode is a compatibility level, with the same meaning as in schema registries.
+type Mode string
+
+const (
+	// None accepts every change.
+	None Mode = "none"
+	// Backward requires the new schema to read data written with the old one.
+	Backward Mode = "backward"
+	// Forward requires the old schema to read data written with the new one.
+	Forward Mode = "forward"
+	// Full requires both backward and forward compatibility.
+	Full Mode = "full"
+)
+
+// ChangeKind identifies the kind of difference between two schemas.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Retyped ChangeKind = "retyped"
+	Renamed ChangeKind = "renamed"
+)
+
+// Change describes a single difference between two schemas.
+type Change struct {
+	Kind     ChangeKind
+	Path     string              // Dotted path of the column in the new schema, or in the old one when removed
+	OldPath  string              // Dotted path of the column in the old schema
+	Old      domain.SchemaColumn // Column in the old schema, nil when added
+	New      domain.SchemaColumn // Column in the new schema, nil when removed
+	Backward bool                // True if the new schema can read old data despite the change
+	Forward  bool                // True if the old schema can read new data despite the change
+}
+
+// Breaks reports whether the change violates the given mode.
+func (c Change) Breaks(mode Mode) bool {
+	switch mode {
+	case Backward:
+		return !c.Backward
+	case Forward:
+		return !c.Forward
+	case Full:
+		return !c.Backward || !c.Forward
+	}
+	return false
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added as %s", c.Path, describe(c.New))
+	case Removed:
+		return fmt.Sprintf("%s: removed", c.Path)
+	case Renamed:
+		return fmt.Sprintf("%s: renamed from %s", c.Path, c.OldPath)
+	default:
+		return fmt.Sprintf("%s: retyped from %s to %s", c.Path, describe(c.Old), describe(c.New))
+	}
+}
+
+// IncompatibleError is returned by Check when changes violate the mode.
+type IncompatibleError struct {
+	Mode    Mode
+	Changes []Change // Breaking changes
+}
+
+func (e *IncompatibleError) Error() string {
+	parts := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		parts[i] = c.String()
+	}
+	return fmt.Sprintf("schema is not %s compatible: %s", e.Mode, strings.Join(parts, "; "))
+}
+
+// Checker diffs schema versions and enforces a compatibility mode.
+type Checker struct {
+	Mode    Mode              // Compatibility level enforced by Check
+	Aliases map[string]string // New column path -> old column path, for renamed columns
+}
+
+// NewChecker creates a new Checker enforcing mode.
+func NewChecker(mode Mode) *Checker {
+	return &Checker{
+		Mode:    mode,
+		Aliases: make(map[string]string),
+	}
+}
+
+// Alias declares that the column at newPath was renamed from oldPath, and
+// returns the checker for chaining.
+func (c *Checker) Alias(newPath, oldPath string) *Checker {
+	if c.Aliases == nil {
+		c.Aliases = make(map[string]string)
+	}
+	c.Aliases[newPath] = oldPath
+	return c
+}
+
+// Check returns an *IncompatibleError if the change from the old schema to
+// the new one violates the checker mode.
+func (c *Checker) Check(from, to *domain.DataSchema) error {
+	var breaking []Change
+	for _, change := range c.Diff(from, to) {
+		if change.Breaks(c.Mode) {
+			breaking = append(breaking, change)
+		}
+	}
+	if len(breaking) > 0 {
+		return &IncompatibleError{Mode: c.Mode, Changes: breaking}
+	}
+	return nil
+}
+
+// Diff lists the differences between the old schema and the new one, nested
+// custom types included. Columns are matched by ID, or through Aliases when
+// renamed.
+func (c *Checker) Diff(from, to *domain.DataSchema) []Change {
+	d := &differ{aliases: c.Aliases, visited: make(map[[2]*domain.DataSchema]bool)}
+	d.schemas(from, to, "", "")
+	return d.changes
+}
+
+type differ struct {
+	aliases map[string]string
+	visited map[[2]*domain.DataSchema]bool
+	changes []Change
+}
+
+func (d *differ) schemas(from, to *domain.DataSchema, oldPrefix, newPrefix string) {
+	if from == nil || to == nil {
+		return
+	}
+	key := [2]*domain.DataSchema{from, to}
+	if d.visited[key] {
+		return
+	}
+	d.visited[key] = true
+
+	matched := make(map[string]bool)
+	for _, newCol := range to.Columns {
+		path := newPrefix + newCol.GetID()
+
+		oldCol := from.Column(newCol.GetID())
+		if alias, ok := d.aliases[path]; ok {
+			if aliased := from.Column(lastSegment(alias, oldPrefix)); aliased != nil {
+				oldCol = aliased
+			}
+		}
+
+		if oldCol == nil {
+			d.changes = append(d.changes, Change{Kind: Added, Path: path, New: newCol, Backward: true, Forward: true})
+			continue
+		}
+
+		matched[oldCol.GetID()] = true
+		oldPath := oldPrefix + oldCol.GetID()
+		if oldCol.GetID() != newCol.GetID() {
+			d.changes = append(d.changes, Change{Kind: Renamed, Path: path, OldPath: oldPath, Old: oldCol, New: newCol, Backward: true, Forward: true})
+		}
+		d.columns(oldCol, newCol, oldPath, path)
+	}
+
+	for _, oldCol := range from.Columns {
+		if !matched[oldCol.GetID()] {
+			path := oldPrefix + oldCol.GetID()
+			d.changes = append(d.changes, Change{Kind: Removed, Path: path, OldPath: path, Old: oldCol, Backward: true, Forward: true})
+		}
+	}
+}
+
+func (d *differ) columns(oldCol, newCol domain.SchemaColumn, oldPath, newPath string) {
+	oldCustom, oldIsCustom := oldCol.GetType().(domain.CustomType)
+	newCustom, newIsCustom := newCol.GetType().(domain.CustomType)
+
+	if oldCol.IsArray() == newCol.IsArray() && oldIsCustom && newIsCustom {
+		d.schemas(oldCustom.Schema, newCustom.Schema, oldPath+".", newPath+".")
+		return
+	}
+	if oldCol.IsArray() == newCol.IsArray() && sameType(oldCol.GetType(), newCol.GetType()) {
+		return
+	}
+
+	d.changes = append(d.changes, Change{
+		Kind:     Retyped,
+		Path:     newPath,
+		OldPath:  oldPath,
+		Old:      oldCol,
+		New:      newCol,
+		Backward: readable(newCol, oldCol),
+		Forward:  readable(oldCol, newCol),
+	})
+}
+
+// readable reports whether values written for the writer column can be
+// read with the reader column.
+func readable(reader, writer domain.SchemaColumn) bool {
+	if reader.IsArray() != writer.IsArray() {
+		return false
+	}
+	return CanRead(reader.GetType(), writer.GetType())
+}
+
+// CanRead reports whether a value written as writer can be read as reader
+// without loss. Custom types are compared by name only; use Checker.Diff to
+// compare their columns.
+func CanRead(reader, writer domain.SchemaType) bool {
+	if sameType(reader, writer) {
+		return true
+	}
+	switch {
+	case reader == domain.NativeTypeFloat && writer == domain.NativeTypeInt:
+		return true
+	case reader == domain.NativeTypeString && writer == domain.NativeTypeDate:
+		return true
+	}
+	return false
+}
+
+func sameType(a, b domain.SchemaType) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.IsNative() == b.IsNative() && a.GetTypeName() == b.GetTypeName()
+}
+
+// lastSegment returns the column ID an alias path refers to within the
+// schema at prefix, or an empty string if the alias points elsewhere.
+func lastSegment(path, prefix string) string {
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+	rest := strings.TrimPrefix(path, prefix)
+	if strings.Contains(rest, ".") {
+		return ""
+	}
+	return rest
+}
+
+func describe(col domain.SchemaColumn) string {
+	if col == nil || col.GetType() == nil {
+		return "unknown"
+	}
+	if col.IsArray() {
+		return "[]" + col.GetType().GetTypeName()
+	}
+	return col.GetType().GetTypeName()
+}
@@ -0,0 +1,218 @@
+package evolution
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func productV1() *domain.DataSchema {
+	return &domain.DataSchema{
+		ID: "Product",
+		Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+		},
+	}
+}
+
+func TestChecker_Diff(t *testing.T) {
+	t.Run("should report no change for identical schemas", func(t *testing.T) {
+		changes := NewChecker(Full).Diff(productV1(), productV1())
+
+		assert.Empty(t, changes)
+	})
+
+	t.Run("should report added, removed and retyped columns", func(t *testing.T) {
+		v2 := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+				domain.SchemaColumnSingle{ID: "category", SchemaType: domain.NativeTypeString},
+			},
+		}
+
+		changes := NewChecker(Full).Diff(productV1(), v2)
+
+		require.Len(t, changes, 3)
+		assert.Equal(t, Retyped, changes[0].Kind)
+		assert.Equal(t, "price", changes[0].Path)
+		assert.True(t, changes[0].Backward)
+		assert.False(t, changes[0].Forward)
+		assert.Equal(t, Added, changes[1].Kind)
+		assert.Equal(t, "category", changes[1].Path)
+		assert.Equal(t, Removed, changes[2].Kind)
+		assert.Equal(t, "stock", changes[2].Path)
+	})
+
+	t.Run("should report renamed columns with alias", func(t *testing.T) {
+		v2 := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "title", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeInt},
+				domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+			},
+		}
+
+		changes := NewChecker(Full).Alias("title", "name").Diff(productV1(), v2)
+
+		require.Len(t, changes, 1)
+		assert.Equal(t, Renamed, changes[0].Kind)
+		assert.Equal(t, "title", changes[0].Path)
+		assert.Equal(t, "name", changes[0].OldPath)
+		assert.Equal(t, "title: renamed from name", changes[0].String())
+	})
+
+	t.Run("should report nested custom type changes with paths", func(t *testing.T) {
+		oldAddress := &domain.DataSchema{
+			ID: "Address",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "zip", SchemaType: domain.NativeTypeInt},
+			},
+		}
+		newAddress := &domain.DataSchema{
+			ID: "Address",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "zip", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "country", SchemaType: domain.NativeTypeString},
+			},
+		}
+		v1 := &domain.DataSchema{ID: "User", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnArray{ID: "addresses", RefSchema: domain.CustomType{Name: "Address", Schema: oldAddress}},
+		}}
+		v2 := &domain.DataSchema{ID: "User", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnArray{ID: "addresses", RefSchema: domain.CustomType{Name: "Address", Schema: newAddress}},
+		}}
+
+		changes := NewChecker(Full).Diff(v1, v2)
+
+		require.Len(t, changes, 2)
+		assert.Equal(t, "addresses.zip: retyped from int to string", changes[0].String())
+		assert.False(t, changes[0].Backward)
+		assert.Equal(t, "addresses.country: added as string", changes[1].String())
+	})
+
+	t.Run("should resolve nested aliases", func(t *testing.T) {
+		oldAddress := &domain.DataSchema{ID: "Address", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "zipcode", SchemaType: domain.NativeTypeString},
+		}}
+		newAddress := &domain.DataSchema{ID: "Address", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "zip", SchemaType: domain.NativeTypeString},
+		}}
+		v1 := &domain.DataSchema{ID: "User", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "Address", Schema: oldAddress}},
+		}}
+		v2 := &domain.DataSchema{ID: "User", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "Address", Schema: newAddress}},
+		}}
+
+		changes := NewChecker(Full).Alias("address.zip", "address.zipcode").Diff(v1, v2)
+
+		require.Len(t, changes, 1)
+		assert.Equal(t, "address.zip: renamed from address.zipcode", changes[0].String())
+	})
+
+	t.Run("should report array to single change as incompatible", func(t *testing.T) {
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnArray{ID: "price", RefSchema: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+		}}
+
+		changes := NewChecker(Full).Diff(productV1(), v2)
+
+		require.Len(t, changes, 1)
+		assert.Equal(t, "price: retyped from int to []int", changes[0].String())
+		assert.False(t, changes[0].Backward)
+		assert.False(t, changes[0].Forward)
+	})
+
+	t.Run("should terminate on recursive schemas", func(t *testing.T) {
+		node := &domain.DataSchema{ID: "Node"}
+		node.Columns = []domain.SchemaColumn{
+			domain.SchemaColumnArray{ID: "children", RefSchema: domain.CustomType{Name: "Node", Schema: node}},
+		}
+
+		changes := NewChecker(Full).Diff(node, node)
+
+		assert.Empty(t, changes)
+	})
+}
+
+func TestChecker_Check(t *testing.T) {
+	widened := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+		domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+		domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+	}}
+
+	tests := []struct {
+		name     string
+		mode     Mode
+		from, to *domain.DataSchema
+		wantErr  bool
+	}{
+		{"backward accepts widening", Backward, productV1(), widened, false},
+		{"backward rejects narrowing", Backward, widened, productV1(), true},
+		{"forward accepts narrowing", Forward, widened, productV1(), false},
+		{"forward rejects widening", Forward, productV1(), widened, true},
+		{"full rejects widening", Full, productV1(), widened, true},
+		{"none accepts everything", None, productV1(), widened, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewChecker(tt.mode).Check(tt.from, tt.to)
+
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var incompatible *IncompatibleError
+			require.True(t, errors.As(err, &incompatible))
+			assert.Equal(t, tt.mode, incompatible.Mode)
+			assert.Len(t, incompatible.Changes, 1)
+			assert.Contains(t, err.Error(), "price: retyped")
+		})
+	}
+
+	t.Run("should accept added and removed columns in full mode", func(t *testing.T) {
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "category", SchemaType: domain.NativeTypeString},
+		}}
+
+		err := NewChecker(Full).Check(productV1(), v2)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestCanRead(t *testing.T) {
+	tests := []struct {
+		reader, writer domain.SchemaType
+		want           bool
+	}{
+		{domain.NativeTypeInt, domain.NativeTypeInt, true},
+		{domain.NativeTypeFloat, domain.NativeTypeInt, true},
+		{domain.NativeTypeInt, domain.NativeTypeFloat, false},
+		{domain.NativeTypeString, domain.NativeTypeDate, true},
+		{domain.NativeTypeDate, domain.NativeTypeString, false},
+		{domain.NativeTypeBool, domain.NativeTypeInt, false},
+		{domain.CustomType{Name: "A"}, domain.CustomType{Name: "A"}, true},
+		{domain.CustomType{Name: "A"}, domain.NativeTypeString, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reader.GetTypeName()+"<-"+tt.writer.GetTypeName(), func(t *testing.T) {
+			assert.Equal(t, tt.want, CanRead(tt.reader, tt.writer))
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package evolution
+
+import (
+	"fmt"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// ColumnFunc computes the value of a new column from a record of the old schema.
+type ColumnFunc func(old *domain.Record) (domain.Value, error)
+
+// Migration upgrades records from one schema version to the next.
+//
+// Columns are carried over by ID, or from their previous name when renamed.
+// Values of retyped columns are promoted when CanRead allows it, or cast
+// with Cast when set. Columns missing from the old schema are left unset,
+// and columns removed from the new schema are dropped. Derived columns can
+// be computed with Compute. Migration implements ports.TransformPort.
+type Migration struct {
+	From    *domain.DataSchema    // Old schema version
+	To      *domain.DataSchema    // New schema version
+	Aliases map[string]string     // New column path -> old column path, for renamed columns
+	Compute map[string]ColumnFunc // New top-level column ID -> function computing its value
+	Cast    *domain.CastOptions   // Casts retyped values CanRead rejects, nil to only promote
+}
+
+// NewMigration creates a new Migration from one schema version to another.
+func NewMigration(from, to *domain.DataSchema) *Migration {
+	return &Migration{
+		From:    from,
+		To:      to,
+		Aliases: make(map[string]string),
+		Compute: make(map[string]ColumnFunc),
+	}
+}
+
+// Rename declares that the column at newPath was renamed from oldPath, and
+// returns the migration for chaining.
+func (m *Migration) Rename(newPath, oldPath string) *Migration {
+	if m.Aliases == nil {
+		m.Aliases = make(map[string]string)
+	}
+	m.Aliases[newPath] = oldPath
+	return m
+}
+
+// Derive sets the function computing a top-level column of the new schema,
+// and returns the migration for chaining.
+func (m *Migration) Derive(columnID string, fn ColumnFunc) *Migration {
+	if m.Compute == nil {
+		m.Compute = make(map[string]ColumnFunc)
+	}
+	m.Compute[columnID] = fn
+	return m
+}
+
+// Checker returns a Checker sharing the migration aliases.
+func (m *Migration) Checker(mode Mode) *Checker {
+	return &Checker{Mode: mode, Aliases: m.Aliases}
+}
+
+// Migrate returns a copy of record upgraded to the new schema.
+func (m *Migration) Migrate(record *domain.Record) (*domain.Record, error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	result, err := m.record(record, m.From, m.To, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	for columnID, fn := range m.Compute {
+		value, err := fn(record)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", columnID, err)
+		}
+		result.Set(columnID, value)
+	}
+
+	return result, nil
+}
+
+// Transform upgrades every record of the input to the new schema.
+func (m *Migration) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	result := domain.NewRecordSet(m.To)
+	for i, record := range input.Records {
+		migrated, err := m.Migrate(record)
+		if err != nil {
+			return nil, domain.NewRecordError(i, record, err)
+		}
+		result.Add(migrated)
+	}
+	return result, nil
+}
+
+func (m *Migration) record(record *domain.Record, from, to *domain.DataSchema, oldPrefix, newPrefix string) (*domain.Record, error) {
+	result := domain.NewRecord(to)
+	result.Source = record.Source
+
+	for _, newCol := range to.Columns {
+		path := newPrefix + newCol.GetID()
+		oldID := newCol.GetID()
+		if alias, ok := m.Aliases[path]; ok {
+			oldID = lastSegment(alias, oldPrefix)
+		}
+
+		value, exists := record.Values[oldID]
+		if !exists {
+			continue
+		}
+
+		oldCol := from.Column(oldID)
+		migrated, err := m.value(value, oldCol, newCol, oldPrefix+oldID+".", path+".")
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", path, err)
+		}
+		result.Set(newCol.GetID(), migrated)
+	}
+
+	return result, nil
+}
+
+func (m *Migration) value(value domain.Value, oldCol, newCol domain.SchemaColumn, oldPrefix, newPrefix string) (domain.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if value.IsNull() {
+		return domain.NullValue{Type: newCol.GetType()}, nil
+	}
+
+	if arr, ok := value.(domain.ArrayValue); ok {
+		if !newCol.IsArray() {
+			return nil, fmt.Errorf("cannot convert array to %s", newCol.GetType().GetTypeName())
+		}
+		elements := make([]domain.Value, 0, len(arr.Elements))
+		for i, elem := range arr.Elements {
+			migrated, err := m.single(elem, oldCol, newCol.GetType(), oldPrefix, newPrefix)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elements = append(elements, migrated)
+		}
+		return domain.ArrayValue{ElementType: newCol.GetType(), Elements: elements}, nil
+	}
+
+	if newCol.IsArray() {
+		return nil, fmt.Errorf("cannot convert %T to array", value)
+	}
+	return m.single(value, oldCol, newCol.GetType(), oldPrefix, newPrefix)
+}
+
+func (m *Migration) single(value domain.Value, oldCol domain.SchemaColumn, newType domain.SchemaType, oldPrefix, newPrefix string) (domain.Value, error) {
+	if value == nil || value.IsNull() {
+		return domain.NullValue{Type: newType}, nil
+	}
+
+	if nested, ok := value.(domain.RecordValue); ok {
+		custom, ok := newType.(domain.CustomType)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert record to %s", newType.GetTypeName())
+		}
+		from := nested.Record.Schema
+		if oldCol != nil {
+			if oldCustom, ok := oldCol.GetType().(domain.CustomType); ok && oldCustom.Schema != nil {
+				from = oldCustom.Schema
+			}
+		}
+		if from == nil || custom.Schema == nil {
+			return nil, fmt.Errorf("custom type %s has no schema", custom.Name)
+		}
+		migrated, err := m.record(nested.Record, from, custom.Schema, oldPrefix, newPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return domain.RecordValue{Record: migrated}, nil
+	}
+
+	return m.promote(value, newType)
+}
+
+// promote converts a native value to newType when CanRead allows it, or
+// when the migration has cast options.
+func (m *Migration) promote(value domain.Value, newType domain.SchemaType) (domain.Value, error) {
+	if sameType(value.GetType(), newType) {
+		return value, nil
+	}
+
+	native, ok := newType.(domain.NativeType)
+	switch {
+	case ok && CanRead(newType, value.GetType()):
+		return domain.Cast(value, native, domain.CastOptions{})
+	case ok && m.Cast != nil:
+		return domain.Cast(value, native, *m.Cast)
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to %s", value.GetType().GetTypeName(), newType.GetTypeName())
+}
+
+// Chain upgrades records across successive schema versions.
+// Each record is migrated from its own schema to the last version, so that
+// a RecordSet mixing versions can be upgraded at once. Schemas are matched
+// by identity, so migrations must use the schemas given to the sources.
+type Chain []*Migration
+
+// Migrate returns record upgraded to the last schema version of the chain.
+func (c Chain) Migrate(record *domain.Record) (*domain.Record, error) {
+	if len(c) == 0 || record == nil {
+		return record, nil
+	}
+
+	start := -1
+	for i, m := range c {
+		if m.From == record.Schema {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		if record.Schema == c[len(c)-1].To {
+			return record, nil
+		}
+		return nil, fmt.Errorf("no migration from schema %s", schemaID(record.Schema))
+	}
+
+	result := record
+	for _, m := range c[start:] {
+		var err error
+		result, err = m.Migrate(result)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s to %s: %w", schemaID(m.From), schemaID(m.To), err)
+		}
+	}
+	return result, nil
+}
+
+// Transform upgrades every record of the input to the last schema version.
+func (c Chain) Transform(input *domain.RecordSet) (*domain.RecordSet, error) {
+	if input == nil || len(c) == 0 {
+		return input, nil
+	}
+
+	result := domain.NewRecordSet(c[len(c)-1].To)
+	for i, record := range input.Records {
+		migrated, err := c.Migrate(record)
+		if err != nil {
+			return nil, domain.NewRecordError(i, record, err)
+		}
+		result.Add(migrated)
+	}
+	return result, nil
+}
+
+func schemaID(schema *domain.DataSchema) string {
+	if schema == nil {
+		return "<nil>"
+	}
+	return schema.ID
+}
@@ -0,0 +1,225 @@
+package evolution
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigration_Migrate(t *testing.T) {
+	t.Run("should carry, rename, promote, drop and derive columns", func(t *testing.T) {
+		v1 := productV1()
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "title", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "in_stock", SchemaType: domain.NativeTypeBool},
+		}}
+
+		record := domain.NewRecord(v1)
+		record.Source = "products.json"
+		record.Set("name", domain.StringValue("Laptop"))
+		record.Set("price", domain.IntValue(999))
+		record.Set("stock", domain.IntValue(5))
+
+		migration := NewMigration(v1, v2).
+			Rename("title", "name").
+			Derive("in_stock", func(old *domain.Record) (domain.Value, error) {
+				return domain.BoolValue(old.GetInt("stock") > 0), nil
+			})
+
+		migrated, err := migration.Migrate(record)
+
+		require.NoError(t, err)
+		assert.Same(t, v2, migrated.Schema)
+		assert.Equal(t, "products.json", migrated.Source)
+		assert.Equal(t, "Laptop", migrated.GetString("title"))
+		assert.Equal(t, 999.0, migrated.GetFloat("price"))
+		assert.True(t, migrated.GetBool("in_stock"))
+		assert.Nil(t, migrated.Get("stock"))
+		assert.Nil(t, migrated.Get("name"))
+	})
+
+	t.Run("should migrate nested records and arrays", func(t *testing.T) {
+		oldItem := &domain.DataSchema{ID: "Item", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "qty", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "at", SchemaType: domain.NativeTypeDate},
+		}}
+		newItem := &domain.DataSchema{ID: "Item", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "at", SchemaType: domain.NativeTypeString},
+		}}
+		v1 := &domain.DataSchema{ID: "Order", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnArray{ID: "items", RefSchema: domain.CustomType{Name: "Item", Schema: oldItem}},
+		}}
+		v2 := &domain.DataSchema{ID: "Order", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnArray{ID: "items", RefSchema: domain.CustomType{Name: "Item", Schema: newItem}},
+		}}
+
+		item := domain.NewRecord(oldItem)
+		item.Set("qty", domain.IntValue(2))
+		item.Set("at", domain.DateValue(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)))
+		order := domain.NewRecord(v1)
+		order.Set("items", domain.ArrayValue{
+			ElementType: domain.CustomType{Name: "Item", Schema: oldItem},
+			Elements:    []domain.Value{domain.RecordValue{Record: item}, domain.NullValue{}},
+		})
+
+		migrated, err := NewMigration(v1, v2).Rename("items.quantity", "items.qty").Migrate(order)
+
+		require.NoError(t, err)
+		items := migrated.GetArray("items")
+		require.Len(t, items, 2)
+		newRecord := items[0].(domain.RecordValue).Record
+		assert.Same(t, newItem, newRecord.Schema)
+		assert.Equal(t, 2.0, newRecord.GetFloat("quantity"))
+		assert.Equal(t, "2024-01-15T10:30:00Z", newRecord.GetString("at"))
+		assert.True(t, items[1].IsNull())
+	})
+
+	t.Run("should keep nulls with the new type", func(t *testing.T) {
+		v1 := productV1()
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+		}}
+		record := domain.NewRecord(v1)
+		record.Set("price", domain.NullValue{Type: domain.NativeTypeInt})
+
+		migrated, err := NewMigration(v1, v2).Migrate(record)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.NullValue{Type: domain.NativeTypeFloat}, migrated.Get("price"))
+	})
+
+	t.Run("should return error for incompatible retype", func(t *testing.T) {
+		v1 := productV1()
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeInt},
+		}}
+		record := domain.NewRecord(v1)
+		record.Set("name", domain.StringValue("Laptop"))
+
+		_, err := NewMigration(v1, v2).Migrate(record)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "column name: cannot convert string to int")
+	})
+
+	t.Run("should cast retyped values with cast options", func(t *testing.T) {
+		v1 := productV1()
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeInt},
+		}}
+		migration := NewMigration(v1, v2)
+		migration.Cast = &domain.CastOptions{Mode: domain.CastLenient}
+
+		record := domain.NewRecord(v1)
+		record.Set("name", domain.StringValue(" 42 "))
+		migrated, err := migration.Migrate(record)
+		require.NoError(t, err)
+		assert.Equal(t, domain.IntValue(42), migrated.Get("name"))
+
+		record.Set("name", domain.StringValue("Laptop"))
+		_, err = migration.Migrate(record)
+		assert.EqualError(t, err, `column name: cannot parse "Laptop" as int`)
+	})
+
+	t.Run("should return error from derived column", func(t *testing.T) {
+		v1 := productV1()
+		migration := NewMigration(v1, v1).Derive("stock", func(*domain.Record) (domain.Value, error) {
+			return nil, assert.AnError
+		})
+
+		_, err := migration.Migrate(domain.NewRecord(v1))
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestMigration_Transform(t *testing.T) {
+	t.Run("should migrate every record", func(t *testing.T) {
+		v1 := productV1()
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+		}}
+		input := domain.NewRecordSet(v1)
+		for _, price := range []int64{1, 2} {
+			record := domain.NewRecord(v1)
+			record.Set("price", domain.IntValue(price))
+			input.Add(record)
+		}
+
+		result, err := NewMigration(v1, v2).Transform(input)
+
+		require.NoError(t, err)
+		assert.Same(t, v2, result.Schema)
+		assert.Equal(t, 2.0, result.Last().GetFloat("price"))
+	})
+
+	t.Run("should report failing record index", func(t *testing.T) {
+		v1 := productV1()
+		v2 := &domain.DataSchema{ID: "Product", Columns: []domain.SchemaColumn{
+			domain.SchemaColumnArray{ID: "price", RefSchema: domain.NativeTypeInt},
+		}}
+		input := domain.NewRecordSet(v1)
+		record := domain.NewRecord(v1)
+		record.Set("price", domain.IntValue(1))
+		input.Add(record)
+
+		_, err := NewMigration(v1, v2).Transform(input)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "record 0: column price: cannot convert")
+	})
+}
+
+func TestChain(t *testing.T) {
+	v1 := productV1()
+	v2 := &domain.DataSchema{ID: "ProductV2", Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "title", SchemaType: domain.NativeTypeString},
+		domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeInt},
+	}}
+	v3 := &domain.DataSchema{ID: "ProductV3", Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "title", SchemaType: domain.NativeTypeString},
+		domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+	}}
+	chain := Chain{
+		NewMigration(v1, v2).Rename("title", "name"),
+		NewMigration(v2, v3),
+	}
+
+	t.Run("should upgrade mixed versions to the last one", func(t *testing.T) {
+		old := domain.NewRecord(v1)
+		old.Set("name", domain.StringValue("Laptop"))
+		old.Set("price", domain.IntValue(10))
+		middle := domain.NewRecord(v2)
+		middle.Set("title", domain.StringValue("Phone"))
+		middle.Set("price", domain.IntValue(5))
+		latest := domain.NewRecord(v3)
+		latest.Set("title", domain.StringValue("Tablet"))
+
+		input := domain.NewRecordSet(v1)
+		input.Add(old)
+		input.Add(middle)
+		input.Add(latest)
+
+		result, err := chain.Transform(input)
+
+		require.NoError(t, err)
+		assert.Same(t, v3, result.Schema)
+		assert.Equal(t, "Laptop", result.Get(0).GetString("title"))
+		assert.Equal(t, 10.0, result.Get(0).GetFloat("price"))
+		assert.Same(t, v3, result.Get(1).Schema)
+		assert.Same(t, latest, result.Get(2))
+	})
+
+	t.Run("should return error for unknown schema", func(t *testing.T) {
+		_, err := chain.Migrate(domain.NewRecord(&domain.DataSchema{ID: "Other"}))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no migration from schema Other")
+	})
+}
@@ -0,0 +1,188 @@
+package inference
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// GoCode returns the inferred schema as Go source declaring one variable per
+// schema, nested schemas first.
+func (r *Result) GoCode() (string, error) {
+	return FormatGo(r.Schema)
+}
+
+// YAML returns the inferred schema as YAML, including nullability.
+func (r *Result) YAML() string {
+	nullable := make(map[string]bool, len(r.Columns))
+	for _, c := range r.Columns {
+		nullable[c.Path] = c.Nullable || c.Optional
+	}
+	return formatYAML(r.Schema, nullable)
+}
+
+// FormatGo returns Go source declaring the schema as domain.DataSchema
+// literals, one variable per schema with nested schemas declared first.
+func FormatGo(schema *domain.DataSchema) (string, error) {
+	var b strings.Builder
+	vars := &goVars{names: map[*domain.DataSchema]string{}, count: map[string]int{}}
+	writeGoSchema(&b, schema, vars)
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format Go code: %w", err)
+	}
+	return strings.TrimRight(string(src), "\n") + "\n", nil
+}
+
+// FormatYAML returns the schema as YAML, with nested schemas inlined.
+func FormatYAML(schema *domain.DataSchema) string {
+	return formatYAML(schema, nil)
+}
+
+// goVars names the variable of each schema declared by FormatGo.
+type goVars struct {
+	names map[*domain.DataSchema]string // Variable of each schema declared
+	count map[string]int                // Schemas declared per name derived from their ID
+}
+
+// declare returns a variable name for schema unused by other schemas, so
+// that IDs differing only by characters dropped by goVarName get a suffix.
+func (v *goVars) declare(schema *domain.DataSchema) string {
+	name := goVarName(schema.ID)
+	v.count[name]++
+	if n := v.count[name]; n > 1 {
+		name = fmt.Sprintf("%s%d", name, n)
+	}
+	v.names[schema] = name
+	return name
+}
+
+func writeGoSchema(b *strings.Builder, schema *domain.DataSchema, vars *goVars) {
+	if _, declared := vars.names[schema]; schema == nil || declared {
+		return
+	}
+	name := vars.declare(schema)
+
+	for _, col := range schema.Columns {
+		if custom, ok := col.GetType().(domain.CustomType); ok {
+			writeGoSchema(b, custom.Schema, vars)
+		}
+	}
+
+	fmt.Fprintf(b, "%s := &domain.DataSchema{\n", name)
+	fmt.Fprintf(b, "ID: %s,\n", strconv.Quote(schema.ID))
+	b.WriteString("Columns: []domain.SchemaColumn{\n")
+	for _, col := range schema.Columns {
+		if col.IsArray() {
+			fmt.Fprintf(b, "domain.SchemaColumnArray{ID: %s, RefSchema: %s},\n", strconv.Quote(col.GetID()), goType(col.GetType(), vars))
+		} else {
+			fmt.Fprintf(b, "domain.SchemaColumnSingle{ID: %s, SchemaType: %s},\n", strconv.Quote(col.GetID()), goType(col.GetType(), vars))
+		}
+	}
+	b.WriteString("},\n}\n\n")
+}
+
+func goType(t domain.SchemaType, vars *goVars) string {
+	switch v := t.(type) {
+	case domain.NativeType:
+		switch v {
+		case domain.NativeTypeString:
+			return "domain.NativeTypeString"
+		case domain.NativeTypeInt:
+			return "domain.NativeTypeInt"
+		case domain.NativeTypeFloat:
+			return "domain.NativeTypeFloat"
+		case domain.NativeTypeDate:
+			return "domain.NativeTypeDate"
+		case domain.NativeTypeBool:
+			return "domain.NativeTypeBool"
+		}
+		return fmt.Sprintf("domain.NativeType(%s)", strconv.Quote(string(v)))
+	case domain.CustomType:
+		schema := "nil"
+		if v.Schema != nil {
+			schema = vars.names[v.Schema]
+		}
+		return fmt.Sprintf("domain.CustomType{Name: %s, Schema: %s}", strconv.Quote(v.Name), schema)
+	}
+	return "nil"
+}
+
+// goVarName derives a variable name from a schema ID, so that
+// "ShippingAddress" becomes "shippingAddressSchema".
+func goVarName(id string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9' && b.Len() > 0:
+			if upper {
+				b.WriteString(strings.ToUpper(string(c)))
+			} else {
+				b.WriteRune(c)
+			}
+			upper = false
+		case c >= 'A' && c <= 'Z':
+			if b.Len() == 0 {
+				b.WriteString(strings.ToLower(string(c)))
+			} else {
+				b.WriteRune(c)
+			}
+			upper = false
+		default:
+			upper = b.Len() > 0
+		}
+	}
+	return b.String() + "Schema"
+}
+
+func formatYAML(schema *domain.DataSchema, nullable map[string]bool) string {
+	var b strings.Builder
+	writeYAMLSchema(&b, schema, "", "", nullable)
+	return b.String()
+}
+
+func writeYAMLSchema(b *strings.Builder, schema *domain.DataSchema, indent, prefix string, nullable map[string]bool) {
+	fmt.Fprintf(b, "%sid: %s\n", indent, yamlString(schema.ID))
+	if len(schema.Columns) == 0 {
+		fmt.Fprintf(b, "%scolumns: []\n", indent)
+		return
+	}
+
+	fmt.Fprintf(b, "%scolumns:\n", indent)
+	for _, col := range schema.Columns {
+		path := prefix + col.GetID()
+		fmt.Fprintf(b, "%s  - id: %s\n", indent, yamlString(col.GetID()))
+		fmt.Fprintf(b, "%s    type: %s\n", indent, yamlString(col.GetType().GetTypeName()))
+		if col.IsArray() {
+			fmt.Fprintf(b, "%s    array: true\n", indent)
+		}
+		if nullable[path] {
+			fmt.Fprintf(b, "%s    nullable: true\n", indent)
+		}
+		if custom, ok := col.GetType().(domain.CustomType); ok && custom.Schema != nil {
+			fmt.Fprintf(b, "%s    schema:\n", indent)
+			writeYAMLSchema(b, custom.Schema, indent+"      ", path+".", nullable)
+		}
+	}
+}
+
+// yamlString quotes s when it could be misread as another YAML scalar.
+func yamlString(s string) string {
+	if s == "" || strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t") ||
+		strings.TrimSpace(s) != s || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+package inference
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatGo(t *testing.T) {
+	t.Run("should declare nested schemas first", func(t *testing.T) {
+		addressSchema := &domain.DataSchema{
+			ID: "ShippingAddress",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+			},
+		}
+		schema := &domain.DataSchema{
+			ID: "Order",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "total", SchemaType: domain.NativeTypeFloat},
+				domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "ShippingAddress", Schema: addressSchema}},
+			},
+		}
+
+		code, err := FormatGo(schema)
+
+		require.NoError(t, err)
+		assert.Equal(t, `shippingAddressSchema := &domain.DataSchema{
+	ID: "ShippingAddress",
+	Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+	},
+}
+
+orderSchema := &domain.DataSchema{
+	ID: "Order",
+	Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "total", SchemaType: domain.NativeTypeFloat},
+		domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+		domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "ShippingAddress", Schema: shippingAddressSchema}},
+	},
+}
+`, code)
+	})
+
+	t.Run("should declare shared schemas once", func(t *testing.T) {
+		item := &domain.DataSchema{ID: "Item"}
+		schema := &domain.DataSchema{
+			ID: "Order",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "a", SchemaType: domain.CustomType{Name: "Item", Schema: item}},
+				domain.SchemaColumnSingle{ID: "b", SchemaType: domain.CustomType{Name: "Item", Schema: item}},
+			},
+		}
+
+		code, err := FormatGo(schema)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(code, "itemSchema := "))
+	})
+
+	t.Run("should suffix schemas whose IDs give the same variable name", func(t *testing.T) {
+		first := &domain.DataSchema{ID: "Éclair"}
+		second := &domain.DataSchema{ID: "Èclair"}
+		schema := &domain.DataSchema{
+			ID: "Cake",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "a", SchemaType: domain.CustomType{Name: "Éclair", Schema: first}},
+				domain.SchemaColumnSingle{ID: "b", SchemaType: domain.CustomType{Name: "Èclair", Schema: second}},
+			},
+		}
+
+		code, err := FormatGo(schema)
+
+		require.NoError(t, err)
+		assert.Contains(t, code, "clairSchema := ")
+		assert.Contains(t, code, "clairSchema2 := ")
+		assert.Contains(t, code, `SchemaType: domain.CustomType{Name: "Éclair", Schema: clairSchema}}`)
+		assert.Contains(t, code, `SchemaType: domain.CustomType{Name: "Èclair", Schema: clairSchema2}}`)
+	})
+}
+
+func TestResult_YAML(t *testing.T) {
+	t.Run("should print schema with nullability", func(t *testing.T) {
+		result := inferJSON(t, `[
+			{"name": "Laptop", "tags": ["a"], "address": {"city": "Paris"}},
+			{"name": "Phone", "tags": null, "address": {"city": null}}
+		]`)
+
+		assert.Equal(t, `id: Product
+columns:
+  - id: name
+    type: string
+  - id: tags
+    type: string
+    array: true
+    nullable: true
+  - id: address
+    type: Address
+    schema:
+      id: Address
+      columns:
+        - id: city
+          type: string
+          nullable: true
+`, result.YAML())
+	})
+}
+
+func TestResult_GoCode(t *testing.T) {
+	t.Run("should print inferred schema as Go code", func(t *testing.T) {
+		result := inferJSON(t, `[{"count": 1}]`)
+
+		code, err := result.GoCode()
+
+		require.NoError(t, err)
+		assert.Contains(t, code, `domain.SchemaColumnSingle{ID: "count", SchemaType: domain.NativeTypeInt}`)
+	})
+}
+
+func TestFormatYAML(t *testing.T) {
+	t.Run("should quote ambiguous scalars", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "yes",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "a: b", SchemaType: domain.NativeTypeInt},
+			},
+		}
+
+		assert.Equal(t, `id: "yes"
+columns:
+  - id: "a: b"
+    type: int
+`, FormatYAML(schema))
+	})
+
+	t.Run("should print empty columns", func(t *testing.T) {
+		assert.Equal(t, "id: Empty\ncolumns: []\n", FormatYAML(&domain.DataSchema{ID: "Empty"}))
+	})
+}
@@ -0,0 +1,482 @@
+// Package inference proposes a domain.DataSchema from sample data.
+//
+// The Inferrer scans raw records, widens the observed types (int to float,
+// date to string, numbers and bools mixed with strings to string), detects
+// dates, tracks nullability and turns nested objects
+// into CustomType columns with generated sub-schemas.
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Inferrer scans sample records and proposes a schema.
+type Inferrer struct {
+	DateLayouts []string // Layouts recognised as dates, defaults to RFC3339 as read by JSONSource
+	MaxRecords  int      // Maximum number of records scanned, 0 scans all of them
+}
+
+// NewInferrer creates a new Inferrer with default settings.
+func NewInferrer() *Inferrer {
+	return &Inferrer{
+		DateLayouts: []string{time.RFC3339},
+	}
+}
+
+// Result holds an inferred schema and what was learned about each column.
+type Result struct {
+	Schema  *domain.DataSchema // Proposed schema
+	Columns []ColumnInfo       // Every column, nested ones included, in schema order
+	Records int                // Number of records scanned
+}
+
+// ColumnInfo describes an inferred column.
+type ColumnInfo struct {
+	Path     string            // Dotted path of the column (e.g., "address.city")
+	Type     domain.SchemaType // Inferred type
+	Array    bool              // True if the column holds an array
+	Nullable bool              // True if a null value was seen
+	Optional bool              // True if the column was missing from some records
+}
+
+// Column returns the information about the column at path, or nil if unknown.
+func (r *Result) Column(path string) *ColumnInfo {
+	for i := range r.Columns {
+		if r.Columns[i].Path == path {
+			return &r.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Infer proposes a schema with the given ID from raw records.
+func (i *Inferrer) Infer(id string, records []domain.RawRecord) (*Result, error) {
+	root := newObject()
+	n := 0
+	for _, record := range records {
+		if i.MaxRecords > 0 && n >= i.MaxRecords {
+			break
+		}
+		if err := i.observeObject(root, record.Data); err != nil {
+			return nil, &domain.RecordError{Index: n, Source: record.Source, Err: err}
+		}
+		n++
+	}
+	return i.resolve(id, root, n)
+}
+
+// InferJSON proposes a schema with the given ID from JSON input. The input is
+// either an array of objects, as read by JSONSource, or a stream of objects
+// such as NDJSON. Columns keep the order in which they first appear. Input
+// past MaxRecords records is not read.
+func (i *Inferrer) InferJSON(id string, r io.Reader) (*Result, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	root := newObject()
+	n := 0
+	done := func() bool {
+		return i.MaxRecords > 0 && n >= i.MaxRecords
+	}
+	observe := func(value any) error {
+		if err := i.observeObject(root, value); err != nil {
+			return &domain.RecordError{Index: n, Err: err}
+		}
+		n++
+		return nil
+	}
+
+	for !done() {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+
+		if tok == json.Delim('[') {
+			for dec.More() && !done() {
+				value, err := decodeValue(dec, nil)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse JSON: %w", err)
+				}
+				if err := observe(value); err != nil {
+					return nil, err
+				}
+			}
+			if done() {
+				break
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("failed to parse JSON: %w", err)
+			}
+			continue
+		}
+
+		value := any(tok)
+		if tok == json.Delim('{') {
+			value, err = decodeValue(dec, tok)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse JSON: %w", err)
+			}
+		}
+		if err := observe(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return i.resolve(id, root, n)
+}
+
+// kind is a bit set of the JSON kinds observed for a field.
+type kind uint8
+
+const (
+	kindNull kind = 1 << iota
+	kindBool
+	kindInt
+	kindFloat
+	kindDate
+	kindString
+	kindObject
+	kindArray
+)
+
+// field accumulates observations for a single column.
+type field struct {
+	kinds   kind
+	present int     // Number of parent objects containing the field
+	object  *object // Merged shape of nested objects
+	elem    *field  // Merged shape of array elements
+}
+
+// object accumulates observations for an object and keeps key order.
+type object struct {
+	order  []string
+	fields map[string]*field
+	count  int
+}
+
+func newObject() *object {
+	return &object{fields: make(map[string]*field)}
+}
+
+func (o *object) field(name string) *field {
+	f, ok := o.fields[name]
+	if !ok {
+		f = &field{}
+		o.fields[name] = f
+		o.order = append(o.order, name)
+	}
+	return f
+}
+
+// orderedObject is a JSON object that remembers its key order.
+type orderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (i *Inferrer) observeObject(o *object, value any) error {
+	var keys []string
+	var values map[string]any
+
+	switch v := value.(type) {
+	case orderedObject:
+		keys, values = v.keys, v.values
+	case map[string]any:
+		values = v
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	default:
+		return fmt.Errorf("expected object, got %T", value)
+	}
+
+	o.count++
+	for _, k := range keys {
+		f := o.field(k)
+		f.present++
+		if err := i.observe(f, values[k]); err != nil {
+			return fmt.Errorf("column %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func (i *Inferrer) observe(f *field, value any) error {
+	switch v := value.(type) {
+	case nil:
+		f.kinds |= kindNull
+	case bool:
+		f.kinds |= kindBool
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			f.kinds |= kindInt
+		} else {
+			f.kinds |= kindFloat
+		}
+	case float64:
+		f.kinds |= numberKind(v)
+	case float32:
+		f.kinds |= numberKind(float64(v))
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		f.kinds |= kindInt
+	case uint:
+		f.kinds |= unsignedKind(uint64(v))
+	case uint64:
+		f.kinds |= unsignedKind(v)
+	case uintptr:
+		f.kinds |= unsignedKind(uint64(v))
+	case string:
+		if i.isDate(v) {
+			f.kinds |= kindDate
+		} else {
+			f.kinds |= kindString
+		}
+	case time.Time:
+		f.kinds |= kindDate
+	case orderedObject, map[string]any:
+		f.kinds |= kindObject
+		if f.object == nil {
+			f.object = newObject()
+		}
+		return i.observeObject(f.object, v)
+	case []any:
+		f.kinds |= kindArray
+		if f.elem == nil {
+			f.elem = &field{}
+		}
+		for idx, item := range v {
+			if err := i.observe(f.elem, item); err != nil {
+				return fmt.Errorf("element %d: %w", idx, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value type: %T", value)
+	}
+	return nil
+}
+
+func numberKind(v float64) kind {
+	if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+		return kindInt
+	}
+	return kindFloat
+}
+
+// unsignedKind returns kindInt for values fitting in an int64, like
+// numberKind.
+func unsignedKind(v uint64) kind {
+	if v <= math.MaxInt64 {
+		return kindInt
+	}
+	return kindFloat
+}
+
+func (i *Inferrer) isDate(s string) bool {
+	for _, layout := range i.DateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolver turns accumulated observations into schemas.
+type resolver struct {
+	names   map[string]int
+	columns []ColumnInfo
+}
+
+func (i *Inferrer) resolve(id string, root *object, records int) (*Result, error) {
+	r := &resolver{names: map[string]int{id: 1}}
+	schema, err := r.schema(id, "", root)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Schema: schema, Columns: r.columns, Records: records}, nil
+}
+
+func (r *resolver) schema(id, prefix string, o *object) (*domain.DataSchema, error) {
+	schema := &domain.DataSchema{ID: id, Columns: make([]domain.SchemaColumn, 0, len(o.order))}
+
+	for _, name := range o.order {
+		f := o.fields[name]
+		path := prefix + name
+
+		info := ColumnInfo{
+			Path:     path,
+			Nullable: f.kinds&kindNull != 0,
+			Optional: f.present < o.count,
+		}
+		// Reserve the position so that parents are listed before children.
+		idx := len(r.columns)
+		r.columns = append(r.columns, info)
+
+		var column domain.SchemaColumn
+		if f.kinds&kindArray != 0 {
+			if f.kinds&^(kindArray|kindNull) != 0 {
+				return nil, fmt.Errorf("column %s: conflicting types %s", path, f.kinds)
+			}
+			elemType, err := r.elementType(name, path, f.elem)
+			if err != nil {
+				return nil, err
+			}
+			column = domain.SchemaColumnArray{ID: name, RefSchema: elemType}
+		} else {
+			schemaType, err := r.singleType(name, path, f)
+			if err != nil {
+				return nil, err
+			}
+			column = domain.SchemaColumnSingle{ID: name, SchemaType: schemaType}
+		}
+
+		r.columns[idx].Type = column.GetType()
+		r.columns[idx].Array = column.IsArray()
+		schema.Columns = append(schema.Columns, column)
+	}
+
+	return schema, nil
+}
+
+func (r *resolver) elementType(name, path string, elem *field) (domain.SchemaType, error) {
+	if elem == nil || elem.kinds&^kindNull == 0 {
+		return domain.NativeTypeString, nil
+	}
+	if elem.kinds&kindArray != 0 {
+		return nil, fmt.Errorf("column %s: nested arrays are not supported", path)
+	}
+	return r.singleType(name, path, elem)
+}
+
+func (r *resolver) singleType(name, path string, f *field) (domain.SchemaType, error) {
+	kinds := f.kinds &^ kindNull
+
+	if kinds&kindObject != 0 {
+		if kinds != kindObject {
+			return nil, fmt.Errorf("column %s: conflicting types %s", path, kinds)
+		}
+		typeName := r.typeName(name)
+		sub, err := r.schema(typeName, path+".", f.object)
+		if err != nil {
+			return nil, err
+		}
+		return domain.CustomType{Name: typeName, Schema: sub}, nil
+	}
+
+	// Strings hold any scalar, so numbers and bools mixed with strings or
+	// dates widen to string.
+	if kinds&kindString != 0 || kinds&kindDate != 0 && kinds != kindDate {
+		return domain.NativeTypeString, nil
+	}
+
+	switch kinds {
+	case 0:
+		return domain.NativeTypeString, nil
+	case kindInt:
+		return domain.NativeTypeInt, nil
+	case kindFloat, kindInt | kindFloat:
+		return domain.NativeTypeFloat, nil
+	case kindDate:
+		return domain.NativeTypeDate, nil
+	case kindBool:
+		return domain.NativeTypeBool, nil
+	default:
+		return nil, fmt.Errorf("column %s: conflicting types %s", path, kinds)
+	}
+}
+
+// typeName derives a unique CustomType name from a column name, so that
+// "shipping_address" becomes "ShippingAddress".
+func (r *resolver) typeName(column string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(column, func(c rune) bool {
+		return c == '_' || c == '-' || c == ' ' || c == '.'
+	}) {
+		first, size := utf8.DecodeRuneInString(part)
+		b.WriteRune(unicode.ToUpper(first))
+		b.WriteString(part[size:])
+	}
+	name := b.String()
+	if name == "" {
+		name = "Type"
+	}
+
+	r.names[name]++
+	if n := r.names[name]; n > 1 {
+		return fmt.Sprintf("%s%d", name, n)
+	}
+	return name
+}
+
+func (k kind) String() string {
+	names := []string{"null", "bool", "int", "float", "date", "string", "object", "array"}
+	var parts []string
+	for i, name := range names {
+		if k&(1<<i) != 0 {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// decodeValue decodes the next JSON value, keeping the key order of objects.
+// If tok is a delimiter it is used as the first token of the value.
+func decodeValue(dec *json.Decoder, tok json.Token) (any, error) {
+	if _, ok := tok.(json.Delim); !ok {
+		var err error
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := orderedObject{values: make(map[string]any)}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			value, err := decodeValue(dec, nil)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := obj.values[key]; !dup {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		arr := make([]any, 0)
+		for dec.More() {
+			value, err := decodeValue(dec, nil)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+
+	return tok, nil
+}
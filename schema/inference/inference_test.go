@@ -0,0 +1,258 @@
+package inference
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inferJSON(t *testing.T, input string) *Result {
+	t.Helper()
+	result, err := NewInferrer().InferJSON("Product", strings.NewReader(input))
+	require.NoError(t, err)
+	return result
+}
+
+func TestInferrer_InferJSON(t *testing.T) {
+	t.Run("should infer native types in first appearance order", func(t *testing.T) {
+		result := inferJSON(t, `[
+			{"name": "Laptop", "stock": 5, "price": 999.99, "active": true, "created_at": "2024-01-15T10:30:00Z"}
+		]`)
+
+		assert.Equal(t, "Product", result.Schema.ID)
+		assert.Equal(t, []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+			domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+			domain.SchemaColumnSingle{ID: "created_at", SchemaType: domain.NativeTypeDate},
+		}, result.Schema.Columns)
+		assert.Equal(t, 1, result.Records)
+	})
+
+	t.Run("should widen int to float", func(t *testing.T) {
+		result := inferJSON(t, `[{"price": 10}, {"price": 10.5}]`)
+
+		assert.Equal(t, domain.NativeTypeFloat, result.Schema.Columns[0].GetType())
+	})
+
+	t.Run("should treat decimal notation as float", func(t *testing.T) {
+		result := inferJSON(t, `[{"price": 10.0}]`)
+
+		assert.Equal(t, domain.NativeTypeFloat, result.Schema.Columns[0].GetType())
+	})
+
+	t.Run("should widen date to string", func(t *testing.T) {
+		result := inferJSON(t, `[{"when": "2024-01-15T10:30:00Z"}, {"when": "yesterday"}]`)
+
+		assert.Equal(t, domain.NativeTypeString, result.Schema.Columns[0].GetType())
+	})
+
+	t.Run("should widen numbers and bools mixed with strings to string", func(t *testing.T) {
+		result := inferJSON(t, `[
+			{"sku": 1042, "code": "2024-01-15T10:30:00Z", "flag": true},
+			{"sku": "A-17", "code": 7.5, "flag": "unknown"}
+		]`)
+
+		assert.Equal(t, domain.NativeTypeString, result.Column("sku").Type)
+		assert.Equal(t, domain.NativeTypeString, result.Column("code").Type)
+		assert.Equal(t, domain.NativeTypeString, result.Column("flag").Type)
+	})
+
+	t.Run("should track nullability and missing columns", func(t *testing.T) {
+		result := inferJSON(t, `[
+			{"name": "Laptop", "description": null},
+			{"name": "Phone", "description": "Smart", "color": "black"}
+		]`)
+
+		assert.Equal(t, domain.NativeTypeString, result.Column("description").Type)
+		assert.True(t, result.Column("description").Nullable)
+		assert.False(t, result.Column("description").Optional)
+		assert.True(t, result.Column("color").Optional)
+		assert.False(t, result.Column("name").Nullable)
+		assert.False(t, result.Column("name").Optional)
+	})
+
+	t.Run("should default all-null column to nullable string", func(t *testing.T) {
+		result := inferJSON(t, `[{"note": null}]`)
+
+		assert.Equal(t, domain.NativeTypeString, result.Column("note").Type)
+		assert.True(t, result.Column("note").Nullable)
+	})
+
+	t.Run("should infer arrays", func(t *testing.T) {
+		result := inferJSON(t, `[{"tags": ["a", "b"], "scores": [1, 2.5], "empty": []}]`)
+
+		assert.Equal(t, domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString}, result.Schema.Columns[0])
+		assert.Equal(t, domain.SchemaColumnArray{ID: "scores", RefSchema: domain.NativeTypeFloat}, result.Schema.Columns[1])
+		assert.Equal(t, domain.SchemaColumnArray{ID: "empty", RefSchema: domain.NativeTypeString}, result.Schema.Columns[2])
+		assert.True(t, result.Column("tags").Array)
+	})
+
+	t.Run("should infer nested objects as custom types", func(t *testing.T) {
+		result := inferJSON(t, `[
+			{"shipping_address": {"city": "Paris"}},
+			{"shipping_address": {"city": "Lyon", "zip": 69000}}
+		]`)
+
+		custom, ok := result.Schema.Columns[0].GetType().(domain.CustomType)
+		require.True(t, ok)
+		assert.Equal(t, "ShippingAddress", custom.Name)
+		assert.Equal(t, "ShippingAddress", custom.Schema.ID)
+		assert.Equal(t, []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnSingle{ID: "zip", SchemaType: domain.NativeTypeInt},
+		}, custom.Schema.Columns)
+		assert.True(t, result.Column("shipping_address.zip").Optional)
+	})
+
+	t.Run("should infer arrays of objects", func(t *testing.T) {
+		result := inferJSON(t, `[{"items": [{"sku": "A"}, {"sku": "B", "qty": 2}]}]`)
+
+		col := result.Schema.Columns[0]
+		require.True(t, col.IsArray())
+		custom := col.GetType().(domain.CustomType)
+		assert.Equal(t, "Items", custom.Name)
+		assert.Len(t, custom.Schema.Columns, 2)
+		assert.True(t, result.Column("items.qty").Optional)
+	})
+
+	t.Run("should generate unique type names", func(t *testing.T) {
+		result := inferJSON(t, `[{"meta": {"a": 1}, "child": {"meta": {"b": 2}}}]`)
+
+		first := result.Schema.Columns[0].GetType().(domain.CustomType)
+		child := result.Schema.Columns[1].GetType().(domain.CustomType)
+		nested := child.Schema.Columns[0].GetType().(domain.CustomType)
+		assert.Equal(t, "Meta", first.Name)
+		assert.Equal(t, "Meta2", nested.Name)
+	})
+
+	t.Run("should capitalize non-ASCII type names", func(t *testing.T) {
+		result := inferJSON(t, `[{"éclair_box": {"a": 1}}]`)
+
+		custom := result.Schema.Columns[0].GetType().(domain.CustomType)
+		assert.Equal(t, "ÉclairBox", custom.Name)
+	})
+
+	t.Run("should accept a stream of objects", func(t *testing.T) {
+		result := inferJSON(t, "{\"id\": 1}\n{\"id\": 2}\n")
+
+		assert.Equal(t, 2, result.Records)
+		assert.Equal(t, domain.NativeTypeInt, result.Schema.Columns[0].GetType())
+	})
+
+	t.Run("should stop after MaxRecords", func(t *testing.T) {
+		inferrer := NewInferrer()
+		inferrer.MaxRecords = 1
+
+		result, err := inferrer.InferJSON("Product", strings.NewReader(`[{"id": 1}, {"id": "x"}]`))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Records)
+		assert.Equal(t, domain.NativeTypeInt, result.Schema.Columns[0].GetType())
+	})
+
+	t.Run("should not read past MaxRecords", func(t *testing.T) {
+		inferrer := NewInferrer()
+		inferrer.MaxRecords = 2
+
+		array, err := inferrer.InferJSON("Product", strings.NewReader(`[{"id": 1}, {"id": 2}, {"id": `))
+		require.NoError(t, err)
+		stream, err := inferrer.InferJSON("Product", strings.NewReader("{\"id\": 1}\n{\"id\": 2}\n{\"id\": "))
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, array.Records)
+		assert.Equal(t, 2, stream.Records)
+	})
+
+	t.Run("should use configured date layouts", func(t *testing.T) {
+		inferrer := NewInferrer()
+		inferrer.DateLayouts = []string{time.DateOnly}
+
+		result, err := inferrer.InferJSON("Event", strings.NewReader(`[{"day": "2024-01-15"}]`))
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.NativeTypeDate, result.Schema.Columns[0].GetType())
+	})
+
+	t.Run("should return error for conflicting types", func(t *testing.T) {
+		_, err := NewInferrer().InferJSON("Product", strings.NewReader(`[{"id": 1}, {"id": true}]`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "column id: conflicting types bool, int")
+	})
+
+	t.Run("should return error for object mixed with primitive", func(t *testing.T) {
+		_, err := NewInferrer().InferJSON("Product", strings.NewReader(`[{"a": {"b": 1}}, {"a": 1}]`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "conflicting types")
+	})
+
+	t.Run("should return error for nested arrays", func(t *testing.T) {
+		_, err := NewInferrer().InferJSON("Product", strings.NewReader(`[{"matrix": [[1]]}]`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "nested arrays are not supported")
+	})
+
+	t.Run("should return error for non-object records", func(t *testing.T) {
+		_, err := NewInferrer().InferJSON("Product", strings.NewReader(`[1, 2]`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "record 0: expected object")
+	})
+
+	t.Run("should return error for invalid JSON", func(t *testing.T) {
+		_, err := NewInferrer().InferJSON("Product", strings.NewReader(`[{"a": }]`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse JSON")
+	})
+}
+
+func TestInferrer_Infer(t *testing.T) {
+	t.Run("should infer from raw records", func(t *testing.T) {
+		records := []domain.RawRecord{
+			{Source: "a", Data: map[string]any{"name": "Laptop", "stock": float64(5), "seen": time.Now()}},
+			{Source: "b", Data: map[string]any{"name": "Phone", "stock": int64(3)}},
+		}
+
+		result, err := NewInferrer().Infer("Product", records)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Records)
+		assert.Equal(t, domain.NativeTypeString, result.Column("name").Type)
+		assert.Equal(t, domain.NativeTypeInt, result.Column("stock").Type)
+		assert.Equal(t, domain.NativeTypeDate, result.Column("seen").Type)
+		assert.True(t, result.Column("seen").Optional)
+	})
+
+	t.Run("should infer unsigned ints", func(t *testing.T) {
+		records := []domain.RawRecord{
+			{Data: map[string]any{"id": uint(7), "views": uint64(42), "hash": uint64(math.MaxUint64)}},
+		}
+
+		result, err := NewInferrer().Infer("Product", records)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.NativeTypeInt, result.Column("id").Type)
+		assert.Equal(t, domain.NativeTypeInt, result.Column("views").Type)
+		assert.Equal(t, domain.NativeTypeFloat, result.Column("hash").Type)
+	})
+
+	t.Run("should return error for unsupported values", func(t *testing.T) {
+		records := []domain.RawRecord{{Data: map[string]any{"ch": make(chan int)}}}
+
+		_, err := NewInferrer().Infer("Product", records)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported value type")
+	})
+}
@@ -0,0 +1,441 @@
+// Package jsonschema converts a domain.DataSchema to and from JSON Schema
+// draft 2020-12.
+//
+// The mapping is lossless for every schema this library can express:
+//
+//	NativeTypeString  {"type": ["string", "null"]}
+//	NativeTypeInt     {"type": ["integer", "null"]}
+//	NativeTypeFloat   {"type": ["number", "null"]}
+//	NativeTypeBool    {"type": ["boolean", "null"]}
+//	NativeTypeDate    {"type": ["string", "null"], "format": "date-time"}
+//	SchemaColumnArray {"type": ["array", "null"], "items": ...}
+//	CustomType        {"anyOf": [{"$ref": "#/$defs/<Name>"}, {"type": "null"}]}
+//
+// Columns keep their order, every column is nullable and optional as with
+// JSONSource, and the schema ID is carried by "title".
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+)
+
+// Draft is the JSON Schema dialect produced by Marshal.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// node is a JSON Schema object restricted to the keywords used by the mapping.
+type node struct {
+	Schema      string           `json:"$schema,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	Type        types            `json:"type,omitempty"`
+	Format      string           `json:"format,omitempty"`
+	Properties  *properties      `json:"properties,omitempty"`
+	Items       *node            `json:"items,omitempty"`
+	Ref         string           `json:"$ref,omitempty"`
+	AnyOf       []*node          `json:"anyOf,omitempty"`
+	OneOf       []*node          `json:"oneOf,omitempty"`
+	Defs        map[string]*node `json:"$defs,omitempty"`
+	Definitions map[string]*node `json:"definitions,omitempty"`
+}
+
+// types is the "type" keyword, either a single name or a list of names.
+type types []string
+
+func (t types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = types{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (t types) has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// properties is the "properties" keyword, keeping the order of its members.
+type properties struct {
+	names []string
+	nodes map[string]*node
+}
+
+func (p *properties) add(name string, n *node) {
+	if p.nodes == nil {
+		p.nodes = make(map[string]*node)
+	}
+	p.names = append(p.names, name)
+	p.nodes[name] = n
+}
+
+func (p properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range p.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.nodes[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (p *properties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("properties must be an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var n node
+		if err := dec.Decode(&n); err != nil {
+			return err
+		}
+		name := tok.(string)
+		if _, dup := p.nodes[name]; dup {
+			p.nodes[name] = &n
+			continue
+		}
+		p.add(name, &n)
+	}
+	return nil
+}
+
+// Marshal exports the schema as an indented JSON Schema document.
+func Marshal(schema *domain.DataSchema) ([]byte, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("cannot export nil schema")
+	}
+
+	e := &exporter{defs: make(map[string]*node), schemas: make(map[string]*domain.DataSchema)}
+	root, err := e.object(schema)
+	if err != nil {
+		return nil, err
+	}
+	root.Schema = Draft
+	if len(e.defs) > 0 {
+		root.Defs = e.defs
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type exporter struct {
+	defs    map[string]*node
+	schemas map[string]*domain.DataSchema
+}
+
+func (e *exporter) object(schema *domain.DataSchema) (*node, error) {
+	n := &node{Title: schema.ID, Type: types{"object"}, Properties: &properties{}}
+	for _, col := range schema.Columns {
+		prop, err := e.column(col)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.GetID(), err)
+		}
+		n.Properties.add(col.GetID(), prop)
+	}
+	return n, nil
+}
+
+func (e *exporter) column(col domain.SchemaColumn) (*node, error) {
+	if col.IsArray() {
+		items, err := e.value(col.GetType())
+		if err != nil {
+			return nil, err
+		}
+		return &node{Type: types{"array", "null"}, Items: items}, nil
+	}
+
+	n, err := e.value(col.GetType())
+	if err != nil {
+		return nil, err
+	}
+	if n.Ref != "" {
+		return &node{AnyOf: []*node{n, {Type: types{"null"}}}}, nil
+	}
+	n.Type = append(n.Type, "null")
+	return n, nil
+}
+
+// value returns the non-nullable node for a single value of schemaType.
+func (e *exporter) value(schemaType domain.SchemaType) (*node, error) {
+	switch t := schemaType.(type) {
+	case domain.NativeType:
+		switch t {
+		case domain.NativeTypeString:
+			return &node{Type: types{"string"}}, nil
+		case domain.NativeTypeInt:
+			return &node{Type: types{"integer"}}, nil
+		case domain.NativeTypeFloat:
+			return &node{Type: types{"number"}}, nil
+		case domain.NativeTypeBool:
+			return &node{Type: types{"boolean"}}, nil
+		case domain.NativeTypeDate:
+			return &node{Type: types{"string"}, Format: "date-time"}, nil
+		}
+		return nil, fmt.Errorf("unknown native type: %s", t)
+
+	case domain.CustomType:
+		if t.Schema == nil {
+			return nil, fmt.Errorf("custom type %s has no schema", t.Name)
+		}
+		if err := e.define(t); err != nil {
+			return nil, err
+		}
+		return &node{Ref: "#/$defs/" + pointerEscaper.Replace(t.Name)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported schema type: %T", schemaType)
+}
+
+func (e *exporter) define(t domain.CustomType) error {
+	if existing, ok := e.schemas[t.Name]; ok {
+		if existing != t.Schema {
+			return fmt.Errorf("custom type %s refers to two different schemas", t.Name)
+		}
+		return nil
+	}
+
+	// Register before descending so that recursive types terminate.
+	e.schemas[t.Name] = t.Schema
+	def, err := e.object(t.Schema)
+	if err != nil {
+		return fmt.Errorf("custom type %s: %w", t.Name, err)
+	}
+	e.defs[t.Name] = def
+	return nil
+}
+
+// Unmarshal imports a JSON Schema document into a DataSchema.
+//
+// Besides the documents produced by Marshal, it accepts the common shapes
+// of hand-written schemas: single types, inline nested objects, "definitions"
+// and "oneOf" alternatives with "null".
+func Unmarshal(data []byte) (*domain.DataSchema, error) {
+	var root node
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON Schema: %w", err)
+	}
+
+	defs := root.Defs
+	if defs == nil {
+		defs = root.Definitions
+	}
+
+	i := &importer{defs: defs, schemas: make(map[string]*domain.DataSchema)}
+	id := root.Title
+	if id == "" {
+		id = "Root"
+	}
+	return i.object(id, &root)
+}
+
+type importer struct {
+	defs    map[string]*node
+	schemas map[string]*domain.DataSchema
+}
+
+func (i *importer) object(id string, n *node) (*domain.DataSchema, error) {
+	schema := &domain.DataSchema{ID: id}
+	if err := i.fill(schema, n); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (i *importer) fill(schema *domain.DataSchema, n *node) error {
+	if len(n.Type) > 0 && !n.Type.has("object") {
+		return fmt.Errorf("schema %s: expected object type, got %s", schema.ID, strings.Join(n.Type, ", "))
+	}
+	if n.Properties == nil {
+		return nil
+	}
+
+	for _, name := range n.Properties.names {
+		col, err := i.column(name, n.Properties.nodes[name])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", name, err)
+		}
+		schema.Columns = append(schema.Columns, col)
+	}
+	return nil
+}
+
+func (i *importer) column(name string, n *node) (domain.SchemaColumn, error) {
+	n, err := nonNull(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Ref == "" && n.Type.has("array") {
+		if n.Items == nil {
+			return nil, fmt.Errorf("array has no items")
+		}
+		items, err := nonNull(n.Items)
+		if err != nil {
+			return nil, err
+		}
+		if items.Ref == "" && items.Type.has("array") {
+			return nil, fmt.Errorf("nested arrays are not supported")
+		}
+		elemType, err := i.value(name, items)
+		if err != nil {
+			return nil, err
+		}
+		return domain.SchemaColumnArray{ID: name, RefSchema: elemType}, nil
+	}
+
+	schemaType, err := i.value(name, n)
+	if err != nil {
+		return nil, err
+	}
+	return domain.SchemaColumnSingle{ID: name, SchemaType: schemaType}, nil
+}
+
+// nonNull strips the null alternative from a node, so that both
+// {"anyOf": [X, {"type": "null"}]} and {"type": ["string", "null"]} yield X.
+func nonNull(n *node) (*node, error) {
+	alternatives := n.AnyOf
+	if alternatives == nil {
+		alternatives = n.OneOf
+	}
+	if alternatives == nil {
+		return n, nil
+	}
+
+	var result *node
+	for _, alt := range alternatives {
+		if len(alt.Type) == 1 && alt.Type[0] == "null" {
+			continue
+		}
+		if result != nil {
+			return nil, fmt.Errorf("union types are not supported")
+		}
+		result = alt
+	}
+	if result == nil {
+		return nil, fmt.Errorf("column only allows null")
+	}
+	return result, nil
+}
+
+func (i *importer) value(name string, n *node) (domain.SchemaType, error) {
+	if n.Ref != "" {
+		return i.ref(n.Ref)
+	}
+
+	var kinds []string
+	for _, t := range n.Type {
+		if t != "null" {
+			kinds = append(kinds, t)
+		}
+	}
+	if len(kinds) != 1 {
+		return nil, fmt.Errorf("expected exactly one non-null type, got %q", []string(n.Type))
+	}
+
+	switch kinds[0] {
+	case "string":
+		if n.Format == "date-time" {
+			return domain.NativeTypeDate, nil
+		}
+		return domain.NativeTypeString, nil
+	case "integer":
+		return domain.NativeTypeInt, nil
+	case "number":
+		return domain.NativeTypeFloat, nil
+	case "boolean":
+		return domain.NativeTypeBool, nil
+	case "object":
+		typeName := n.Title
+		if typeName == "" {
+			typeName = name
+		}
+		schema, err := i.object(typeName, n)
+		if err != nil {
+			return nil, err
+		}
+		return domain.CustomType{Name: typeName, Schema: schema}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type: %s", kinds[0])
+}
+
+// pointerEscaper and pointerUnescaper escape the definition names of
+// references as JSON pointer tokens (RFC 6901).
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func (i *importer) ref(ref string) (domain.SchemaType, error) {
+	var name string
+	switch {
+	case strings.HasPrefix(ref, "#/$defs/"):
+		name = strings.TrimPrefix(ref, "#/$defs/")
+	case strings.HasPrefix(ref, "#/definitions/"):
+		name = strings.TrimPrefix(ref, "#/definitions/")
+	default:
+		return nil, fmt.Errorf("unsupported reference: %s", ref)
+	}
+	if strings.Contains(name, "/") {
+		return nil, fmt.Errorf("unsupported reference: %s", ref)
+	}
+	name = pointerUnescaper.Replace(name)
+
+	if schema, ok := i.schemas[name]; ok {
+		return domain.CustomType{Name: name, Schema: schema}, nil
+	}
+
+	def, ok := i.defs[name]
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference: %s", ref)
+	}
+
+	id := def.Title
+	if id == "" {
+		id = name
+	}
+	// Register before filling so that recursive definitions terminate.
+	schema := &domain.DataSchema{ID: id}
+	i.schemas[name] = schema
+	if err := i.fill(schema, def); err != nil {
+		return nil, fmt.Errorf("definition %s: %w", name, err)
+	}
+	return domain.CustomType{Name: name, Schema: schema}, nil
+}
@@ -0,0 +1,370 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	t.Run("should export native types in column order", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Product",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "stock", SchemaType: domain.NativeTypeInt},
+				domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+				domain.SchemaColumnSingle{ID: "active", SchemaType: domain.NativeTypeBool},
+				domain.SchemaColumnSingle{ID: "created_at", SchemaType: domain.NativeTypeDate},
+				domain.SchemaColumnArray{ID: "tags", RefSchema: domain.NativeTypeString},
+			},
+		}
+
+		data, err := Marshal(schema)
+
+		require.NoError(t, err)
+		assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Product",
+  "type": "object",
+  "properties": {
+    "name": {
+      "type": [
+        "string",
+        "null"
+      ]
+    },
+    "stock": {
+      "type": [
+        "integer",
+        "null"
+      ]
+    },
+    "price": {
+      "type": [
+        "number",
+        "null"
+      ]
+    },
+    "active": {
+      "type": [
+        "boolean",
+        "null"
+      ]
+    },
+    "created_at": {
+      "type": [
+        "string",
+        "null"
+      ],
+      "format": "date-time"
+    },
+    "tags": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "string"
+      }
+    }
+  }
+}`, string(data))
+	})
+
+	t.Run("should export custom types as references", func(t *testing.T) {
+		address := &domain.DataSchema{
+			ID: "Address",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "city", SchemaType: domain.NativeTypeString},
+			},
+		}
+		schema := &domain.DataSchema{
+			ID: "User",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "Address", Schema: address}},
+			},
+		}
+
+		data, err := Marshal(schema)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"title": "User",
+			"type": "object",
+			"properties": {
+				"address": {"anyOf": [{"$ref": "#/$defs/Address"}, {"type": "null"}]}
+			},
+			"$defs": {
+				"Address": {
+					"title": "Address",
+					"type": "object",
+					"properties": {"city": {"type": ["string", "null"]}}
+				}
+			}
+		}`, string(data))
+	})
+
+	t.Run("should return error for custom type without schema", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "User",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "address", SchemaType: domain.CustomType{Name: "Address"}},
+			},
+		}
+
+		_, err := Marshal(schema)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "column address: custom type Address has no schema")
+	})
+
+	t.Run("should return error for conflicting custom type names", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "User",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "a", SchemaType: domain.CustomType{Name: "Address", Schema: &domain.DataSchema{ID: "A"}}},
+				domain.SchemaColumnSingle{ID: "b", SchemaType: domain.CustomType{Name: "Address", Schema: &domain.DataSchema{ID: "B"}}},
+			},
+		}
+
+		_, err := Marshal(schema)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "two different schemas")
+	})
+
+	t.Run("should return error for unknown native type", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Test",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "field", SchemaType: domain.NativeType("unknown")},
+			},
+		}
+
+		_, err := Marshal(schema)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown native type")
+	})
+
+	t.Run("should return error for nil schema", func(t *testing.T) {
+		_, err := Marshal(nil)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Run("should round-trip nested and array custom types", func(t *testing.T) {
+		item := &domain.DataSchema{
+			ID: "Item",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "sku", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "price", SchemaType: domain.NativeTypeFloat},
+			},
+		}
+		customer := &domain.DataSchema{
+			ID: "Customer",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+				domain.SchemaColumnSingle{ID: "since", SchemaType: domain.NativeTypeDate},
+			},
+		}
+		schema := &domain.DataSchema{
+			ID: "Order",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt},
+				domain.SchemaColumnSingle{ID: "paid", SchemaType: domain.NativeTypeBool},
+				domain.SchemaColumnSingle{ID: "customer", SchemaType: domain.CustomType{Name: "Customer", Schema: customer}},
+				domain.SchemaColumnArray{ID: "items", RefSchema: domain.CustomType{Name: "Item", Schema: item}},
+				domain.SchemaColumnArray{ID: "gifts", RefSchema: domain.CustomType{Name: "Item", Schema: item}},
+				domain.SchemaColumnArray{ID: "scores", RefSchema: domain.NativeTypeInt},
+			},
+		}
+
+		data, err := Marshal(schema)
+		require.NoError(t, err)
+
+		imported, err := Unmarshal(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, schema, imported)
+		assert.Same(t,
+			imported.Columns[3].GetType().(domain.CustomType).Schema,
+			imported.Columns[4].GetType().(domain.CustomType).Schema)
+	})
+
+	t.Run("should keep custom type name distinct from schema ID", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Event",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "cpe", SchemaType: domain.CustomType{Name: "CPE", Schema: &domain.DataSchema{ID: "CPEv23"}}},
+			},
+		}
+
+		data, err := Marshal(schema)
+		require.NoError(t, err)
+
+		imported, err := Unmarshal(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, schema, imported)
+	})
+
+	t.Run("should escape type names in references", func(t *testing.T) {
+		schema := &domain.DataSchema{
+			ID: "Event",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "unit", SchemaType: domain.CustomType{Name: "km/h~v2", Schema: &domain.DataSchema{ID: "Speed"}}},
+			},
+		}
+
+		data, err := Marshal(schema)
+		require.NoError(t, err)
+		imported, err := Unmarshal(data)
+
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"$ref": "#/$defs/km~1h~0v2"`)
+		assert.Contains(t, string(data), `"km/h~v2": {`)
+		assert.Equal(t, schema, imported)
+	})
+
+	t.Run("should round-trip recursive custom types", func(t *testing.T) {
+		node := &domain.DataSchema{ID: "Node"}
+		node.Columns = []domain.SchemaColumn{
+			domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+			domain.SchemaColumnArray{ID: "children", RefSchema: domain.CustomType{Name: "Node", Schema: node}},
+		}
+		schema := &domain.DataSchema{
+			ID: "Tree",
+			Columns: []domain.SchemaColumn{
+				domain.SchemaColumnSingle{ID: "root", SchemaType: domain.CustomType{Name: "Node", Schema: node}},
+			},
+		}
+
+		data, err := Marshal(schema)
+		require.NoError(t, err)
+
+		imported, err := Unmarshal(data)
+
+		require.NoError(t, err)
+		root := imported.Columns[0].GetType().(domain.CustomType).Schema
+		children := root.Columns[1].GetType().(domain.CustomType).Schema
+		assert.Same(t, root, children)
+		assert.Equal(t, "Node", root.ID)
+	})
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Run("should import hand-written schema", func(t *testing.T) {
+		data := []byte(`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"title": "User",
+			"type": "object",
+			"required": ["id"],
+			"properties": {
+				"id": {"type": "integer"},
+				"email": {"type": "string", "format": "email"},
+				"address": {
+					"type": "object",
+					"properties": {"city": {"type": "string"}}
+				},
+				"manager": {"oneOf": [{"type": "null"}, {"$ref": "#/definitions/Person"}]}
+			},
+			"definitions": {
+				"Person": {"type": "object", "properties": {"name": {"type": "string"}}}
+			}
+		}`)
+
+		schema, err := Unmarshal(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, "User", schema.ID)
+		require.Len(t, schema.Columns, 4)
+		assert.Equal(t, domain.SchemaColumnSingle{ID: "id", SchemaType: domain.NativeTypeInt}, schema.Columns[0])
+		assert.Equal(t, domain.SchemaColumnSingle{ID: "email", SchemaType: domain.NativeTypeString}, schema.Columns[1])
+
+		address := schema.Columns[2].GetType().(domain.CustomType)
+		assert.Equal(t, "address", address.Name)
+		assert.Equal(t, "city", address.Schema.Columns[0].GetID())
+
+		manager := schema.Columns[3].GetType().(domain.CustomType)
+		assert.Equal(t, "Person", manager.Name)
+		assert.Equal(t, "Person", manager.Schema.ID)
+	})
+
+	t.Run("should default root ID", func(t *testing.T) {
+		schema, err := Unmarshal([]byte(`{"type": "object"}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "Root", schema.ID)
+		assert.Empty(t, schema.Columns)
+	})
+
+	t.Run("should return error for invalid JSON", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse JSON Schema")
+	})
+
+	t.Run("should return error for non-object root", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"type": "array"}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "expected object type")
+	})
+
+	t.Run("should return error for unresolved reference", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"properties": {"a": {"$ref": "#/$defs/Missing"}}}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "column a: unresolved reference")
+	})
+
+	t.Run("should return error for external reference", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"properties": {"a": {"$ref": "other.json"}}}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported reference")
+	})
+
+	t.Run("should return error for nested reference", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"$defs": {"a": {"properties": {}}}, "properties": {"a": {"$ref": "#/$defs/a/properties"}}}`))
+
+		assert.ErrorContains(t, err, "unsupported reference: #/$defs/a/properties")
+	})
+
+	t.Run("should return error for union types", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"properties": {"a": {"type": ["string", "integer"]}}}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "expected exactly one non-null type")
+	})
+
+	t.Run("should return error for array without items", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"properties": {"a": {"type": "array"}}}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "array has no items")
+	})
+
+	t.Run("should return error for nested arrays", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"properties": {"a": {"type": "array", "items": {"type": "array", "items": {"type": "string"}}}}}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "nested arrays are not supported")
+	})
+
+	t.Run("should return error for null-only column", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"properties": {"a": {"anyOf": [{"type": "null"}]}}}`))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "only allows null")
+	})
+}
@@ -0,0 +1,354 @@
+// Package server exposes pipelines over HTTP.
+//
+// A Handler runs a pipeline on the body of each request and responds with
+// the records stored, in the format asked by the Accept header. A Server
+// routes requests to named handlers, and can also trigger a run of their
+// configured pipeline:
+//
+//	srv := server.NewServer()
+//	srv.Register("orders", server.NewHandler(orders, schema))
+//	http.ListenAndServe(":8080", srv)
+//
+//	// POST /pipelines/orders       transforms the request body
+//	// POST /pipelines/orders/run   runs the orders pipeline as configured
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/compression"
+	"github.com/spaghettifactory-oss/pipeforge/adapters/source"
+	"github.com/spaghettifactory-oss/pipeforge/adapters/store"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// DefaultMaxBodyBytes is the request body size limit of a Handler whose
+// MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = 10 << 20
+
+// Media types of the request and response bodies.
+const (
+	MediaTypeJSON   = "application/json"
+	MediaTypeNDJSON = "application/x-ndjson"
+	MediaTypeCSV    = "text/csv"
+)
+
+// format reads and writes the records of a body.
+type format struct {
+	mediaType string
+	source    func(r io.Reader, schema *domain.DataSchema) ports.SourcePort
+	store     func(w io.Writer) ports.StorePort
+}
+
+var formats = []format{
+	{
+		mediaType: MediaTypeJSON,
+		source: func(r io.Reader, schema *domain.DataSchema) ports.SourcePort {
+			src := source.NewJSONSourceFromReader(r, schema)
+			src.Uncompressed = true
+			return src
+		},
+		store: func(w io.Writer) ports.StorePort {
+			return store.NewJSONStoreToWriter(w)
+		},
+	},
+	{
+		mediaType: MediaTypeNDJSON,
+		source: func(r io.Reader, schema *domain.DataSchema) ports.SourcePort {
+			src := source.NewNDJSONSourceFromReader(r, schema)
+			src.Uncompressed = true
+			return src
+		},
+		store: func(w io.Writer) ports.StorePort {
+			return store.NewNDJSONStoreToWriter(w)
+		},
+	},
+	{
+		mediaType: MediaTypeCSV,
+		source: func(r io.Reader, schema *domain.DataSchema) ports.SourcePort {
+			src := source.NewCSVSourceFromReader(r, schema)
+			src.Uncompressed = true
+			return src
+		},
+		store: func(w io.Writer) ports.StorePort {
+			return store.NewCSVStoreToWriter(w)
+		},
+	},
+}
+
+// contentEncodings maps Content-Encoding values to the codecs decoding
+// them. Bodies are only decompressed when they declare their encoding.
+var contentEncodings = map[string]compression.Compression{
+	"gzip":   compression.Gzip,
+	"x-gzip": compression.Gzip,
+	"zstd":   compression.Zstd,
+}
+
+// lookup returns the format of a media type, nil if it is not supported.
+// "application/ndjson" is accepted for NDJSON.
+func lookup(mediaType string) *format {
+	if mediaType == "application/ndjson" {
+		mediaType = MediaTypeNDJSON
+	}
+	for i := range formats {
+		if formats[i].mediaType == mediaType {
+			return &formats[i]
+		}
+	}
+	return nil
+}
+
+// Handler runs Pipeline on the records of each POST request body and
+// responds with the records stored.
+//
+// The body is read as JSON, NDJSON or CSV according to its Content-Type,
+// JSON when it has none, and the response is written in the format
+// preferred by the Accept header, the request format when any is accepted.
+// Failures are responded as an ErrorResponse.
+//
+// Bodies are decompressed only when their Content-Encoding is gzip or zstd,
+// never by sniffing their bytes, and MaxBodyBytes limits their size both
+// before and after decompression.
+//
+// Each request runs a copy of Pipeline whose Source and Store are replaced,
+// so that requests run concurrently: its transform must be safe for
+// concurrent use.
+type Handler struct {
+	Pipeline     *pipeline.DataPipeline // Pipeline run for each request, its Source and Store being replaced
+	Schema       *domain.DataSchema     // Schema of the request body
+	MaxBodyBytes int64                  // Request body size limit, compressed and decompressed, DefaultMaxBodyBytes if zero, unlimited if negative
+	Logger       *slog.Logger           // Receives the requests served and failed, nil disables logging
+}
+
+// NewHandler creates a Handler running p on request bodies of the given
+// schema.
+func NewHandler(p *pipeline.DataPipeline, schema *domain.DataSchema) *Handler {
+	return &Handler{Pipeline: p, Schema: schema}
+}
+
+// ServeHTTP runs the pipeline on the request body.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, &ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	in, err := requestFormat(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnsupportedMediaType, &ErrorResponse{Error: err.Error()})
+		return
+	}
+	out := negotiate(r.Header.Get("Accept"), in)
+	if out == nil {
+		writeJSON(w, http.StatusNotAcceptable, &ErrorResponse{Error: "no acceptable response format"})
+		return
+	}
+
+	body := r.Body
+	limit := h.maxBodyBytes()
+	if limit > 0 {
+		body = http.MaxBytesReader(w, body, limit)
+	}
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		codec, ok := compression.Lookup(contentEncodings[strings.ToLower(encoding)])
+		if !ok || codec.NewReader == nil {
+			writeJSON(w, http.StatusUnsupportedMediaType, &ErrorResponse{Error: fmt.Sprintf("unsupported content encoding %s", encoding)})
+			return
+		}
+		decoded, err := codec.NewReader(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, &ErrorResponse{Error: fmt.Sprintf("invalid %s body: %v", encoding, err)})
+			return
+		}
+		defer decoded.Close()
+		// The limit applies to the decompressed body as well.
+		body = decoded
+		if limit > 0 {
+			body = http.MaxBytesReader(w, body, limit)
+		}
+	}
+
+	var buf bytes.Buffer
+	run := *h.Pipeline
+	run.Source = in.source(body, h.Schema)
+	run.Store = out.store(&buf)
+	result, err := run.RunWithResult()
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	logging.Or(h.Logger).Debug("served request", "path", r.URL.Path, "records", result.Count(), "format", out.mediaType)
+	w.Header().Set("Content-Type", out.mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (h *Handler) maxBodyBytes() int64 {
+	if h.MaxBodyBytes == 0 {
+		return DefaultMaxBodyBytes
+	}
+	return h.MaxBodyBytes
+}
+
+// internalError is the message of the errors hidden from responses.
+const internalError = "internal server error"
+
+// fail responds the error of a run.
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusOf(err)
+	response := newErrorResponse(err)
+	if status == http.StatusInternalServerError {
+		logging.Or(h.Logger).Error("request failed", "path", r.URL.Path, "error", err)
+		response.Error = internalError
+	} else {
+		logging.Or(h.Logger).Debug("request rejected", "path", r.URL.Path, "status", status, "error", err)
+	}
+	writeJSON(w, status, response)
+}
+
+// requestFormat returns the format of the request body.
+func requestFormat(r *http.Request) (*format, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return lookup(MediaTypeJSON), nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q", contentType)
+	}
+	f := lookup(mediaType)
+	if f == nil {
+		return nil, fmt.Errorf("unsupported content type %s", mediaType)
+	}
+	return f, nil
+}
+
+// negotiate returns the format preferred by an Accept header, def when the
+// header is empty or accepts any type, nil when no format is acceptable.
+func negotiate(accept string, def *format) *format {
+	if strings.TrimSpace(accept) == "" {
+		return def
+	}
+
+	type ranged struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []ranged
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, ranged{mediaType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, rng := range ranges {
+		if rng.mediaType == "*/*" {
+			return def
+		}
+		if prefix, ok := strings.CutSuffix(rng.mediaType, "/*"); ok {
+			if strings.HasPrefix(def.mediaType, prefix+"/") {
+				return def
+			}
+			for i := range formats {
+				if strings.HasPrefix(formats[i].mediaType, prefix+"/") {
+					return &formats[i]
+				}
+			}
+			continue
+		}
+		if f := lookup(rng.mediaType); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// ErrorResponse is the JSON body of a failed request.
+type ErrorResponse struct {
+	Error  string `json:"error"`            // Error message
+	Stage  string `json:"stage,omitempty"`  // Pipeline stage that failed
+	Record *int   `json:"record,omitempty"` // Index of the record that failed, from 0
+	Column string `json:"column,omitempty"` // Path of the column that failed
+}
+
+func newErrorResponse(err error) *ErrorResponse {
+	response := &ErrorResponse{Error: err.Error()}
+	var stageErr *domain.StageError
+	if errors.As(err, &stageErr) {
+		response.Stage = stageErr.Stage
+	}
+	var recordErr *domain.RecordError
+	if errors.As(err, &recordErr) {
+		index := recordErr.Index
+		response.Record = &index
+	}
+	var schemaErr *domain.SchemaError
+	if errors.As(err, &schemaErr) {
+		response.Column = schemaErr.Column
+	}
+	return response
+}
+
+// statusOf returns the status code of a failed run: 413 for a body over the
+// size limit, 422 for records not matching the schema, 400 for a body that
+// could not be read and 500 otherwise.
+func statusOf(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	var recordErr *domain.RecordError
+	var schemaErr *domain.SchemaError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var csvErr *csv.ParseError
+	var stageErr *domain.StageError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &recordErr), errors.As(err, &schemaErr):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &csvErr),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return http.StatusBadRequest
+	case errors.As(err, &stageErr) && stageErr.Stage == pipeline.StageLoad:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", MediaTypeJSON)
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
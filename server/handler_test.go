@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var productSchema = &domain.DataSchema{
+	ID: "Product",
+	Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+		domain.SchemaColumnSingle{ID: "quantity", SchemaType: domain.NativeTypeInt},
+	},
+}
+
+func newProductHandler() *Handler {
+	return NewHandler(&pipeline.DataPipeline{Transform: transform.NewAddIntTransform("quantity", 1)}, productSchema)
+}
+
+func post(h http.Handler, path, contentType, accept, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func postEncoded(h http.Handler, encoding, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func gzipped(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	assert.Equal(t, MediaTypeJSON, rec.Header().Get("Content-Type"))
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	t.Run("should transform a JSON body and respond JSON", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "", "", `[{"name": "Laptop", "quantity": 5}]`)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MediaTypeJSON, rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `[{"name": "Laptop", "quantity": 6}]`, rec.Body.String())
+	})
+
+	t.Run("should respond in the request format by default", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "application/ndjson", "*/*", "{\"name\": \"Laptop\", \"quantity\": 5}\n")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MediaTypeNDJSON, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "{\"name\":\"Laptop\",\"quantity\":6}\n", rec.Body.String())
+	})
+
+	t.Run("should respond in the preferred accepted format", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "text/csv; charset=utf-8", "application/json;q=0.5, text/csv", "name,quantity\nLaptop,5\n")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MediaTypeCSV, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "name,quantity\nLaptop,6\n", rec.Body.String())
+	})
+
+	t.Run("should reject unsupported content types", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "application/xml", "", "<products/>")
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+		assert.Equal(t, "unsupported content type application/xml", decodeError(t, rec).Error)
+	})
+
+	t.Run("should reject requests accepting no supported format", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "", "application/xml, text/csv;q=0", "[]")
+
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	})
+
+	t.Run("should reject other methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		newProductHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+	})
+
+	t.Run("should reject bodies over the size limit", func(t *testing.T) {
+		h := newProductHandler()
+		h.MaxBodyBytes = 10
+
+		rec := post(h, "/", "", "", `[{"name": "Laptop", "quantity": 5}]`)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Equal(t, "load", decodeError(t, rec).Stage)
+	})
+
+	t.Run("should limit the decompressed size of gzip bodies", func(t *testing.T) {
+		h := newProductHandler()
+		h.MaxBodyBytes = 1000
+		body := "[" + strings.Repeat(`{"name": "Laptop", "quantity": 5},`, 1000) + `{"name": "Phone"}]`
+		compressed := gzipped(t, body)
+		require.Less(t, len(compressed), 1000)
+
+		rec := postEncoded(h, "gzip", compressed)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("should decompress bodies declaring their encoding only", func(t *testing.T) {
+		compressed := gzipped(t, `[{"name": "Laptop", "quantity": 5}]`)
+
+		rec := postEncoded(newProductHandler(), "gzip", compressed)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"name": "Laptop", "quantity": 6}]`, rec.Body.String())
+
+		rec = postEncoded(newProductHandler(), "", compressed)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("should reject unsupported content encodings", func(t *testing.T) {
+		rec := postEncoded(newProductHandler(), "br", "[]")
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+		assert.Equal(t, "unsupported content encoding br", decodeError(t, rec).Error)
+	})
+
+	t.Run("should report the record and column not matching the schema", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "", "", `[{"name": "Laptop"}, {"quantity": "many"}]`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		response := decodeError(t, rec)
+		assert.Equal(t, "load", response.Stage)
+		require.NotNil(t, response.Record)
+		assert.Equal(t, 1, *response.Record)
+		assert.Equal(t, "quantity", response.Column)
+		assert.Contains(t, response.Error, "expected number, got string")
+	})
+
+	t.Run("should reject malformed bodies", func(t *testing.T) {
+		rec := post(newProductHandler(), "/", "", "", `[{"name": `)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "load", decodeError(t, rec).Stage)
+	})
+
+	t.Run("should hide the message of internal errors", func(t *testing.T) {
+		h := NewHandler(&pipeline.DataPipeline{Transform: transform.ErrorTransform{}}, productSchema)
+
+		rec := post(h, "/", "", "", `[]`)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		response := decodeError(t, rec)
+		assert.Equal(t, "internal server error", response.Error)
+		assert.Equal(t, "transform", response.Stage)
+	})
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+)
+
+// Server routes requests to the handlers registered by name:
+//
+//	POST /pipelines/{name}       runs the handler on the request body
+//	POST /pipelines/{name}/run   runs the handler's pipeline as configured,
+//	                             responding with its pipeline.RunReport
+//
+// The errors of a failed run are logged, and replaced in its report by
+// "internal server error".
+//
+// A pipeline runs once at a time through the run endpoint: a run requested
+// while one is in progress gets 409 Conflict. Use NewServer to create one.
+type Server struct {
+	Logger *slog.Logger // Receives the runs triggered and failed, nil disables logging
+
+	mu       sync.RWMutex
+	handlers map[string]*registered
+	mux      *http.ServeMux
+}
+
+// registered is a handler and the lock of its pipeline runs.
+type registered struct {
+	handler *Handler
+	running sync.Mutex
+}
+
+// NewServer creates a Server with no pipelines.
+func NewServer() *Server {
+	s := &Server{handlers: map[string]*registered{}, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /pipelines/{name}", s.transform)
+	s.mux.HandleFunc("POST /pipelines/{name}/run", s.run)
+	return s
+}
+
+// Register serves h under name, replacing the handler registered before.
+func (s *Server) Register(name string, h *Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = &registered{handler: h}
+}
+
+// ServeHTTP routes the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) transform(w http.ResponseWriter, r *http.Request) {
+	reg := s.lookup(w, r)
+	if reg == nil {
+		return
+	}
+	reg.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request) {
+	reg := s.lookup(w, r)
+	if reg == nil {
+		return
+	}
+	name := r.PathValue("name")
+	if !reg.running.TryLock() {
+		writeJSON(w, http.StatusConflict, &ErrorResponse{Error: fmt.Sprintf("pipeline %s is already running", name)})
+		return
+	}
+	defer reg.running.Unlock()
+
+	logging.Or(s.Logger).Info("pipeline run triggered", "pipeline", name)
+	_, report, err := reg.handler.Pipeline.RunWithReport()
+	if err != nil {
+		logging.Or(s.Logger).Error("pipeline run failed", "pipeline", name, "error", err)
+		writeJSON(w, http.StatusInternalServerError, maskErrors(report))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// maskErrors replaces the error messages of a failed run, which may hold
+// file paths or internal details, like Handler hides the errors of 500
+// responses. The stages that failed are still reported.
+func maskErrors(report *pipeline.RunReport) *pipeline.RunReport {
+	masked := *report
+	masked.Error = internalError
+	masked.Stages = make([]pipeline.StageReport, len(report.Stages))
+	for i, stage := range report.Stages {
+		if stage.Error != "" {
+			stage.Error = internalError
+		}
+		if stage.Attempts != nil {
+			stage.Attempts = append([]ports.Attempt(nil), stage.Attempts...)
+			for j := range stage.Attempts {
+				if stage.Attempts[j].Error != "" {
+					stage.Attempts[j].Error = internalError
+				}
+			}
+		}
+		masked.Stages[i] = stage
+	}
+	return &masked
+}
+
+// lookup returns the handler named by the request, or responds 404.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *registered {
+	name := r.PathValue("name")
+	s.mu.RLock()
+	reg := s.handlers[name]
+	s.mu.RUnlock()
+	if reg == nil {
+		writeJSON(w, http.StatusNotFound, &ErrorResponse{Error: fmt.Sprintf("unknown pipeline %s", name)})
+	}
+	return reg
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/logger"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/source"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/store"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSource loads an empty set once released.
+type blockingSource struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *blockingSource) Load() (*domain.RecordSet, error) {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return domain.NewRecordSet(productSchema), nil
+}
+
+func TestServer(t *testing.T) {
+	t.Run("should transform the body with the named pipeline", func(t *testing.T) {
+		srv := NewServer()
+		srv.Register("products", newProductHandler())
+
+		rec := post(srv, "/pipelines/products", "text/csv", "", "name,quantity\nLaptop,1\n")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "name,quantity\nLaptop,2\n", rec.Body.String())
+	})
+
+	t.Run("should respond 404 for unknown pipelines", func(t *testing.T) {
+		srv := NewServer()
+
+		rec := post(srv, "/pipelines/products", "", "", "[]")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "unknown pipeline products", decodeError(t, rec).Error)
+	})
+
+	t.Run("should run the configured pipeline and respond its report", func(t *testing.T) {
+		srv := NewServer()
+		srv.Register("products", NewHandler(&pipeline.DataPipeline{
+			Source:    source.EmptySource{},
+			Transform: transform.EmptyTransform{},
+			Store:     store.EmptyStore{},
+		}, productSchema))
+
+		rec := post(srv, "/pipelines/products/run", "", "", "")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var report pipeline.RunReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Empty(t, report.Error)
+		assert.Len(t, report.Stages, 3)
+	})
+
+	t.Run("should respond the report of a failed run with its errors hidden", func(t *testing.T) {
+		var logs bytes.Buffer
+		srv := NewServer()
+		srv.Logger = logger.NewTextLogger(&logs)
+		srv.Register("products", NewHandler(&pipeline.DataPipeline{
+			Source:    source.EmptySource{},
+			Transform: transform.ErrorTransform{},
+			Store:     store.EmptyStore{},
+		}, productSchema))
+
+		rec := post(srv, "/pipelines/products/run", "", "", "")
+
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		var report pipeline.RunReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, "internal server error", report.Error)
+		require.Len(t, report.Stages, 2)
+		assert.Empty(t, report.Stages[0].Error)
+		assert.Equal(t, "internal server error", report.Stages[1].Error)
+		assert.NotContains(t, rec.Body.String(), "transform error")
+		assert.Contains(t, logs.String(), `msg="pipeline run failed" pipeline=products error="stage transform: transform error"`)
+	})
+
+	t.Run("should reject a run while the pipeline is running", func(t *testing.T) {
+		src := &blockingSource{started: make(chan struct{}), release: make(chan struct{})}
+		srv := NewServer()
+		srv.Register("products", NewHandler(&pipeline.DataPipeline{
+			Source:    src,
+			Transform: transform.EmptyTransform{},
+			Store:     store.EmptyStore{},
+		}, productSchema))
+		first := make(chan int)
+		go func() { first <- post(srv, "/pipelines/products/run", "", "", "").Code }()
+		<-src.started
+
+		rec := post(srv, "/pipelines/products/run", "", "", "")
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "pipeline products is already running", decodeError(t, rec).Error)
+		close(src.release)
+		assert.Equal(t, http.StatusOK, <-first)
+	})
+
+	t.Run("should reject other methods", func(t *testing.T) {
+		srv := NewServer()
+		srv.Register("products", newProductHandler())
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pipelines/products", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
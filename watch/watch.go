@@ -0,0 +1,258 @@
+// Package watch runs a pipeline for each file landing in a directory.
+//
+// A Watcher polls the directory, so that it works on every file system,
+// including network mounts. Once a new file has kept the same size and
+// modification time for StableFor, the pipeline runs with a source reading
+// that file, then the file is moved to the done or failed subdirectory:
+//
+//	w := watch.New("inbox", orders, schema)
+//	w.Include = []string{"*.json", "*.json.gz"}
+//	w.Run(ctx) // until ctx is cancelled
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/adapters/source"
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/logging"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+	"github.com/spaghettifactory-oss/pipeforge/scheduler"
+)
+
+// Default subdirectories receiving processed files.
+const (
+	DefaultDoneDir   = "done"
+	DefaultFailedDir = "failed"
+)
+
+// Watcher runs Pipeline for each stable file of Dir. Files are processed one
+// at a time, in name order. Subdirectories and files whose name starts with
+// "." are ignored, so that producers can write to a hidden temporary file
+// and rename it.
+type Watcher struct {
+	Dir       string                             // Directory watched, not recursively
+	Include   []string                           // Patterns the file name must match, empty matches every file
+	Pipeline  *pipeline.DataPipeline             // Pipeline run for each file, its Source being replaced by one reading the file
+	Open      func(path string) ports.SourcePort // Source reading a file, defaults to a JSONSource with Schema
+	Schema    *domain.DataSchema                 // Schema of the default JSONSource
+	Interval  time.Duration                      // Time between polls, one second if zero
+	StableFor time.Duration                      // Time a file must stay unchanged before it is processed
+	DoneDir   string                             // Subdirectory of Dir receiving files processed successfully, DefaultDoneDir if empty
+	FailedDir string                             // Subdirectory of Dir receiving files whose run failed, with a ".error" file holding the error; DefaultFailedDir if empty
+	Clock     scheduler.Clock                    // Time source, nil for the system clock
+	Logger    *slog.Logger                       // Receives the files processed and failed, nil disables logging
+
+	seen  map[string]observation // Files waiting to be stable
+	stuck map[string]bool        // Files processed but not moved, ignored until they change
+}
+
+// observation is the state of a file when it was last seen changing.
+type observation struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// New creates a Watcher running p for each JSON file landing in dir, once it
+// was unchanged for two seconds.
+func New(dir string, p *pipeline.DataPipeline, schema *domain.DataSchema) *Watcher {
+	return &Watcher{
+		Dir:       dir,
+		Pipeline:  p,
+		Schema:    schema,
+		Interval:  time.Second,
+		StableFor: 2 * time.Second,
+	}
+}
+
+// Run polls the directory until ctx is done. A file being processed when
+// ctx is done is finished first, the files left are processed by the next
+// Run. Failures to read the directory are logged
+// and retried on the next poll. A Watcher must not run twice at a time.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.Pipeline == nil {
+		return errors.New("watcher requires a pipeline")
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		if err := w.poll(ctx); err != nil {
+			logging.Or(w.Logger).Error("failed to poll directory", "dir", w.Dir, "error", err)
+		}
+		if err := w.clock().Sleep(ctx, interval); err != nil {
+			return nil
+		}
+	}
+}
+
+// poll processes the files of the directory that became stable, stopping
+// between files once ctx is done.
+func (w *Watcher) poll(ctx context.Context) error {
+	entries, err := os.ReadDir(w.Dir)
+	if err != nil {
+		return err
+	}
+	if w.seen == nil {
+		w.seen, w.stuck = map[string]observation{}, map[string]bool{}
+	}
+
+	now := w.clock().Now()
+	present := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || !w.included(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		present[name] = true
+
+		last, ok := w.seen[name]
+		if !ok || last.size != info.Size() || !last.modTime.Equal(info.ModTime()) {
+			w.seen[name] = observation{size: info.Size(), modTime: info.ModTime(), since: now}
+			delete(w.stuck, name)
+			continue
+		}
+		if w.stuck[name] || now.Sub(last.since) < w.StableFor {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if w.process(name) {
+			delete(w.seen, name)
+		} else {
+			w.stuck[name] = true
+		}
+	}
+
+	for name := range w.seen {
+		if !present[name] {
+			delete(w.seen, name)
+			delete(w.stuck, name)
+		}
+	}
+	return nil
+}
+
+// process runs the pipeline on a file and moves it, returning false when
+// the file could not be moved.
+func (w *Watcher) process(name string) bool {
+	file := filepath.Join(w.Dir, name)
+	logger := logging.Or(w.Logger).With("path", file)
+
+	run := *w.Pipeline
+	run.Source = w.open(file)
+	start := time.Now()
+	runErr := runPipeline(&run, logger)
+
+	target := w.DoneDir
+	if target == "" {
+		target = DefaultDoneDir
+	}
+	if runErr != nil {
+		target = w.FailedDir
+		if target == "" {
+			target = DefaultFailedDir
+		}
+	}
+	moved, err := move(file, filepath.Join(w.Dir, target))
+	if err == nil && runErr != nil {
+		err = os.WriteFile(moved+".error", []byte(runErr.Error()+"\n"), 0o644)
+	}
+	if err != nil {
+		logger.Error("failed to move file", "error", err)
+		return false
+	}
+
+	if runErr != nil {
+		logger.Error("file failed", "moved_to", moved, "duration", time.Since(start), "error", runErr)
+		return true
+	}
+	logger.Info("file processed", "moved_to", moved, "duration", time.Since(start))
+	return true
+}
+
+// runPipeline runs p. A panic is recovered and returned as the run error,
+// so that the file is moved to the failed directory instead of stopping the
+// watcher, which would panic again on the same file when restarted.
+func runPipeline(p *pipeline.DataPipeline, logger *slog.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("run panicked: %v", r)
+			logger.Error("run panicked", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	return p.Run()
+}
+
+// move moves file to dir, creating dir, and returns the new path. A number
+// is added to the name when dir already holds a file with the same name.
+func move(file, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := filepath.Base(file)
+	target := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	for i := 1; ; i++ {
+		_, err := os.Lstat(target)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		target = filepath.Join(dir, strings.TrimSuffix(name, ext)+"."+strconv.Itoa(i)+ext)
+	}
+	if err := os.Rename(file, target); err != nil {
+		return "", fmt.Errorf("failed to move file: %w", err)
+	}
+	return target, nil
+}
+
+func (w *Watcher) included(name string) bool {
+	if len(w.Include) == 0 {
+		return true
+	}
+	for _, pattern := range w.Include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) open(file string) ports.SourcePort {
+	if w.Open != nil {
+		return w.Open(file)
+	}
+	return &source.JSONSource{FilePath: file, Schema: w.Schema, Logger: w.Logger}
+}
+
+func (w *Watcher) clock() scheduler.Clock {
+	if w.Clock != nil {
+		return w.Clock
+	}
+	return scheduler.SystemClock
+}
@@ -0,0 +1,228 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spaghettifactory-oss/pipeforge/domain"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/clock"
+	"github.com/spaghettifactory-oss/pipeforge/internal/mock/transform"
+	"github.com/spaghettifactory-oss/pipeforge/pipeline"
+	"github.com/spaghettifactory-oss/pipeforge/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var productSchema = &domain.DataSchema{
+	ID: "Product",
+	Columns: []domain.SchemaColumn{
+		domain.SchemaColumnSingle{ID: "name", SchemaType: domain.NativeTypeString},
+	},
+}
+
+// recordingStore keeps the names stored, failing with err when set.
+type recordingStore struct {
+	names []string
+	err   error
+}
+
+func (s *recordingStore) Store(data *domain.RecordSet) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, r := range data.Records {
+		s.names = append(s.names, r.GetString("name"))
+	}
+	return nil
+}
+
+// panicStore panics on every call.
+type panicStore struct{}
+
+func (panicStore) Store(*domain.RecordSet) error { panic("boom") }
+
+// newWatcher returns a Watcher of a temporary directory with a fake clock.
+func newWatcher(t *testing.T) (*Watcher, *recordingStore, *clock.Clock) {
+	t.Helper()
+	store := &recordingStore{}
+	p := &pipeline.DataPipeline{Transform: &transform.EmptyTransform{}, Store: store}
+	w := New(t.TempDir(), p, productSchema)
+	clk := clock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w.Clock = clk
+	return w, store, clk
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	t.Run("should process files once stable and move them to done", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		writeFile(t, filepath.Join(w.Dir, "products.json"), `[{"name": "Laptop"}]`)
+
+		require.NoError(t, w.poll(context.Background()))
+		clk.Advance(time.Second)
+		require.NoError(t, w.poll(context.Background()))
+		assert.Empty(t, store.names)
+
+		clk.Advance(time.Second)
+		require.NoError(t, w.poll(context.Background()))
+
+		assert.Equal(t, []string{"Laptop"}, store.names)
+		assert.NoFileExists(t, filepath.Join(w.Dir, "products.json"))
+		assert.FileExists(t, filepath.Join(w.Dir, "done", "products.json"))
+	})
+
+	t.Run("should wait while a file keeps changing", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		path := filepath.Join(w.Dir, "products.json")
+		writeFile(t, path, `[{"name": "Laptop"}`)
+		require.NoError(t, w.poll(context.Background()))
+
+		clk.Advance(5 * time.Second)
+		writeFile(t, path, `[{"name": "Laptop"}]`)
+		require.NoError(t, w.poll(context.Background()))
+		assert.Empty(t, store.names)
+
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(context.Background()))
+		assert.Equal(t, []string{"Laptop"}, store.names)
+	})
+
+	t.Run("should move failed files with their error", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		store.err = errors.New("disk full")
+		writeFile(t, filepath.Join(w.Dir, "products.json"), `[{"name": "Laptop"}]`)
+
+		require.NoError(t, w.poll(context.Background()))
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(context.Background()))
+
+		assert.FileExists(t, filepath.Join(w.Dir, "failed", "products.json"))
+		data, err := os.ReadFile(filepath.Join(w.Dir, "failed", "products.json.error"))
+		require.NoError(t, err)
+		assert.Equal(t, "stage store: disk full\n", string(data))
+	})
+
+	t.Run("should move files whose run panicked to failed", func(t *testing.T) {
+		w, _, clk := newWatcher(t)
+		w.Pipeline.Store = panicStore{}
+		writeFile(t, filepath.Join(w.Dir, "products.json"), `[{"name": "Laptop"}]`)
+
+		require.NoError(t, w.poll(context.Background()))
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(context.Background()))
+
+		assert.NoFileExists(t, filepath.Join(w.Dir, "products.json"))
+		data, err := os.ReadFile(filepath.Join(w.Dir, "failed", "products.json.error"))
+		require.NoError(t, err)
+		assert.Equal(t, "run panicked: boom\n", string(data))
+	})
+
+	t.Run("should number files already processed under the same name", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		require.NoError(t, os.Mkdir(filepath.Join(w.Dir, "done"), 0o755))
+		writeFile(t, filepath.Join(w.Dir, "done", "products.json"), `[]`)
+		writeFile(t, filepath.Join(w.Dir, "products.json"), `[{"name": "Phone"}]`)
+
+		require.NoError(t, w.poll(context.Background()))
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(context.Background()))
+
+		assert.Equal(t, []string{"Phone"}, store.names)
+		assert.FileExists(t, filepath.Join(w.Dir, "done", "products.1.json"))
+	})
+
+	t.Run("should only process included files", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		w.Include = []string{"*.json"}
+		writeFile(t, filepath.Join(w.Dir, "products.json"), `[{"name": "Laptop"}]`)
+		writeFile(t, filepath.Join(w.Dir, "products.csv"), `name`)
+		writeFile(t, filepath.Join(w.Dir, ".upload.json"), `[{"name": "Phone"}]`)
+
+		require.NoError(t, w.poll(context.Background()))
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(context.Background()))
+
+		assert.Equal(t, []string{"Laptop"}, store.names)
+		assert.FileExists(t, filepath.Join(w.Dir, "products.csv"))
+		assert.FileExists(t, filepath.Join(w.Dir, ".upload.json"))
+	})
+
+	t.Run("should bind the source built by Open to the file", func(t *testing.T) {
+		w, _, clk := newWatcher(t)
+		var opened []string
+		w.Open = func(path string) ports.SourcePort {
+			opened = append(opened, path)
+			return &emptySource{}
+		}
+		writeFile(t, filepath.Join(w.Dir, "a.json"), `x`)
+		writeFile(t, filepath.Join(w.Dir, "b.json"), `x`)
+
+		require.NoError(t, w.poll(context.Background()))
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(context.Background()))
+
+		assert.Equal(t, []string{filepath.Join(w.Dir, "a.json"), filepath.Join(w.Dir, "b.json")}, opened)
+		assert.Nil(t, w.Pipeline.Source)
+	})
+
+	t.Run("should return error for a missing directory", func(t *testing.T) {
+		w, _, _ := newWatcher(t)
+		w.Dir = filepath.Join(w.Dir, "missing")
+
+		assert.Error(t, w.poll(context.Background()))
+	})
+}
+
+type emptySource struct{}
+
+func (emptySource) Load() (*domain.RecordSet, error) { return domain.NewRecordSet(nil), nil }
+
+func TestWatcher_Run(t *testing.T) {
+	t.Run("should poll until the context is cancelled", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		writeFile(t, filepath.Join(w.Dir, "products.json"), `[{"name": "Laptop"}]`)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- w.Run(ctx) }()
+
+		for range 3 {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+		}
+		clk.BlockUntil(1)
+		cancel()
+
+		require.NoError(t, <-done)
+		assert.Equal(t, []string{"Laptop"}, store.names)
+	})
+
+	t.Run("should stop between files once the context is cancelled", func(t *testing.T) {
+		w, store, clk := newWatcher(t)
+		writeFile(t, filepath.Join(w.Dir, "a.json"), `[{"name": "Laptop"}]`)
+		writeFile(t, filepath.Join(w.Dir, "b.json"), `[{"name": "Phone"}]`)
+		ctx, cancel := context.WithCancel(context.Background())
+		w.Pipeline.Use(pipeline.Hooks{After: func(ports.StageEvent) { cancel() }}.Middleware())
+
+		require.NoError(t, w.poll(ctx))
+		clk.Advance(2 * time.Second)
+		require.NoError(t, w.poll(ctx))
+
+		assert.Equal(t, []string{"Laptop"}, store.names)
+		assert.FileExists(t, filepath.Join(w.Dir, "b.json"))
+	})
+
+	t.Run("should require a pipeline", func(t *testing.T) {
+		err := (&Watcher{Dir: t.TempDir()}).Run(context.Background())
+
+		assert.EqualError(t, err, "watcher requires a pipeline")
+	})
+}